@@ -1,93 +1,368 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
+	"database/sql"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"restapi/internal/api/handlers"
 	mw "restapi/internal/api/middlewares"
 	"restapi/internal/api/router"
+	"restapi/internal/config"
+	"restapi/internal/health"
+	"restapi/internal/metrics"
+	"restapi/internal/models"
+	"restapi/internal/reports"
+	"restapi/internal/repository/sqlconnect"
+	"restapi/internal/tracing"
 	"restapi/pkg/utils"
 
-	"github.com/joho/godotenv"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
 func main() {
-	// Load .env from current working directory (project root)
-	// If .env not found — continue and rely on OS env vars
-	if err := godotenv.Load(); err != nil {
-		log.Println("Warning: .env file not found. Using OS environment variables.")
+	configPath := flag.String("config", os.Getenv("CONFIG_FILE"), "path to an optional YAML or TOML config file")
+	printConfig := flag.Bool("print-config", false, "print the effective configuration with secrets redacted and exit")
+	flag.Parse()
+
+	// Defaults < config file < .env < OS environment; fails fast on missing secrets
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalln("Invalid configuration:", err)
+	}
+
+	if *printConfig {
+		out, err := cfg.Redacted().YAML()
+		if err != nil {
+			log.Fatalln("Error printing configuration:", err)
+		}
+		fmt.Print(out)
+		return
 	}
 
-	port := os.Getenv("API_PORT")
-	if port == "" {
-		// sensible default
-		port = ":3000"
+	utils.SetJWTConfig(cfg.JWT.Secret, cfg.JWT.ExpiresIn)
+	sqlconnect.Configure(cfg)
+	if err := reports.Configure(cfg.Reports); err != nil {
+		log.Fatalln("Error loading the report card font:", err)
 	}
 
-	cert := os.Getenv("CERT_FILE")
-	key := os.Getenv("KEY_FILE")
+	port := cfg.Server.Port
+	cert := cfg.Server.CertFile
+	key := cfg.Server.KeyFile
 
 	// If you run HTTPS/TLS, you must provide cert & key
 	if cert == "" || key == "" {
 		log.Println("Warning: CERT_FILE or KEY_FILE is empty. Server will start WITHOUT TLS.")
 	}
 
+	// Tracing: exporter is chosen by tracing.exporter / OTEL_TRACES_EXPORTER (otlp, stdout, none)
+	shutdownTracing, err := tracing.Init(context.Background(), cfg.Tracing)
+	if err != nil {
+		log.Fatalln("Error initializing tracing:", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	// Shared DB connection pool
+	if err := sqlconnect.InitDBPool(); err != nil {
+		log.Fatalln("Error connecting to the database:", err)
+	}
+	defer sqlconnect.CloseDBPool()
+
+	db, err := sqlconnect.GetDB()
+	if err != nil {
+		log.Fatalln("Error connecting to the database:", err)
+	}
+	if err := sqlconnect.EnsureSchema(context.Background()); err != nil {
+		log.Fatalln("Error creating database tables:", err)
+	}
+	if err := metrics.RegisterDBStats(db, cfg.DB.Name); err != nil {
+		log.Println("Warning: could not register DB pool metrics:", err)
+	}
+
 	// Safer minimum TLS version
 	tlsConfig := &tls.Config{
 		MinVersion: tls.VersionTLS12,
 	}
 
-	// HPP options (example)
+	// Router
+	r := router.MainRouter()
+
+	// HPP: each route accepts exactly the query params it declares in the route
+	// table (list endpoints: their filter/sort schema), others take none
 	hppOptions := mw.HPPOptions{
 		CheckQuery:                  true,
 		CheckBody:                   true,
 		CheckBodyOnlyForContentType: "application/x-www-form-urlencoded",
-		Whitelist:                   []string{"sortBy", "sortOrder", "name", "age", "class"},
+		CheckJSONBody:               true,
+		MultiValued:                 []string{"sortby"},
+		Lookup:                      r.Policy,
+		ReportOnly:                  cfg.HPP.ReportOnly,
 	}
 
-	// Router
-	r := router.MainRouter()
-
-	// JWT middleware excluded paths (public routes)
-	jwtMiddleware := mw.MiddlewaresExcludePaths(
-		mw.JWTMiddleware,
-		"/execs/login",
-		"/execs/forgotpassword",
-		"/execs/resetpassword/reset",
-	)
+	// Routes declared Public in the route table skip JWT
+	jwtMiddleware := mw.SkipPublic(mw.JWTMiddleware, r.Policy)
 
-	// Apply middlewares
-	secureMux := utils.ApplyMiddlewares(
-		r,
+	// Rate limiter runs after JWT so it can key on the authenticated user
+	middlewares := []utils.Middleware{
 		mw.SecurityHeaders,
-		mw.Compression,
+		mw.CompressionWithOptions(mw.CompressionOptions{
+			MinSize:      cfg.Compression.MinSize,
+			ContentTypes: cfg.Compression.ContentTypes,
+		}),
+		mw.Sanitize(sanitizeOptions()),
+		// Runs before Sanitize re-encodes the body and loses duplicate keys
 		mw.Hpp(hppOptions),
-		mw.XSSMiddleware,
+		// Caps the body before Sanitize and the handlers read it
+		mw.RequestLimits(requestLimitOptions(cfg.Limits, r.Policy)),
+	}
+	if cfg.RateLimit.Enabled {
+		store, err := rateLimitStore(cfg.RateLimit, db)
+		if err != nil {
+			log.Fatalln("Error initializing the rate limit store:", err)
+		}
+		rateLimiter := mw.NewRateLimiterWithStore(store, rateLimiterOptions(cfg.RateLimit, r.Policy))
+		defer rateLimiter.Stop()
+		middlewares = append(middlewares, rateLimiter.Middleware)
+	}
+	middlewares = append(middlewares,
 		jwtMiddleware,
 		mw.ResponseTimeMiddleware,
-		mw.Cors,
+		mw.CORS(corsOptions(cfg.CORS)),
 	)
 
+	// Apply middlewares
+	secureMux := utils.ApplyMiddlewares(r, middlewares...)
+
+	// Readiness checks
+	health.Register("database", sqlconnect.PingDb)
+	health.Register("schema", sqlconnect.CheckSchema)
+	health.Register("mailer", sqlconnect.CheckMailer)
+
+	// Probes and /metrics are served outside the middleware chain so
+	// orchestrators and scrapers don't need a JWT cookie or an Origin header
+	rootMux := http.NewServeMux()
+	rootMux.Handle("GET /metrics", metrics.Handler())
+	rootMux.HandleFunc("GET /healthz", health.LivenessHandler)
+	rootMux.HandleFunc("GET /readyz", health.ReadinessHandler)
+	rootMux.HandleFunc("GET /version", health.VersionHandler)
+	rootMux.Handle("/", otelhttp.NewHandler(secureMux, "http.server"))
+
+	// Recovery sits above everything, probes included, and tags its logs with the request id
+	handler := mw.RequestID(mw.Recovery(mw.RecoveryOptions{CrashReportDir: cfg.Server.CrashReportDir})(rootMux))
+
+	// Timeouts guard against slowloris-style clients holding connections open
 	server := &http.Server{
-		Addr:      port,
-		Handler:   secureMux,
-		TLSConfig: tlsConfig,
+		Addr:              port,
+		Handler:           handler,
+		TLSConfig:         tlsConfig,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+		MaxHeaderBytes:    cfg.Server.MaxHeaderBytes,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		fmt.Println("Server is running on:", port)
+
+		// Start server: prefer TLS if cert & key are provided
+		if cert != "" && key != "" {
+			serverErr <- server.ListenAndServeTLS(cert, key)
+		} else {
+			serverErr <- server.ListenAndServe()
+		}
+	}()
+
+	select {
+	case err := <-serverErr:
+		if err != nil && err != http.ErrServerClosed {
+			log.Fatalln("Error starting the server:", err)
+		}
+		return
+	case <-ctx.Done():
+	}
+	stop()
+
+	// Fail readiness first and keep serving for the readiness delay so the
+	// load balancer stops sending new requests, then let in-flight requests
+	// finish within the drain period
+	log.Println("Shutting down: draining in-flight requests...")
+	health.MarkShuttingDown()
+	time.Sleep(cfg.Server.ReadinessDelay)
+
+	drainCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownDrain)
+	defer cancel()
+
+	if err := server.Shutdown(drainCtx); err != nil {
+		log.Println("Error during shutdown, closing remaining connections:", err)
+		server.Close()
 	}
 
-	fmt.Println("Server is running on:", port)
+	// Mail started by handlers still needs the DB pool, closed on return
+	backgroundCtx, cancelBackground := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancelBackground()
+	if !handlers.WaitBackground(backgroundCtx) {
+		log.Println("Background work did not finish before shutdown")
+	}
+	log.Println("Server stopped")
+}
 
-	// Start server: prefer TLS if cert & key are provided
-	var err error
-	if cert != "" && key != "" {
-		err = server.ListenAndServeTLS(cert, key)
-	} else {
-		err = server.ListenAndServe()
+// rateLimitStore picks where buckets live. The sql and redis stores share
+// limits between instances; memory keeps them per process.
+func rateLimitStore(cfg config.RateLimitConfig, db *sql.DB) (mw.LimiterStore, error) {
+	switch cfg.Store {
+	case "sql":
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return mw.NewSQLLimiterStore(ctx, db)
+	case "redis":
+		client := redis.NewClient(&redis.Options{
+			Addr:     cfg.Redis.Addr,
+			Password: cfg.Redis.Password,
+			DB:       cfg.Redis.DB,
+		})
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := client.Ping(ctx).Err(); err != nil {
+			client.Close()
+			return nil, err
+		}
+		return mw.NewRedisLimiterStore(client), nil
+	default:
+		return mw.NewMemoryLimiterStore(), nil
 	}
+}
+
+// sanitizeOptions binds request bodies to their models so each field gets the
+// policy from its `sanitize` tag. Imports may also be posted as CSV.
+func sanitizeOptions() mw.SanitizeOptions {
+	imports := []string{"text/csv", "multipart/form-data"}
+	return mw.SanitizeOptions{
+		Routes: []mw.SanitizeRoute{
+			{Pattern: "/execs", Model: models.Exec{}},
+			{Pattern: "/execs/{id}", Model: models.Exec{}},
+			{Pattern: "/execs/login", Model: models.Exec{}},
+			{Pattern: "/execs/forgotpassword", Model: models.Exec{}},
+			{Pattern: "/execs/{id}/updatepassword", Model: models.UpdatePasswordRequest{}},
+			{Pattern: "/execs/resetpassword/reset/{resetcode}", Model: models.ResetPasswordRequest{}},
+			{Pattern: "POST /students", Model: models.Student{}, ContentTypes: imports},
+			{Pattern: "/students", Model: models.Student{}},
+			{Pattern: "/students/{id}", Model: models.Student{}},
+			{Pattern: "POST /teachers", Model: models.Teacher{}, ContentTypes: imports},
+			{Pattern: "/teachers", Model: models.Teacher{}},
+			{Pattern: "/teachers/{id}", Model: models.Teacher{}},
+			{Pattern: "/attendance", Model: models.Attendance{}},
+			{Pattern: "/attendance/{id}", Model: models.Attendance{}},
+			{Pattern: "/assessments", Model: models.Assessment{}},
+			{Pattern: "/assessments/{id}", Model: models.Assessment{}},
+			{Pattern: "/assessments/{id}/scores", Model: models.Score{}},
+			{Pattern: "/students/{id}/report-comments", Model: models.ReportComment{}},
+			{Pattern: "POST /guardians", Model: models.Guardian{}, ContentTypes: imports},
+			{Pattern: "/guardians", Model: models.Guardian{}},
+			{Pattern: "/guardians/{id}", Model: models.Guardian{}},
+			{Pattern: "/students/{id}/guardians/{guardianId}", Model: models.GuardianLink{}},
+			{Pattern: "/years", Model: models.AcademicYear{}},
+			{Pattern: "/years/{id}", Model: models.AcademicYear{}},
+			{Pattern: "/years/{id}/terms", Model: models.Term{}},
+			{Pattern: "/terms/{id}", Model: models.Term{}},
+			{Pattern: "/periods", Model: models.Period{}},
+			{Pattern: "/periods/{id}", Model: models.Period{}},
+			{Pattern: "/rooms", Model: models.Room{}},
+			{Pattern: "/rooms/{id}", Model: models.Room{}},
+			{Pattern: "/timetable/slots", Model: models.TimetableSlot{}},
+			{Pattern: "/timetable/slots/{id}", Model: models.TimetableSlot{}},
+			{Pattern: "/teachers/{id}/unavailability", Model: models.Unavailability{}},
+			{Pattern: "/leave-requests", Model: models.LeaveRequest{}},
+			{Pattern: "/leave-requests/{id}/decision", Model: models.LeaveDecision{}},
+			{Pattern: "/leave-requests/{id}/substitutions", Model: models.Substitution{}},
+			{Pattern: "/leads", Model: models.Lead{}},
+			{Pattern: "/leads/{id}", Model: models.Lead{}},
+			{Pattern: "/leads/{id}/guardians", Model: models.LeadGuardian{}},
+			{Pattern: "/leads/{id}/stage", Model: models.StageMove{}},
+			{Pattern: "/leads/{id}/activities", Model: models.LeadActivity{}},
+			{Pattern: "/leads/{id}/convert", Model: models.LeadConversion{}},
+			{Pattern: "/notes/{id}", Model: models.Note{}},
+			{Pattern: "/students/{id}/notes", Model: models.Note{}},
+			{Pattern: "/teachers/{id}/notes", Model: models.Note{}},
+			{Pattern: "/guardians/{id}/notes", Model: models.Note{}},
+			{Pattern: "/custom-fields", Model: models.CustomField{}},
+			{Pattern: "/custom-fields/{id}", Model: models.CustomField{}},
+		},
+	}
+}
 
-	if err != nil && err != http.ErrServerClosed {
-		log.Fatalln("Error starting the server:", err)
+func rateLimiterOptions(cfg config.RateLimitConfig, lookup mw.RouteLookup) mw.RateLimiterOptions {
+	options := mw.RateLimiterOptions{
+		Default:        mw.RateLimitPolicy{Limit: cfg.Default.Limit, Window: cfg.Default.Window},
+		Classes:        make(map[string]mw.RateLimitPolicy, len(cfg.Classes)),
+		Lookup:         lookup,
+		Roles:          make(map[string]mw.RateLimitPolicy, len(cfg.Roles)),
+		TrustedProxies: cfg.TrustedProxies,
+	}
+	for class, rule := range cfg.Classes {
+		options.Classes[class] = mw.RateLimitPolicy{Limit: rule.Limit, Window: rule.Window}
+	}
+	for role, rule := range cfg.Roles {
+		options.Roles[role] = mw.RateLimitPolicy{Limit: rule.Limit, Window: rule.Window}
+	}
+	for _, route := range cfg.Routes {
+		options.Routes = append(options.Routes, mw.RouteRateLimit{
+			Method: route.Method,
+			Path:   route.Path,
+			Policy: mw.RateLimitPolicy{Limit: route.Limit, Window: route.Window},
+		})
+	}
+	return options
+}
+
+func corsOptions(cfg config.CORSConfig) mw.CORSOptions {
+	options := corsRuleOptions(cfg.CORSRule)
+	for _, route := range cfg.Routes {
+		options.Routes = append(options.Routes, mw.RouteCORS{
+			Path:    route.Path,
+			Options: corsRuleOptions(route.CORSRule),
+		})
+	}
+	return options
+}
+
+func corsRuleOptions(rule config.CORSRule) mw.CORSOptions {
+	return mw.CORSOptions{
+		AllowedOrigins:   rule.AllowedOrigins,
+		AllowedMethods:   rule.AllowedMethods,
+		AllowedHeaders:   rule.AllowedHeaders,
+		ExposedHeaders:   rule.ExposedHeaders,
+		AllowCredentials: rule.AllowCredentials,
+		MaxAge:           rule.MaxAge,
+	}
+}
+
+func requestLimitOptions(cfg config.LimitsConfig, lookup mw.RouteLookup) mw.RequestLimitOptions {
+	options := mw.RequestLimitOptions{
+		MaxBodyBytes: cfg.MaxBodyBytes,
+		Timeout:      cfg.Timeout,
+		Lookup:       lookup,
+	}
+	for _, route := range cfg.Routes {
+		options.Routes = append(options.Routes, mw.RouteRequestLimit{
+			Method:       route.Method,
+			Path:         route.Path,
+			MaxBodyBytes: route.MaxBodyBytes,
+			Timeout:      route.Timeout,
+		})
 	}
+	return options
 }
@@ -0,0 +1,375 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"restapi/internal/models"
+	"restapi/internal/repository/sqlconnect"
+	"strings"
+)
+
+func GetYearsHandler(w http.ResponseWriter, r *http.Request) {
+	years, err := sqlconnect.GetYears(r.Context())
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string                `json:"status"`
+		Count  int                   `json:"count"`
+		Data   []models.AcademicYear `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(years),
+		Data:   years,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func GetOneYearHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid Year Id", http.StatusBadRequest)
+		return
+	}
+
+	year, err := sqlconnect.GetYearByID(r.Context(), id)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(year)
+}
+
+func AddYearHandler(w http.ResponseWriter, r *http.Request) {
+	var year models.AcademicYear
+	if err := json.NewDecoder(r.Body).Decode(&year); err != nil {
+		log.Println(err)
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	year.ID = 0
+
+	if err := validatePeriod(&year.Name, &year.StartDate, &year.EndDate); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	saved, err := sqlconnect.SaveYear(r.Context(), year)
+	if errors.Is(err, sqlconnect.ErrYearOverlap) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(saved)
+}
+
+// PatchYearHandler applies the fields present in the body; "current": true
+// makes the year the current one.
+func PatchYearHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid Year Id", http.StatusBadRequest)
+		return
+	}
+
+	year, err := sqlconnect.GetYearByID(r.Context(), id)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusNotFound)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&year); err != nil {
+		log.Println(err)
+		http.Error(w, "Invalid Request Payload", http.StatusBadRequest)
+		return
+	}
+	year.ID = id
+
+	if err := validatePeriod(&year.Name, &year.StartDate, &year.EndDate); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	saved, err := sqlconnect.SaveYear(r.Context(), year)
+	if errors.Is(err, sqlconnect.ErrYearOverlap) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(saved)
+}
+
+// validatePeriod checks the name and dates shared by years and terms.
+func validatePeriod(name, start, end *string) error {
+	*name = strings.TrimSpace(*name)
+	if *name == "" {
+		return errors.New("name is required")
+	}
+	var err error
+	if *start, err = parseDate(*start); err != nil {
+		return err
+	}
+	if *end, err = parseDate(*end); err != nil {
+		return err
+	}
+	if *start >= *end {
+		return errors.New("start_date must be before end_date")
+	}
+	return nil
+}
+
+func GetTermsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid Year Id", http.StatusBadRequest)
+		return
+	}
+
+	terms, err := sqlconnect.GetTerms(r.Context(), id)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string        `json:"status"`
+		Count  int           `json:"count"`
+		Data   []models.Term `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(terms),
+		Data:   terms,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func AddTermHandler(w http.ResponseWriter, r *http.Request) {
+	yearID, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid Year Id", http.StatusBadRequest)
+		return
+	}
+
+	var term models.Term
+	if err := json.NewDecoder(r.Body).Decode(&term); err != nil {
+		log.Println(err)
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	term.ID = 0
+	term.YearID = yearID
+
+	saveTerm(w, r, term, http.StatusCreated)
+}
+
+// PatchTermHandler applies the fields present in the body to the term.
+func PatchTermHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid Term Id", http.StatusBadRequest)
+		return
+	}
+
+	term, err := sqlconnect.GetTermByID(r.Context(), id)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusNotFound)
+		return
+	}
+	yearID := term.YearID
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&term); err != nil {
+		log.Println(err)
+		http.Error(w, "Invalid Request Payload", http.StatusBadRequest)
+		return
+	}
+	// A term stays in its year
+	term.ID, term.YearID = id, yearID
+
+	saveTerm(w, r, term, http.StatusOK)
+}
+
+func saveTerm(w http.ResponseWriter, r *http.Request, term models.Term, status int) {
+	if err := validatePeriod(&term.Name, &term.StartDate, &term.EndDate); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	saved, err := sqlconnect.SaveTerm(r.Context(), term)
+	if errors.Is(err, sqlconnect.ErrPeriodOverlap) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(saved)
+}
+
+func DeleteTermHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid Term Id", http.StatusBadRequest)
+		return
+	}
+
+	if err := sqlconnect.DeleteTerm(r.Context(), id); err != nil {
+		dbErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string `json:"status"`
+		ID     int    `json:"id"`
+	}{
+		Status: "Term successfully deleted",
+		ID:     id,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// SyncEnrollmentsHandler records every student's current class for the year.
+func SyncEnrollmentsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid Year Id", http.StatusBadRequest)
+		return
+	}
+
+	changed, err := sqlconnect.SyncEnrollments(r.Context(), id)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status  string `json:"status"`
+		YearID  int    `json:"year_id"`
+		Changed int    `json:"changed"`
+	}{
+		Status:  "success",
+		YearID:  id,
+		Changed: changed,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetYearClassHandler lists a class as it was enrolled during the year.
+func GetYearClassHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid Year Id", http.StatusBadRequest)
+		return
+	}
+
+	enrollments, err := sqlconnect.GetYearClass(r.Context(), id, r.PathValue("class"))
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string              `json:"status"`
+		Count  int                 `json:"count"`
+		Data   []models.Enrollment `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(enrollments),
+		Data:   enrollments,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func GetStudentEnrollmentsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid Student Id", http.StatusBadRequest)
+		return
+	}
+
+	enrollments, err := sqlconnect.GetStudentEnrollments(r.Context(), id)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string              `json:"status"`
+		Count  int                 `json:"count"`
+		Data   []models.Enrollment `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(enrollments),
+		Data:   enrollments,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// PromoteHandler moves the year's students into the next year, or with
+// "dry_run": true only returns what would happen.
+func PromoteHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid Year Id", http.StatusBadRequest)
+		return
+	}
+
+	var req models.PromotionRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		log.Println(err)
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if req.ToYearID <= 0 || req.ToYearID == id {
+		http.Error(w, "to_year_id must name another academic year", http.StatusBadRequest)
+		return
+	}
+	if req.FinalGrade == 0 {
+		req.FinalGrade = sqlconnect.FinalGrade()
+	}
+
+	plan, err := sqlconnect.PromoteYear(r.Context(), id, req)
+	if errors.Is(err, sqlconnect.ErrAlreadyPromoted) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(plan)
+}
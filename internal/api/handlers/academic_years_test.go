@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestValidatePeriod(t *testing.T) {
+	tests := []struct {
+		name       string
+		start, end string
+		title      string
+		wantErr    bool
+	}{
+		{"valid year", "2024-09-01", "2025-06-30", "2024/25", false},
+		{"missing name", "2024-09-01", "2025-06-30", " ", true},
+		{"bad date", "1 Sep 2024", "2025-06-30", "2024/25", true},
+		{"end before start", "2025-06-30", "2024-09-01", "2024/25", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePeriod(&tt.title, &tt.start, &tt.end)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validatePeriod() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRequestPeriodWithoutTerm(t *testing.T) {
+	// Без term_id и year_id работает обычный диапазон from/to
+	p, err := requestPeriod(httptest.NewRequest("GET", "/students/1/grades?from=2024-09-01&to=2024-12-31", nil), 30)
+	if err != nil || p.From != "2024-09-01" || p.To != "2024-12-31" || p.YearID != 0 {
+		t.Errorf("requestPeriod() = %+v, %v", p, err)
+	}
+
+	if _, err := requestPeriod(httptest.NewRequest("GET", "/students/1/grades?term_id=first", nil), 30); err == nil {
+		t.Error("expected an error for a non-numeric term_id")
+	}
+}
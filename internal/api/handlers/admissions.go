@@ -0,0 +1,506 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"restapi/internal/models"
+	"restapi/internal/repository/sqlconnect"
+	"slices"
+	"strings"
+)
+
+// GetAdmissionStagesHandler lists the configured pipeline stages in order.
+func GetAdmissionStagesHandler(w http.ResponseWriter, r *http.Request) {
+	pipeline := sqlconnect.Pipeline()
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string                  `json:"status"`
+		Count  int                     `json:"count"`
+		Data   []models.AdmissionStage `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(pipeline),
+		Data:   pipeline,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetAdmissionMetricsHandler reports the funnel of the leads created over the
+// term_id, year_id or from/to period, by default the last 365 days.
+func GetAdmissionMetricsHandler(w http.ResponseWriter, r *http.Request) {
+	p, err := requestPeriod(r, 365)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	metrics, err := sqlconnect.GetAdmissionMetrics(r.Context(), p.From, p.To)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(metrics)
+}
+
+func GetLeadsHandler(w http.ResponseWriter, r *http.Request) {
+	page, limit := getPaginationParams(r)
+	leads, total, err := sqlconnect.GetLeads(r.Context(), r, limit, page)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	response := struct {
+		Status   string        `json:"status"`
+		Count    int           `json:"count"`
+		Page     int           `json:"page"`
+		PageSize int           `json:"page_size"`
+		Data     []models.Lead `json:"data"`
+	}{
+		Status:   "success",
+		Count:    total,
+		Page:     page,
+		PageSize: limit,
+		Data:     leads,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func GetOneLeadHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid Lead Id", http.StatusBadRequest)
+		return
+	}
+
+	lead, err := sqlconnect.GetLeadByID(r.Context(), id)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(lead)
+}
+
+// AddLeadHandler records an inquiry. The lead starts in the first stage unless
+// another open stage is given; it is assigned separately.
+func AddLeadHandler(w http.ResponseWriter, r *http.Request) {
+	var lead models.Lead
+	if err := json.NewDecoder(r.Body).Decode(&lead); err != nil {
+		log.Println(err)
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if err := validateLead(&lead); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := validateLeadGuardians(lead.Guardians); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	pipeline := sqlconnect.Pipeline()
+	if lead.Stage == "" {
+		lead.Stage = pipeline.Initial()
+	} else if stage, ok := pipeline.Stage(lead.Stage); !ok || stage.Outcome != models.OutcomeOpen {
+		http.Error(w, "stage must be an open stage", http.StatusBadRequest)
+		return
+	}
+	lead.AssignedTo = 0
+	lead.StudentID = 0
+
+	added, err := sqlconnect.AddLead(r.Context(), lead, currentUserID(r))
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(added)
+}
+
+// validateLead requires a name and at least one way to reach the family.
+func validateLead(l *models.Lead) error {
+	l.FirstName = strings.TrimSpace(l.FirstName)
+	l.LastName = strings.TrimSpace(l.LastName)
+	l.Email = strings.TrimSpace(l.Email)
+	l.Phone = strings.TrimSpace(l.Phone)
+	l.DesiredClass = strings.TrimSpace(l.DesiredClass)
+	l.Source = strings.ToLower(strings.TrimSpace(l.Source))
+	if l.FirstName == "" || l.LastName == "" {
+		return errors.New("first_name and last_name are required")
+	}
+	if l.Phone == "" && l.Email == "" && !slices.ContainsFunc(l.Guardians, func(g models.LeadGuardian) bool {
+		return g.Phone != "" || g.Email != ""
+	}) {
+		return errors.New("phone or email of the lead or a guardian is required")
+	}
+	if l.Email != "" && !strings.Contains(l.Email, "@") {
+		return errors.New("invalid email")
+	}
+	return nil
+}
+
+// validateLeadGuardians checks the guardians the way guardians of students
+// are checked; the relationship defaults to parent.
+func validateLeadGuardians(guardians []models.LeadGuardian) error {
+	primary := 0
+	for i := range guardians {
+		g := &guardians[i]
+		guardian := models.Guardian{FirstName: g.FirstName, LastName: g.LastName, Phone: g.Phone, Email: g.Email}
+		if err := validateGuardian(&guardian); err != nil {
+			return err
+		}
+		g.FirstName, g.LastName, g.Phone, g.Email = guardian.FirstName, guardian.LastName, guardian.Phone, guardian.Email
+
+		g.Relationship = strings.ToLower(strings.TrimSpace(g.Relationship))
+		if g.Relationship == "" {
+			g.Relationship = "parent"
+		}
+		if !slices.Contains(models.GuardianRelationships, g.Relationship) {
+			return errors.New("relationship must be one of " + strings.Join(models.GuardianRelationships, ", "))
+		}
+		if g.Primary {
+			primary++
+		}
+	}
+	if primary > 1 {
+		return errors.New("only one guardian can be the primary contact")
+	}
+	return nil
+}
+
+// PatchLeadHandler applies the contact fields present in the body; stage,
+// assignee and guardians have their own endpoints.
+func PatchLeadHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid Lead Id", http.StatusBadRequest)
+		return
+	}
+
+	lead, err := sqlconnect.GetLeadByID(r.Context(), id)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusNotFound)
+		return
+	}
+
+	original := lead
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&lead); err != nil {
+		log.Println(err)
+		http.Error(w, "Invalid Request Payload", http.StatusBadRequest)
+		return
+	}
+	if lead.Stage != original.Stage || lead.AssignedTo != original.AssignedTo || lead.StudentID != original.StudentID ||
+		!slices.Equal(lead.Guardians, original.Guardians) {
+		http.Error(w, "stage, assignee and guardians are changed through their own endpoints", http.StatusBadRequest)
+		return
+	}
+	lead.ID = id
+
+	if err := validateLead(&lead); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	updated, err := sqlconnect.UpdateLead(r.Context(), lead)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+func DeleteLeadHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid Lead Id", http.StatusBadRequest)
+		return
+	}
+
+	if err := sqlconnect.DeleteLead(r.Context(), id); err != nil {
+		dbErrorResponse(w, err, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string `json:"status"`
+		ID     int    `json:"id"`
+	}{
+		Status: "Lead successfully deleted",
+		ID:     id,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// SetLeadGuardiansHandler replaces the guardians of a lead with the array in
+// the body.
+func SetLeadGuardiansHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid Lead Id", http.StatusBadRequest)
+		return
+	}
+
+	var guardians []models.LeadGuardian
+	if err := json.NewDecoder(r.Body).Decode(&guardians); err != nil {
+		log.Println(err)
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if err := validateLeadGuardians(guardians); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	lead, err := sqlconnect.SetLeadGuardians(r.Context(), id, guardians)
+	if errors.Is(err, models.ErrLeadConverted) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(lead)
+}
+
+// AssignLeadHandler hands the lead to the exec in {"exec_id"}; 0 unassigns it.
+func AssignLeadHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid Lead Id", http.StatusBadRequest)
+		return
+	}
+
+	var body struct {
+		ExecID *int `json:"exec_id"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&body); err != nil || body.ExecID == nil || *body.ExecID < 0 {
+		http.Error(w, "exec_id is required", http.StatusBadRequest)
+		return
+	}
+
+	lead, err := sqlconnect.AssignLead(r.Context(), id, *body.ExecID)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(lead)
+}
+
+// MoveLeadHandler moves the lead to another stage; the enrolled stage is only
+// reached through conversion.
+func MoveLeadHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid Lead Id", http.StatusBadRequest)
+		return
+	}
+
+	var move models.StageMove
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&move); err != nil {
+		log.Println(err)
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	move.Stage = strings.TrimSpace(move.Stage)
+	move.Note = strings.TrimSpace(move.Note)
+
+	lead, err := sqlconnect.MoveLead(r.Context(), id, move, currentUserID(r))
+	if errors.Is(err, models.ErrLeadConverted) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(lead)
+}
+
+func GetLeadHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid Lead Id", http.StatusBadRequest)
+		return
+	}
+	if _, err := sqlconnect.GetLeadByID(r.Context(), id); err != nil {
+		dbErrorResponse(w, err, http.StatusNotFound)
+		return
+	}
+
+	changes, err := sqlconnect.GetLeadHistory(r.Context(), id)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string               `json:"status"`
+		Count  int                  `json:"count"`
+		Data   []models.StageChange `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(changes),
+		Data:   changes,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetLeadTimelineHandler merges the activities and stage changes of a lead,
+// newest first.
+func GetLeadTimelineHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid Lead Id", http.StatusBadRequest)
+		return
+	}
+	if _, err := sqlconnect.GetLeadByID(r.Context(), id); err != nil {
+		dbErrorResponse(w, err, http.StatusNotFound)
+		return
+	}
+
+	activities, err := sqlconnect.GetLeadActivities(r.Context(), id)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusInternalServerError)
+		return
+	}
+	changes, err := sqlconnect.GetLeadHistory(r.Context(), id)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusInternalServerError)
+		return
+	}
+	timeline := models.Timeline(activities, changes)
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string                 `json:"status"`
+		Count  int                    `json:"count"`
+		Data   []models.TimelineEntry `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(timeline),
+		Data:   timeline,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// AddLeadActivityHandler logs a call, meeting or other contact with the family.
+func AddLeadActivityHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid Lead Id", http.StatusBadRequest)
+		return
+	}
+
+	var activity models.LeadActivity
+	if err := json.NewDecoder(r.Body).Decode(&activity); err != nil {
+		log.Println(err)
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if err := validateLeadActivity(&activity); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, err := sqlconnect.GetLeadByID(r.Context(), id); err != nil {
+		dbErrorResponse(w, err, http.StatusNotFound)
+		return
+	}
+	activity.LeadID = id
+	activity.AuthorID = currentUserID(r)
+
+	added, err := sqlconnect.AddLeadActivity(r.Context(), activity)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(added)
+}
+
+// validateLeadActivity requires a known type, note by default, and a body.
+func validateLeadActivity(a *models.LeadActivity) error {
+	a.Type = strings.ToLower(strings.TrimSpace(a.Type))
+	if a.Type == "" {
+		a.Type = "note"
+	}
+	if !slices.Contains(models.LeadActivityTypes, a.Type) {
+		return errors.New("type must be one of " + strings.Join(models.LeadActivityTypes, ", "))
+	}
+	a.Body = strings.TrimSpace(a.Body)
+	if a.Body == "" {
+		return errors.New("body is required")
+	}
+	return nil
+}
+
+// ConvertLeadHandler enrolls the lead: it creates the student and guardians
+// and moves the lead to the enrolled stage in one step.
+func ConvertLeadHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid Lead Id", http.StatusBadRequest)
+		return
+	}
+
+	var conversion models.LeadConversion
+	if r.ContentLength != 0 {
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&conversion); err != nil {
+			log.Println(err)
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+	}
+	conversion.Class = strings.TrimSpace(conversion.Class)
+	conversion.Email = strings.TrimSpace(conversion.Email)
+	if conversion.Email != "" && !strings.Contains(conversion.Email, "@") {
+		http.Error(w, "invalid email", http.StatusBadRequest)
+		return
+	}
+
+	result, err := sqlconnect.ConvertLead(r.Context(), id, conversion, currentUserID(r))
+	if errors.Is(err, models.ErrLeadConverted) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"restapi/internal/models"
+	"testing"
+)
+
+func TestValidateLead(t *testing.T) {
+	tests := []struct {
+		name    string
+		lead    models.Lead
+		wantErr bool
+	}{
+		{"valid", models.Lead{FirstName: "Anna", LastName: "Ivanova", Email: "anna@example.com"}, false},
+		// Связаться можно и через родителя
+		{"guardian contact", models.Lead{FirstName: "Anna", LastName: "Ivanova", Guardians: []models.LeadGuardian{{Phone: "+7 900 000-00-00"}}}, false},
+		{"no name", models.Lead{FirstName: " ", LastName: "Ivanova", Phone: "123"}, true},
+		{"no contact", models.Lead{FirstName: "Anna", LastName: "Ivanova"}, true},
+		{"bad email", models.Lead{FirstName: "Anna", LastName: "Ivanova", Email: "anna"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateLead(&tt.lead); (err != nil) != tt.wantErr {
+				t.Errorf("validateLead() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateLeadGuardians(t *testing.T) {
+	mother := models.LeadGuardian{FirstName: "Olga", LastName: "Ivanova", Phone: "123", Relationship: "Mother", Primary: true}
+	father := models.LeadGuardian{FirstName: "Ivan", LastName: "Ivanov", Email: "ivan@example.com"}
+
+	tests := []struct {
+		name      string
+		guardians []models.LeadGuardian
+		wantErr   bool
+	}{
+		{"valid", []models.LeadGuardian{mother, father}, false},
+		{"none", nil, false},
+		// Только один основной контакт
+		{"two primary", []models.LeadGuardian{mother, mother}, true},
+		{"no contact", []models.LeadGuardian{{FirstName: "Ivan", LastName: "Ivanov"}}, true},
+		{"unknown relationship", []models.LeadGuardian{{FirstName: "Ivan", LastName: "Ivanov", Phone: "1", Relationship: "neighbour"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateLeadGuardians(tt.guardians); (err != nil) != tt.wantErr {
+				t.Errorf("validateLeadGuardians() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Отношение нормализуется, по умолчанию — parent
+	guardians := []models.LeadGuardian{mother, father}
+	if err := validateLeadGuardians(guardians); err != nil {
+		t.Fatal(err)
+	}
+	if guardians[0].Relationship != "mother" || guardians[1].Relationship != "parent" {
+		t.Errorf("relationships = %q, %q", guardians[0].Relationship, guardians[1].Relationship)
+	}
+}
+
+func TestValidateLeadActivity(t *testing.T) {
+	tests := []struct {
+		name     string
+		activity models.LeadActivity
+		wantType string
+		wantErr  bool
+	}{
+		{"note by default", models.LeadActivity{Body: "Asked about fees"}, "note", false},
+		{"call", models.LeadActivity{Type: " Call ", Body: "Called mother"}, "call", false},
+		{"unknown type", models.LeadActivity{Type: "sms", Body: "Hi"}, "", true},
+		{"empty body", models.LeadActivity{Type: "note", Body: "  "}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateLeadActivity(&tt.activity)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateLeadActivity() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && tt.activity.Type != tt.wantType {
+				t.Errorf("type = %q, want %q", tt.activity.Type, tt.wantType)
+			}
+		})
+	}
+}
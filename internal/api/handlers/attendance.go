@@ -0,0 +1,263 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"restapi/internal/models"
+	"restapi/internal/repository/sqlconnect"
+	"time"
+)
+
+// RecordAttendanceHandler saves the marks of a whole class session and alerts
+// execs about students who reached the absence threshold.
+func RecordAttendanceHandler(w http.ResponseWriter, r *http.Request) {
+	var session models.AttendanceSession
+	err := json.NewDecoder(r.Body).Decode(&session)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	if err := validateAttendanceSession(&session); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	records, err := sqlconnect.RecordAttendanceSession(r.Context(), session, currentUserID(r))
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	var absent []int
+	for _, record := range records {
+		if record.Status == models.AttendanceAbsent {
+			absent = append(absent, record.StudentID)
+		}
+	}
+	if len(absent) > 0 {
+		if _, err := sqlconnect.RaiseAbsenceAlerts(r.Context(), absent, session.Date); err != nil {
+			// The marks are saved; a failed check is retried with the next absence
+			log.Println("Error checking absence alerts:", err)
+		}
+		// Mail goes out after the response; it must not hold up the teacher.
+		// Alerts whose mail failed earlier go out with the new ones.
+		runAfterResponse(r, 30*time.Second, func(ctx context.Context) {
+			if err := sqlconnect.NotifyAbsenceAlerts(ctx); err != nil {
+				log.Println("Error sending absence alerts:", err)
+			}
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	response := struct {
+		Status string              `json:"status"`
+		Count  int                 `json:"count"`
+		Data   []models.Attendance `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(records),
+		Data:   records,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// validateAttendanceSession checks the session and normalizes its date and period.
+func validateAttendanceSession(session *models.AttendanceSession) error {
+	if session.Class == "" {
+		return errors.New("class is required")
+	}
+	date, err := parseDate(session.Date)
+	if err != nil {
+		return err
+	}
+	session.Date = date
+	if session.Period == 0 {
+		session.Period = 1
+	}
+	if session.Period < 0 {
+		return errors.New("period must be positive")
+	}
+	if len(session.Records) == 0 {
+		return errors.New("records are required")
+	}
+
+	seen := make(map[int]bool, len(session.Records))
+	for _, mark := range session.Records {
+		if mark.StudentID <= 0 {
+			return errors.New("student_id is required")
+		}
+		if seen[mark.StudentID] {
+			return fmt.Errorf("student %d is listed twice", mark.StudentID)
+		}
+		seen[mark.StudentID] = true
+		if !mark.Status.Valid() {
+			return fmt.Errorf("invalid status %q for student %d", mark.Status, mark.StudentID)
+		}
+	}
+	return nil
+}
+
+func PatchAttendanceHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "Invalid Attendance Id", http.StatusBadRequest)
+		return
+	}
+
+	var mark models.AttendanceMark
+	err = json.NewDecoder(r.Body).Decode(&mark)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "Invalid Request Payload", http.StatusBadRequest)
+		return
+	}
+	if !mark.Status.Valid() {
+		http.Error(w, fmt.Sprintf("invalid status %q", mark.Status), http.StatusBadRequest)
+		return
+	}
+
+	updated, err := sqlconnect.UpdateAttendance(r.Context(), id, mark.Status, mark.Note, currentUserID(r))
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+func GetAttendanceHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "Invalid Attendance Id", http.StatusBadRequest)
+		return
+	}
+
+	changes, err := sqlconnect.GetAttendanceHistory(r.Context(), id)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string                    `json:"status"`
+		Count  int                       `json:"count"`
+		Data   []models.AttendanceChange `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(changes),
+		Data:   changes,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetStudentAttendanceHandler summarizes a student's attendance between the
+// term_id, year_id or from and to query params (default: the last 30 days).
+func GetStudentAttendanceHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "Invalid Student Id", http.StatusBadRequest)
+		return
+	}
+
+	p, err := requestPeriod(r, 30)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+	from, to := p.From, p.To
+
+	records, err := sqlconnect.GetStudentAttendance(r.Context(), id, from, to)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	summary := models.AttendanceSummary{StudentID: id, From: from, To: to}
+	for _, record := range records {
+		summary.Add(record)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status  string                   `json:"status"`
+		Summary models.AttendanceSummary `json:"summary"`
+		Data    []models.Attendance      `json:"data"`
+	}{
+		Status:  "success",
+		Summary: summary,
+		Data:    records,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetClassRosterHandler lists a class with each student's marks for the day
+// given by the date query param (default: today).
+func GetClassRosterHandler(w http.ResponseWriter, r *http.Request) {
+	class := r.PathValue("class")
+
+	date := time.Now().Format(time.DateOnly)
+	if value := r.URL.Query().Get("date"); value != "" {
+		parsed, err := parseDate(value)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		date = parsed
+	}
+
+	roster, err := sqlconnect.GetClassRoster(r.Context(), class, date)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string               `json:"status"`
+		Class  string               `json:"class"`
+		Date   string               `json:"date"`
+		Count  int                  `json:"count"`
+		Data   []models.RosterEntry `json:"data"`
+	}{
+		Status: "success",
+		Class:  class,
+		Date:   date,
+		Count:  len(roster),
+		Data:   roster,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func GetAttendanceAlertsHandler(w http.ResponseWriter, r *http.Request) {
+	_, limit := getPaginationParams(r)
+
+	alerts, err := sqlconnect.GetAttendanceAlerts(r.Context(), limit)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string                   `json:"status"`
+		Count  int                      `json:"count"`
+		Data   []models.AttendanceAlert `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(alerts),
+		Data:   alerts,
+	}
+	json.NewEncoder(w).Encode(response)
+}
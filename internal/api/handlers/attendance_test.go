@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"restapi/internal/models"
+	"testing"
+	"time"
+)
+
+func TestValidateAttendanceSession(t *testing.T) {
+	mark := models.AttendanceMark{StudentID: 1, Status: models.AttendancePresent}
+
+	tests := []struct {
+		name    string
+		session models.AttendanceSession
+		wantErr bool
+	}{
+		{"valid session", models.AttendanceSession{Class: "10A", Date: "2024-09-02", Records: []models.AttendanceMark{mark}}, false},
+		{"missing class", models.AttendanceSession{Date: "2024-09-02", Records: []models.AttendanceMark{mark}}, true},
+		{"bad date", models.AttendanceSession{Class: "10A", Date: "02.09.2024", Records: []models.AttendanceMark{mark}}, true},
+		{"no records", models.AttendanceSession{Class: "10A", Date: "2024-09-02"}, true},
+		{"negative period", models.AttendanceSession{Class: "10A", Date: "2024-09-02", Period: -1, Records: []models.AttendanceMark{mark}}, true},
+		{"unknown status", models.AttendanceSession{Class: "10A", Date: "2024-09-02", Records: []models.AttendanceMark{{StudentID: 1, Status: "sick"}}}, true},
+		// Один ученик не может быть отмечен дважды за занятие
+		{"duplicate student", models.AttendanceSession{Class: "10A", Date: "2024-09-02", Records: []models.AttendanceMark{mark, mark}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateAttendanceSession(&tt.session)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateAttendanceSession() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && tt.session.Period != 1 {
+				t.Errorf("period = %d, want default 1", tt.session.Period)
+			}
+		})
+	}
+}
+
+func TestDateRange(t *testing.T) {
+	today := time.Now().Format(time.DateOnly)
+
+	tests := []struct {
+		name     string
+		query    string
+		wantFrom string
+		wantTo   string
+		wantErr  bool
+	}{
+		{"explicit range", "?from=2024-09-01&to=2024-09-30", "2024-09-01", "2024-09-30", false},
+		{"default from", "?to=2024-09-30", "2024-09-01", "2024-09-30", false},
+		{"default to", "?from=2024-09-01", "2024-09-01", today, false},
+		{"reversed range", "?from=2024-09-30&to=2024-09-01", "", "", true},
+		{"invalid date", "?from=yesterday", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to, err := dateRange(httptest.NewRequest("GET", "/students/1/attendance"+tt.query, nil), 30)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("dateRange() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if from != tt.wantFrom || to != tt.wantTo {
+				t.Errorf("dateRange() = %s..%s, want %s..%s", from, to, tt.wantFrom, tt.wantTo)
+			}
+		})
+	}
+}
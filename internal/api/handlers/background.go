@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// background tracks work that outlives its request, such as mail sent after
+// the response, so shutdown can wait for it before closing the DB pool.
+var background sync.WaitGroup
+
+// runAfterResponse runs fn in its own goroutine with a context that keeps the
+// request's values but not its cancellation, bounded by timeout.
+func runAfterResponse(r *http.Request, timeout time.Duration, fn func(ctx context.Context)) {
+	parent := context.WithoutCancel(r.Context())
+	background.Add(1)
+	go func() {
+		defer background.Done()
+		ctx, cancel := context.WithTimeout(parent, timeout)
+		defer cancel()
+		fn(ctx)
+	}()
+}
+
+// WaitBackground blocks until background work started by handlers finished
+// or ctx is done, and reports whether it finished.
+func WaitBackground(ctx context.Context) bool {
+	done := make(chan struct{})
+	go func() {
+		background.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWaitBackground(t *testing.T) {
+	r := httptest.NewRequest("POST", "/attendance", nil)
+	release := make(chan struct{})
+	finished := false
+	runAfterResponse(r, time.Second, func(ctx context.Context) {
+		<-release
+		finished = true
+	})
+
+	// Пока работа не закончилась, ожидание прерывается по контексту
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if WaitBackground(ctx) {
+		t.Fatal("WaitBackground() = true while work is running")
+	}
+
+	close(release)
+	if !WaitBackground(context.Background()) {
+		t.Fatal("WaitBackground() = false, want true")
+	}
+	if !finished {
+		t.Error("WaitBackground() returned before the work finished")
+	}
+}
@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"restapi/internal/models"
+	"restapi/internal/reports"
+	"restapi/internal/repository/sqlconnect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// GetCustomFieldsHandler lists the custom field definitions, optionally of
+// one resource. Admins see every field, other roles the ones shown to them.
+func GetCustomFieldsHandler(w http.ResponseWriter, r *http.Request) {
+	resource := r.URL.Query().Get("resource")
+	if resource != "" && !slices.Contains(models.CustomFieldResources, resource) {
+		http.Error(w, "resource must be one of "+strings.Join(models.CustomFieldResources, ", "), http.StatusBadRequest)
+		return
+	}
+
+	fields, err := sqlconnect.VisibleCustomFields(r.Context(), resource)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string               `json:"status"`
+		Count  int                  `json:"count"`
+		Data   []models.CustomField `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(fields),
+		Data:   fields,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func AddCustomFieldHandler(w http.ResponseWriter, r *http.Request) {
+	var field models.CustomField
+	if err := json.NewDecoder(r.Body).Decode(&field); err != nil {
+		log.Println(err)
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	field.ID = 0
+	saveCustomField(w, r, field, http.StatusCreated)
+}
+
+// PatchCustomFieldHandler applies the fields present in the body. The
+// resource, name and type are fixed once values may be stored.
+func PatchCustomFieldHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid Custom Field Id", http.StatusBadRequest)
+		return
+	}
+
+	existing, err := sqlconnect.GetCustomFieldByID(r.Context(), id)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusNotFound)
+		return
+	}
+
+	field := existing
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&field); err != nil {
+		log.Println(err)
+		http.Error(w, "Invalid Request Payload", http.StatusBadRequest)
+		return
+	}
+	if field.Resource != existing.Resource || field.Name != existing.Name || field.Type != existing.Type {
+		http.Error(w, "resource, name and type cannot be changed", http.StatusBadRequest)
+		return
+	}
+	field.ID = id
+
+	saveCustomField(w, r, field, http.StatusOK)
+}
+
+func saveCustomField(w http.ResponseWriter, r *http.Request, field models.CustomField, status int) {
+	if err := field.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	saved, err := sqlconnect.SaveCustomField(r.Context(), field)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(saved)
+}
+
+// DeleteCustomFieldHandler removes the field and every value stored for it.
+func DeleteCustomFieldHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid Custom Field Id", http.StatusBadRequest)
+		return
+	}
+
+	if err := sqlconnect.DeleteCustomField(r.Context(), id); err != nil {
+		dbErrorResponse(w, err, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string `json:"status"`
+		ID     int    `json:"id"`
+	}{
+		Status: "Custom field successfully deleted",
+		ID:     id,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// takeCustom removes the "custom" object from a patch and returns the values
+// it changes, validated against fields.
+func takeCustom(update map[string]interface{}, fields models.CustomFields) (map[string]string, error) {
+	raw, ok := update["custom"]
+	if !ok {
+		return nil, nil
+	}
+	delete(update, "custom")
+	custom, ok := raw.(map[string]interface{})
+	if !ok && raw != nil {
+		return nil, errors.New("custom must be an object")
+	}
+	return fields.Parse(custom, false)
+}
+
+// customColumns are the CSV export columns of fields, named like the
+// columns the bulk imports accept.
+func customColumns(fields models.CustomFields) []string {
+	columns := make([]string, len(fields))
+	for i, f := range fields {
+		columns[i] = "custom." + f.Name
+	}
+	return columns
+}
+
+// customCells are the stored values of fields for one record, in column order.
+func customCells(fields models.CustomFields, values map[string]string) []string {
+	cells := make([]string, len(fields))
+	for i, f := range fields {
+		cells[i] = values[f.Name]
+	}
+	return cells
+}
+
+// listFormat reads the format param of exportable lists: json (the default)
+// or csv.
+func listFormat(r *http.Request) (string, error) {
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "json":
+		return "json", nil
+	case "csv":
+		return format, nil
+	}
+	return "", errors.New("format must be json or csv")
+}
+
+// writeCSV answers with the rows as a CSV file named name.csv.
+func writeCSV(w http.ResponseWriter, name string, header []string, rows [][]string) {
+	var table bytes.Buffer
+	if err := reports.Table(&table, header, rows); err != nil {
+		log.Println("Error rendering CSV:", err)
+		http.Error(w, "Error generating export", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.csv"`, safeFileName(name)))
+	w.Header().Set("Content-Length", strconv.Itoa(table.Len()))
+	w.Write(table.Bytes())
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"restapi/internal/models"
+	"testing"
+)
+
+func TestTakeCustom(t *testing.T) {
+	fields := models.CustomFields{{Name: "bus_route", Type: models.FieldText}}
+
+	tests := []struct {
+		name    string
+		update  map[string]interface{}
+		want    map[string]string
+		wantErr bool
+	}{
+		{"no custom", map[string]interface{}{"id": "1", "class": "10A"}, nil, false},
+		{"custom", map[string]interface{}{"id": "1", "custom": map[string]interface{}{"bus_route": "12"}}, map[string]string{"bus_route": "12"}, false},
+		{"not an object", map[string]interface{}{"id": "1", "custom": "12"}, nil, true},
+		{"unknown field", map[string]interface{}{"id": "1", "custom": map[string]interface{}{"medical": "none"}}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := takeCustom(tt.update, fields)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("takeCustom() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("takeCustom() = %v, want %v", got, tt.want)
+			}
+			// Объект custom не доходит до обновления основных полей
+			if _, ok := tt.update["custom"]; ok && !tt.wantErr {
+				t.Errorf("takeCustom() left custom in the update")
+			}
+		})
+	}
+}
+
+func TestListFormat(t *testing.T) {
+	tests := []struct {
+		url     string
+		want    string
+		wantErr bool
+	}{
+		{"/students", "json", false},
+		{"/students?format=json", "json", false},
+		{"/students?format=csv", "csv", false},
+		{"/students?format=xlsx", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := listFormat(httptest.NewRequest("GET", tt.url, nil))
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("listFormat(%s) = %q, %v, want %q", tt.url, got, err, tt.want)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"restapi/internal/metrics"
 	"restapi/internal/models"
 	"restapi/internal/repository/sqlconnect"
 	"restapi/pkg/utils"
@@ -16,9 +17,9 @@ import (
 func GetExecsHandler(w http.ResponseWriter, r *http.Request) {
 
 	var execs []models.Exec
-	execs, err := sqlconnect.GetExecsDbHandler(execs, r)
+	execs, err := sqlconnect.GetExecsDbHandler(r.Context(), execs, r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		dbErrorResponse(w, err, http.StatusBadRequest)
 		return
 	}
 
@@ -47,9 +48,9 @@ func GetOneExecHandler(w http.ResponseWriter, r *http.Request) {
 		fmt.Println(err)
 		return
 	}
-	exec, err := sqlconnect.GetExecByID(id)
+	exec, err := sqlconnect.GetExecByID(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		dbErrorResponse(w, err, http.StatusInternalServerError)
 		return
 	}
 
@@ -64,7 +65,7 @@ func AddExecsHandler(w http.ResponseWriter, r *http.Request) {
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		http.Error(w, "Error reading request body", http.StatusInternalServerError)
+		readBodyError(w, err)
 		return
 	}
 	defer r.Body.Close()
@@ -106,9 +107,9 @@ func AddExecsHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	addedExecs, err := sqlconnect.AddExecsDBHandler(newExecs)
+	addedExecs, err := sqlconnect.AddExecsDBHandler(r.Context(), newExecs)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		dbErrorResponse(w, err, http.StatusInternalServerError)
 		return
 	}
 
@@ -137,10 +138,10 @@ func PatchExecsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = sqlconnect.PatchExecs(updates)
+	err = sqlconnect.PatchExecs(r.Context(), updates)
 	if err != nil {
 		log.Println(err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		dbErrorResponse(w, err, http.StatusBadRequest)
 		return
 	}
 	w.WriteHeader(http.StatusNoContent)
@@ -163,9 +164,9 @@ func PatchOneExecHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	updatedExec, err := sqlconnect.PatchOneExec(id, updates)
+	updatedExec, err := sqlconnect.PatchOneExec(r.Context(), id, updates)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		dbErrorResponse(w, err, http.StatusInternalServerError)
 		return
 	}
 
@@ -183,9 +184,9 @@ func DeleteOneExecHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = sqlconnect.DeleteOneExec(id)
+	err = sqlconnect.DeleteOneExec(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		dbErrorResponse(w, err, http.StatusBadRequest)
 		return
 	}
 
@@ -215,19 +216,22 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := sqlconnect.GetUserByUsername(req.Username)
+	user, err := sqlconnect.GetUserByUsername(r.Context(), req.Username)
 	if err != nil {
+		metrics.LoginAttemptsTotal.WithLabelValues("failure").Inc()
 		http.Error(w, "Invalid username or password", http.StatusBadRequest)
 		return
 	}
 
 	if user.InactiveStatus {
+		metrics.LoginAttemptsTotal.WithLabelValues("failure").Inc()
 		http.Error(w, "Account is inactive", http.StatusForbidden)
 		return
 	}
 
 	err = utils.VerifyPassword(req.Password, user.Password)
 	if err != nil {
+		metrics.LoginAttemptsTotal.WithLabelValues("failure").Inc()
 		http.Error(w, err.Error(), http.StatusForbidden)
 		return
 	}
@@ -237,6 +241,7 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Could not create login token", http.StatusInternalServerError)
 		return
 	}
+	metrics.LoginAttemptsTotal.WithLabelValues("success").Inc()
 
 	http.SetCookie(w, &http.Cookie{
 		Name:     "Bearer",
@@ -303,9 +308,9 @@ func UpdatePasswordHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = sqlconnect.UpdatePasswordInDb(userId, req.CurrentPassword, req.NewPassword)
+	_, err = sqlconnect.UpdatePasswordInDb(r.Context(), userId, req.CurrentPassword, req.NewPassword)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		dbErrorResponse(w, err, http.StatusBadRequest)
 		return
 	}
 
@@ -329,24 +334,21 @@ func ForgotPasswordHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	r.Body.Close()
 
-	err = sqlconnect.ForgotPasswordDbHandler(req.Email)
+	err = sqlconnect.ForgotPasswordDbHandler(r.Context(), req.Email)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		dbErrorResponse(w, err, http.StatusBadRequest)
 		return
 	}
 
+	// The email is echoed back, so make sure it is never sniffed as HTML
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 	fmt.Fprintf(w, "Password reset link sent to %s", req.Email)
 }
 
 func ResetPasswordHandler(w http.ResponseWriter, r *http.Request) {
 	token := r.PathValue("resetcode")
 
-	type request struct {
-		NewPassword     string `json:"new_password"`
-		ConfirmPassword string `json:"confirm_password"`
-	}
-
-	var req request
+	var req models.ResetPasswordRequest
 	err := json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
 		http.Error(w, "Invalid values in request", http.StatusBadRequest)
@@ -358,11 +360,12 @@ func ResetPasswordHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = sqlconnect.ResetPasswordDbHandler(token, req.NewPassword)
+	err = sqlconnect.ResetPasswordDbHandler(r.Context(), token, req.NewPassword)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		dbErrorResponse(w, err, http.StatusBadRequest)
 		return
 	}
 
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 	fmt.Fprintln(w, "Password reset successfully")
 }
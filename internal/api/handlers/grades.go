@@ -0,0 +1,330 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"restapi/internal/models"
+	"restapi/internal/repository/sqlconnect"
+	"slices"
+	"strings"
+)
+
+func AddAssessmentHandler(w http.ResponseWriter, r *http.Request) {
+	var assessment models.Assessment
+	err := json.NewDecoder(r.Body).Decode(&assessment)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	if err := validateAssessment(&assessment); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	assessment.CreatedBy = currentUserID(r)
+
+	added, err := sqlconnect.AddAssessment(r.Context(), assessment)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(added)
+}
+
+// validateAssessment checks required fields and normalizes the date; a
+// missing weight counts as 1.
+func validateAssessment(a *models.Assessment) error {
+	if a.Class == "" || a.Subject == "" || a.Title == "" {
+		return errors.New("class, subject and title are required")
+	}
+	a.Type = strings.ToLower(a.Type)
+	if !slices.Contains(models.AssessmentTypes, a.Type) {
+		return fmt.Errorf("type must be one of %s", strings.Join(models.AssessmentTypes, ", "))
+	}
+	if a.MaxScore <= 0 {
+		return errors.New("max_score must be positive")
+	}
+	if a.Weight == 0 {
+		a.Weight = 1
+	}
+	if a.Weight < 0 {
+		return errors.New("weight must not be negative")
+	}
+	date, err := parseDate(a.Date)
+	if err != nil {
+		return err
+	}
+	a.Date = date
+	return nil
+}
+
+// GetAssessmentsHandler lists assessments filtered by class, subject, from and to.
+func GetAssessmentsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	from, to := query.Get("from"), query.Get("to")
+	for _, value := range []*string{&from, &to} {
+		if *value == "" {
+			continue
+		}
+		parsed, err := parseDate(*value)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		*value = parsed
+	}
+
+	assessments, err := sqlconnect.GetAssessments(r.Context(), query.Get("class"), query.Get("subject"), from, to)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string              `json:"status"`
+		Count  int                 `json:"count"`
+		Data   []models.Assessment `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(assessments),
+		Data:   assessments,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func GetOneAssessmentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid Assessment Id", http.StatusBadRequest)
+		return
+	}
+
+	assessment, err := sqlconnect.GetAssessmentByID(r.Context(), id)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(assessment)
+}
+
+// PatchAssessmentHandler applies the fields present in the body to the assessment.
+func PatchAssessmentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid Assessment Id", http.StatusBadRequest)
+		return
+	}
+
+	assessment, err := sqlconnect.GetAssessmentByID(r.Context(), id)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusNotFound)
+		return
+	}
+
+	err = json.NewDecoder(r.Body).Decode(&assessment)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "Invalid Request Payload", http.StatusBadRequest)
+		return
+	}
+	assessment.ID = id
+
+	if err := validateAssessment(&assessment); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	updated, err := sqlconnect.UpdateAssessment(r.Context(), assessment)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+func DeleteAssessmentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid Assessment Id", http.StatusBadRequest)
+		return
+	}
+
+	err = sqlconnect.DeleteAssessment(r.Context(), id)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string `json:"status"`
+		ID     int    `json:"id"`
+	}{
+		Status: "Assessment successfully deleted",
+		ID:     id,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// RecordScoresHandler takes the scores of one assessment as an array.
+func RecordScoresHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid Assessment Id", http.StatusBadRequest)
+		return
+	}
+
+	var scores []models.Score
+	err = json.NewDecoder(r.Body).Decode(&scores)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if len(scores) == 0 {
+		http.Error(w, "scores are required", http.StatusBadRequest)
+		return
+	}
+	seen := make(map[int]bool, len(scores))
+	for _, score := range scores {
+		if score.StudentID <= 0 || seen[score.StudentID] {
+			http.Error(w, "every score needs a distinct student_id", http.StatusBadRequest)
+			return
+		}
+		seen[score.StudentID] = true
+	}
+
+	saved, err := sqlconnect.RecordScores(r.Context(), id, scores, currentUserID(r))
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	response := struct {
+		Status string         `json:"status"`
+		Count  int            `json:"count"`
+		Data   []models.Score `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(saved),
+		Data:   saved,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func GetAssessmentScoresHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid Assessment Id", http.StatusBadRequest)
+		return
+	}
+
+	scores, err := sqlconnect.GetAssessmentScores(r.Context(), id)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string         `json:"status"`
+		Count  int            `json:"count"`
+		Data   []models.Score `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(scores),
+		Data:   scores,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetStudentGradesHandler returns a student's grade history with weighted
+// averages per subject for the term_id, year_id or from/to period (default:
+// the last 365 days).
+func GetStudentGradesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid Student Id", http.StatusBadRequest)
+		return
+	}
+
+	p, err := requestPeriod(r, 365)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+	from, to := p.From, p.To
+
+	entries, err := sqlconnect.GetStudentGrades(r.Context(), id, r.URL.Query().Get("subject"), from, to)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status   string                  `json:"status"`
+		From     string                  `json:"from"`
+		To       string                  `json:"to"`
+		Averages []models.SubjectAverage `json:"averages"`
+		Data     []models.GradeEntry     `json:"data"`
+	}{
+		Status:   "success",
+		From:     from,
+		To:       to,
+		Averages: models.SubjectAverages(entries, sqlconnect.GradeScale()),
+		Data:     entries,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetGradeDistributionHandler summarizes the weighted averages of a class in
+// the subject query param over the term_id, year_id or from/to period.
+func GetGradeDistributionHandler(w http.ResponseWriter, r *http.Request) {
+	class := r.PathValue("class")
+	subject := r.URL.Query().Get("subject")
+	if subject == "" {
+		http.Error(w, "subject is required", http.StatusBadRequest)
+		return
+	}
+
+	p, err := requestPeriod(r, 365)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+	from, to := p.From, p.To
+
+	averages, err := sqlconnect.GetClassAverages(r.Context(), class, subject, from, to)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	distribution := models.GradeDistribution{Class: class, Subject: subject, From: from, To: to}
+	distribution.Distribute(averages, sqlconnect.GradeScale())
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string                   `json:"status"`
+		Data   models.GradeDistribution `json:"data"`
+	}{
+		Status: "success",
+		Data:   distribution,
+	}
+	json.NewEncoder(w).Encode(response)
+}
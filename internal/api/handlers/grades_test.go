@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"restapi/internal/models"
+	"testing"
+)
+
+func TestValidateAssessment(t *testing.T) {
+	valid := models.Assessment{Class: "10A", Subject: "math", Type: "Quiz", Title: "Fractions", MaxScore: 20, Date: "2024-09-02"}
+
+	tests := []struct {
+		name    string
+		mutate  func(a *models.Assessment)
+		wantErr bool
+	}{
+		{"valid assessment", func(a *models.Assessment) {}, false},
+		{"missing subject", func(a *models.Assessment) { a.Subject = "" }, true},
+		{"unknown type", func(a *models.Assessment) { a.Type = "essay" }, true},
+		{"zero max score", func(a *models.Assessment) { a.MaxScore = 0 }, true},
+		{"negative weight", func(a *models.Assessment) { a.Weight = -1 }, true},
+		{"bad date", func(a *models.Assessment) { a.Date = "02.09.2024" }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := valid
+			tt.mutate(&a)
+			err := validateAssessment(&a)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateAssessment() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			// Тип приводится к нижнему регистру, вес по умолчанию — 1
+			if !tt.wantErr && (a.Type != "quiz" || a.Weight != 1) {
+				t.Errorf("normalized = %+v, want type quiz and weight 1", a)
+			}
+		})
+	}
+}
@@ -0,0 +1,318 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"restapi/internal/models"
+	"restapi/internal/repository/sqlconnect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+func GetGuardiansHandler(w http.ResponseWriter, r *http.Request) {
+	page, limit := getPaginationParams(r)
+	guardians, total, err := sqlconnect.GetGuardians(r.Context(), r, limit, page)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	response := struct {
+		Status   string            `json:"status"`
+		Count    int               `json:"count"`
+		Page     int               `json:"page"`
+		PageSize int               `json:"page_size"`
+		Data     []models.Guardian `json:"data"`
+	}{
+		Status:   "success",
+		Count:    total,
+		Page:     page,
+		PageSize: limit,
+		Data:     guardians,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func GetOneGuardianHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid Guardian Id", http.StatusBadRequest)
+		return
+	}
+
+	guardian, err := sqlconnect.GetGuardianByID(r.Context(), id)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(guardian)
+}
+
+// AddGuardiansHandler creates guardians from a JSON array or a CSV import.
+func AddGuardiansHandler(w http.ResponseWriter, r *http.Request) {
+	body, err := readBulkBody(r, models.Guardian{})
+	if err != nil {
+		readBodyError(w, err)
+		return
+	}
+	defer r.Body.Close()
+
+	var rawGuardians []map[string]interface{}
+	if err := json.Unmarshal(body, &rawGuardians); err != nil {
+		http.Error(w, "Invalid Request Body", http.StatusBadRequest)
+		return
+	}
+
+	allowedFields := make(map[string]struct{})
+	for _, field := range GetFieldNames(models.Guardian{}) {
+		allowedFields[field] = struct{}{}
+	}
+	for _, guardian := range rawGuardians {
+		for key := range guardian {
+			if _, ok := allowedFields[key]; !ok {
+				http.Error(w, "Unacceptable field found in request. Only use allowed fields.", http.StatusBadRequest)
+				return
+			}
+		}
+	}
+
+	var newGuardians []models.Guardian
+	if err := json.Unmarshal(body, &newGuardians); err != nil {
+		http.Error(w, "Invalid Request Body", http.StatusBadRequest)
+		return
+	}
+	for i := range newGuardians {
+		if err := validateGuardian(&newGuardians[i]); err != nil {
+			http.Error(w, fmt.Sprintf("guardian %d: %v", i+1, err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	added, err := sqlconnect.AddGuardians(r.Context(), newGuardians)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	response := struct {
+		Status string            `json:"status"`
+		Count  int               `json:"count"`
+		Data   []models.Guardian `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(added),
+		Data:   added,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// validateGuardian requires a name and at least one way to reach the guardian.
+func validateGuardian(g *models.Guardian) error {
+	g.FirstName = strings.TrimSpace(g.FirstName)
+	g.LastName = strings.TrimSpace(g.LastName)
+	g.Phone = strings.TrimSpace(g.Phone)
+	g.Email = strings.TrimSpace(g.Email)
+	if g.FirstName == "" || g.LastName == "" {
+		return errors.New("first_name and last_name are required")
+	}
+	if g.Phone == "" && g.Email == "" {
+		return errors.New("phone or email is required")
+	}
+	if g.Email != "" && !strings.Contains(g.Email, "@") {
+		return errors.New("invalid email")
+	}
+	return nil
+}
+
+// PatchGuardianHandler applies the fields present in the body to the guardian.
+func PatchGuardianHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid Guardian Id", http.StatusBadRequest)
+		return
+	}
+
+	guardian, err := sqlconnect.GetGuardianByID(r.Context(), id)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusNotFound)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&guardian); err != nil {
+		log.Println(err)
+		http.Error(w, "Invalid Request Payload", http.StatusBadRequest)
+		return
+	}
+	guardian.ID = id
+
+	if err := validateGuardian(&guardian); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	updated, err := sqlconnect.UpdateGuardian(r.Context(), guardian)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+func DeleteGuardianHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid Guardian Id", http.StatusBadRequest)
+		return
+	}
+
+	if err := sqlconnect.DeleteGuardian(r.Context(), id); err != nil {
+		dbErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string `json:"status"`
+		ID     int    `json:"id"`
+	}{
+		Status: "Guardian successfully deleted",
+		ID:     id,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func GetGuardianChildrenHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid Guardian Id", http.StatusBadRequest)
+		return
+	}
+
+	children, err := sqlconnect.GetGuardianChildren(r.Context(), id)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string                 `json:"status"`
+		Count  int                    `json:"count"`
+		Data   []models.GuardianChild `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(children),
+		Data:   children,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func GetStudentGuardiansHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid Student Id", http.StatusBadRequest)
+		return
+	}
+
+	guardians, err := sqlconnect.GetStudentGuardians(r.Context(), id)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string                   `json:"status"`
+		Count  int                      `json:"count"`
+		Data   []models.StudentGuardian `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(guardians),
+		Data:   guardians,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// linkIDs parses the {id} (student) and {guardianId} path values.
+func linkIDs(r *http.Request) (int, int, error) {
+	studentID, err := pathID(r)
+	if err != nil {
+		return 0, 0, errors.New("Invalid Student Id")
+	}
+	guardianID, err := strconv.Atoi(r.PathValue("guardianId"))
+	if err != nil {
+		return 0, 0, errors.New("Invalid Guardian Id")
+	}
+	return studentID, guardianID, nil
+}
+
+// LinkGuardianHandler creates or replaces the link between a student and a guardian.
+func LinkGuardianHandler(w http.ResponseWriter, r *http.Request) {
+	studentID, guardianID, err := linkIDs(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var link models.GuardianLink
+	if err := json.NewDecoder(r.Body).Decode(&link); err != nil {
+		log.Println(err)
+		http.Error(w, "Invalid Request Payload", http.StatusBadRequest)
+		return
+	}
+	link.StudentID = studentID
+	link.GuardianID = guardianID
+	link.Relationship = strings.ToLower(strings.TrimSpace(link.Relationship))
+	if !slices.Contains(models.GuardianRelationships, link.Relationship) {
+		http.Error(w, "relationship must be one of "+strings.Join(models.GuardianRelationships, ", "), http.StatusBadRequest)
+		return
+	}
+
+	saved, err := sqlconnect.LinkGuardian(r.Context(), link)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(saved)
+}
+
+func UnlinkGuardianHandler(w http.ResponseWriter, r *http.Request) {
+	studentID, guardianID, err := linkIDs(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := sqlconnect.UnlinkGuardian(r.Context(), studentID, guardianID); err != nil {
+		dbErrorResponse(w, err, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status     string `json:"status"`
+		StudentID  int    `json:"student_id"`
+		GuardianID int    `json:"guardian_id"`
+	}{
+		Status:     "Guardian successfully unlinked",
+		StudentID:  studentID,
+		GuardianID: guardianID,
+	}
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"restapi/internal/models"
+	"testing"
+)
+
+func TestValidateGuardian(t *testing.T) {
+	tests := []struct {
+		name     string
+		guardian models.Guardian
+		wantErr  bool
+	}{
+		{"phone only", models.Guardian{FirstName: "Anna", LastName: "Ivanova", Phone: "+7 900 000-00-00"}, false},
+		{"email only", models.Guardian{FirstName: "Anna", LastName: "Ivanova", Email: "anna@example.com"}, false},
+		{"missing last name", models.Guardian{FirstName: "Anna", Phone: "555"}, true},
+		// Нужен хотя бы один способ связи
+		{"no contact", models.Guardian{FirstName: "Anna", LastName: "Ivanova"}, true},
+		{"blank phone", models.Guardian{FirstName: "Anna", LastName: "Ivanova", Phone: "   "}, true},
+		{"bad email", models.Guardian{FirstName: "Anna", LastName: "Ivanova", Email: "anna"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateGuardian(&tt.guardian)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateGuardian() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
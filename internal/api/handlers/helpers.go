@@ -1,10 +1,19 @@
 package handlers
 
 import (
+	"encoding/csv"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
 	"reflect"
+	"restapi/internal/repository/sqlconnect"
 	"restapi/pkg/utils"
+	"strconv"
 	"strings"
+	"time"
 )
 
 func CheckBlankFields(value interface{}) error {
@@ -29,3 +38,182 @@ func GetFieldNames(model interface{}) []string {
 	}
 	return fields
 }
+
+// readBodyError answers a failed body read: 413 when the size limit was hit,
+// 400 for malformed CSV, otherwise 500 like before.
+func readBodyError(w http.ResponseWriter, err error) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if errors.Is(err, errInvalidCSV) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	http.Error(w, "Error reading request body", http.StatusInternalServerError)
+}
+
+// dbErrorResponse answers a failed repository call with status, except that a
+// canceled or timed-out request gets 503 so it isn't mistaken for bad input.
+func dbErrorResponse(w http.ResponseWriter, err error, status int) {
+	if errors.Is(err, sqlconnect.ErrQueryCanceled) || errors.Is(err, sqlconnect.ErrQueryTimeout) {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	http.Error(w, err.Error(), status)
+}
+
+var errInvalidCSV = errors.New("invalid CSV body")
+
+// readBulkBody returns the request body as JSON. Imports may also be sent as
+// text/csv or as a multipart "file" field holding CSV; the header row names
+// the JSON fields, "custom.<name>" columns go into the custom object, and
+// values are sanitized using the model's `sanitize` tags.
+func readBulkBody(r *http.Request, model interface{}) ([]byte, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	var source io.Reader
+	switch mediaType {
+	case "text/csv":
+		source = r.Body
+	case "multipart/form-data":
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%w: %v", errInvalidCSV, err)
+		}
+		defer file.Close()
+		source = file
+	default:
+		return io.ReadAll(r.Body)
+	}
+
+	records, err := csv.NewReader(source).ReadAll()
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %v", errInvalidCSV, err)
+	}
+	if len(records) < 2 {
+		return nil, fmt.Errorf("%w: expected a header row and at least one record", errInvalidCSV)
+	}
+
+	header := records[0]
+	rows := make([]interface{}, 0, len(records)-1)
+	for _, record := range records[1:] {
+		row := make(map[string]interface{}, len(header))
+		custom := make(map[string]interface{})
+		for i, column := range header {
+			column = strings.TrimSpace(column)
+			if name, ok := strings.CutPrefix(column, "custom."); ok {
+				custom[name] = record[i]
+				continue
+			}
+			row[column] = record[i]
+		}
+		if len(custom) > 0 {
+			row["custom"] = custom
+		}
+		rows = append(rows, row)
+	}
+	return json.Marshal(utils.FieldPoliciesOf(model).Sanitize(rows))
+}
+
+// currentUserID returns the exec id from the JWT claims, 0 if there is none.
+func currentUserID(r *http.Request) int {
+	return utils.UserID(r.Context())
+}
+
+// pathID parses the {id} path value.
+func pathID(r *http.Request) (int, error) {
+	return strconv.Atoi(r.PathValue("id"))
+}
+
+// parseDate accepts YYYY-MM-DD and returns it normalized.
+func parseDate(value string) (string, error) {
+	date, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return "", fmt.Errorf("invalid date %q, use YYYY-MM-DD", value)
+	}
+	return date.Format(time.DateOnly), nil
+}
+
+// dateRange reads the from and to query params. Missing values default to
+// the defaultDays days up to today.
+func dateRange(r *http.Request, defaultDays int) (string, string, error) {
+	to := time.Now().Format(time.DateOnly)
+	if value := r.URL.Query().Get("to"); value != "" {
+		parsed, err := parseDate(value)
+		if err != nil {
+			return "", "", err
+		}
+		to = parsed
+	}
+
+	end, _ := time.Parse(time.DateOnly, to)
+	from := end.AddDate(0, 0, 1-defaultDays).Format(time.DateOnly)
+	if value := r.URL.Query().Get("from"); value != "" {
+		parsed, err := parseDate(value)
+		if err != nil {
+			return "", "", err
+		}
+		from = parsed
+	}
+
+	if from > to {
+		return "", "", errors.New("from must not be after to")
+	}
+	return from, to, nil
+}
+
+// period is the date range a request covers.
+type period struct {
+	From, To string
+	// Label names the term or academic year; YearID is 0 unless one was selected
+	Label  string
+	YearID int
+}
+
+// requestPeriod resolves the term_id or year_id query param to its dates and
+// falls back to from/to (see dateRange) when neither is given.
+func requestPeriod(r *http.Request, defaultDays int) (period, error) {
+	query := r.URL.Query()
+	switch {
+	case query.Get("term_id") != "":
+		id, err := strconv.Atoi(query.Get("term_id"))
+		if err != nil {
+			return period{}, errors.New("invalid term_id")
+		}
+		term, err := sqlconnect.GetTermByID(r.Context(), id)
+		if err != nil {
+			return period{}, err
+		}
+		year, err := sqlconnect.GetYearByID(r.Context(), term.YearID)
+		if err != nil {
+			return period{}, err
+		}
+		return period{From: term.StartDate, To: term.EndDate, Label: term.Name + " " + year.Name, YearID: year.ID}, nil
+	case query.Get("year_id") != "":
+		id, err := strconv.Atoi(query.Get("year_id"))
+		if err != nil {
+			return period{}, errors.New("invalid year_id")
+		}
+		year, err := sqlconnect.GetYearByID(r.Context(), id)
+		if err != nil {
+			return period{}, err
+		}
+		return period{From: year.StartDate, To: year.EndDate, Label: year.Name, YearID: year.ID}, nil
+	}
+
+	from, to, err := dateRange(r, defaultDays)
+	if err != nil {
+		return period{}, err
+	}
+	return period{From: from, To: to}, nil
+}
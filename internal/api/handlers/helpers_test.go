@@ -1,7 +1,12 @@
 package handlers
 
 import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http/httptest"
 	"restapi/internal/models"
+	"strings"
 	"testing"
 )
 
@@ -91,3 +96,69 @@ func TestGetFieldNames(t *testing.T) {
 	}
 }
 
+func TestReadBulkBody(t *testing.T) {
+	csvBody := "first_name,last_name,email,class\nO'Brien,Doe,<b>john@example.com</b>,10A\n"
+
+	var multipartBody bytes.Buffer
+	mw := multipart.NewWriter(&multipartBody)
+	part, _ := mw.CreateFormFile("file", "students.csv")
+	part.Write([]byte(csvBody))
+	mw.Close()
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantErr     bool
+		wantEmail   string
+	}{
+		{"json passes through", "application/json", `[{"email":"john@example.com"}]`, false, "john@example.com"},
+		// Заголовок CSV задаёт JSON-поля, значения чистятся по тегам модели
+		{"csv", "text/csv", csvBody, false, "john@example.com"},
+		{"multipart file", mw.FormDataContentType(), multipartBody.String(), false, "john@example.com"},
+		{"csv without records", "text/csv", "first_name,email\n", true, ""},
+		{"ragged csv", "text/csv", "first_name,email\nJohn\n", true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/students", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+
+			body, err := readBulkBody(req, models.Student{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readBulkBody() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			var students []models.Student
+			if err := json.Unmarshal(body, &students); err != nil {
+				t.Fatalf("readBulkBody() returned invalid JSON %s: %v", body, err)
+			}
+			if len(students) != 1 || students[0].Email != tt.wantEmail {
+				t.Errorf("readBulkBody() = %s, want one student with email %q", body, tt.wantEmail)
+			}
+		})
+	}
+}
+
+func TestReadBulkBodyCustomColumns(t *testing.T) {
+	// Столбцы custom.<имя> попадают в объект custom, как в экспорте
+	req := httptest.NewRequest("POST", "/students", strings.NewReader("first_name,custom.bus_route,custom.allergies\nAnna, 12 ,\n"))
+	req.Header.Set("Content-Type", "text/csv")
+
+	body, err := readBulkBody(req, models.Student{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var students []models.Student
+	if err := json.Unmarshal(body, &students); err != nil {
+		t.Fatalf("readBulkBody() returned invalid JSON %s: %v", body, err)
+	}
+	if len(students) != 1 || students[0].FirstName != "Anna" || len(students[0].Custom) != 2 ||
+		students[0].Custom["bus_route"] != "12" || students[0].Custom["allergies"] != "" {
+		t.Errorf("readBulkBody() = %s, want Anna with bus_route 12 and empty allergies", body)
+	}
+}
@@ -0,0 +1,323 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"restapi/internal/models"
+	"restapi/internal/repository/sqlconnect"
+	"strconv"
+	"time"
+)
+
+// GetLeaveRequestsHandler lists leave filtered by status, teacher_id and the
+// from/to period it overlaps.
+func GetLeaveRequestsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	status := query.Get("status")
+	switch models.LeaveStatus(status) {
+	case "", models.LeavePending, models.LeaveApproved, models.LeaveRejected, models.LeaveCancelled:
+	default:
+		http.Error(w, "invalid status", http.StatusBadRequest)
+		return
+	}
+	teacherID := 0
+	if value := query.Get("teacher_id"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			http.Error(w, "invalid teacher_id", http.StatusBadRequest)
+			return
+		}
+		teacherID = id
+	}
+	var from, to string
+	if query.Get("from") != "" || query.Get("to") != "" {
+		var err error
+		if from, to, err = dateRange(r, 30); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	requests, err := sqlconnect.GetLeaveRequests(r.Context(), status, teacherID, from, to)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string                `json:"status"`
+		Count  int                   `json:"count"`
+		Data   []models.LeaveRequest `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(requests),
+		Data:   requests,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func GetOneLeaveHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid Leave Id", http.StatusBadRequest)
+		return
+	}
+
+	leave, err := sqlconnect.GetLeaveByID(r.Context(), id)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(leave)
+}
+
+// AddLeaveRequestHandler files leave for a teacher; it waits for a manager's
+// decision.
+func AddLeaveRequestHandler(w http.ResponseWriter, r *http.Request) {
+	var leave models.LeaveRequest
+	if err := json.NewDecoder(r.Body).Decode(&leave); err != nil {
+		log.Println(err)
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if err := validateLeave(&leave); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	leave.RequestedBy = currentUserID(r)
+
+	added, err := sqlconnect.AddLeaveRequest(r.Context(), leave)
+	if errors.Is(err, sqlconnect.ErrLeaveOverlap) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(added)
+}
+
+// validateLeave checks the teacher and the days of the leave; one-day leave
+// starts and ends on the same date.
+func validateLeave(l *models.LeaveRequest) error {
+	if l.TeacherID <= 0 {
+		return errors.New("teacher_id is required")
+	}
+	var err error
+	if l.StartDate, err = parseDate(l.StartDate); err != nil {
+		return err
+	}
+	if l.EndDate, err = parseDate(l.EndDate); err != nil {
+		return err
+	}
+	if l.StartDate > l.EndDate {
+		return errors.New("start_date must not be after end_date")
+	}
+	return nil
+}
+
+// DecideLeaveHandler approves, rejects or cancels leave.
+func DecideLeaveHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid Leave Id", http.StatusBadRequest)
+		return
+	}
+
+	var decision models.LeaveDecision
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&decision); err != nil {
+		log.Println(err)
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	switch decision.Status {
+	case models.LeaveApproved, models.LeaveRejected, models.LeaveCancelled:
+	default:
+		http.Error(w, "status must be approved, rejected or cancelled", http.StatusBadRequest)
+		return
+	}
+
+	leave, err := sqlconnect.DecideLeave(r.Context(), id, decision, currentUserID(r))
+	if errors.Is(err, sqlconnect.ErrLeaveDecided) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(leave)
+}
+
+// GetLeaveCoverHandler lists the lessons missed through the leave with their
+// substitutes, or up to limit (default 5) suggested substitutes.
+func GetLeaveCoverHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid Leave Id", http.StatusBadRequest)
+		return
+	}
+	limit := 5
+	if value := r.URL.Query().Get("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > 50 {
+			http.Error(w, "limit must be between 1 and 50", http.StatusBadRequest)
+			return
+		}
+	}
+
+	lessons, err := sqlconnect.GetLeaveCover(r.Context(), id, limit)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusNotFound)
+		return
+	}
+
+	uncovered := 0
+	for _, lesson := range lessons {
+		if lesson.Substitution == nil {
+			uncovered++
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status    string               `json:"status"`
+		Count     int                  `json:"count"`
+		Uncovered int                  `json:"uncovered"`
+		Data      []models.CoverLesson `json:"data"`
+	}{
+		Status:    "success",
+		Count:     len(lessons),
+		Uncovered: uncovered,
+		Data:      lessons,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// AssignSubstituteHandler puts a substitute on one missed lesson and emails
+// the substitute and the class after responding.
+func AssignSubstituteHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid Leave Id", http.StatusBadRequest)
+		return
+	}
+
+	var sub models.Substitution
+	if err := json.NewDecoder(r.Body).Decode(&sub); err != nil {
+		log.Println(err)
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if sub.SlotID <= 0 || sub.TeacherID <= 0 {
+		http.Error(w, "slot_id and teacher_id are required", http.StatusBadRequest)
+		return
+	}
+	if sub.Date, err = parseDate(sub.Date); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	sub.AssignedBy = currentUserID(r)
+
+	saved, err := sqlconnect.AssignSubstitute(r.Context(), id, sub)
+	if errors.Is(err, sqlconnect.ErrLeaveNotApproved) || errors.Is(err, sqlconnect.ErrSubstituteBusy) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// Mail goes out after the response, like absence alerts, together with
+	// notices that failed earlier
+	runAfterResponse(r, 30*time.Second, func(ctx context.Context) {
+		if err := sqlconnect.NotifySubstitutions(ctx); err != nil {
+			log.Println("Error sending substitution notices:", err)
+		}
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(saved)
+}
+
+func DeleteSubstitutionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid Substitution Id", http.StatusBadRequest)
+		return
+	}
+
+	if err := sqlconnect.DeleteSubstitution(r.Context(), id); err != nil {
+		dbErrorResponse(w, err, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string `json:"status"`
+		ID     int    `json:"id"`
+	}{
+		Status: "Substitution successfully deleted",
+		ID:     id,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetTeacherSubstitutionsHandler lists the lessons a teacher covers over the
+// term_id, year_id or from/to period, by default the next 14 days.
+func GetTeacherSubstitutionsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid Teacher Id", http.StatusBadRequest)
+		return
+	}
+
+	today := time.Now()
+	p := period{From: today.Format(time.DateOnly), To: today.AddDate(0, 0, 13).Format(time.DateOnly)}
+	query := r.URL.Query()
+	if query.Get("term_id") != "" || query.Get("year_id") != "" || query.Get("from") != "" || query.Get("to") != "" {
+		if p, err = requestPeriod(r, 14); err != nil {
+			dbErrorResponse(w, err, http.StatusBadRequest)
+			return
+		}
+	}
+
+	subs, err := sqlconnect.GetTeacherSubstitutions(r.Context(), id, p.From, p.To)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string                `json:"status"`
+		From   string                `json:"from"`
+		To     string                `json:"to"`
+		Count  int                   `json:"count"`
+		Data   []models.Substitution `json:"data"`
+	}{
+		Status: "success",
+		From:   p.From,
+		To:     p.To,
+		Count:  len(subs),
+		Data:   subs,
+	}
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"restapi/internal/models"
+	"testing"
+)
+
+func TestValidateLeave(t *testing.T) {
+	tests := []struct {
+		name    string
+		leave   models.LeaveRequest
+		wantErr bool
+	}{
+		{"valid", models.LeaveRequest{TeacherID: 1, StartDate: "2024-09-02", EndDate: "2024-09-06"}, false},
+		// Отпуск на один день начинается и заканчивается в одну дату
+		{"one day", models.LeaveRequest{TeacherID: 1, StartDate: "2024-09-02", EndDate: "2024-09-02"}, false},
+		{"no teacher", models.LeaveRequest{StartDate: "2024-09-02", EndDate: "2024-09-06"}, true},
+		{"bad date", models.LeaveRequest{TeacherID: 1, StartDate: "02.09.2024", EndDate: "2024-09-06"}, true},
+		{"end before start", models.LeaveRequest{TeacherID: 1, StartDate: "2024-09-06", EndDate: "2024-09-02"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateLeave(&tt.leave); (err != nil) != tt.wantErr {
+				t.Errorf("validateLeave() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,321 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"restapi/internal/models"
+	"restapi/internal/repository/sqlconnect"
+	"restapi/pkg/utils"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// GetNotesHandler lists the notes the user may read across all contacts,
+// filtered by subject, type, visibility, author and tags.
+func GetNotesHandler(w http.ResponseWriter, r *http.Request) {
+	writeNotes(w, r, "", 0)
+}
+
+// ContactNotesHandler lists the notes of one contact of subjectType.
+func ContactNotesHandler(subjectType string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := pathID(r)
+		if err != nil {
+			http.Error(w, "Invalid Id", http.StatusBadRequest)
+			return
+		}
+		if _, err := sqlconnect.ContactEmail(r.Context(), subjectType, id); err != nil {
+			dbErrorResponse(w, err, http.StatusNotFound)
+			return
+		}
+		writeNotes(w, r, subjectType, id)
+	}
+}
+
+func writeNotes(w http.ResponseWriter, r *http.Request, subjectType string, subjectID int) {
+	page, limit := getPaginationParams(r)
+	notes, total, err := sqlconnect.GetNotes(r.Context(), r, subjectType, subjectID, limit, page)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	response := struct {
+		Status   string        `json:"status"`
+		Count    int           `json:"count"`
+		Page     int           `json:"page"`
+		PageSize int           `json:"page_size"`
+		Data     []models.Note `json:"data"`
+	}{
+		Status:   "success",
+		Count:    total,
+		Page:     page,
+		PageSize: limit,
+		Data:     notes,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// AddContactNoteHandler records a note against a contact of subjectType;
+// the author is the user from the JWT.
+func AddContactNoteHandler(subjectType string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := pathID(r)
+		if err != nil {
+			http.Error(w, "Invalid Id", http.StatusBadRequest)
+			return
+		}
+
+		var note models.Note
+		if err := json.NewDecoder(r.Body).Decode(&note); err != nil {
+			log.Println(err)
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+		if err := validateNote(&note); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if _, err := sqlconnect.ContactEmail(r.Context(), subjectType, id); err != nil {
+			dbErrorResponse(w, err, http.StatusNotFound)
+			return
+		}
+		note.SubjectType = subjectType
+		note.SubjectID = id
+		note.AuthorID = currentUserID(r)
+
+		added, err := sqlconnect.AddNote(r.Context(), note)
+		if err != nil {
+			dbErrorResponse(w, err, http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(added)
+	}
+}
+
+// validateNote requires a body and checks the type, visibility and tags;
+// notes default to type note, visible to all staff.
+func validateNote(n *models.Note) error {
+	n.Type = strings.ToLower(strings.TrimSpace(n.Type))
+	if n.Type == "" {
+		n.Type = "note"
+	}
+	if !slices.Contains(models.NoteTypes, n.Type) {
+		return errors.New("type must be one of " + strings.Join(models.NoteTypes, ", "))
+	}
+	n.Body = strings.TrimSpace(n.Body)
+	if n.Body == "" {
+		return errors.New("body is required")
+	}
+	if utf8.RuneCountInString(n.Body) > 10000 {
+		return errors.New("body must be at most 10000 characters")
+	}
+	switch n.Visibility {
+	case "":
+		n.Visibility = models.VisibleToStaff
+	case models.VisibleToStaff, models.VisibleToManagers, models.VisibleToAuthor:
+	default:
+		return errors.New("visibility must be staff, managers or private")
+	}
+	tags, err := models.NormalizeTags(n.Tags)
+	if err != nil {
+		return err
+	}
+	n.Tags = tags
+	return nil
+}
+
+// visibleNote loads the note and hides it as missing from users who may not
+// read it.
+func visibleNote(r *http.Request, id int) (models.Note, error) {
+	note, err := sqlconnect.GetNoteByID(r.Context(), id)
+	if err != nil {
+		return models.Note{}, err
+	}
+	if !note.VisibleTo(utils.UserRole(r.Context()), currentUserID(r)) {
+		return models.Note{}, errors.New("Note not found")
+	}
+	return note, nil
+}
+
+func GetOneNoteHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid Note Id", http.StatusBadRequest)
+		return
+	}
+
+	note, err := visibleNote(r, id)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(note)
+}
+
+// canChangeNote allows the author and admins to edit or delete a note.
+func canChangeNote(r *http.Request, note models.Note) bool {
+	return utils.UserRole(r.Context()) == "admin" || note.AuthorID == currentUserID(r)
+}
+
+// PatchNoteHandler applies the type, body, visibility and tags present in the
+// body; the contact and author stay.
+func PatchNoteHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid Note Id", http.StatusBadRequest)
+		return
+	}
+
+	note, err := visibleNote(r, id)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusNotFound)
+		return
+	}
+	if !canChangeNote(r, note) {
+		http.Error(w, models.ErrNoteForbidden.Error(), http.StatusForbidden)
+		return
+	}
+
+	var patch struct {
+		Type       *string                `json:"type"`
+		Body       *string                `json:"body"`
+		Visibility *models.NoteVisibility `json:"visibility"`
+		Tags       *[]string              `json:"tags"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&patch); err != nil {
+		log.Println(err)
+		http.Error(w, "Invalid Request Payload", http.StatusBadRequest)
+		return
+	}
+	if patch.Type != nil {
+		note.Type = *patch.Type
+	}
+	if patch.Body != nil {
+		note.Body = *patch.Body
+	}
+	if patch.Visibility != nil {
+		note.Visibility = *patch.Visibility
+	}
+	if patch.Tags != nil {
+		note.Tags = *patch.Tags
+	}
+	if err := validateNote(&note); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	updated, err := sqlconnect.UpdateNote(r.Context(), note)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+func DeleteNoteHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid Note Id", http.StatusBadRequest)
+		return
+	}
+
+	note, err := visibleNote(r, id)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusNotFound)
+		return
+	}
+	if !canChangeNote(r, note) {
+		http.Error(w, models.ErrNoteForbidden.Error(), http.StatusForbidden)
+		return
+	}
+
+	if err := sqlconnect.DeleteNote(r.Context(), id); err != nil {
+		dbErrorResponse(w, err, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string `json:"status"`
+		ID     int    `json:"id"`
+	}{
+		Status: "Note successfully deleted",
+		ID:     id,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetNoteTagsHandler lists the tags in use on notes the user may read.
+func GetNoteTagsHandler(w http.ResponseWriter, r *http.Request) {
+	tags, err := sqlconnect.GetNoteTags(r.Context())
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string            `json:"status"`
+		Count  int               `json:"count"`
+		Data   []models.TagCount `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(tags),
+		Data:   tags,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// ContactTimelineHandler merges the notes, record changes and emails of a
+// contact of subjectType, newest first, up to limit (default 50) events.
+func ContactTimelineHandler(subjectType string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := pathID(r)
+		if err != nil {
+			http.Error(w, "Invalid Id", http.StatusBadRequest)
+			return
+		}
+		limit := 50
+		if value := r.URL.Query().Get("limit"); value != "" {
+			limit, err = strconv.Atoi(value)
+			if err != nil || limit < 1 || limit > 500 {
+				http.Error(w, "limit must be between 1 and 500", http.StatusBadRequest)
+				return
+			}
+		}
+
+		events, err := sqlconnect.GetContactTimeline(r.Context(), subjectType, id, limit)
+		if err != nil {
+			dbErrorResponse(w, err, http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		response := struct {
+			Status string                `json:"status"`
+			Count  int                   `json:"count"`
+			Data   []models.ContactEvent `json:"data"`
+		}{
+			Status: "success",
+			Count:  len(events),
+			Data:   events,
+		}
+		json.NewEncoder(w).Encode(response)
+	}
+}
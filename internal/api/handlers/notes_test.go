@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"reflect"
+	"restapi/internal/models"
+	"testing"
+)
+
+func TestValidateNote(t *testing.T) {
+	tests := []struct {
+		name    string
+		note    models.Note
+		want    models.Note
+		wantErr bool
+	}{
+		// По умолчанию заметка видна всем сотрудникам
+		{"defaults", models.Note{Body: " Called home "}, models.Note{Type: "note", Body: "Called home", Visibility: models.VisibleToStaff, Tags: []string{}}, false},
+		{"incident", models.Note{Type: "Incident", Body: "Fight in the canteen", Visibility: models.VisibleToManagers, Tags: []string{"Behaviour", "behaviour"}},
+			models.Note{Type: "incident", Body: "Fight in the canteen", Visibility: models.VisibleToManagers, Tags: []string{"behaviour"}}, false},
+		{"empty body", models.Note{Body: " "}, models.Note{}, true},
+		{"unknown type", models.Note{Type: "sms", Body: "Hi"}, models.Note{}, true},
+		{"unknown visibility", models.Note{Body: "Hi", Visibility: "public"}, models.Note{}, true},
+		{"bad tag", models.Note{Body: "Hi", Tags: []string{"a b"}}, models.Note{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateNote(&tt.note)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateNote() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(tt.note, tt.want) {
+				t.Errorf("validateNote() = %+v, want %+v", tt.note, tt.want)
+			}
+		})
+	}
+}
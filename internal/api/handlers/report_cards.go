@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"restapi/internal/models"
+	"restapi/internal/reports"
+	"restapi/internal/repository/sqlconnect"
+	"strconv"
+	"strings"
+)
+
+// reportPeriod reads the period of a report: term_id or year_id, or a term
+// label with from/to (default: the last 90 days). The label keys the teacher
+// comments and is printed on the card.
+func reportPeriod(r *http.Request) (period, error) {
+	p, err := requestPeriod(r, 90)
+	if err != nil {
+		return period{}, err
+	}
+	if term := strings.TrimSpace(r.URL.Query().Get("term")); term != "" {
+		p.Label = term
+	}
+	if p.Label == "" {
+		return period{}, fmt.Errorf("term, term_id or year_id is required")
+	}
+	return p, nil
+}
+
+// GetReportCardHandler returns a student's report card for the term as a PDF.
+func GetReportCardHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid Student Id", http.StatusBadRequest)
+		return
+	}
+
+	p, err := reportPeriod(r)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	card, err := sqlconnect.GetReportCard(r.Context(), id, p.Label, p.From, p.To)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusNotFound)
+		return
+	}
+
+	pdf, err := reports.ReportCard(card)
+	if err != nil {
+		log.Println("Error rendering report card:", err)
+		http.Error(w, "Error generating report card", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="report-card-%d.pdf"`, id))
+	w.Header().Set("Content-Length", strconv.Itoa(len(pdf)))
+	w.Write(pdf)
+}
+
+// GetClassReportCardsHandler returns the report cards of a whole class as a zip
+// of PDFs. With term_id or year_id the class is taken as enrolled that year.
+func GetClassReportCardsHandler(w http.ResponseWriter, r *http.Request) {
+	class := r.PathValue("class")
+
+	p, err := reportPeriod(r)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	cards, err := sqlconnect.GetClassReportCards(r.Context(), class, p.YearID, p.Label, p.From, p.To)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusInternalServerError)
+		return
+	}
+	if len(cards) == 0 {
+		http.Error(w, "No students in class "+class, http.StatusNotFound)
+		return
+	}
+
+	// Rendered in memory first so a failure can still be reported as an error
+	var archive bytes.Buffer
+	if err := reports.ClassZip(&archive, cards); err != nil {
+		log.Println("Error rendering report cards:", err)
+		http.Error(w, "Error generating report cards", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="report-cards-%s.zip"`, safeFileName(class)))
+	w.Header().Set("Content-Length", strconv.Itoa(archive.Len()))
+	w.Write(archive.Bytes())
+}
+
+// safeFileName keeps a path value usable inside a Content-Disposition header.
+func safeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '"' || r == '\\' || r == '/' || r < 0x20 {
+			return '-'
+		}
+		return r
+	}, name)
+}
+
+// SetReportCommentHandler saves a teacher comment for the student's term report;
+// without a subject it is the general comment.
+func SetReportCommentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid Student Id", http.StatusBadRequest)
+		return
+	}
+
+	var comment models.ReportComment
+	err = json.NewDecoder(r.Body).Decode(&comment)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	comment.Term = strings.TrimSpace(comment.Term)
+	if comment.Term == "" || strings.TrimSpace(comment.Comment) == "" {
+		http.Error(w, "term and comment are required", http.StatusBadRequest)
+		return
+	}
+	comment.StudentID = id
+	comment.AuthorID = currentUserID(r)
+
+	saved, err := sqlconnect.SetReportComment(r.Context(), comment)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(saved)
+}
@@ -3,7 +3,6 @@ package handlers
 import (
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"restapi/internal/models"
@@ -11,16 +10,55 @@ import (
 	"strconv"
 )
 
+// GetStudentsHandler lists a page of students with their custom fields, or
+// with format=csv exports every matching student.
 func GetStudentsHandler(w http.ResponseWriter, r *http.Request) {
 
 	var students []models.Student
 
-	page, limit := getPaginationParams(r)
-	students, totalStudents, err := sqlconnect.GetStudentsDbHandler(students, r, limit, page)
+	format, err := listFormat(r)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	fields, err := sqlconnect.VisibleCustomFields(r.Context(), models.ContactStudent)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	page, limit := getPaginationParams(r)
+	if format == "csv" {
+		page, limit = 1, 0
+	}
+	students, totalStudents, err := sqlconnect.GetStudentsDbHandler(r.Context(), students, r, limit, page, fields)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	ids := make([]int, len(students))
+	for i, student := range students {
+		ids[i] = student.ID
+	}
+	custom, err := sqlconnect.GetCustomValues(r.Context(), fields, ids)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	if format == "csv" {
+		rows := make([][]string, len(students))
+		for i, s := range students {
+			rows[i] = append([]string{strconv.Itoa(s.ID), s.FirstName, s.LastName, s.Email, s.Class}, customCells(fields, custom[s.ID])...)
+		}
+		header := append([]string{"id", "first_name", "last_name", "email", "class"}, customColumns(fields)...)
+		writeCSV(w, "students", header, rows)
+		return
+	}
+	for i := range students {
+		students[i].Custom = fields.Decode(custom[students[i].ID])
+	}
 
 	response := struct {
 		Status   string           `json:"status"`
@@ -65,12 +103,24 @@ func GetOneStudentHandler(w http.ResponseWriter, r *http.Request) {
 		fmt.Println(err)
 		return
 	}
-	student, err := sqlconnect.GetStudentByID(id)
+	student, err := sqlconnect.GetStudentByID(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		dbErrorResponse(w, err, http.StatusInternalServerError)
 		return
 	}
 
+	fields, err := sqlconnect.VisibleCustomFields(r.Context(), models.ContactStudent)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusInternalServerError)
+		return
+	}
+	custom, err := sqlconnect.GetCustomValues(r.Context(), fields, []int{id})
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusInternalServerError)
+		return
+	}
+	student.Custom = fields.Decode(custom[id])
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(student)
 }
@@ -80,9 +130,9 @@ func AddStudentHandler(w http.ResponseWriter, r *http.Request) {
 	var newStudents []models.Student
 	var rawStudents []map[string]interface{}
 
-	body, err := io.ReadAll(r.Body)
+	body, err := readBulkBody(r, models.Student{})
 	if err != nil {
-		http.Error(w, "Error reading request body", http.StatusInternalServerError)
+		readBodyError(w, err)
 		return
 	}
 	defer r.Body.Close()
@@ -125,11 +175,28 @@ func AddStudentHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	addedStudents, err := sqlconnect.AddStudentsDBHandler(newStudents)
+	customFields, err := sqlconnect.VisibleCustomFields(r.Context(), models.ContactStudent)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusInternalServerError)
+		return
+	}
+	customValues := make([]map[string]string, len(newStudents))
+	for i, student := range newStudents {
+		customValues[i], err = customFields.Parse(student.Custom, true)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	addedStudents, err := sqlconnect.AddStudentsDBHandler(r.Context(), newStudents, customFields, customValues)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		dbErrorResponse(w, err, http.StatusInternalServerError)
 		return
 	}
+	for i := range addedStudents {
+		addedStudents[i].Custom = customFields.Decode(customValues[i])
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
@@ -163,12 +230,25 @@ func UpdateStudentHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	updatedStudentFromDB, err := sqlconnect.UpdateStudent(id, updatedStudent)
+	// Replacing a student replaces the custom fields the caller can see
+	fields, err := sqlconnect.VisibleCustomFields(r.Context(), models.ContactStudent)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		dbErrorResponse(w, err, http.StatusInternalServerError)
+		return
+	}
+	customValues, err := fields.Parse(updatedStudent.Custom, true)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	updatedStudentFromDB, err := sqlconnect.UpdateStudent(r.Context(), id, updatedStudent, fields, customValues)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusInternalServerError)
+		return
+	}
+	updatedStudentFromDB.Custom = fields.Decode(customValues)
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(updatedStudentFromDB)
 }
@@ -183,10 +263,24 @@ func PatchStudentsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = sqlconnect.PatchStudent(updates)
+	fields, err := sqlconnect.VisibleCustomFields(r.Context(), models.ContactStudent)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusInternalServerError)
+		return
+	}
+	customValues := make([]map[string]string, len(updates))
+	for i, update := range updates {
+		customValues[i], err = takeCustom(update, fields)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	err = sqlconnect.PatchStudent(r.Context(), updates, fields, customValues)
 	if err != nil {
 		log.Println(err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		dbErrorResponse(w, err, http.StatusBadRequest)
 		return
 	}
 	w.WriteHeader(http.StatusNoContent)
@@ -209,12 +303,29 @@ func PatchOneStudentHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	updatedStudent, err := sqlconnect.PatchOneStudent(id, updates)
+	fields, err := sqlconnect.VisibleCustomFields(r.Context(), models.ContactStudent)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		dbErrorResponse(w, err, http.StatusInternalServerError)
+		return
+	}
+	customValues, err := takeCustom(updates, fields)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	updatedStudent, err := sqlconnect.PatchOneStudent(r.Context(), id, updates, fields, customValues)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusInternalServerError)
+		return
+	}
+	custom, err := sqlconnect.GetCustomValues(r.Context(), fields, []int{id})
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusInternalServerError)
+		return
+	}
+	updatedStudent.Custom = fields.Decode(custom[id])
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(updatedStudent)
 
@@ -229,9 +340,9 @@ func DeleteOneStudentHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = sqlconnect.DeleteOneStudent(id)
+	err = sqlconnect.DeleteOneStudent(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		dbErrorResponse(w, err, http.StatusBadRequest)
 		return
 	}
 
@@ -256,9 +367,9 @@ func DeleteStudentsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	deletedIds, err := sqlconnect.DeleteStudents(ids)
+	deletedIds, err := sqlconnect.DeleteStudents(r.Context(), ids)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		dbErrorResponse(w, err, http.StatusBadRequest)
 		return
 	}
 
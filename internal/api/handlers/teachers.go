@@ -3,7 +3,6 @@ package handlers
 import (
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"restapi/internal/models"
@@ -12,14 +11,51 @@ import (
 	"strconv"
 )
 
+// GetTeachersHandler lists the teachers with their custom fields, or with
+// format=csv exports them.
 func GetTeachersHandler(w http.ResponseWriter, r *http.Request) {
 
 	var teachers []models.Teacher
-	teachers, err := sqlconnect.GetTeachersDbHandler(teachers, r)
+
+	format, err := listFormat(r)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	fields, err := sqlconnect.VisibleCustomFields(r.Context(), models.ContactTeacher)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	teachers, err = sqlconnect.GetTeachersDbHandler(r.Context(), teachers, r, fields)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	ids := make([]int, len(teachers))
+	for i, teacher := range teachers {
+		ids[i] = teacher.ID
+	}
+	custom, err := sqlconnect.GetCustomValues(r.Context(), fields, ids)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	if format == "csv" {
+		rows := make([][]string, len(teachers))
+		for i, t := range teachers {
+			rows[i] = append([]string{strconv.Itoa(t.ID), t.FirstName, t.LastName, t.Email, t.Class, t.Subject}, customCells(fields, custom[t.ID])...)
+		}
+		header := append([]string{"id", "first_name", "last_name", "email", "class", "subject"}, customColumns(fields)...)
+		writeCSV(w, "teachers", header, rows)
+		return
+	}
+	for i := range teachers {
+		teachers[i].Custom = fields.Decode(custom[teachers[i].ID])
+	}
 
 	response := struct {
 		Status string           `json:"status"`
@@ -46,12 +82,24 @@ func GetOneTeacherHandler(w http.ResponseWriter, r *http.Request) {
 		fmt.Println(err)
 		return
 	}
-	teacher, err := sqlconnect.GetTeacherByID(id)
+	teacher, err := sqlconnect.GetTeacherByID(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		dbErrorResponse(w, err, http.StatusInternalServerError)
 		return
 	}
 
+	fields, err := sqlconnect.VisibleCustomFields(r.Context(), models.ContactTeacher)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusInternalServerError)
+		return
+	}
+	custom, err := sqlconnect.GetCustomValues(r.Context(), fields, []int{id})
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusInternalServerError)
+		return
+	}
+	teacher.Custom = fields.Decode(custom[id])
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(teacher)
 }
@@ -61,9 +109,9 @@ func AddTeacherHandler(w http.ResponseWriter, r *http.Request) {
 	var newTeachers []models.Teacher
 	var rawTeachers []map[string]interface{}
 
-	body, err := io.ReadAll(r.Body)
+	body, err := readBulkBody(r, models.Teacher{})
 	if err != nil {
-		http.Error(w, "Error reading request body", http.StatusInternalServerError)
+		readBodyError(w, err)
 		return
 	}
 	defer r.Body.Close()
@@ -106,11 +154,28 @@ func AddTeacherHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	addedTeachers, err := sqlconnect.AddTeachersDBHandler(newTeachers)
+	customFields, err := sqlconnect.VisibleCustomFields(r.Context(), models.ContactTeacher)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		dbErrorResponse(w, err, http.StatusInternalServerError)
+		return
+	}
+	customValues := make([]map[string]string, len(newTeachers))
+	for i, teacher := range newTeachers {
+		customValues[i], err = customFields.Parse(teacher.Custom, true)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	addedTeachers, err := sqlconnect.AddTeachersDBHandler(r.Context(), newTeachers, customFields, customValues)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusInternalServerError)
 		return
 	}
+	for i := range addedTeachers {
+		addedTeachers[i].Custom = customFields.Decode(customValues[i])
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
@@ -144,11 +209,24 @@ func UpdateTeacherHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	updatedTeacherFromDB, err := sqlconnect.UpdateTeacher(id, updatedTeacher)
+	// Replacing a teacher replaces the custom fields the caller can see
+	fields, err := sqlconnect.VisibleCustomFields(r.Context(), models.ContactTeacher)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		dbErrorResponse(w, err, http.StatusInternalServerError)
+		return
+	}
+	customValues, err := fields.Parse(updatedTeacher.Custom, true)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	updatedTeacherFromDB, err := sqlconnect.UpdateTeacher(r.Context(), id, updatedTeacher, fields, customValues)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusInternalServerError)
 		return
 	}
+	updatedTeacherFromDB.Custom = fields.Decode(customValues)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(updatedTeacherFromDB)
@@ -164,10 +242,24 @@ func PatchTeachersHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = sqlconnect.PatchTeachers(updates)
+	fields, err := sqlconnect.VisibleCustomFields(r.Context(), models.ContactTeacher)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusInternalServerError)
+		return
+	}
+	customValues := make([]map[string]string, len(updates))
+	for i, update := range updates {
+		customValues[i], err = takeCustom(update, fields)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	err = sqlconnect.PatchTeachers(r.Context(), updates, fields, customValues)
 	if err != nil {
 		log.Println(err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		dbErrorResponse(w, err, http.StatusBadRequest)
 		return
 	}
 	w.WriteHeader(http.StatusNoContent)
@@ -190,11 +282,28 @@ func PatchOneTeacherHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	updatedTeacher, err := sqlconnect.PatchOneTeacher(id, updates)
+	fields, err := sqlconnect.VisibleCustomFields(r.Context(), models.ContactTeacher)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		dbErrorResponse(w, err, http.StatusInternalServerError)
+		return
+	}
+	customValues, err := takeCustom(updates, fields)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	updatedTeacher, err := sqlconnect.PatchOneTeacher(r.Context(), id, updates, fields, customValues)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusInternalServerError)
+		return
+	}
+	custom, err := sqlconnect.GetCustomValues(r.Context(), fields, []int{id})
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusInternalServerError)
 		return
 	}
+	updatedTeacher.Custom = fields.Decode(custom[id])
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(updatedTeacher)
@@ -210,9 +319,9 @@ func DeleteOneTeacherHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = sqlconnect.DeleteOneTeacher(id)
+	err = sqlconnect.DeleteOneTeacher(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		dbErrorResponse(w, err, http.StatusBadRequest)
 		return
 	}
 
@@ -236,9 +345,9 @@ func DeleteTeachersHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	deletedIds, err := sqlconnect.DeleteTeachers(ids)
+	deletedIds, err := sqlconnect.DeleteTeachers(r.Context(), ids)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		dbErrorResponse(w, err, http.StatusBadRequest)
 		return
 	}
 
@@ -258,9 +367,9 @@ func GetStudentsByTeacherId(w http.ResponseWriter, r *http.Request) {
 
 	var students []models.Student
 
-	students, err := sqlconnect.GetStudentsByTeacherIdFromDb(teacherId, students)
+	students, err := sqlconnect.GetStudentsByTeacherIdFromDb(r.Context(), teacherId, students)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		dbErrorResponse(w, err, http.StatusInternalServerError)
 		return
 	}
 
@@ -280,7 +389,8 @@ func GetStudentsByTeacherId(w http.ResponseWriter, r *http.Request) {
 
 func GetStudentCountByTeacherId(w http.ResponseWriter, r *http.Request) {
 
-	_, err := utils.AuthorizeUser(r.Context().Value(utils.ContextKey("role")).(string), "admin", "manager", "exec")
+	role, _ := r.Context().Value(utils.ContextKey("role")).(string)
+	_, err := utils.AuthorizeUser(role, "admin", "manager", "exec")
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -288,9 +398,9 @@ func GetStudentCountByTeacherId(w http.ResponseWriter, r *http.Request) {
 
 	teacherId := r.PathValue("id")
 
-	studentCount, err := sqlconnect.GetStudentCountByTeacherIdFromDb(teacherId)
+	studentCount, err := sqlconnect.GetStudentCountByTeacherIdFromDb(r.Context(), teacherId)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		dbErrorResponse(w, err, http.StatusInternalServerError)
 		return
 	}
 
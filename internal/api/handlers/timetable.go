@@ -0,0 +1,574 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"restapi/internal/models"
+	"restapi/internal/reports"
+	"restapi/internal/repository/sqlconnect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func GetPeriodsHandler(w http.ResponseWriter, r *http.Request) {
+	periods, err := sqlconnect.GetPeriods(r.Context())
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string          `json:"status"`
+		Count  int             `json:"count"`
+		Data   []models.Period `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(periods),
+		Data:   periods,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func AddPeriodHandler(w http.ResponseWriter, r *http.Request) {
+	var period models.Period
+	if err := json.NewDecoder(r.Body).Decode(&period); err != nil {
+		log.Println(err)
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	period.ID = 0
+
+	savePeriod(w, r, period, http.StatusCreated)
+}
+
+// PatchPeriodHandler applies the fields present in the body to the period.
+func PatchPeriodHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid Period Id", http.StatusBadRequest)
+		return
+	}
+
+	period, err := sqlconnect.GetPeriodByID(r.Context(), id)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusNotFound)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&period); err != nil {
+		log.Println(err)
+		http.Error(w, "Invalid Request Payload", http.StatusBadRequest)
+		return
+	}
+	period.ID = id
+
+	savePeriod(w, r, period, http.StatusOK)
+}
+
+func savePeriod(w http.ResponseWriter, r *http.Request, period models.Period, status int) {
+	if err := validateLessonPeriod(&period); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	saved, err := sqlconnect.SavePeriod(r.Context(), period)
+	if errors.Is(err, sqlconnect.ErrLessonPeriodOverlap) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(saved)
+}
+
+// validateLessonPeriod checks the name and normalizes the times to HH:MM:SS.
+func validateLessonPeriod(p *models.Period) error {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" {
+		return errors.New("name is required")
+	}
+	var err error
+	if p.StartTime, err = parseClock(p.StartTime); err != nil {
+		return err
+	}
+	if p.EndTime, err = parseClock(p.EndTime); err != nil {
+		return err
+	}
+	if p.StartTime >= p.EndTime {
+		return errors.New("start_time must be before end_time")
+	}
+	return nil
+}
+
+// parseClock accepts HH:MM or HH:MM:SS and returns HH:MM:SS.
+func parseClock(value string) (string, error) {
+	for _, layout := range []string{"15:04", time.TimeOnly} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.Format(time.TimeOnly), nil
+		}
+	}
+	return "", fmt.Errorf("invalid time %q, use HH:MM", value)
+}
+
+func DeletePeriodHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid Period Id", http.StatusBadRequest)
+		return
+	}
+
+	if err := sqlconnect.DeletePeriod(r.Context(), id); err != nil {
+		dbErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string `json:"status"`
+		ID     int    `json:"id"`
+	}{
+		Status: "Period successfully deleted",
+		ID:     id,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func GetRoomsHandler(w http.ResponseWriter, r *http.Request) {
+	rooms, err := sqlconnect.GetRooms(r.Context())
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string        `json:"status"`
+		Count  int           `json:"count"`
+		Data   []models.Room `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(rooms),
+		Data:   rooms,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func AddRoomHandler(w http.ResponseWriter, r *http.Request) {
+	var room models.Room
+	if err := json.NewDecoder(r.Body).Decode(&room); err != nil {
+		log.Println(err)
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	room.ID = 0
+
+	saveRoom(w, r, room, http.StatusCreated)
+}
+
+// PatchRoomHandler applies the fields present in the body to the room.
+func PatchRoomHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid Room Id", http.StatusBadRequest)
+		return
+	}
+
+	room, err := sqlconnect.GetRoomByID(r.Context(), id)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusNotFound)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&room); err != nil {
+		log.Println(err)
+		http.Error(w, "Invalid Request Payload", http.StatusBadRequest)
+		return
+	}
+	room.ID = id
+
+	saveRoom(w, r, room, http.StatusOK)
+}
+
+func saveRoom(w http.ResponseWriter, r *http.Request, room models.Room, status int) {
+	room.Name = strings.TrimSpace(room.Name)
+	if room.Name == "" {
+		http.Error(w, "name is required", http.StatusBadRequest)
+		return
+	}
+	if room.Capacity < 0 {
+		http.Error(w, "capacity must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	saved, err := sqlconnect.SaveRoom(r.Context(), room)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(saved)
+}
+
+func DeleteRoomHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid Room Id", http.StatusBadRequest)
+		return
+	}
+
+	if err := sqlconnect.DeleteRoom(r.Context(), id); err != nil {
+		dbErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string `json:"status"`
+		ID     int    `json:"id"`
+	}{
+		Status: "Room successfully deleted",
+		ID:     id,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func AddSlotHandler(w http.ResponseWriter, r *http.Request) {
+	var slot models.TimetableSlot
+	if err := json.NewDecoder(r.Body).Decode(&slot); err != nil {
+		log.Println(err)
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	slot.ID = 0
+
+	saveSlot(w, r, slot, http.StatusCreated)
+}
+
+// PatchSlotHandler applies the fields present in the body to the slot, e.g.
+// {"room_id": 4} to move a lesson; "room_id": 0 removes the room.
+func PatchSlotHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid Slot Id", http.StatusBadRequest)
+		return
+	}
+
+	slot, err := sqlconnect.GetSlotByID(r.Context(), id)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusNotFound)
+		return
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&slot); err != nil {
+		log.Println(err)
+		http.Error(w, "Invalid Request Payload", http.StatusBadRequest)
+		return
+	}
+	slot.ID = id
+
+	saveSlot(w, r, slot, http.StatusOK)
+}
+
+// saveSlot answers a clash with 409 and the list of conflicts.
+func saveSlot(w http.ResponseWriter, r *http.Request, slot models.TimetableSlot, status int) {
+	if err := validateSlot(&slot); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	saved, conflicts, err := sqlconnect.SaveSlot(r.Context(), slot)
+	if errors.Is(err, sqlconnect.ErrTimetableConflict) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		response := struct {
+			Status    string            `json:"status"`
+			Error     string            `json:"error"`
+			Conflicts []models.Conflict `json:"conflicts"`
+		}{
+			Status:    "conflict",
+			Error:     err.Error(),
+			Conflicts: conflicts,
+		}
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(saved)
+}
+
+// validateSlot checks the required fields of a slot.
+func validateSlot(s *models.TimetableSlot) error {
+	s.Class = strings.TrimSpace(s.Class)
+	s.Subject = strings.TrimSpace(s.Subject)
+	if s.Class == "" || s.Subject == "" {
+		return errors.New("class and subject are required")
+	}
+	if s.Weekday < 1 || s.Weekday > 7 {
+		return errors.New("weekday must be 1 (Monday) to 7 (Sunday)")
+	}
+	if s.PeriodID <= 0 || s.TeacherID <= 0 {
+		return errors.New("period_id and teacher_id are required")
+	}
+	if s.RoomID < 0 {
+		return errors.New("invalid room_id")
+	}
+	return nil
+}
+
+func DeleteSlotHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid Slot Id", http.StatusBadRequest)
+		return
+	}
+
+	if err := sqlconnect.DeleteSlot(r.Context(), id); err != nil {
+		dbErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string `json:"status"`
+		ID     int    `json:"id"`
+	}{
+		Status: "Timetable slot successfully deleted",
+		ID:     id,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func GetTeacherTimetableHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid Teacher Id", http.StatusBadRequest)
+		return
+	}
+
+	slots, err := sqlconnect.GetTeacherTimetable(r.Context(), id)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusInternalServerError)
+		return
+	}
+	writeTimetable(w, r, "teacher-"+strconv.Itoa(id), slots)
+}
+
+func GetClassTimetableHandler(w http.ResponseWriter, r *http.Request) {
+	class := r.PathValue("class")
+
+	slots, err := sqlconnect.GetClassTimetable(r.Context(), class)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusInternalServerError)
+		return
+	}
+	writeTimetable(w, r, "class-"+class, slots)
+}
+
+func GetRoomTimetableHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid Room Id", http.StatusBadRequest)
+		return
+	}
+
+	slots, err := sqlconnect.GetRoomTimetable(r.Context(), id)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusInternalServerError)
+		return
+	}
+	writeTimetable(w, r, "room-"+strconv.Itoa(id), slots)
+}
+
+// writeTimetable answers with the slots as JSON, or with format=ics as an
+// iCalendar file of weekly events over the calendar period.
+func writeTimetable(w http.ResponseWriter, r *http.Request, name string, slots []models.TimetableSlot) {
+	switch r.URL.Query().Get("format") {
+	case "", "json":
+	case "ics":
+		p, err := calendarPeriod(r)
+		if err != nil {
+			dbErrorResponse(w, err, http.StatusBadRequest)
+			return
+		}
+
+		var calendar bytes.Buffer
+		if err := reports.Calendar(&calendar, name, slots, p.From, p.To); err != nil {
+			log.Println("Error rendering calendar:", err)
+			http.Error(w, "Error generating calendar", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="timetable-%s.ics"`, safeFileName(name)))
+		w.Header().Set("Content-Length", strconv.Itoa(calendar.Len()))
+		w.Write(calendar.Bytes())
+		return
+	default:
+		http.Error(w, "format must be json or ics", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string                 `json:"status"`
+		Count  int                    `json:"count"`
+		Data   []models.TimetableSlot `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(slots),
+		Data:   slots,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// calendarPeriod is the span an exported timetable repeats over: term_id,
+// year_id or from/to, by default the current academic year.
+func calendarPeriod(r *http.Request) (period, error) {
+	query := r.URL.Query()
+	for _, param := range []string{"term_id", "year_id", "from", "to"} {
+		if query.Get(param) != "" {
+			return requestPeriod(r, 365)
+		}
+	}
+
+	year, err := sqlconnect.GetCurrentYear(r.Context())
+	if err != nil {
+		return period{}, err
+	}
+	return period{From: year.StartDate, To: year.EndDate, Label: year.Name, YearID: year.ID}, nil
+}
+
+// GetConflictsHandler lists every clash in the current timetable.
+func GetConflictsHandler(w http.ResponseWriter, r *http.Request) {
+	conflicts, err := sqlconnect.GetTimetableConflicts(r.Context())
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string            `json:"status"`
+		Count  int               `json:"count"`
+		Data   []models.Conflict `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(conflicts),
+		Data:   conflicts,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func GetUnavailabilityHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid Teacher Id", http.StatusBadRequest)
+		return
+	}
+
+	entries, err := sqlconnect.GetTeacherUnavailability(r.Context(), id)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string                  `json:"status"`
+		Count  int                     `json:"count"`
+		Data   []models.Unavailability `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(entries),
+		Data:   entries,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// AddUnavailabilityHandler blocks the teacher on a weekday; without a
+// period_id the whole day.
+func AddUnavailabilityHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid Teacher Id", http.StatusBadRequest)
+		return
+	}
+
+	var entry models.Unavailability
+	if err := json.NewDecoder(r.Body).Decode(&entry); err != nil {
+		log.Println(err)
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if entry.Weekday < 1 || entry.Weekday > 7 {
+		http.Error(w, "weekday must be 1 (Monday) to 7 (Sunday)", http.StatusBadRequest)
+		return
+	}
+	if entry.PeriodID < 0 {
+		http.Error(w, "invalid period_id", http.StatusBadRequest)
+		return
+	}
+	entry.ID = 0
+	entry.TeacherID = id
+
+	added, err := sqlconnect.AddUnavailability(r.Context(), entry)
+	if err != nil {
+		dbErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(added)
+}
+
+func DeleteUnavailabilityHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid Teacher Id", http.StatusBadRequest)
+		return
+	}
+	entryID, err := strconv.Atoi(r.PathValue("entryId"))
+	if err != nil {
+		http.Error(w, "Invalid Entry Id", http.StatusBadRequest)
+		return
+	}
+
+	if err := sqlconnect.DeleteUnavailability(r.Context(), id, entryID); err != nil {
+		dbErrorResponse(w, err, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string `json:"status"`
+		ID     int    `json:"id"`
+	}{
+		Status: "Unavailability successfully deleted",
+		ID:     entryID,
+	}
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"restapi/internal/models"
+	"testing"
+)
+
+func TestParseClock(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"08:30", "08:30:00", false},
+		{"14:05:30", "14:05:30", false},
+		{"8.30", "", true},
+		{"25:00", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseClock(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseClock(%q) = %q, %v; want %q, error %v", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateLessonPeriod(t *testing.T) {
+	p := models.Period{Name: " 1 ", StartTime: "08:30", EndTime: "09:15"}
+	if err := validateLessonPeriod(&p); err != nil || p.Name != "1" || p.StartTime != "08:30:00" {
+		t.Errorf("validateLessonPeriod() = %+v, %v", p, err)
+	}
+
+	// Урок не может закончиться раньше, чем начался
+	p = models.Period{Name: "2", StartTime: "10:00", EndTime: "09:15"}
+	if err := validateLessonPeriod(&p); err == nil {
+		t.Error("expected an error for end before start")
+	}
+}
+
+func TestValidateSlot(t *testing.T) {
+	valid := models.TimetableSlot{Weekday: 1, PeriodID: 1, Class: "10A", Subject: "math", TeacherID: 3}
+
+	tests := []struct {
+		name    string
+		change  func(*models.TimetableSlot)
+		wantErr bool
+	}{
+		{"valid", func(s *models.TimetableSlot) {}, false},
+		{"with room", func(s *models.TimetableSlot) { s.RoomID = 2 }, false},
+		{"sunday", func(s *models.TimetableSlot) { s.Weekday = 7 }, false},
+		{"no weekday", func(s *models.TimetableSlot) { s.Weekday = 0 }, true},
+		{"weekday 8", func(s *models.TimetableSlot) { s.Weekday = 8 }, true},
+		{"no teacher", func(s *models.TimetableSlot) { s.TeacherID = 0 }, true},
+		{"blank subject", func(s *models.TimetableSlot) { s.Subject = "  " }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slot := valid
+			tt.change(&slot)
+			if err := validateSlot(&slot); (err != nil) != tt.wantErr {
+				t.Errorf("validateSlot() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -2,37 +2,271 @@ package middlewares
 
 import (
 	"compress/gzip"
+	"compress/zlib"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
 )
 
+type CompressionOptions struct {
+	// MinSize is the smallest body worth compressing; smaller bodies are sent as is
+	MinSize int
+	// ContentTypes lists compressible media types; entries ending in "/" match a prefix
+	ContentTypes []string
+}
+
+func DefaultCompressionOptions() CompressionOptions {
+	return CompressionOptions{
+		MinSize: 1024,
+		ContentTypes: []string{
+			"text/",
+			"application/json",
+			"application/problem+json",
+			"application/javascript",
+			"application/xml",
+			"image/svg+xml",
+		},
+	}
+}
+
+// Compression applies DefaultCompressionOptions.
 func Compression(next http.Handler) http.Handler {
+	return CompressionWithOptions(DefaultCompressionOptions())(next)
+}
+
+// CompressionWithOptions compresses responses with the best encoding the client
+// accepts (brotli, gzip or deflate). The decision is made once MinSize bytes are
+// buffered, so small, already encoded and streaming responses pass through untouched.
+func CompressionWithOptions(options CompressionOptions) func(http.Handler) http.Handler {
 	fmt.Println("Compression Middleware...")
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		fmt.Println("Compression Middleware being returned...")
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Println("Compression Middleware being returned...")
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+			if encoding == "" || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressResponseWriter{
+				ResponseWriter: w,
+				encoding:       encoding,
+				options:        options,
+				status:         http.StatusOK,
+			}
+			next.ServeHTTP(cw, r)
+			// Not deferred: after a panic the buffered body must not go out,
+			// so Recovery can still answer with a 500
+			cw.Close()
+			fmt.Println("Sent response from Compression Middleware")
+		})
+	}
+}
 
-		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
-			next.ServeHTTP(w, r)
-			return
+// Server preference when the client gives several encodings the same q-value
+var supportedEncodings = []string{"br", "gzip", "deflate"}
+
+// negotiateEncoding picks the encoding with the highest q-value, honouring
+// "*" and q=0 exclusions. It returns "" when identity should be used.
+func negotiateEncoding(acceptEncoding string) string {
+	if acceptEncoding == "" {
+		return ""
+	}
+
+	weights := make(map[string]float64)
+	wildcard := -1.0
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		q := 1.0
+		if name, value, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(name) == "q" {
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if coding == "*" {
+			wildcard = q
+		} else {
+			weights[coding] = q
 		}
+	}
 
-		w.Header().Set("Content-Encoding", "gzip")
-		gz := gzip.NewWriter(w)
-		defer gz.Close()
+	best, bestQ := "", 0.0
+	for _, encoding := range supportedEncodings {
+		q, ok := weights[encoding]
+		if !ok {
+			q = wildcard
+		}
+		if q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	return best
+}
 
-		w = &gzipResponseWriter{ResponseWriter: w, Writer: gz}
+// compressor is implemented by the gzip, zlib and brotli writers.
+type compressor interface {
+	io.WriteCloser
+	Reset(w io.Writer)
+	Flush() error
+}
 
-		next.ServeHTTP(w, r)
-		fmt.Println("Sent response from Compression Middleware")
-	})
+var compressorPools = map[string]*sync.Pool{
+	"gzip":    {New: func() any { return gzip.NewWriter(io.Discard) }},
+	"deflate": {New: func() any { return zlib.NewWriter(io.Discard) }},
+	"br":      {New: func() any { return brotli.NewWriterLevel(io.Discard, brotli.DefaultCompression) }},
 }
 
-type gzipResponseWriter struct {
+type compressResponseWriter struct {
 	http.ResponseWriter
-	Writer *gzip.Writer
+	encoding string
+	options  CompressionOptions
+
+	status      int
+	wroteHeader bool
+	decided     bool
+	buf         []byte
+	compressor  compressor
+}
+
+func (cw *compressResponseWriter) WriteHeader(code int) {
+	if cw.wroteHeader {
+		return
+	}
+	// Informational responses go straight out and don't end the header phase
+	if code >= 100 && code < 200 {
+		cw.ResponseWriter.WriteHeader(code)
+		return
+	}
+	cw.wroteHeader = true
+	cw.status = code
+	if !bodyAllowed(code) {
+		cw.decide(false)
+	}
+}
+
+func (cw *compressResponseWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if !cw.decided {
+		cw.buf = append(cw.buf, b...)
+		if len(cw.buf) < cw.options.MinSize {
+			return len(b), nil
+		}
+		if err := cw.decide(true); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+	if cw.compressor != nil {
+		return cw.compressor.Write(b)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+// Flush means the handler is streaming: whatever is buffered decides the
+// encoding now, and compressed output is flushed block by block.
+func (cw *compressResponseWriter) Flush() {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if !cw.decided {
+		cw.decide(len(cw.buf) >= cw.options.MinSize)
+	}
+	if cw.compressor != nil {
+		cw.compressor.Flush()
+	}
+	if flusher, ok := cw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Close sends a body that never reached MinSize and returns the compressor to its pool.
+func (cw *compressResponseWriter) Close() error {
+	if !cw.decided {
+		if !cw.wroteHeader && len(cw.buf) == 0 {
+			return nil
+		}
+		cw.decide(false)
+	}
+	if cw.compressor == nil {
+		return nil
+	}
+	err := cw.compressor.Close()
+	cw.compressor.Reset(io.Discard)
+	compressorPools[cw.encoding].Put(cw.compressor)
+	cw.compressor = nil
+	return err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (cw *compressResponseWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// decide sends the headers, compressing only if large is true and the response qualifies,
+// then writes out the buffered body.
+func (cw *compressResponseWriter) decide(large bool) error {
+	cw.decided = true
+	header := cw.Header()
+
+	if header.Get("Content-Type") == "" && len(cw.buf) > 0 {
+		header.Set("Content-Type", http.DetectContentType(cw.buf))
+	}
+	if large && cw.shouldCompress() {
+		header.Del("Content-Length")
+		header.Set("Content-Encoding", cw.encoding)
+		// The compressed representation is no longer byte-identical
+		if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			header.Set("ETag", "W/"+etag)
+		}
+		cw.compressor = compressorPools[cw.encoding].Get().(compressor)
+		cw.compressor.Reset(cw.ResponseWriter)
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.status)
+	if len(cw.buf) == 0 {
+		return nil
+	}
+	buf := cw.buf
+	cw.buf = nil
+	var err error
+	if cw.compressor != nil {
+		_, err = cw.compressor.Write(buf)
+	} else {
+		_, err = cw.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+func (cw *compressResponseWriter) shouldCompress() bool {
+	header := cw.Header()
+	if !bodyAllowed(cw.status) || header.Get("Content-Encoding") != "" {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil || mediaType == "text/event-stream" {
+		return false
+	}
+	for _, allowed := range cw.options.ContentTypes {
+		if mediaType == allowed || (strings.HasSuffix(allowed, "/") && strings.HasPrefix(mediaType, allowed)) {
+			return true
+		}
+	}
+	return false
 }
 
-func (g *gzipResponseWriter) Write(b []byte) (int, error) {
-	return g.Writer.Write(b)
+func bodyAllowed(status int) bool {
+	return status != http.StatusNoContent && status != http.StatusNotModified
 }
@@ -1,42 +1,48 @@
 package middlewares
 
 import (
+	"bytes"
 	"compress/gzip"
+	"compress/zlib"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
+
+	"github.com/andybalholm/brotli"
 )
 
 func TestCompression(t *testing.T) {
 	tests := []struct {
-		name              string
-		acceptEncoding    string
-		shouldCompress    bool
+		name                 string
+		acceptEncoding       string
+		shouldCompress       bool
 		checkContentEncoding bool
 	}{
 		{
-			name:              "gzip accepted",
-			acceptEncoding:    "gzip",
-			shouldCompress:    true,
+			name:                 "gzip accepted",
+			acceptEncoding:       "gzip",
+			shouldCompress:       true,
 			checkContentEncoding: true,
 		},
 		{
-			name:              "no gzip",
-			acceptEncoding:    "deflate",
-			shouldCompress:    false,
+			name:                 "no gzip",
+			acceptEncoding:       "deflate",
+			shouldCompress:       false,
 			checkContentEncoding: false,
 		},
 		{
-			name:              "empty accept encoding",
-			acceptEncoding:    "",
-			shouldCompress:    false,
+			name:                 "empty accept encoding",
+			acceptEncoding:       "",
+			shouldCompress:       false,
 			checkContentEncoding: false,
 		},
 		{
-			name:              "gzip in multiple encodings",
-			acceptEncoding:    "gzip, deflate, br",
-			shouldCompress:    true,
+			name:                 "gzip in multiple encodings",
+			acceptEncoding:       "gzip, deflate;q=0.5, br;q=0.8",
+			shouldCompress:       true,
 			checkContentEncoding: true,
 		},
 	}
@@ -50,7 +56,8 @@ func TestCompression(t *testing.T) {
 
 			rr := httptest.NewRecorder()
 			handler := Compression(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-				w.Write([]byte("test response"))
+				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+				w.Write([]byte(testBody))
 			}))
 
 			handler.ServeHTTP(rr, req)
@@ -72,8 +79,8 @@ func TestCompression(t *testing.T) {
 					t.Fatalf("Failed to decompress: %v", err)
 				}
 
-				if string(decompressed) != "test response" {
-					t.Errorf("Decompressed content mismatch: got %s, want test response", string(decompressed))
+				if string(decompressed) != testBody {
+					t.Errorf("Decompressed content mismatch: got %d bytes, want %d", len(decompressed), len(testBody))
 				}
 			} else {
 				if rr.Header().Get("Content-Encoding") == "gzip" {
@@ -84,25 +91,178 @@ func TestCompression(t *testing.T) {
 	}
 }
 
-func TestGzipResponseWriter(t *testing.T) {
-	rr := httptest.NewRecorder()
-	gz := gzip.NewWriter(rr)
-	
-	grw := &gzipResponseWriter{
-		ResponseWriter: rr,
-		Writer:         gz,
+// Тело больше порога сжатия по умолчанию
+var testBody = strings.Repeat("test response ", 100)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		acceptEncoding string
+		want           string
+	}{
+		{"", ""},
+		{"gzip", "gzip"},
+		{"gzip, deflate, br", "br"},
+		{"gzip;q=1.0, br;q=0.5", "gzip"},
+		{"br;q=0, gzip", "gzip"},
+		{"deflate", "deflate"},
+		{"*", "br"},
+		{"*;q=0.5, gzip;q=0.8", "gzip"},
+		{"identity", ""},
+		{"gzip;q=0", ""},
+		{"GZIP", "gzip"},
 	}
 
-	testData := []byte("test data")
-	n, err := grw.Write(testData)
-	if err != nil {
-		t.Fatalf("Write failed: %v", err)
+	for _, tt := range tests {
+		t.Run(tt.acceptEncoding, func(t *testing.T) {
+			if got := negotiateEncoding(tt.acceptEncoding); got != tt.want {
+				t.Errorf("negotiateEncoding(%q) = %q, want %q", tt.acceptEncoding, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompressionEncodings(t *testing.T) {
+	readers := map[string]func(io.Reader) (io.Reader, error){
+		"gzip":    func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		"deflate": func(r io.Reader) (io.Reader, error) { return zlib.NewReader(r) },
+		"br":      func(r io.Reader) (io.Reader, error) { return brotli.NewReader(r), nil },
 	}
 
-	if n != len(testData) {
-		t.Errorf("Write returned %d bytes, want %d", n, len(testData))
+	for encoding, newReader := range readers {
+		t.Run(encoding, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.Header.Set("Accept-Encoding", encoding)
+
+			rr := httptest.NewRecorder()
+			Compression(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("Content-Length", strconv.Itoa(len(testBody)))
+				w.Write([]byte(testBody))
+			})).ServeHTTP(rr, req)
+
+			if got := rr.Header().Get("Content-Encoding"); got != encoding {
+				t.Fatalf("Content-Encoding = %q, want %q", got, encoding)
+			}
+			if rr.Header().Get("Content-Length") != "" {
+				t.Errorf("Content-Length must be removed from compressed responses")
+			}
+			if rr.Header().Get("Vary") != "Accept-Encoding" {
+				t.Errorf("Vary = %q, want Accept-Encoding", rr.Header().Get("Vary"))
+			}
+
+			reader, err := newReader(rr.Body)
+			if err != nil {
+				t.Fatalf("Failed to create %s reader: %v", encoding, err)
+			}
+			decompressed, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatalf("Failed to decompress: %v", err)
+			}
+			if string(decompressed) != testBody {
+				t.Errorf("Decompressed content mismatch")
+			}
+		})
+	}
+}
+
+func TestCompressionPassthrough(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{
+			name: "below minimum size",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"status":"ok"}`))
+			},
+		},
+		{
+			name: "no content",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			},
+		},
+		{
+			name: "content type not in allowlist",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "image/png")
+				w.Write([]byte(testBody))
+			},
+		},
+		{
+			name: "already encoded",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/plain")
+				w.Header().Set("Content-Encoding", "identity")
+				w.Write([]byte(testBody))
+			},
+		},
+		{
+			name: "event stream",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/event-stream")
+				w.Write([]byte("data: 1\n\n"))
+				w.(http.Flusher).Flush()
+				w.Write([]byte(testBody))
+			},
+		},
 	}
 
-	gz.Close()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.Header.Set("Accept-Encoding", "gzip, br")
+
+			rr := httptest.NewRecorder()
+			want := httptest.NewRecorder()
+			Compression(tt.handler).ServeHTTP(rr, req)
+			tt.handler(want, req)
+
+			if got := rr.Header().Get("Content-Encoding"); got != want.Header().Get("Content-Encoding") {
+				t.Errorf("Content-Encoding = %q, want %q", got, want.Header().Get("Content-Encoding"))
+			}
+			if rr.Code != want.Code {
+				t.Errorf("status = %d, want %d", rr.Code, want.Code)
+			}
+			if rr.Body.String() != want.Body.String() {
+				t.Errorf("body was modified")
+			}
+		})
+	}
 }
 
+func TestCompressionFlush(t *testing.T) {
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	rr := httptest.NewRecorder()
+	Compression(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(testBody))
+		w.(http.Flusher).Flush()
+
+		// После Flush клиент уже может прочитать первую часть
+		if !rr.Flushed {
+			t.Errorf("Flush did not reach the underlying writer")
+		}
+		reader, err := gzip.NewReader(bytes.NewReader(rr.Body.Bytes()))
+		if err != nil {
+			t.Fatalf("Failed to create gzip reader: %v", err)
+		}
+		chunk := make([]byte, len(testBody))
+		if _, err := io.ReadFull(reader, chunk); err != nil {
+			t.Errorf("flushed data is not readable: %v", err)
+		}
+		w.Write([]byte("tail"))
+	})).ServeHTTP(rr, req)
+
+	reader, err := gzip.NewReader(rr.Body)
+	if err != nil {
+		t.Fatalf("Failed to create gzip reader: %v", err)
+	}
+	decompressed, _ := io.ReadAll(reader)
+	if string(decompressed) != testBody+"tail" {
+		t.Errorf("Decompressed content mismatch")
+	}
+}
@@ -3,54 +3,242 @@ package middlewares
 import (
 	"fmt"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // api is hosted at www.myapi.com
 // frontend server is at www.myfrontend.com
 
-// Allowed origins
-var allowedOrigins = []string{
-	"https://my-origin-url.com",
-	"https://www.myfrontend.com",
-	"https://localhost:3000",
+type CORSOptions struct {
+	// AllowedOrigins are exact origins, "*" for any origin, or wildcard
+	// subdomains such as "https://*.myfrontend.com"
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+	// Routes override the policy for paths starting with Path; the first match wins
+	Routes []RouteCORS
 }
 
+// RouteCORS overrides CORS for one path prefix. Empty lists and a zero MaxAge
+// are inherited from the default policy; AllowCredentials is taken as is.
+type RouteCORS struct {
+	Path    string
+	Options CORSOptions
+}
+
+// DefaultCORSOptions is the policy Cors uses.
+func DefaultCORSOptions() CORSOptions {
+	return CORSOptions{
+		AllowedOrigins: []string{
+			"https://my-origin-url.com",
+			"https://www.myfrontend.com",
+			"https://localhost:3000",
+		},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization"},
+		ExposedHeaders:   []string{"Authorization"},
+		AllowCredentials: true,
+		MaxAge:           time.Hour,
+	}
+}
+
+type corsPolicy struct {
+	anyOrigin        bool
+	origins          []string
+	wildcards        []string
+	methods          []string
+	headers          []string
+	anyHeader        bool
+	exposedHeaders   string
+	allowCredentials bool
+	maxAge           string
+}
+
+func newCORSPolicy(options CORSOptions) *corsPolicy {
+	p := &corsPolicy{
+		exposedHeaders:   strings.Join(options.ExposedHeaders, ", "),
+		allowCredentials: options.AllowCredentials,
+	}
+	for _, origin := range options.AllowedOrigins {
+		origin = strings.ToLower(strings.TrimSpace(origin))
+		switch {
+		case origin == "*":
+			p.anyOrigin = true
+		case strings.Contains(origin, "://*."):
+			p.wildcards = append(p.wildcards, origin)
+		case origin != "":
+			p.origins = append(p.origins, origin)
+		}
+	}
+	for _, method := range options.AllowedMethods {
+		p.methods = append(p.methods, strings.ToUpper(strings.TrimSpace(method)))
+	}
+	for _, header := range options.AllowedHeaders {
+		header = strings.TrimSpace(header)
+		if header == "*" {
+			p.anyHeader = true
+			continue
+		}
+		p.headers = append(p.headers, http.CanonicalHeaderKey(header))
+	}
+	if options.MaxAge > 0 {
+		p.maxAge = strconv.Itoa(int(options.MaxAge.Seconds()))
+	}
+	return p
+}
+
+// Cors applies DefaultCORSOptions.
 func Cors(next http.Handler) http.Handler {
+	return CORS(DefaultCORSOptions())(next)
+}
+
+// CORS answers preflight requests itself and adds CORS headers to actual
+// requests. Requests without an Origin header (curl, server-to-server) pass through.
+func CORS(options CORSOptions) func(http.Handler) http.Handler {
 	fmt.Println("Cors Middleware...")
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		fmt.Println("Cors Middleware being returned...")
-		origin := r.Header.Get("Origin")
+	defaultPolicy := newCORSPolicy(options)
+	type routePolicy struct {
+		path   string
+		policy *corsPolicy
+	}
+	var routes []routePolicy
+	for _, route := range options.Routes {
+		routes = append(routes, routePolicy{route.Path, newCORSPolicy(mergeCORSOptions(options, route.Options))})
+	}
 
-		if isOriginAllowed(origin) {
-			w.Header().Set("Access-Control-Allow-Origin", origin)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Println("Cors Middleware being returned...")
+			policy := defaultPolicy
+			for _, route := range routes {
+				if strings.HasPrefix(r.URL.Path, route.path) {
+					policy = route.policy
+					break
+				}
+			}
 
-		} else {
-			http.Error(w, "Not allowed by CORS", http.StatusForbidden)
-			return
-		}
+			// The response depends on Origin, so shared caches must key on it
+			w.Header().Add("Vary", "Origin")
 
-		// Set other CORS headers
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		w.Header().Set("Access-Control-Expose-Headers", "Authorization")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE")
-		w.Header().Set("Access-Control-Allow-Credentials", "true")
-		w.Header().Set("Access-Control-Max-Age", "3600")
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !policy.isOriginAllowed(origin) {
+				http.Error(w, "Not allowed by CORS", http.StatusForbidden)
+				return
+			}
+
+			// Handle preflight request
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				policy.preflight(w, r, origin)
+				return
+			}
 
-		// Handle preflight request
-		if r.Method == http.MethodOptions {
+			policy.setAllowOrigin(w, origin)
+			if policy.exposedHeaders != "" {
+				w.Header().Set("Access-Control-Expose-Headers", policy.exposedHeaders)
+			}
+
+			next.ServeHTTP(w, r)
+			fmt.Println("Cors Middleware ends...")
+		})
+	}
+}
+
+func (p *corsPolicy) preflight(w http.ResponseWriter, r *http.Request, origin string) {
+	w.Header().Add("Vary", "Access-Control-Request-Method")
+	w.Header().Add("Vary", "Access-Control-Request-Headers")
+
+	method := strings.ToUpper(r.Header.Get("Access-Control-Request-Method"))
+	if !slices.Contains(p.methods, method) {
+		http.Error(w, "Method not allowed by CORS", http.StatusForbidden)
+		return
+	}
+
+	var requested []string
+	for _, header := range strings.Split(r.Header.Get("Access-Control-Request-Headers"), ",") {
+		header = strings.TrimSpace(header)
+		if header == "" {
+			continue
+		}
+		if !p.anyHeader && !slices.Contains(p.headers, http.CanonicalHeaderKey(header)) {
+			http.Error(w, "Header not allowed by CORS", http.StatusForbidden)
 			return
 		}
+		requested = append(requested, header)
+	}
 
-		next.ServeHTTP(w, r)
-		fmt.Println("Cors Middleware ends...")
-	})
+	p.setAllowOrigin(w, origin)
+	w.Header().Set("Access-Control-Allow-Methods", strings.Join(p.methods, ", "))
+	if len(requested) > 0 {
+		w.Header().Set("Access-Control-Allow-Headers", strings.Join(requested, ", "))
+	}
+	if p.maxAge != "" {
+		w.Header().Set("Access-Control-Max-Age", p.maxAge)
+	}
+	w.WriteHeader(http.StatusNoContent)
 }
 
-func isOriginAllowed(origin string) bool {
-	for _, allowedOrigin := range allowedOrigins {
-		if origin == allowedOrigin {
-			return true
+// setAllowOrigin echoes the origin unless any origin is allowed without
+// credentials; browsers refuse "*" on credentialed requests.
+func (p *corsPolicy) setAllowOrigin(w http.ResponseWriter, origin string) {
+	if p.anyOrigin && !p.allowCredentials {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+	} else {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+	}
+	if p.allowCredentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+func (p *corsPolicy) isOriginAllowed(origin string) bool {
+	origin = strings.ToLower(origin)
+	if origin == "" {
+		return false
+	}
+	if p.anyOrigin || slices.Contains(p.origins, origin) {
+		return true
+	}
+	for _, pattern := range p.wildcards {
+		// "https://*.example.com" matches "https://app.example.com" but not "https://example.com"
+		scheme, suffix, _ := strings.Cut(pattern, "*")
+		if strings.HasPrefix(origin, scheme) && strings.HasSuffix(origin, suffix) && len(origin) > len(scheme)+len(suffix) {
+			sub := origin[len(scheme) : len(origin)-len(suffix)]
+			if !strings.ContainsAny(sub, "/:@") {
+				return true
+			}
 		}
 	}
 	return false
 }
+
+func mergeCORSOptions(base, override CORSOptions) CORSOptions {
+	merged := override
+	if len(merged.AllowedOrigins) == 0 {
+		merged.AllowedOrigins = base.AllowedOrigins
+	}
+	if len(merged.AllowedMethods) == 0 {
+		merged.AllowedMethods = base.AllowedMethods
+	}
+	if len(merged.AllowedHeaders) == 0 {
+		merged.AllowedHeaders = base.AllowedHeaders
+	}
+	if len(merged.ExposedHeaders) == 0 {
+		merged.ExposedHeaders = base.ExposedHeaders
+	}
+	if merged.MaxAge == 0 {
+		merged.MaxAge = base.MaxAge
+	}
+	merged.Routes = nil
+	return merged
+}
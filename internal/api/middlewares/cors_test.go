@@ -28,9 +28,10 @@ func TestCors(t *testing.T) {
 			expectedStatus: http.StatusForbidden,
 		},
 		{
+			// curl и server-to-server запросы без Origin проходят
 			name:           "empty origin",
 			origin:         "",
-			expectedStatus: http.StatusForbidden,
+			expectedStatus: http.StatusOK,
 		},
 	}
 
@@ -64,16 +65,27 @@ func TestCors(t *testing.T) {
 func TestCorsPreflight(t *testing.T) {
 	req := httptest.NewRequest("OPTIONS", "/test", nil)
 	req.Header.Set("Origin", "https://my-origin-url.com")
+	req.Header.Set("Access-Control-Request-Method", "PATCH")
+	req.Header.Set("Access-Control-Request-Headers", "content-type")
 
 	rr := httptest.NewRecorder()
 	handler := Cors(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
+		t.Errorf("preflight request reached the handler")
 	}))
 
 	handler.ServeHTTP(rr, req)
 
-	if status := rr.Code; status != http.StatusOK {
-		t.Errorf("preflight request returned wrong status code: got %v want %v", status, http.StatusOK)
+	if status := rr.Code; status != http.StatusNoContent {
+		t.Errorf("preflight request returned wrong status code: got %v want %v", status, http.StatusNoContent)
+	}
+	if got := rr.Header().Get("Access-Control-Allow-Headers"); got != "content-type" {
+		t.Errorf("Access-Control-Allow-Headers = %q, want content-type", got)
+	}
+	if got := rr.Header().Get("Access-Control-Max-Age"); got != "3600" {
+		t.Errorf("Access-Control-Max-Age = %q, want 3600", got)
+	}
+	if got := rr.Header().Values("Vary"); len(got) == 0 || got[0] != "Origin" {
+		t.Errorf("Vary = %v, want Origin first", got)
 	}
 
 	// Проверяем CORS заголовки
@@ -97,10 +109,103 @@ func TestIsOriginAllowed(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := isOriginAllowed(tt.origin); got != tt.want {
+			if got := newCORSPolicy(DefaultCORSOptions()).isOriginAllowed(tt.origin); got != tt.want {
 				t.Errorf("isOriginAllowed() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
 
+
+func TestCorsWildcardOrigins(t *testing.T) {
+	policy := newCORSPolicy(CORSOptions{AllowedOrigins: []string{"https://*.myfrontend.com"}})
+
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"https://app.myfrontend.com", true},
+		{"https://a.b.myfrontend.com", true},
+		{"https://myfrontend.com", false},
+		{"http://app.myfrontend.com", false},
+		{"https://evil.com/.myfrontend.com", false},
+		{"https://app.myfrontend.com.evil.com", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.origin, func(t *testing.T) {
+			if got := policy.isOriginAllowed(tt.origin); got != tt.want {
+				t.Errorf("isOriginAllowed(%q) = %v, want %v", tt.origin, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCorsPreflightRejected(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		headers string
+	}{
+		{"method not allowed", "TRACE", ""},
+		{"header not allowed", "GET", "X-Custom"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("OPTIONS", "/test", nil)
+			req.Header.Set("Origin", "https://my-origin-url.com")
+			req.Header.Set("Access-Control-Request-Method", tt.method)
+			if tt.headers != "" {
+				req.Header.Set("Access-Control-Request-Headers", tt.headers)
+			}
+
+			rr := httptest.NewRecorder()
+			Cors(http.NotFoundHandler()).ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusForbidden {
+				t.Errorf("status = %v, want %v", rr.Code, http.StatusForbidden)
+			}
+			if rr.Header().Get("Access-Control-Allow-Origin") != "" {
+				t.Errorf("rejected preflight must not allow the origin")
+			}
+		})
+	}
+}
+
+func TestCorsRouteOverride(t *testing.T) {
+	options := DefaultCORSOptions()
+	options.Routes = []RouteCORS{
+		{Path: "/public", Options: CORSOptions{AllowedOrigins: []string{"*"}}},
+	}
+	handler := CORS(options)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+		expectedOrigin string
+	}{
+		{"override allows any origin", "/public/info", http.StatusOK, "*"},
+		{"default policy elsewhere", "/students", http.StatusForbidden, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			req.Header.Set("Origin", "https://anyone.example")
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("status = %v, want %v", rr.Code, tt.expectedStatus)
+			}
+			if got := rr.Header().Get("Access-Control-Allow-Origin"); got != tt.expectedOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.expectedOrigin)
+			}
+		})
+	}
+}
@@ -1,8 +1,13 @@
 package middlewares
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
+	"log"
 	"net/http"
+	"slices"
 	"strings"
 )
 
@@ -10,7 +15,47 @@ type HPPOptions struct {
 	CheckQuery                  bool
 	CheckBody                   bool
 	CheckBodyOnlyForContentType string
-	Whitelist                   []string
+	// CheckJSONBody rejects JSON bodies that repeat a key within one object
+	CheckJSONBody bool
+	// Whitelist and MultiValued apply to routes without their own rule
+	Whitelist   []string
+	MultiValued []string
+	// Routes are checked in order; the first match wins
+	Routes []HPPRoute
+	// Lookup supplies the query parameters a route declares, with MultiValued
+	// as its repeatable ones. Routes still take precedence.
+	Lookup RouteLookup
+	// ReportOnly logs violations instead of stripping or rejecting
+	ReportOnly bool
+}
+
+// HPPRule lists the parameters a route accepts. Parameters in MultiValued may
+// repeat; any other repeated parameter is collapsed to a single value.
+type HPPRule struct {
+	Allowed     []string
+	MultiValued []string
+}
+
+// HPPRoute applies Rule to requests whose path starts with Path.
+// An empty Method matches every method.
+type HPPRoute struct {
+	Method string
+	Path   string
+	Rule   HPPRule
+}
+
+func (options HPPOptions) ruleFor(r *http.Request) HPPRule {
+	for _, route := range options.Routes {
+		if (route.Method == "" || route.Method == r.Method) && strings.HasPrefix(r.URL.Path, route.Path) {
+			return route.Rule
+		}
+	}
+	if options.Lookup != nil {
+		if policy, ok := options.Lookup(r); ok {
+			return HPPRule{Allowed: policy.Query, MultiValued: options.MultiValued}
+		}
+	}
+	return HPPRule{Allowed: options.Whitelist, MultiValued: options.MultiValued}
 }
 
 func Hpp(options HPPOptions) func(http.Handler) http.Handler {
@@ -18,13 +63,40 @@ func Hpp(options HPPOptions) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			fmt.Println("HPP Middleware being returned...")
+			rule := options.ruleFor(r)
+
 			if options.CheckBody && r.Method == http.MethodPost && isCorrectContentType(r, options.CheckBodyOnlyForContentType) {
 
-				filterBodyParams(r, options.Whitelist)
+				filterBodyParams(r, rule, options.ReportOnly)
+			}
+			if options.CheckJSONBody && isCorrectContentType(r, "application/json") && r.Body != nil {
+				body, err := io.ReadAll(r.Body)
+				if err != nil {
+					// Never hand a truncated body to the handler
+					if isBodyTooLarge(err) {
+						http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+						return
+					}
+					http.Error(w, "Error reading request body", http.StatusBadRequest)
+					return
+				}
+				r.Body = io.NopCloser(bytes.NewReader(body))
+
+				duplicates, err := duplicateJSONKeys(body, rule)
+				if err != nil {
+					// Malformed JSON is reported by the next reader of the body
+					log.Println("HPP: could not inspect JSON body:", err)
+				} else if len(duplicates) > 0 {
+					if !options.ReportOnly {
+						http.Error(w, "Duplicate parameters in request body: "+strings.Join(duplicates, ", "), http.StatusBadRequest)
+						return
+					}
+					log.Printf("HPP report: %s %s repeats JSON keys %v", r.Method, r.URL.Path, duplicates)
+				}
 			}
 			if options.CheckQuery && r.URL.Query() != nil {
 
-				filterQueryParams(r, options.Whitelist)
+				filterQueryParams(r, rule, options.ReportOnly)
 			}
 			next.ServeHTTP(w, r)
 			fmt.Println("HPP Middleware ends...")
@@ -36,7 +108,7 @@ func isCorrectContentType(r *http.Request, contentType string) bool {
 	return strings.Contains(r.Header.Get("Content-Type"), contentType)
 }
 
-func filterBodyParams(r *http.Request, whitelist []string) {
+func filterBodyParams(r *http.Request, rule HPPRule, reportOnly bool) {
 	err := r.ParseForm()
 	if err != nil {
 		fmt.Println(err)
@@ -44,36 +116,114 @@ func filterBodyParams(r *http.Request, whitelist []string) {
 	}
 
 	for k, v := range r.Form {
-		if len(v) > 1 {
-			r.Form.Set(k, v[0])
-
+		if len(v) > 1 && !isWhiteListed(k, rule.MultiValued) {
+			if reportOnly {
+				log.Printf("HPP report: %s %s repeats body parameter %q", r.Method, r.URL.Path, k)
+			} else {
+				r.Form.Set(k, v[0])
+			}
 		}
-		if !isWhiteListed(k, whitelist) {
-			delete(r.Form, k)
+		if !isWhiteListed(k, rule.Allowed) {
+			if reportOnly {
+				log.Printf("HPP report: %s %s has unexpected body parameter %q", r.Method, r.URL.Path, k)
+			} else {
+				delete(r.Form, k)
+			}
 		}
 	}
 }
 
-func filterQueryParams(r *http.Request, whitelist []string) {
+func filterQueryParams(r *http.Request, rule HPPRule, reportOnly bool) {
 	query := r.URL.Query()
 
 	for k, v := range query {
-		if len(v) > 1 {
-
-			query.Set(k, v[len(v)-1])
+		if len(v) > 1 && !isWhiteListed(k, rule.MultiValued) {
+			if reportOnly {
+				log.Printf("HPP report: %s %s repeats query parameter %q", r.Method, r.URL.Path, k)
+			} else {
+				query.Set(k, v[len(v)-1])
+			}
 		}
-		if !isWhiteListed(k, whitelist) {
-			query.Del(k)
+		if !isWhiteListed(k, rule.Allowed) {
+			if reportOnly {
+				log.Printf("HPP report: %s %s has unexpected query parameter %q", r.Method, r.URL.Path, k)
+			} else {
+				query.Del(k)
+			}
 		}
 	}
-	r.URL.RawQuery = query.Encode()
+	if !reportOnly {
+		r.URL.RawQuery = query.Encode()
+	}
 }
 
+// isWhiteListed matches param by name, or by prefix against entries ending
+// in "*" such as "custom.*".
 func isWhiteListed(param string, whitelist []string) bool {
-	for _, v := range whitelist {
-		if param == v {
-			return true
+	return slices.ContainsFunc(whitelist, func(allowed string) bool {
+		if prefix, ok := strings.CutSuffix(allowed, "*"); ok {
+			return strings.HasPrefix(param, prefix)
+		}
+		return param == allowed
+	})
+}
+
+// duplicateJSONKeys returns keys repeated within a single JSON object, at any
+// depth, that the rule doesn't allow to repeat.
+func duplicateJSONKeys(body []byte, rule HPPRule) ([]string, error) {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil, nil
+	}
+
+	var duplicates []string
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	if err := walkJSON(decoder, func(key string) {
+		if !isWhiteListed(key, rule.MultiValued) && !slices.Contains(duplicates, key) {
+			duplicates = append(duplicates, key)
+		}
+	}); err != nil {
+		return nil, err
+	}
+	return duplicates, nil
+}
+
+// walkJSON consumes one JSON value and calls onDuplicate for each key seen
+// twice in the same object.
+func walkJSON(decoder *json.Decoder, onDuplicate func(string)) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	delim, ok := token.(json.Delim)
+	if !ok {
+		return nil
+	}
+
+	switch delim {
+	case '{':
+		seen := make(map[string]bool)
+		for decoder.More() {
+			keyToken, err := decoder.Token()
+			if err != nil {
+				return err
+			}
+			key := keyToken.(string)
+			if seen[key] {
+				onDuplicate(key)
+			}
+			seen[key] = true
+			if err := walkJSON(decoder, onDuplicate); err != nil {
+				return err
+			}
+		}
+	case '[':
+		for decoder.More() {
+			if err := walkJSON(decoder, onDuplicate); err != nil {
+				return err
+			}
 		}
 	}
-	return false
+	// Closing delimiter
+	_, err = decoder.Token()
+	return err
 }
@@ -0,0 +1,127 @@
+package middlewares
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHppQuery(t *testing.T) {
+	options := HPPOptions{
+		CheckQuery: true,
+		Routes: []HPPRoute{{
+			Method: "GET",
+			Path:   "/students",
+			Rule:   HPPRule{Allowed: []string{"first_name", "sortby", "page", "custom.*"}, MultiValued: []string{"sortby"}},
+		}},
+	}
+
+	tests := []struct {
+		name       string
+		url        string
+		reportOnly bool
+		want       string
+	}{
+		{"allowed filter kept", "/students?first_name=John", false, "first_name=John"},
+		{"unknown param stripped", "/students?first_name=John&admin=1", false, "first_name=John"},
+		// Префикс со звёздочкой пропускает параметры произвольных полей
+		{"wildcard prefix kept", "/students?custom.bus_route=12&customer=1", false, "custom.bus_route=12"},
+		{"multi-valued sortby kept", "/students?sortby=first_name:asc&sortby=email:desc", false, "sortby=first_name%3Aasc&sortby=email%3Adesc"},
+		{"repeated single param collapsed to last", "/students?page=1&page=2", false, "page=2"},
+		{"other routes take no params", "/teachers/1?first_name=John", false, ""},
+		{"report mode leaves query untouched", "/students?page=1&page=2&admin=1", true, "page=1&page=2&admin=1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options.ReportOnly = tt.reportOnly
+			var got string
+			handler := Hpp(options)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.URL.RawQuery
+			}))
+
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", tt.url, nil))
+
+			if got != tt.want {
+				t.Errorf("query = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHppJSONBody(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		reportOnly     bool
+		expectedStatus int
+	}{
+		{"distinct keys", `{"first_name":"John","last_name":"Doe"}`, false, http.StatusOK},
+		{"duplicate key", `{"first_name":"John","first_name":"Admin"}`, false, http.StatusBadRequest},
+		{"duplicate key in array item", `[{"id":"1"},{"id":"2","id":"3"}]`, false, http.StatusBadRequest},
+		{"same key in different objects", `[{"id":"1"},{"id":"2"}]`, false, http.StatusOK},
+		{"report mode passes through", `{"role":"exec","role":"admin"}`, true, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := HPPOptions{CheckJSONBody: true, ReportOnly: tt.reportOnly}
+			var body string
+			handler := Hpp(options)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				b, _ := io.ReadAll(r.Body)
+				body = string(b)
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest("POST", "/students", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("status = %v, want %v", rr.Code, tt.expectedStatus)
+			}
+			// Тело должно дойти до обработчика без изменений
+			if rr.Code == http.StatusOK && body != tt.body {
+				t.Errorf("body = %q, want %q", body, tt.body)
+			}
+		})
+	}
+}
+
+func TestHppJSONBodyReadError(t *testing.T) {
+	tests := []struct {
+		name           string
+		limit          int64
+		expectedStatus int
+	}{
+		// Тело больше лимита не должно дойти до обработчика обрезанным
+		{"body over the limit", 10, http.StatusRequestEntityTooLarge},
+		{"body within the limit", 1 << 10, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			handler := Hpp(HPPOptions{CheckJSONBody: true})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/students", strings.NewReader(`{"first_name":"John","last_name":"Doe"}`))
+			req.Header.Set("Content-Type", "application/json")
+			req.Body = http.MaxBytesReader(rr, req.Body, tt.limit)
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("status = %v, want %v", rr.Code, tt.expectedStatus)
+			}
+			if called != (tt.expectedStatus == http.StatusOK) {
+				t.Errorf("handler called = %v", called)
+			}
+		})
+	}
+}
@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"log"
 	"net/http"
-	"os"
 	"restapi/pkg/utils"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -23,15 +22,13 @@ func JWTMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		jwtSecret := os.Getenv("JWT_SECRET")
-
 		parsedToken, err := jwt.Parse(token.Value, func(token *jwt.Token) (interface{}, error) {
 
 			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 			}
 
-			return []byte(jwtSecret), nil
+			return utils.JWTSecret(), nil
 		})
 		if err != nil {
 			if errors.Is(err, jwt.ErrTokenExpired) {
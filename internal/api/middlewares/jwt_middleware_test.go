@@ -3,7 +3,6 @@ package middlewares
 import (
 	"net/http"
 	"net/http/httptest"
-	"os"
 	"restapi/pkg/utils"
 	"testing"
 	"time"
@@ -12,16 +11,9 @@ import (
 )
 
 func TestJWTMiddleware(t *testing.T) {
-	// Устанавливаем тестовый JWT_SECRET
-	originalSecret := os.Getenv("JWT_SECRET")
-	os.Setenv("JWT_SECRET", "test-secret-key-for-jwt-middleware")
-	defer func() {
-		if originalSecret != "" {
-			os.Setenv("JWT_SECRET", originalSecret)
-		} else {
-			os.Unsetenv("JWT_SECRET")
-		}
-	}()
+	// Устанавливаем тестовый JWT секрет
+	utils.SetJWTConfig("test-secret-key-for-jwt-middleware", 15*time.Minute)
+	defer utils.SetJWTConfig("", 15*time.Minute)
 
 	tests := []struct {
 		name           string
@@ -92,15 +84,8 @@ func TestJWTMiddleware(t *testing.T) {
 }
 
 func TestJWTMiddlewareExpiredToken(t *testing.T) {
-	originalSecret := os.Getenv("JWT_SECRET")
-	os.Setenv("JWT_SECRET", "test-secret-key")
-	defer func() {
-		if originalSecret != "" {
-			os.Setenv("JWT_SECRET", originalSecret)
-		} else {
-			os.Unsetenv("JWT_SECRET")
-		}
-	}()
+	utils.SetJWTConfig("test-secret-key", 15*time.Minute)
+	defer utils.SetJWTConfig("", 15*time.Minute)
 
 	// Создаем истекший токен
 	expiredTime := time.Now().Add(-24 * time.Hour)
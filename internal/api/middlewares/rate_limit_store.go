@@ -0,0 +1,99 @@
+package middlewares
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimitResult is the outcome of taking one token from a bucket.
+type RateLimitResult struct {
+	Allowed    bool
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+// LimiterStore keeps token buckets. Take must refill and consume atomically so
+// that several API instances sharing a store enforce one limit together.
+type LimiterStore interface {
+	Take(ctx context.Context, key string, policy RateLimitPolicy, now time.Time) (RateLimitResult, error)
+	// Evict drops buckets last used before cutoff. Stores that expire keys on
+	// their own may do nothing.
+	Evict(ctx context.Context, cutoff time.Time) error
+	Close() error
+}
+
+// resultFor builds the result from the tokens left in the bucket after the take.
+func resultFor(tokens float64, allowed bool, policy RateLimitPolicy) RateLimitResult {
+	capacity := float64(policy.Limit)
+	rate := policy.ratePerSecond()
+
+	result := RateLimitResult{Allowed: allowed}
+	if !allowed {
+		result.RetryAfter = secondsToDuration((1 - tokens) / rate)
+	}
+	result.Remaining = int(tokens)
+	result.Reset = secondsToDuration((capacity - tokens) / rate)
+	return result
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
+
+type bucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// memoryLimiterStore keeps buckets in process memory; limits are per instance.
+type memoryLimiterStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+func NewMemoryLimiterStore() LimiterStore {
+	return &memoryLimiterStore{buckets: make(map[string]*bucket)}
+}
+
+func (s *memoryLimiterStore) Take(_ context.Context, key string, policy RateLimitPolicy, now time.Time) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	capacity := float64(policy.Limit)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, lastSeen: now}
+		s.buckets[key] = b
+	}
+
+	b.tokens = math.Min(capacity, b.tokens+math.Max(0, now.Sub(b.lastSeen).Seconds())*policy.ratePerSecond())
+	if now.After(b.lastSeen) {
+		b.lastSeen = now
+	}
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	return resultFor(b.tokens, allowed, policy), nil
+}
+
+func (s *memoryLimiterStore) Evict(_ context.Context, cutoff time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, b := range s.buckets {
+		if b.lastSeen.Before(cutoff) {
+			delete(s.buckets, key)
+		}
+	}
+	return nil
+}
+
+func (s *memoryLimiterStore) Close() error {
+	return nil
+}
@@ -0,0 +1,86 @@
+package middlewares
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const createRateLimitTable = `CREATE TABLE IF NOT EXISTS rate_limit_buckets (
+	bucket_key VARCHAR(255) NOT NULL PRIMARY KEY,
+	tokens DOUBLE NOT NULL,
+	updated_at DOUBLE NOT NULL,
+	allowed BOOLEAN NOT NULL,
+	INDEX idx_rate_limit_buckets_updated_at (updated_at)
+)`
+
+// The upsert refills and consumes in one statement, so concurrent requests on
+// different instances serialize on the row lock. MySQL evaluates the UPDATE
+// assignments left to right: allowed sees the old tokens, tokens sees the new allowed.
+const takeRateLimitToken = `INSERT INTO rate_limit_buckets (bucket_key, tokens, updated_at, allowed)
+VALUES (?, ? - 1, ?, TRUE)
+ON DUPLICATE KEY UPDATE
+	allowed = LEAST(?, tokens + GREATEST(0, ? - updated_at) * ?) >= 1,
+	tokens = LEAST(?, tokens + GREATEST(0, ? - updated_at) * ?) - IF(allowed, 1, 0),
+	updated_at = GREATEST(updated_at, ?)`
+
+// SQLLimiterStore keeps buckets in the rate_limit_buckets table of a MySQL database.
+type SQLLimiterStore struct {
+	db *sql.DB
+}
+
+// NewSQLLimiterStore creates the bucket table if it does not exist yet.
+// The store borrows db and does not close it.
+func NewSQLLimiterStore(ctx context.Context, db *sql.DB) (*SQLLimiterStore, error) {
+	if _, err := db.ExecContext(ctx, createRateLimitTable); err != nil {
+		return nil, err
+	}
+	return &SQLLimiterStore{db: db}, nil
+}
+
+func (s *SQLLimiterStore) Take(ctx context.Context, key string, policy RateLimitPolicy, now time.Time) (RateLimitResult, error) {
+	capacity := float64(policy.Limit)
+	rate := policy.ratePerSecond()
+	ts := unixSeconds(now)
+
+	// The read-back must run on the connection that did the upsert
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, takeRateLimitToken,
+		key, capacity, ts,
+		capacity, ts, rate,
+		capacity, ts, rate,
+		ts,
+	)
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+
+	var tokens float64
+	var allowed bool
+	err = tx.QueryRowContext(ctx, "SELECT tokens, allowed FROM rate_limit_buckets WHERE bucket_key = ?", key).Scan(&tokens, &allowed)
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return RateLimitResult{}, err
+	}
+	return resultFor(tokens, allowed, policy), nil
+}
+
+func (s *SQLLimiterStore) Evict(ctx context.Context, cutoff time.Time) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM rate_limit_buckets WHERE updated_at < ?", unixSeconds(cutoff))
+	return err
+}
+
+func (s *SQLLimiterStore) Close() error {
+	return nil
+}
+
+func unixSeconds(t time.Time) float64 {
+	return float64(t.UnixMicro()) / 1e6
+}
@@ -0,0 +1,167 @@
+package middlewares
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/redis/go-redis/v9"
+)
+
+// Каждое хранилище должно вести себя одинаково: одни и те же тесты
+// прогоняются для памяти, Redis (miniredis) и MySQL (если задан DSN)
+func limiterStores(t *testing.T) map[string]LimiterStore {
+	t.Helper()
+
+	stores := map[string]LimiterStore{
+		"memory": NewMemoryLimiterStore(),
+	}
+
+	mr := miniredis.RunT(t)
+	stores["redis"] = NewRedisLimiterStore(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+
+	// MySQL проверяется только при наличии тестовой базы
+	if dsn := os.Getenv("RATE_LIMIT_TEST_MYSQL_DSN"); dsn != "" {
+		db, err := sql.Open("mysql", dsn)
+		if err != nil {
+			t.Fatalf("open mysql: %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+		store, err := NewSQLLimiterStore(context.Background(), db)
+		if err != nil {
+			t.Fatalf("create sql store: %v", err)
+		}
+		db.Exec("DELETE FROM rate_limit_buckets")
+		stores["sql"] = store
+	}
+	return stores
+}
+
+func TestLimiterStoreTake(t *testing.T) {
+	policy := RateLimitPolicy{Limit: 2, Window: time.Minute}
+	now := time.Now()
+
+	for name, store := range limiterStores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			steps := []struct {
+				at            time.Time
+				wantAllowed   bool
+				wantRemaining int
+			}{
+				{now, true, 1},
+				{now, true, 0},
+				{now, false, 0},
+				// Один токен восстанавливается за 30s
+				{now.Add(30 * time.Second), true, 0},
+				{now.Add(30 * time.Second), false, 0},
+			}
+
+			for i, step := range steps {
+				result, err := store.Take(ctx, name+"-take", policy, step.at)
+				if err != nil {
+					t.Fatalf("step %d: Take() error = %v", i, err)
+				}
+				if result.Allowed != step.wantAllowed {
+					t.Errorf("step %d: Allowed = %v, want %v", i, result.Allowed, step.wantAllowed)
+				}
+				if result.Remaining != step.wantRemaining {
+					t.Errorf("step %d: Remaining = %d, want %d", i, result.Remaining, step.wantRemaining)
+				}
+				if !result.Allowed && result.RetryAfter <= 0 {
+					t.Errorf("step %d: RetryAfter = %v, want > 0", i, result.RetryAfter)
+				}
+			}
+		})
+	}
+}
+
+func TestLimiterStoreConcurrentTakes(t *testing.T) {
+	policy := RateLimitPolicy{Limit: 10, Window: time.Hour}
+	now := time.Now()
+
+	for name, store := range limiterStores(t) {
+		t.Run(name, func(t *testing.T) {
+			var mu sync.Mutex
+			var wg sync.WaitGroup
+			allowed := 0
+			for i := 0; i < 50; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					result, err := store.Take(context.Background(), name+"-concurrent", policy, now)
+					if err != nil {
+						t.Errorf("Take() error = %v", err)
+						return
+					}
+					if result.Allowed {
+						mu.Lock()
+						allowed++
+						mu.Unlock()
+					}
+				}()
+			}
+			wg.Wait()
+
+			if allowed != policy.Limit {
+				t.Errorf("allowed %d requests, want exactly %d", allowed, policy.Limit)
+			}
+		})
+	}
+}
+
+// Два экземпляра API с общим Redis делят один лимит
+func TestRateLimiterSharedStore(t *testing.T) {
+	mr := miniredis.RunT(t)
+	options := RateLimiterOptions{Default: RateLimitPolicy{Limit: 2, Window: time.Minute}}
+
+	first := NewRateLimiterWithStore(NewRedisLimiterStore(redis.NewClient(&redis.Options{Addr: mr.Addr()})), options)
+	defer first.Stop()
+	second := NewRateLimiterWithStore(NewRedisLimiterStore(redis.NewClient(&redis.Options{Addr: mr.Addr()})), options)
+	defer second.Stop()
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	instances := []http.Handler{first.Middleware(handler), second.Middleware(handler)}
+
+	want := []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests}
+	for i, status := range want {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.RemoteAddr = "203.0.113.5:4000"
+		rr := httptest.NewRecorder()
+		instances[i%2].ServeHTTP(rr, req)
+		if rr.Code != status {
+			t.Errorf("request %d: status = %d, want %d", i, rr.Code, status)
+		}
+	}
+}
+
+// При недоступном хранилище запросы пропускаются
+func TestRateLimiterFailsOpen(t *testing.T) {
+	mr := miniredis.RunT(t)
+	limiter := NewRateLimiterWithStore(
+		NewRedisLimiterStore(redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})),
+		RateLimiterOptions{Default: RateLimitPolicy{Limit: 1, Window: time.Minute}},
+	)
+	defer limiter.Stop()
+	mr.Close()
+
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	for i := 0; i < 2; i++ {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/test", nil))
+		if rr.Code != http.StatusOK {
+			t.Errorf("request %d: status = %d, want %d", i, rr.Code, http.StatusOK)
+		}
+	}
+}
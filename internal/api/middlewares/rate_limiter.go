@@ -1,50 +1,263 @@
 package middlewares
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"math"
+	"net"
 	"net/http"
+	"restapi/internal/metrics"
+	"restapi/pkg/utils"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
 
+// RateLimitPolicy allows Limit requests per Window with bursts of up to Limit.
+type RateLimitPolicy struct {
+	Limit  int
+	Window time.Duration
+}
+
+func (p RateLimitPolicy) ratePerSecond() float64 {
+	return float64(p.Limit) / p.Window.Seconds()
+}
+
+// RouteRateLimit overrides the policy for requests whose path starts with Path.
+// An empty Method matches every method.
+type RouteRateLimit struct {
+	Method string
+	Path   string
+	Policy RateLimitPolicy
+}
+
+type RateLimiterOptions struct {
+	Default RateLimitPolicy
+	// Routes are checked in order; the first match wins over role limits
+	Routes []RouteRateLimit
+	// Classes apply to routes whose RoutePolicy names them, looked up with Lookup
+	Classes map[string]RateLimitPolicy
+	Lookup  RouteLookup
+	// Roles apply to authenticated users with the given JWT role
+	Roles map[string]RateLimitPolicy
+	// TrustedProxies lists CIDRs (or single IPs) allowed to set X-Forwarded-For
+	TrustedProxies []string
+	// APIKeyHeader identifies API clients; defaults to X-API-Key
+	APIKeyHeader string
+	// IdleTTL is how long an untouched bucket is kept; defaults to twice the longest window
+	IdleTTL time.Duration
+}
+
 type rateLimiter struct {
-	mu        sync.Mutex
-	visitors  map[string]int
-	limit     int
-	resetTime time.Duration
+	store    LimiterStore
+	options  RateLimiterOptions
+	proxies  []*net.IPNet
+	stop     chan struct{}
+	stopOnce sync.Once
 }
 
+// NewRateLimiter allows limit requests per resetTime for every client.
 func NewRateLimiter(limit int, resetTime time.Duration) *rateLimiter {
-	rl := &rateLimiter{
-		visitors:  make(map[string]int),
-		limit:     limit,
-		resetTime: resetTime,
+	return NewRateLimiterWithOptions(RateLimiterOptions{
+		Default: RateLimitPolicy{Limit: limit, Window: resetTime},
+	})
+}
+
+// NewRateLimiterWithOptions keeps buckets in process memory. Use
+// NewRateLimiterWithStore to share limits between replicas.
+func NewRateLimiterWithOptions(options RateLimiterOptions) *rateLimiter {
+	return NewRateLimiterWithStore(NewMemoryLimiterStore(), options)
+}
+
+func NewRateLimiterWithStore(store LimiterStore, options RateLimiterOptions) *rateLimiter {
+	if options.APIKeyHeader == "" {
+		options.APIKeyHeader = "X-API-Key"
+	}
+	if options.IdleTTL <= 0 {
+		options.IdleTTL = 2 * longestWindow(options)
+	}
+	if options.IdleTTL <= 0 {
+		options.IdleTTL = 10 * time.Minute
 	}
 
-	go rl.resetVisitorCount()
+	rl := &rateLimiter{
+		store:   store,
+		options: options,
+		proxies: parseCIDRs(options.TrustedProxies),
+		stop:    make(chan struct{}),
+	}
+	go rl.evictIdle()
 	return rl
 }
 
-func (rl *rateLimiter) resetVisitorCount() {
+func longestWindow(options RateLimiterOptions) time.Duration {
+	longest := options.Default.Window
+	for _, route := range options.Routes {
+		longest = max(longest, route.Policy.Window)
+	}
+	for _, policy := range options.Classes {
+		longest = max(longest, policy.Window)
+	}
+	for _, policy := range options.Roles {
+		longest = max(longest, policy.Window)
+	}
+	return longest
+}
+
+func parseCIDRs(values []string) []*net.IPNet {
+	var nets []*net.IPNet
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if !strings.Contains(value, "/") {
+			if strings.Contains(value, ":") {
+				value += "/128"
+			} else {
+				value += "/32"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(value)
+		if err != nil {
+			utils.ErrorHandler(err, fmt.Sprintf("ignoring invalid trusted proxy %q", value))
+			continue
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets
+}
+
+// evictIdle drops buckets nobody has used for IdleTTL. An idle bucket has
+// refilled completely, so forgetting it doesn't change any decision.
+func (rl *rateLimiter) evictIdle() {
+	ticker := time.NewTicker(rl.options.IdleTTL / 2)
+	defer ticker.Stop()
+
 	for {
-		time.Sleep(rl.resetTime)
-		rl.mu.Lock()
-		rl.visitors = make(map[string]int)
-		rl.mu.Unlock()
+		select {
+		case now := <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), rl.options.IdleTTL/2)
+			err := rl.store.Evict(ctx, now.Add(-rl.options.IdleTTL))
+			cancel()
+			if err != nil {
+				utils.ErrorHandler(err, "error evicting idle rate limit buckets")
+			}
+		case <-rl.stop:
+			return
+		}
 	}
 }
 
+// Stop ends the background eviction goroutine and closes the store.
+// It is safe to call more than once.
+func (rl *rateLimiter) Stop() {
+	rl.stopOnce.Do(func() {
+		close(rl.stop)
+		if err := rl.store.Close(); err != nil {
+			utils.ErrorHandler(err, "error closing rate limit store")
+		}
+	})
+}
+
+// policyFor picks the route override first, then the route's class, then the
+// caller's role, then the default.
+func (rl *rateLimiter) policyFor(r *http.Request) (string, RateLimitPolicy) {
+	for i, route := range rl.options.Routes {
+		if (route.Method == "" || route.Method == r.Method) && strings.HasPrefix(r.URL.Path, route.Path) {
+			return "route" + strconv.Itoa(i), route.Policy
+		}
+	}
+	if rl.options.Lookup != nil {
+		if route, ok := rl.options.Lookup(r); ok {
+			if policy, ok := rl.options.Classes[route.RateLimit]; ok {
+				return "class:" + route.RateLimit, policy
+			}
+		}
+	}
+	if role, ok := r.Context().Value(utils.ContextKey("role")).(string); ok {
+		if policy, ok := rl.options.Roles[role]; ok {
+			return "role:" + role, policy
+		}
+	}
+	return "default", rl.options.Default
+}
+
+// clientKey identifies the caller: authenticated user, then API key, then client IP.
+func (rl *rateLimiter) clientKey(r *http.Request) string {
+	if userId := r.Context().Value(utils.ContextKey("userId")); userId != nil {
+		return fmt.Sprintf("user:%v", userId)
+	}
+	if apiKey := r.Header.Get(rl.options.APIKeyHeader); apiKey != "" {
+		sum := sha256.Sum256([]byte(apiKey))
+		return "apikey:" + hex.EncodeToString(sum[:8])
+	}
+	return "ip:" + rl.clientIP(r)
+}
+
+// clientIP returns the peer address without its port. X-Forwarded-For is only
+// honoured when the peer is a trusted proxy, and is read right to left until
+// the first hop that isn't one.
+func (rl *rateLimiter) clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if !rl.isTrustedProxy(host) {
+		return host
+	}
+
+	hops := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+		if !rl.isTrustedProxy(hop) {
+			return hop
+		}
+		host = hop
+	}
+	return host
+}
+
+func (rl *rateLimiter) isTrustedProxy(value string) bool {
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range rl.proxies {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
 func (rl *rateLimiter) Middleware(next http.Handler) http.Handler {
 	fmt.Println("Rate Limiter Middleware...")
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		fmt.Println("Rate Limiter Middleware being returned...")
-		rl.mu.Lock()
-		defer rl.mu.Unlock()
+		policyName, policy := rl.policyFor(r)
+		result, err := rl.store.Take(r.Context(), policyName+"|"+rl.clientKey(r), policy, time.Now())
+		if err != nil {
+			// Fail open: a store outage shouldn't take the API down with it
+			utils.ErrorHandler(err, "rate limit store unavailable")
+			next.ServeHTTP(w, r)
+			return
+		}
 
-		visitorIP := r.RemoteAddr
-		rl.visitors[visitorIP]++
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(policy.Limit))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+		w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", policy.Limit, ceilSeconds(policy.Window)))
 
-		if rl.visitors[visitorIP] > rl.limit {
+		if !result.Allowed {
+			metrics.RateLimitRejectionsTotal.Inc()
+			w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
 			http.Error(w, "Too many requests", http.StatusTooManyRequests)
 			return
 		}
@@ -52,3 +265,7 @@ func (rl *rateLimiter) Middleware(next http.Handler) http.Handler {
 		fmt.Println("Rate Limiter ends...")
 	})
 }
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
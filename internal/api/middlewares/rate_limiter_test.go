@@ -1,8 +1,10 @@
 package middlewares
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
+	"restapi/pkg/utils"
 	"testing"
 	"time"
 )
@@ -86,3 +88,185 @@ func TestRateLimiterDifferentIPs(t *testing.T) {
 	}
 }
 
+
+func TestRateLimiterStop(t *testing.T) {
+	limiter := NewRateLimiter(2, 10*time.Millisecond)
+
+	// Повторный вызов Stop не должен паниковать
+	limiter.Stop()
+	limiter.Stop()
+}
+
+func TestRateLimiterIgnoresPort(t *testing.T) {
+	limiter := NewRateLimiter(1, time.Minute)
+	defer limiter.Stop()
+
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// Новое соединение с того же IP не должно давать новый лимит
+	for i, addr := range []string{"10.0.0.1:1111", "10.0.0.1:2222"} {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.RemoteAddr = addr
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		want := http.StatusOK
+		if i == 1 {
+			want = http.StatusTooManyRequests
+		}
+		if rr.Code != want {
+			t.Errorf("request %d from %s: status = %v, want %v", i, addr, rr.Code, want)
+		}
+		if i == 1 && rr.Header().Get("Retry-After") == "" {
+			t.Errorf("rejected request is missing Retry-After")
+		}
+	}
+}
+
+func TestRateLimiterHeaders(t *testing.T) {
+	limiter := NewRateLimiter(3, time.Minute)
+	defer limiter.Stop()
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rr := httptest.NewRecorder()
+	limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(rr, req)
+
+	if got := rr.Header().Get("RateLimit-Limit"); got != "3" {
+		t.Errorf("RateLimit-Limit = %q, want 3", got)
+	}
+	if got := rr.Header().Get("RateLimit-Remaining"); got != "2" {
+		t.Errorf("RateLimit-Remaining = %q, want 2", got)
+	}
+	if got := rr.Header().Get("RateLimit-Reset"); got != "20" {
+		t.Errorf("RateLimit-Reset = %q, want 20", got)
+	}
+}
+
+func TestRateLimiterRefill(t *testing.T) {
+	limiter := NewRateLimiter(2, time.Minute)
+	defer limiter.Stop()
+
+	policy := limiter.options.Default
+	now := time.Now()
+	take := func(at time.Time) RateLimitResult {
+		result, _ := limiter.store.Take(context.Background(), "k", policy, at)
+		return result
+	}
+	take(now)
+	take(now)
+	if take(now).Allowed {
+		t.Fatalf("third request in the same instant should be rejected")
+	}
+
+	// Один токен восстанавливается за window/limit = 30s
+	if !take(now.Add(30 * time.Second)).Allowed {
+		t.Errorf("request after refill should be allowed")
+	}
+}
+
+func TestRateLimiterClientIP(t *testing.T) {
+	limiter := NewRateLimiterWithOptions(RateLimiterOptions{
+		Default:        RateLimitPolicy{Limit: 1, Window: time.Minute},
+		TrustedProxies: []string{"10.0.0.0/8"},
+	})
+	defer limiter.Stop()
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		xff        string
+		want       string
+	}{
+		{"direct client", "203.0.113.5:4000", "", "203.0.113.5"},
+		{"untrusted peer cannot spoof", "203.0.113.5:4000", "1.2.3.4", "203.0.113.5"},
+		{"trusted proxy", "10.0.0.2:4000", "198.51.100.7", "198.51.100.7"},
+		{"chain of trusted proxies", "10.0.0.2:4000", "198.51.100.7, 10.1.1.1", "198.51.100.7"},
+		{"spoofed left-most entry ignored", "10.0.0.2:4000", "1.2.3.4, 198.51.100.7", "198.51.100.7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.xff != "" {
+				req.Header.Set("X-Forwarded-For", tt.xff)
+			}
+			if got := limiter.clientIP(req); got != tt.want {
+				t.Errorf("clientIP() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateLimiterPolicyFor(t *testing.T) {
+	limiter := NewRateLimiterWithOptions(RateLimiterOptions{
+		Default: RateLimitPolicy{Limit: 100, Window: time.Minute},
+		Routes:  []RouteRateLimit{{Method: "POST", Path: "/execs/login", Policy: RateLimitPolicy{Limit: 5, Window: time.Minute}}},
+		Roles:   map[string]RateLimitPolicy{"admin": {Limit: 1000, Window: time.Minute}},
+	})
+	defer limiter.Stop()
+
+	login := httptest.NewRequest("POST", "/execs/login", nil)
+	if _, policy := limiter.policyFor(login); policy.Limit != 5 {
+		t.Errorf("login policy limit = %d, want 5", policy.Limit)
+	}
+
+	admin := httptest.NewRequest("GET", "/students", nil)
+	admin = admin.WithContext(context.WithValue(admin.Context(), utils.ContextKey("role"), "admin"))
+	if _, policy := limiter.policyFor(admin); policy.Limit != 1000 {
+		t.Errorf("admin policy limit = %d, want 1000", policy.Limit)
+	}
+
+	anonymous := httptest.NewRequest("GET", "/students", nil)
+	if _, policy := limiter.policyFor(anonymous); policy.Limit != 100 {
+		t.Errorf("default policy limit = %d, want 100", policy.Limit)
+	}
+}
+
+func TestRateLimiterClassPolicy(t *testing.T) {
+	lookup := func(r *http.Request) (RoutePolicy, bool) {
+		if r.URL.Path == "/execs/login" {
+			return RoutePolicy{Public: true, RateLimit: "login"}, true
+		}
+		return RoutePolicy{RateLimit: "default"}, true
+	}
+	limiter := NewRateLimiterWithOptions(RateLimiterOptions{
+		Default: RateLimitPolicy{Limit: 100, Window: time.Minute},
+		Classes: map[string]RateLimitPolicy{"login": {Limit: 5, Window: time.Minute}},
+		Lookup:  lookup,
+		Roles:   map[string]RateLimitPolicy{"admin": {Limit: 1000, Window: time.Minute}},
+	})
+	defer limiter.Stop()
+
+	// Класс маршрута важнее роли
+	login := httptest.NewRequest("POST", "/execs/login", nil)
+	login = login.WithContext(context.WithValue(login.Context(), utils.ContextKey("role"), "admin"))
+	if name, policy := limiter.policyFor(login); policy.Limit != 5 || name != "class:login" {
+		t.Errorf("login policy = %s %d, want class:login 5", name, policy.Limit)
+	}
+
+	// Класс без настроенного лимита не меняет выбор
+	if _, policy := limiter.policyFor(httptest.NewRequest("GET", "/students", nil)); policy.Limit != 100 {
+		t.Errorf("default class limit = %d, want 100", policy.Limit)
+	}
+}
+
+func TestRateLimiterEvictsIdleBuckets(t *testing.T) {
+	limiter := NewRateLimiterWithOptions(RateLimiterOptions{
+		Default: RateLimitPolicy{Limit: 1, Window: time.Minute},
+		IdleTTL: 20 * time.Millisecond,
+	})
+	defer limiter.Stop()
+
+	limiter.store.Take(context.Background(), "k", limiter.options.Default, time.Now())
+	time.Sleep(60 * time.Millisecond)
+
+	store := limiter.store.(*memoryLimiterStore)
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if len(store.buckets) != 0 {
+		t.Errorf("idle bucket was not evicted")
+	}
+}
@@ -0,0 +1,119 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"restapi/internal/metrics"
+	"runtime/debug"
+	"time"
+)
+
+type RecoveryOptions struct {
+	// CrashReportDir, when set, gets one file per recovered panic
+	CrashReportDir string
+}
+
+// problem is an RFC 9457 problem details body.
+type problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// Recovery turns a handler panic into a logged stack trace and a problem+json
+// 500 instead of a dropped connection. If the response had already started,
+// the connection is aborted because the status can no longer change.
+func Recovery(options RecoveryOptions) func(http.Handler) http.Handler {
+	fmt.Println("Recovery Middleware...")
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := &recoveryResponseWriter{ResponseWriter: w}
+			defer func() {
+				recovered := recover()
+				if recovered == nil {
+					return
+				}
+				// net/http uses ErrAbortHandler to abort quietly; let it through
+				if recovered == http.ErrAbortHandler {
+					panic(recovered)
+				}
+
+				stack := debug.Stack()
+				requestID := RequestIDFromContext(r.Context())
+				metrics.PanicsRecoveredTotal.Inc()
+				log.Printf("PANIC: request_id=%s %s %s: %v\n%s", requestID, r.Method, r.URL.Path, recovered, stack)
+
+				if options.CrashReportDir != "" {
+					if err := writeCrashReport(options.CrashReportDir, requestID, r, recovered, stack); err != nil {
+						log.Println("Error writing crash report:", err)
+					}
+				}
+
+				if rw.wroteHeader {
+					panic(http.ErrAbortHandler)
+				}
+
+				w.Header().Del("Content-Length")
+				w.Header().Del("Content-Encoding")
+				w.Header().Set("Content-Type", "application/problem+json")
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(problem{
+					Type:      "about:blank",
+					Title:     http.StatusText(http.StatusInternalServerError),
+					Status:    http.StatusInternalServerError,
+					Detail:    "The server encountered an unexpected error.",
+					Instance:  r.URL.Path,
+					RequestID: requestID,
+				})
+			}()
+
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
+
+func writeCrashReport(dir, requestID string, r *http.Request, recovered any, stack []byte) error {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	name := fmt.Sprintf("crash-%s-%s.log", now.Format("20060102T150405.000000000"), requestID)
+	report := fmt.Sprintf("time: %s\nrequest_id: %s\nrequest: %s %s\npanic: %v\n\n%s",
+		now.Format(time.RFC3339Nano), requestID, r.Method, r.URL.RequestURI(), recovered, stack)
+	return os.WriteFile(filepath.Join(dir, name), []byte(report), 0o640)
+}
+
+type recoveryResponseWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (rw *recoveryResponseWriter) WriteHeader(code int) {
+	if code >= 200 {
+		rw.wroteHeader = true
+	}
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *recoveryResponseWriter) Write(b []byte) (int, error) {
+	rw.wroteHeader = true
+	return rw.ResponseWriter.Write(b)
+}
+
+func (rw *recoveryResponseWriter) Flush() {
+	rw.wroteHeader = true
+	if flusher, ok := rw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (rw *recoveryResponseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
@@ -0,0 +1,99 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"restapi/pkg/utils"
+	"strings"
+	"testing"
+)
+
+func TestRecovery(t *testing.T) {
+	dir := t.TempDir()
+	handler := RequestID(Recovery(RecoveryOptions{CrashReportDir: dir})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Тот же сценарий, что и в хендлере без роли в контексте
+		_ = r.Context().Value(utils.ContextKey("role")).(string)
+	})))
+
+	req := httptest.NewRequest("GET", "/teachers/1/studentcount", nil)
+	req.Header.Set("X-Request-Id", "req-123")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("status = %v, want %v", rr.Code, http.StatusInternalServerError)
+	}
+	if got := rr.Header().Get("Content-Type"); got != "application/problem+json" {
+		t.Errorf("Content-Type = %q, want application/problem+json", got)
+	}
+
+	var body problem
+	if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+		t.Fatalf("invalid problem body: %v", err)
+	}
+	if body.Status != http.StatusInternalServerError || body.RequestID != "req-123" || body.Instance != "/teachers/1/studentcount" {
+		t.Errorf("problem = %+v", body)
+	}
+
+	reports, _ := filepath.Glob(filepath.Join(dir, "crash-*-req-123.log"))
+	if len(reports) != 1 {
+		t.Fatalf("crash reports = %v, want one", reports)
+	}
+	report, _ := os.ReadFile(reports[0])
+	if !strings.Contains(string(report), "interface conversion") || !strings.Contains(string(report), "goroutine") {
+		t.Errorf("crash report lacks the panic or stack trace:\n%s", report)
+	}
+}
+
+func TestRecoveryAfterResponseStarted(t *testing.T) {
+	handler := Recovery(RecoveryOptions{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		panic("boom")
+	}))
+
+	defer func() {
+		// Статус уже отправлен, поэтому соединение обрывается
+		if recovered := recover(); recovered != http.ErrAbortHandler {
+			t.Errorf("recovered = %v, want http.ErrAbortHandler", recovered)
+		}
+	}()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/test", nil))
+}
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{"generated when missing", "", false},
+		{"client id reused", "abc-123", true},
+		{"unsafe client id replaced", "abc\n123", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen string
+			handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = RequestIDFromContext(r.Context())
+			}))
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			if tt.incoming != "" {
+				req.Header.Set("X-Request-Id", tt.incoming)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if seen == "" || rr.Header().Get("X-Request-Id") != seen {
+				t.Errorf("context id %q, header %q", seen, rr.Header().Get("X-Request-Id"))
+			}
+			if (seen == tt.incoming) != tt.keep {
+				t.Errorf("id = %q, incoming %q, keep = %v", seen, tt.incoming, tt.keep)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package middlewares
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"restapi/pkg/utils"
+)
+
+const requestIDHeader = "X-Request-Id"
+
+// RequestID tags every request with an id, reusing the caller's X-Request-Id
+// when it looks sane, and echoes it in the response so logs can be correlated.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, id)
+
+		ctx := context.WithValue(r.Context(), utils.ContextKey("requestId"), id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequestIDFromContext returns the id set by RequestID, or "" outside of it.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(utils.ContextKey("requestId")).(string)
+	return id
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// validRequestID keeps client-supplied ids short and free of characters that
+// could break log lines or headers.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
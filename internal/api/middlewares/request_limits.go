@@ -0,0 +1,122 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type RequestLimitOptions struct {
+	// MaxBodyBytes caps the request body; 0 means no limit
+	MaxBodyBytes int64
+	// Timeout bounds the handler through its request context; 0 means no timeout
+	Timeout time.Duration
+	// Lookup supplies the body limit a route declares; Routes still override it
+	Lookup RouteLookup
+	// Routes are checked in order; the first match wins
+	Routes []RouteRequestLimit
+}
+
+// RouteRequestLimit overrides the limits for requests whose path starts with Path.
+// An empty Method matches every method; zero values inherit the defaults.
+type RouteRequestLimit struct {
+	Method       string
+	Path         string
+	MaxBodyBytes int64
+	Timeout      time.Duration
+}
+
+// RequestLimits rejects oversized bodies with 413 and cancels the request
+// context once the route's timeout passes, so database calls using it stop too.
+func RequestLimits(options RequestLimitOptions) func(http.Handler) http.Handler {
+	fmt.Println("Request Limits Middleware...")
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Println("Request Limits Middleware being returned...")
+			maxBody, timeout := options.limitsFor(r)
+
+			if maxBody > 0 {
+				if r.ContentLength > maxBody {
+					http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+					return
+				}
+				r.Body = http.MaxBytesReader(w, r.Body, maxBody)
+			}
+
+			if timeout <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+
+			tw := &timeoutResponseWriter{ResponseWriter: w}
+			next.ServeHTTP(tw, r.WithContext(ctx))
+
+			// The handler gave up because of the deadline without answering
+			if !tw.wroteHeader && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				http.Error(w, "Request timed out", http.StatusServiceUnavailable)
+			}
+			fmt.Println("Request Limits Middleware ends...")
+		})
+	}
+}
+
+func (o RequestLimitOptions) limitsFor(r *http.Request) (int64, time.Duration) {
+	maxBody, timeout := o.MaxBodyBytes, o.Timeout
+	if o.Lookup != nil {
+		if policy, ok := o.Lookup(r); ok && policy.MaxBodyBytes > 0 {
+			maxBody = policy.MaxBodyBytes
+		}
+	}
+	for _, route := range o.Routes {
+		if (route.Method == "" || route.Method == r.Method) && strings.HasPrefix(r.URL.Path, route.Path) {
+			if route.MaxBodyBytes > 0 {
+				maxBody = route.MaxBodyBytes
+			}
+			if route.Timeout > 0 {
+				timeout = route.Timeout
+			}
+			break
+		}
+	}
+	return maxBody, timeout
+}
+
+// isBodyTooLarge reports whether err came from reading past the body limit.
+func isBodyTooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
+
+type timeoutResponseWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (tw *timeoutResponseWriter) WriteHeader(code int) {
+	if code >= 200 {
+		tw.wroteHeader = true
+	}
+	tw.ResponseWriter.WriteHeader(code)
+}
+
+func (tw *timeoutResponseWriter) Write(b []byte) (int, error) {
+	tw.wroteHeader = true
+	return tw.ResponseWriter.Write(b)
+}
+
+func (tw *timeoutResponseWriter) Flush() {
+	tw.wroteHeader = true
+	if flusher, ok := tw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (tw *timeoutResponseWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
@@ -0,0 +1,129 @@
+package middlewares
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRequestLimitsBodySize(t *testing.T) {
+	options := RequestLimitOptions{
+		MaxBodyBytes: 16,
+		Routes:       []RouteRequestLimit{{Method: "POST", Path: "/teachers", MaxBodyBytes: 64}},
+	}
+	handler := RequestLimits(options)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			if isBodyTooLarge(err) {
+				http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name           string
+		path           string
+		size           int
+		chunked        bool
+		expectedStatus int
+	}{
+		{"within default limit", "/students", 10, false, http.StatusOK},
+		{"over default limit", "/students", 17, false, http.StatusRequestEntityTooLarge},
+		// Без Content-Length лимит срабатывает при чтении
+		{"over limit without content length", "/students", 17, true, http.StatusRequestEntityTooLarge},
+		{"bulk route has larger limit", "/teachers", 60, false, http.StatusOK},
+		{"over bulk route limit", "/teachers", 65, false, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", tt.path, strings.NewReader(strings.Repeat("a", tt.size)))
+			if tt.chunked {
+				req.ContentLength = -1
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("status = %v, want %v", rr.Code, tt.expectedStatus)
+			}
+		})
+	}
+}
+
+func TestRequestLimitsRouteLookup(t *testing.T) {
+	options := RequestLimitOptions{
+		MaxBodyBytes: 16,
+		Lookup: func(r *http.Request) (RoutePolicy, bool) {
+			if r.Method == "POST" && r.URL.Path == "/students" {
+				return RoutePolicy{MaxBodyBytes: 64}, true
+			}
+			return RoutePolicy{}, false
+		},
+		// Настройка из конфигурации перекрывает таблицу маршрутов
+		Routes: []RouteRequestLimit{{Method: "POST", Path: "/teachers", MaxBodyBytes: 32}},
+	}
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		wantMaxBody int64
+	}{
+		{"route table limit", "POST", "/students", 64},
+		{"config override", "POST", "/teachers", 32},
+		{"default", "PATCH", "/students", 16},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			maxBody, _ := options.limitsFor(httptest.NewRequest(tt.method, tt.path, nil))
+			if maxBody != tt.wantMaxBody {
+				t.Errorf("max body = %d, want %d", maxBody, tt.wantMaxBody)
+			}
+		})
+	}
+}
+
+func TestRequestLimitsTimeout(t *testing.T) {
+	options := RequestLimitOptions{
+		Timeout: 20 * time.Millisecond,
+		Routes:  []RouteRequestLimit{{Path: "/slow", Timeout: time.Second}},
+	}
+	handler := RequestLimits(options)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Имитация долгого запроса к БД, который отменяется вместе с контекстом
+		select {
+		case <-r.Context().Done():
+			return
+		case <-time.After(50 * time.Millisecond):
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+	}{
+		{"default timeout cancels the handler", "/students", http.StatusServiceUnavailable},
+		{"route override allows longer requests", "/slow/report", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest("GET", tt.path, nil))
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("status = %v, want %v", rr.Code, tt.expectedStatus)
+			}
+		})
+	}
+}
@@ -3,6 +3,8 @@ package middlewares
 import (
 	"fmt"
 	"net/http"
+	"restapi/internal/metrics"
+	"strconv"
 	"time"
 )
 
@@ -10,16 +12,21 @@ func ResponseTimeMiddleware(next http.Handler) http.Handler {
 	fmt.Println("Response Time Middleware...")
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		fmt.Println("Response Time Middleware being returned...")
-
 		start := time.Now()
+		ctx, route := metrics.WithRoute(r.Context())
+		wrappedWriter := &responseWriter{ResponseWriter: w, status: http.StatusOK, start: start}
 
-		wrappedWriter := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(wrappedWriter, r.WithContext(ctx))
 
 		duration := time.Since(start)
-		w.Header().Set("X-Response-Time", duration.String())
-		next.ServeHTTP(wrappedWriter, r)
+		routeLabel := *route
+		if routeLabel == "" {
+			routeLabel = "unmatched"
+		}
+		status := strconv.Itoa(wrappedWriter.status)
+		metrics.HTTPRequestsTotal.WithLabelValues(r.Method, routeLabel, status).Inc()
+		metrics.HTTPRequestDuration.WithLabelValues(r.Method, routeLabel, status).Observe(duration.Seconds())
 
-		duration = time.Since(start)
 		fmt.Printf("Method: %s, URL: %s, Status: %d, Duration: %v\n", r.Method, r.URL, wrappedWriter.status, duration.String())
 		fmt.Println("Sent Response from Response Time Middleware")
 	})
@@ -27,10 +34,26 @@ func ResponseTimeMiddleware(next http.Handler) http.Handler {
 
 type responseWriter struct {
 	http.ResponseWriter
-	status int
+	status      int
+	start       time.Time
+	wroteHeader bool
 }
 
+// WriteHeader sets X-Response-Time right before the headers are flushed,
+// so the value covers the handler's work instead of being ~0.
 func (rw *responseWriter) WriteHeader(code int) {
+	if rw.wroteHeader {
+		return
+	}
+	rw.wroteHeader = true
 	rw.status = code
+	rw.Header().Set("X-Response-Time", time.Since(rw.start).String())
 	rw.ResponseWriter.WriteHeader(code)
 }
+
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+	return rw.ResponseWriter.Write(b)
+}
@@ -0,0 +1,37 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"restapi/internal/metrics"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestResponseTimeMiddleware(t *testing.T) {
+	handler := ResponseTimeMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		metrics.SetRoute(r.Context(), "GET /test")
+		time.Sleep(5 * time.Millisecond)
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	before := testutil.ToFloat64(metrics.HTTPRequestsTotal.WithLabelValues("GET", "GET /test", "418"))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/test", nil))
+
+	elapsed, err := time.ParseDuration(rr.Header().Get("X-Response-Time"))
+	if err != nil {
+		t.Fatalf("X-Response-Time is not a duration: %v", err)
+	}
+	if elapsed < 5*time.Millisecond {
+		t.Errorf("X-Response-Time = %v, want at least 5ms", elapsed)
+	}
+
+	after := testutil.ToFloat64(metrics.HTTPRequestsTotal.WithLabelValues("GET", "GET /test", "418"))
+	if after-before != 1 {
+		t.Errorf("http_requests_total increased by %v, want 1", after-before)
+	}
+}
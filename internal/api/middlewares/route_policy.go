@@ -0,0 +1,39 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// RoutePolicy is what a route declares about itself for the middlewares that
+// run before the router has picked a handler.
+type RoutePolicy struct {
+	// Public routes are served without authentication
+	Public bool
+	// RateLimit names the rate-limit class; see RateLimiterOptions.Classes
+	RateLimit string
+	// MaxBodyBytes overrides the default body limit; 0 keeps it
+	MaxBodyBytes int64
+	// Query lists the query parameters the route accepts; see HPPOptions.Lookup
+	Query []string
+}
+
+// RouteLookup returns the policy of the route that will serve r. ok is false
+// when no route matches.
+type RouteLookup func(r *http.Request) (policy RoutePolicy, ok bool)
+
+// SkipPublic runs middleware only for routes that are not declared public.
+// Unknown routes are treated as private.
+func SkipPublic(middleware func(http.Handler) http.Handler, lookup RouteLookup) func(http.Handler) http.Handler {
+	fmt.Println("SkipPublic initialized")
+	return func(next http.Handler) http.Handler {
+		protected := middleware(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if policy, ok := lookup(r); ok && policy.Public {
+				next.ServeHTTP(w, r)
+				return
+			}
+			protected.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSkipPublic(t *testing.T) {
+	lookup := func(r *http.Request) (RoutePolicy, bool) {
+		switch r.URL.Path {
+		case "/execs/login":
+			return RoutePolicy{Public: true}, true
+		case "/students":
+			return RoutePolicy{}, true
+		}
+		return RoutePolicy{}, false
+	}
+	deny := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		})
+	}
+	handler := SkipPublic(deny, lookup)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+	}{
+		{"public route", "/execs/login", http.StatusOK},
+		{"private route", "/students", http.StatusUnauthorized},
+		// Неизвестный маршрут считается закрытым
+		{"unknown route", "/execs/login/extra", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest("POST", tt.path, nil))
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("status = %v, want %v", rr.Code, tt.expectedStatus)
+			}
+		})
+	}
+}
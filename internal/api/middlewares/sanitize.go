@@ -6,139 +6,134 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"mime"
 	"net/http"
 	"net/url"
 	"restapi/pkg/utils"
-	"strings"
-
-	"github.com/microcosm-cc/bluemonday"
+	"slices"
 )
 
+type SanitizeOptions struct {
+	// Routes bind request bodies to a model whose `sanitize` tags decide how each
+	// field is cleaned. Unmatched routes clean every field as plain text.
+	Routes []SanitizeRoute
+}
+
+// SanitizeRoute uses ServeMux pattern syntax, e.g. "POST /students" or "/execs/{id}".
+type SanitizeRoute struct {
+	Pattern string
+	Model   interface{}
+	// ContentTypes accepted besides application/json, e.g. text/csv for imports.
+	// Their bodies are passed on as is; the handler sanitizes after parsing.
+	ContentTypes []string
+}
+
+type sanitizeRule struct {
+	policies     utils.FieldPolicies
+	contentTypes []string
+}
+
+// XSSMiddleware cleans every field as plain text and accepts only JSON bodies.
 func XSSMiddleware(next http.Handler) http.Handler {
+	return Sanitize(SanitizeOptions{})(next)
+}
+
+func Sanitize(options SanitizeOptions) func(http.Handler) http.Handler {
 	fmt.Println("****** Intializing XSSMiddleware")
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		fmt.Println("++++++++++++ XSSMiddleware Ran")
 
-		sanitizedPath, err := clean(r.URL.Path)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
-			return
+	// A private mux resolves patterns with the same precedence as the router
+	routes := http.NewServeMux()
+	for _, route := range options.Routes {
+		rule := &sanitizeRule{
+			policies:     utils.FieldPoliciesOf(route.Model),
+			contentTypes: route.ContentTypes,
 		}
+		routes.Handle(route.Pattern, ruleHandler{rule})
+	}
+	defaultRule := &sanitizeRule{policies: utils.FieldPolicies{}}
 
-		params := r.URL.Query()
-		sanitizedQuery := make(map[string][]string)
-		for key, values := range params {
-			sanitizedKey, err := clean(key)
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusBadRequest)
-				return
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Println("++++++++++++ XSSMiddleware Ran")
+
+			rule := defaultRule
+			if h, pattern := routes.Handler(r); pattern != "" {
+				if matched, ok := h.(ruleHandler); ok {
+					rule = matched.rule
+				}
 			}
 
-			var sanitizedValues []string
-			for _, value := range values {
-				cleanValue, err := clean(value)
-				if err != nil {
-					http.Error(w, err.Error(), http.StatusBadRequest)
-					return
+			r.URL.Path = utils.SanitizeString(utils.SanitizeText, r.URL.Path)
+
+			params := r.URL.Query()
+			sanitizedQuery := make(url.Values, len(params))
+			for key, values := range params {
+				cleanKey := utils.SanitizeString(utils.SanitizeText, key)
+				for _, value := range values {
+					sanitizedQuery.Add(cleanKey, utils.SanitizeString(rule.policies.PolicyFor(cleanKey), value))
 				}
-				sanitizedValues = append(sanitizedValues, cleanValue.(string))
 			}
-			sanitizedQuery[sanitizedKey.(string)] = sanitizedValues
-		}
+			r.URL.RawQuery = sanitizedQuery.Encode()
 
-		r.URL.Path = sanitizedPath.(string)
-		r.URL.RawQuery = url.Values(sanitizedQuery).Encode()
+			contentType := r.Header.Get("Content-Type")
+			mediaType, _, _ := mime.ParseMediaType(contentType)
 
-		if r.Header.Get("Content-Type") == "application/json" {
-			if r.Body != nil {
+			switch {
+			case mediaType == "application/json":
+				if r.Body == nil {
+					log.Println("No body in the request")
+					break
+				}
 				bodyBytes, err := io.ReadAll(r.Body)
+				if isBodyTooLarge(err) {
+					http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+					return
+				}
 				if err != nil {
 					http.Error(w, utils.ErrorHandler(err, "Error reading request body").Error(), http.StatusBadRequest)
 					return
 				}
 
-				bodyString := strings.TrimSpace(string(bodyBytes))
-
-				r.Body = io.NopCloser(bytes.NewReader([]byte(bodyString)))
-
-				if len(bodyString) > 0 {
-					var inputData interface{}
-					err := json.NewDecoder(bytes.NewReader([]byte(bodyString))).Decode(&inputData)
-					if err != nil {
-						http.Error(w, utils.ErrorHandler(err, "Invalid JSON body").Error(), http.StatusBadRequest)
-						return
-					}
-
-					sanitizedData, err := clean(inputData)
-					if err != nil {
-						http.Error(w, err.Error(), http.StatusBadRequest)
-						return
-					}
-
-					sanitizedBody, err := json.Marshal(sanitizedData)
-					if err != nil {
-						http.Error(w, utils.ErrorHandler(err, "Error sanitizing body").Error(), http.StatusBadRequest)
-						return
-					}
-
-					r.Body = io.NopCloser(bytes.NewReader(sanitizedBody))
-					fmt.Println("Sanitized body:", string(sanitizedBody))
-				} else {
+				bodyBytes = bytes.TrimSpace(bodyBytes)
+				r.Body = io.NopCloser(bytes.NewReader(bodyBytes))
+				if len(bodyBytes) == 0 {
 					log.Println("Request body is empty")
+					break
 				}
-			} else {
-				log.Println("No body in the request")
-			}
-		} else if r.Header.Get("Content-Type") != "" {
-			log.Printf("Received request with unsupported Content-Type: %s. Expected application/json.\n", r.Header.Get("Content-Type"))
-			http.Error(w, "Unsupported Content-Type. Please use application/json.", http.StatusUnsupportedMediaType)
-			return
-		}
 
-		next.ServeHTTP(w, r)
-		fmt.Println("Sending response from XSSMiddleware Ran")
-	})
-}
+				var inputData interface{}
+				decoder := json.NewDecoder(bytes.NewReader(bodyBytes))
+				// Keep numbers as written; float64 would round large ids
+				decoder.UseNumber()
+				if err := decoder.Decode(&inputData); err != nil {
+					http.Error(w, utils.ErrorHandler(err, "Invalid JSON body").Error(), http.StatusBadRequest)
+					return
+				}
 
-func clean(data interface{}) (interface{}, error) {
+				sanitizedBody, err := json.Marshal(rule.policies.Sanitize(inputData))
+				if err != nil {
+					http.Error(w, utils.ErrorHandler(err, "Error sanitizing body").Error(), http.StatusBadRequest)
+					return
+				}
+				r.Body = io.NopCloser(bytes.NewReader(sanitizedBody))
+			case contentType == "":
+			case slices.Contains(rule.contentTypes, mediaType):
+				// Parsed and sanitized field by field by the handler
+			default:
+				log.Printf("Received request with unsupported Content-Type: %s.\n", contentType)
+				http.Error(w, "Unsupported Content-Type. Please use application/json.", http.StatusUnsupportedMediaType)
+				return
+			}
 
-	switch v := data.(type) {
-	case map[string]interface{}:
-		for key, value := range v {
-			v[key] = sanitizeValue(value)
-		}
-		return v, nil
-	case []interface{}:
-		for i, value := range v {
-			v[i] = sanitizeValue(value)
-		}
-		return v, nil
-	case string:
-		return sanitizeString(v), nil
-	default:
-		return nil, utils.ErrorHandler(fmt.Errorf("unsupported type: %T", data), fmt.Sprintf("unsupported type: %T", data))
+			next.ServeHTTP(w, r)
+			fmt.Println("Sending response from XSSMiddleware Ran")
+		})
 	}
 }
 
-func sanitizeValue(data interface{}) interface{} {
-	switch v := data.(type) {
-	case string:
-		return sanitizeString(v)
-	case map[string]interface{}:
-		for k, value := range v {
-			v[k] = sanitizeValue(value)
-		}
-		return v
-	case []interface{}:
-		for i, value := range v {
-			v[i] = sanitizeValue(value)
-		}
-		return v
-	default:
-		return v
-	}
+// ruleHandler only carries a rule through the ServeMux; it is never served.
+type ruleHandler struct {
+	rule *sanitizeRule
 }
 
-func sanitizeString(value string) string {
-	return bluemonday.UGCPolicy().Sanitize(value)
-}
+func (ruleHandler) ServeHTTP(http.ResponseWriter, *http.Request) {}
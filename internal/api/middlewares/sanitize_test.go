@@ -0,0 +1,126 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type sanitizeTestUser struct {
+	Email    string `json:"email" sanitize:"strict"`
+	Password string `json:"password" sanitize:"none"`
+}
+
+func TestSanitize(t *testing.T) {
+	options := SanitizeOptions{
+		Routes: []SanitizeRoute{
+			{Pattern: "/users/{id}", Model: sanitizeTestUser{}},
+			{Pattern: "POST /users", Model: sanitizeTestUser{}, ContentTypes: []string{"text/csv"}},
+		},
+	}
+	var gotBody string
+	handler := Sanitize(options)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		contentType    string
+		body           string
+		expectedStatus int
+		expectedBody   map[string]string
+	}{
+		{
+			name:           "route policies applied",
+			method:         "PATCH",
+			path:           "/users/1",
+			contentType:    "application/json",
+			body:           `{"email":"<b>a@example.com</b>","password":"<p@ss'>"}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   map[string]string{"email": "a@example.com", "password": "<p@ss'>"},
+		},
+		{
+			// Без модели поля чистятся как текст: разметка остаётся, экранирование — при выводе
+			name:           "unmatched route cleans as text",
+			method:         "POST",
+			path:           "/other",
+			contentType:    "application/json; charset=utf-8",
+			body:           `{"email":" <b>O'Brien</b> ","password":"secret"}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   map[string]string{"email": "<b>O'Brien</b>", "password": "secret"},
+		},
+		{
+			name:           "csv allowed on import route",
+			method:         "POST",
+			path:           "/users",
+			contentType:    "text/csv",
+			body:           "email,password\na@example.com,secret\n",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "csv rejected elsewhere",
+			method:         "PATCH",
+			path:           "/users/1",
+			contentType:    "text/csv",
+			body:           "email\na@example.com\n",
+			expectedStatus: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:           "invalid json",
+			method:         "POST",
+			path:           "/users",
+			contentType:    "application/json",
+			body:           `{"email":`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotBody = ""
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("status = %v, want %v", rr.Code, tt.expectedStatus)
+			}
+			if tt.expectedBody != nil {
+				var got map[string]string
+				if err := json.Unmarshal([]byte(gotBody), &got); err != nil {
+					t.Fatalf("handler got invalid JSON %q: %v", gotBody, err)
+				}
+				for key, want := range tt.expectedBody {
+					if got[key] != want {
+						t.Errorf("%s = %q, want %q", key, got[key], want)
+					}
+				}
+			}
+			if tt.contentType == "text/csv" && rr.Code == http.StatusOK && gotBody != tt.body {
+				t.Errorf("csv body = %q, want it passed through", gotBody)
+			}
+		})
+	}
+}
+
+func TestSanitizeQuery(t *testing.T) {
+	var got string
+	handler := XSSMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.URL.Query().Get("last_name")
+	}))
+
+	req := httptest.NewRequest("GET", "/students?last_name=O%27Brien", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if got != "O'Brien" {
+		t.Errorf("last_name = %q, want %q", got, "O'Brien")
+	}
+}
@@ -0,0 +1,25 @@
+package router
+
+import (
+	"restapi/internal/api/handlers"
+)
+
+func academicYearsRoutes() []Route {
+	return []Route{
+		{Method: "GET", Pattern: "/years", Handler: handlers.GetYearsHandler, Roles: staff, RateLimit: RateLimitDefault},
+		{Method: "POST", Pattern: "/years", Handler: handlers.AddYearHandler, Roles: managers, RateLimit: RateLimitDefault},
+		{Method: "GET", Pattern: "/years/{id}", Handler: handlers.GetOneYearHandler, Roles: staff, RateLimit: RateLimitDefault},
+		{Method: "PATCH", Pattern: "/years/{id}", Handler: handlers.PatchYearHandler, Roles: managers, RateLimit: RateLimitDefault},
+
+		{Method: "GET", Pattern: "/years/{id}/terms", Handler: handlers.GetTermsHandler, Roles: staff, RateLimit: RateLimitDefault},
+		{Method: "POST", Pattern: "/years/{id}/terms", Handler: handlers.AddTermHandler, Roles: managers, RateLimit: RateLimitDefault},
+		{Method: "PATCH", Pattern: "/terms/{id}", Handler: handlers.PatchTermHandler, Roles: managers, RateLimit: RateLimitDefault},
+		{Method: "DELETE", Pattern: "/terms/{id}", Handler: handlers.DeleteTermHandler, Roles: managers, RateLimit: RateLimitDefault},
+
+		// Class membership per year
+		{Method: "POST", Pattern: "/years/{id}/enrollments", Handler: handlers.SyncEnrollmentsHandler, Roles: managers, RateLimit: RateLimitDefault},
+		{Method: "GET", Pattern: "/years/{id}/classes/{class}", Handler: handlers.GetYearClassHandler, Roles: staff, RateLimit: RateLimitDefault},
+		{Method: "GET", Pattern: "/students/{id}/enrollments", Handler: handlers.GetStudentEnrollmentsHandler, Roles: staff, RateLimit: RateLimitDefault},
+		{Method: "POST", Pattern: "/years/{id}/promotion", Handler: handlers.PromoteHandler, Roles: adminOnly, RateLimit: RateLimitDefault},
+	}
+}
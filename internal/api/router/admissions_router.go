@@ -0,0 +1,28 @@
+package router
+
+import (
+	"restapi/internal/api/handlers"
+	"restapi/pkg/utils"
+)
+
+func admissionsRoutes() []Route {
+	return []Route{
+		{Method: "GET", Pattern: "/admissions/stages", Handler: handlers.GetAdmissionStagesHandler, Roles: staff, RateLimit: RateLimitDefault},
+		{Method: "GET", Pattern: "/admissions/metrics", Handler: handlers.GetAdmissionMetricsHandler, Roles: managers, RateLimit: RateLimitDefault, Query: []string{"from", "to", "term_id", "year_id"}},
+
+		{Method: "GET", Pattern: "/leads", Handler: handlers.GetLeadsHandler, Roles: staff, RateLimit: RateLimitDefault, Query: utils.LeadListSchema.QueryParams()},
+		{Method: "POST", Pattern: "/leads", Handler: handlers.AddLeadHandler, Roles: staff, RateLimit: RateLimitDefault},
+		{Method: "GET", Pattern: "/leads/{id}", Handler: handlers.GetOneLeadHandler, Roles: staff, RateLimit: RateLimitDefault},
+		{Method: "PATCH", Pattern: "/leads/{id}", Handler: handlers.PatchLeadHandler, Roles: staff, RateLimit: RateLimitDefault},
+		{Method: "DELETE", Pattern: "/leads/{id}", Handler: handlers.DeleteLeadHandler, Roles: managers, RateLimit: RateLimitDefault},
+		{Method: "PUT", Pattern: "/leads/{id}/guardians", Handler: handlers.SetLeadGuardiansHandler, Roles: staff, RateLimit: RateLimitDefault},
+		{Method: "PUT", Pattern: "/leads/{id}/assignee", Handler: handlers.AssignLeadHandler, Roles: managers, RateLimit: RateLimitDefault},
+
+		// Stage changes, activities and enrollment
+		{Method: "POST", Pattern: "/leads/{id}/stage", Handler: handlers.MoveLeadHandler, Roles: staff, RateLimit: RateLimitDefault},
+		{Method: "GET", Pattern: "/leads/{id}/history", Handler: handlers.GetLeadHistoryHandler, Roles: staff, RateLimit: RateLimitDefault},
+		{Method: "GET", Pattern: "/leads/{id}/timeline", Handler: handlers.GetLeadTimelineHandler, Roles: staff, RateLimit: RateLimitDefault},
+		{Method: "POST", Pattern: "/leads/{id}/activities", Handler: handlers.AddLeadActivityHandler, Roles: staff, RateLimit: RateLimitDefault},
+		{Method: "POST", Pattern: "/leads/{id}/convert", Handler: handlers.ConvertLeadHandler, Roles: managers, RateLimit: RateLimitDefault},
+	}
+}
@@ -0,0 +1,18 @@
+package router
+
+import (
+	"restapi/internal/api/handlers"
+)
+
+func attendanceRoutes() []Route {
+	return []Route{
+		// Bulk marks for one class session
+		{Method: "POST", Pattern: "/attendance", Handler: handlers.RecordAttendanceHandler, Roles: staff, RateLimit: RateLimitDefault},
+		{Method: "PATCH", Pattern: "/attendance/{id}", Handler: handlers.PatchAttendanceHandler, Roles: staff, RateLimit: RateLimitDefault},
+		{Method: "GET", Pattern: "/attendance/{id}/history", Handler: handlers.GetAttendanceHistoryHandler, Roles: staff, RateLimit: RateLimitDefault},
+		{Method: "GET", Pattern: "/attendance/alerts", Handler: handlers.GetAttendanceAlertsHandler, Roles: managers, RateLimit: RateLimitDefault, Query: []string{"limit"}},
+
+		{Method: "GET", Pattern: "/students/{id}/attendance", Handler: handlers.GetStudentAttendanceHandler, Roles: staff, RateLimit: RateLimitDefault, Query: []string{"from", "to", "term_id", "year_id"}},
+		{Method: "GET", Pattern: "/classes/{class}/attendance", Handler: handlers.GetClassRosterHandler, Roles: staff, RateLimit: RateLimitDefault, Query: []string{"date"}},
+	}
+}
@@ -0,0 +1,15 @@
+package router
+
+import (
+	"restapi/internal/api/handlers"
+)
+
+func customFieldsRoutes() []Route {
+	return []Route{
+		// Staff see the fields shown to their role, admins every field
+		{Method: "GET", Pattern: "/custom-fields", Handler: handlers.GetCustomFieldsHandler, Roles: staff, RateLimit: RateLimitDefault, Query: []string{"resource"}},
+		{Method: "POST", Pattern: "/custom-fields", Handler: handlers.AddCustomFieldHandler, Roles: adminOnly, RateLimit: RateLimitDefault},
+		{Method: "PATCH", Pattern: "/custom-fields/{id}", Handler: handlers.PatchCustomFieldHandler, Roles: adminOnly, RateLimit: RateLimitDefault},
+		{Method: "DELETE", Pattern: "/custom-fields/{id}", Handler: handlers.DeleteCustomFieldHandler, Roles: adminOnly, RateLimit: RateLimitDefault},
+	}
+}
@@ -1,26 +1,24 @@
 package router
 
 import (
-	"net/http"
 	"restapi/internal/api/handlers"
+	"restapi/pkg/utils"
 )
 
-func execsRouter() *http.ServeMux {
-	mux := http.NewServeMux()
+func execsRoutes() []Route {
+	return []Route{
+		{Method: "GET", Pattern: "/execs", Handler: handlers.GetExecsHandler, Roles: managers, RateLimit: RateLimitDefault, Query: utils.ExecListSchema.QueryParams()},
+		{Method: "POST", Pattern: "/execs", Handler: handlers.AddExecsHandler, Roles: managers, RateLimit: RateLimitDefault},
+		{Method: "PATCH", Pattern: "/execs", Handler: handlers.PatchExecsHandler, Roles: managers, RateLimit: RateLimitDefault},
 
-	mux.HandleFunc("GET /execs", handlers.GetExecsHandler)
-	mux.HandleFunc("POST /execs", handlers.AddExecsHandler)
-	mux.HandleFunc("PATCH /execs", handlers.PatchExecsHandler)
+		{Method: "GET", Pattern: "/execs/{id}", Handler: handlers.GetOneExecHandler, Roles: managers, RateLimit: RateLimitDefault},
+		{Method: "PATCH", Pattern: "/execs/{id}", Handler: handlers.PatchOneExecHandler, Roles: managers, RateLimit: RateLimitDefault},
+		{Method: "DELETE", Pattern: "/execs/{id}", Handler: handlers.DeleteOneExecHandler, Roles: adminOnly, RateLimit: RateLimitDefault},
+		{Method: "POST", Pattern: "/execs/{id}/updatepassword", Handler: handlers.UpdatePasswordHandler, Roles: staff, RateLimit: RateLimitPasswordReset},
 
-	mux.HandleFunc("GET /execs/{id}", handlers.GetOneExecHandler)
-	mux.HandleFunc("PATCH /execs/{id}", handlers.PatchOneExecHandler)
-	mux.HandleFunc("DELETE /execs/{id}", handlers.DeleteOneExecHandler)
-	mux.HandleFunc("POST /execs/{id}/updatepassword", handlers.UpdatePasswordHandler)
-
-	mux.HandleFunc("POST /execs/login", handlers.LoginHandler)
-	mux.HandleFunc("POST /execs/logout", handlers.LogoutHandler)
-	mux.HandleFunc("POST /execs/forgotpassword", handlers.ForgotPasswordHandler)
-	mux.HandleFunc("POST /execs/resetpassword/reset/{resetcode}", handlers.ResetPasswordHandler)
-
-	return mux
+		{Method: "POST", Pattern: "/execs/login", Handler: handlers.LoginHandler, Public: true, RateLimit: RateLimitLogin},
+		{Method: "POST", Pattern: "/execs/logout", Handler: handlers.LogoutHandler, Public: true, RateLimit: RateLimitDefault},
+		{Method: "POST", Pattern: "/execs/forgotpassword", Handler: handlers.ForgotPasswordHandler, Public: true, RateLimit: RateLimitPasswordReset},
+		{Method: "POST", Pattern: "/execs/resetpassword/reset/{resetcode}", Handler: handlers.ResetPasswordHandler, Public: true, RateLimit: RateLimitPasswordReset},
+	}
 }
@@ -0,0 +1,23 @@
+package router
+
+import (
+	"restapi/internal/api/handlers"
+)
+
+func gradesRoutes() []Route {
+	return []Route{
+		{Method: "GET", Pattern: "/assessments", Handler: handlers.GetAssessmentsHandler, Roles: staff, RateLimit: RateLimitDefault, Query: []string{"class", "subject", "from", "to"}},
+		{Method: "POST", Pattern: "/assessments", Handler: handlers.AddAssessmentHandler, Roles: staff, RateLimit: RateLimitDefault},
+
+		{Method: "GET", Pattern: "/assessments/{id}", Handler: handlers.GetOneAssessmentHandler, Roles: staff, RateLimit: RateLimitDefault},
+		{Method: "PATCH", Pattern: "/assessments/{id}", Handler: handlers.PatchAssessmentHandler, Roles: staff, RateLimit: RateLimitDefault},
+		{Method: "DELETE", Pattern: "/assessments/{id}", Handler: handlers.DeleteAssessmentHandler, Roles: managers, RateLimit: RateLimitDefault},
+
+		// Bulk score entry for one assessment
+		{Method: "GET", Pattern: "/assessments/{id}/scores", Handler: handlers.GetAssessmentScoresHandler, Roles: staff, RateLimit: RateLimitDefault},
+		{Method: "POST", Pattern: "/assessments/{id}/scores", Handler: handlers.RecordScoresHandler, Roles: staff, RateLimit: RateLimitDefault},
+
+		{Method: "GET", Pattern: "/students/{id}/grades", Handler: handlers.GetStudentGradesHandler, Roles: staff, RateLimit: RateLimitDefault, Query: []string{"subject", "from", "to", "term_id", "year_id"}},
+		{Method: "GET", Pattern: "/classes/{class}/grades", Handler: handlers.GetGradeDistributionHandler, Roles: staff, RateLimit: RateLimitDefault, Query: []string{"subject", "from", "to", "term_id", "year_id"}},
+	}
+}
@@ -0,0 +1,23 @@
+package router
+
+import (
+	"restapi/internal/api/handlers"
+	"restapi/pkg/utils"
+)
+
+func guardiansRoutes() []Route {
+	return []Route{
+		{Method: "GET", Pattern: "/guardians", Handler: handlers.GetGuardiansHandler, Roles: staff, RateLimit: RateLimitDefault, Query: utils.GuardianListSchema.QueryParams()},
+		// Bulk import of JSON arrays or CSV files
+		{Method: "POST", Pattern: "/guardians", Handler: handlers.AddGuardiansHandler, Roles: managers, RateLimit: RateLimitDefault, MaxBodyBytes: 10 << 20},
+
+		{Method: "GET", Pattern: "/guardians/{id}", Handler: handlers.GetOneGuardianHandler, Roles: staff, RateLimit: RateLimitDefault},
+		{Method: "PATCH", Pattern: "/guardians/{id}", Handler: handlers.PatchGuardianHandler, Roles: managers, RateLimit: RateLimitDefault},
+		{Method: "DELETE", Pattern: "/guardians/{id}", Handler: handlers.DeleteGuardianHandler, Roles: adminOnly, RateLimit: RateLimitDefault},
+		{Method: "GET", Pattern: "/guardians/{id}/students", Handler: handlers.GetGuardianChildrenHandler, Roles: staff, RateLimit: RateLimitDefault},
+
+		{Method: "GET", Pattern: "/students/{id}/guardians", Handler: handlers.GetStudentGuardiansHandler, Roles: staff, RateLimit: RateLimitDefault},
+		{Method: "PUT", Pattern: "/students/{id}/guardians/{guardianId}", Handler: handlers.LinkGuardianHandler, Roles: managers, RateLimit: RateLimitDefault},
+		{Method: "DELETE", Pattern: "/students/{id}/guardians/{guardianId}", Handler: handlers.UnlinkGuardianHandler, Roles: managers, RateLimit: RateLimitDefault},
+	}
+}
@@ -0,0 +1,20 @@
+package router
+
+import (
+	"restapi/internal/api/handlers"
+)
+
+func leaveRoutes() []Route {
+	return []Route{
+		{Method: "GET", Pattern: "/leave-requests", Handler: handlers.GetLeaveRequestsHandler, Roles: staff, RateLimit: RateLimitDefault, Query: []string{"status", "teacher_id", "from", "to"}},
+		{Method: "POST", Pattern: "/leave-requests", Handler: handlers.AddLeaveRequestHandler, Roles: staff, RateLimit: RateLimitDefault},
+		{Method: "GET", Pattern: "/leave-requests/{id}", Handler: handlers.GetOneLeaveHandler, Roles: staff, RateLimit: RateLimitDefault},
+		{Method: "POST", Pattern: "/leave-requests/{id}/decision", Handler: handlers.DecideLeaveHandler, Roles: managers, RateLimit: RateLimitDefault},
+
+		// Cover for the lessons the teacher misses
+		{Method: "GET", Pattern: "/leave-requests/{id}/cover", Handler: handlers.GetLeaveCoverHandler, Roles: staff, RateLimit: RateLimitDefault, Query: []string{"limit"}},
+		{Method: "POST", Pattern: "/leave-requests/{id}/substitutions", Handler: handlers.AssignSubstituteHandler, Roles: managers, RateLimit: RateLimitDefault},
+		{Method: "DELETE", Pattern: "/substitutions/{id}", Handler: handlers.DeleteSubstitutionHandler, Roles: managers, RateLimit: RateLimitDefault},
+		{Method: "GET", Pattern: "/teachers/{id}/substitutions", Handler: handlers.GetTeacherSubstitutionsHandler, Roles: staff, RateLimit: RateLimitDefault, Query: []string{"from", "to", "term_id", "year_id"}},
+	}
+}
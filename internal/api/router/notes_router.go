@@ -0,0 +1,32 @@
+package router
+
+import (
+	"restapi/internal/api/handlers"
+	"restapi/internal/models"
+	"restapi/pkg/utils"
+)
+
+func notesRoutes() []Route {
+	contactNotes := []string{"type", "visibility", "author_id", "tag", "sortby", "page", "limit"}
+	routes := []Route{
+		{Method: "GET", Pattern: "/notes", Handler: handlers.GetNotesHandler, Roles: staff, RateLimit: RateLimitDefault, Query: utils.NoteListSchema.QueryParams()},
+		{Method: "GET", Pattern: "/notes/tags", Handler: handlers.GetNoteTagsHandler, Roles: staff, RateLimit: RateLimitDefault},
+		{Method: "GET", Pattern: "/notes/{id}", Handler: handlers.GetOneNoteHandler, Roles: staff, RateLimit: RateLimitDefault},
+		// Only the author or an admin may change a note; the handlers check it
+		{Method: "PATCH", Pattern: "/notes/{id}", Handler: handlers.PatchNoteHandler, Roles: staff, RateLimit: RateLimitDefault},
+		{Method: "DELETE", Pattern: "/notes/{id}", Handler: handlers.DeleteNoteHandler, Roles: staff, RateLimit: RateLimitDefault},
+	}
+
+	for _, contact := range []struct{ resource, subjectType string }{
+		{"students", models.ContactStudent},
+		{"teachers", models.ContactTeacher},
+		{"guardians", models.ContactGuardian},
+	} {
+		routes = append(routes,
+			Route{Method: "GET", Pattern: "/" + contact.resource + "/{id}/notes", Handler: handlers.ContactNotesHandler(contact.subjectType), Roles: staff, RateLimit: RateLimitDefault, Query: contactNotes},
+			Route{Method: "POST", Pattern: "/" + contact.resource + "/{id}/notes", Handler: handlers.AddContactNoteHandler(contact.subjectType), Roles: staff, RateLimit: RateLimitDefault},
+			Route{Method: "GET", Pattern: "/" + contact.resource + "/{id}/timeline", Handler: handlers.ContactTimelineHandler(contact.subjectType), Roles: staff, RateLimit: RateLimitDefault, Query: []string{"limit"}},
+		)
+	}
+	return routes
+}
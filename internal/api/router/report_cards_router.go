@@ -0,0 +1,14 @@
+package router
+
+import (
+	"restapi/internal/api/handlers"
+)
+
+func reportCardsRoutes() []Route {
+	return []Route{
+		// PDF for one student, a zip of PDFs for a whole class
+		{Method: "GET", Pattern: "/students/{id}/report-card", Handler: handlers.GetReportCardHandler, Roles: staff, RateLimit: RateLimitDefault, Query: []string{"term", "from", "to", "term_id", "year_id"}},
+		{Method: "GET", Pattern: "/classes/{class}/report-cards", Handler: handlers.GetClassReportCardsHandler, Roles: staff, RateLimit: RateLimitDefault, Query: []string{"term", "from", "to", "term_id", "year_id"}},
+		{Method: "PUT", Pattern: "/students/{id}/report-comments", Handler: handlers.SetReportCommentHandler, Roles: staff, RateLimit: RateLimitDefault},
+	}
+}
@@ -1,14 +1,154 @@
 package router
 
 import (
+	"encoding/json"
 	"net/http"
+	mw "restapi/internal/api/middlewares"
+	"restapi/internal/metrics"
+	"restapi/pkg/utils"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Rate-limit classes a route can declare. Classes without a configured
+// policy fall back to the role or default limit.
+const (
+	RateLimitDefault       = "default"
+	RateLimitLogin         = "login"
+	RateLimitPasswordReset = "password_reset"
 )
 
-func MainRouter() *http.ServeMux {
+var (
+	// staff is every role an exec account can have
+	staff     = []string{"admin", "manager", "exec"}
+	managers  = []string{"admin", "manager"}
+	adminOnly = []string{"admin"}
+)
+
+// Route declares a handler together with the policies the middlewares and
+// the router apply to it.
+type Route struct {
+	Method  string           `json:"method"`
+	Pattern string           `json:"pattern"`
+	Handler http.HandlerFunc `json:"-"`
+	// Public routes skip authentication; every other route lists its Roles
+	Public bool     `json:"public"`
+	Roles  []string `json:"roles,omitempty"`
+	// RateLimit is one of the RateLimit* classes
+	RateLimit string `json:"rate_limit"`
+	// MaxBodyBytes overrides the server-wide body limit; 0 keeps it
+	MaxBodyBytes int64 `json:"max_body_bytes,omitempty"`
+	// Query lists accepted query parameters; HPP strips the others
+	Query []string `json:"query,omitempty"`
+}
+
+func (rt Route) String() string {
+	return rt.Method + " " + rt.Pattern
+}
+
+func (rt Route) policy() mw.RoutePolicy {
+	return mw.RoutePolicy{Public: rt.Public, RateLimit: rt.RateLimit, MaxBodyBytes: rt.MaxBodyBytes, Query: rt.Query}
+}
+
+// Routes is the route table of the API.
+func Routes() []Route {
+	var routes []Route
+	routes = append(routes, execsRoutes()...)
+	routes = append(routes, studentsRoutes()...)
+	routes = append(routes, teachersRoutes()...)
+	routes = append(routes, attendanceRoutes()...)
+	routes = append(routes, gradesRoutes()...)
+	routes = append(routes, reportCardsRoutes()...)
+	routes = append(routes, guardiansRoutes()...)
+	routes = append(routes, academicYearsRoutes()...)
+	routes = append(routes, timetableRoutes()...)
+	routes = append(routes, leaveRoutes()...)
+	routes = append(routes, admissionsRoutes()...)
+	routes = append(routes, notesRoutes()...)
+	routes = append(routes, customFieldsRoutes()...)
+	return routes
+}
+
+// Router serves a route table from a single ServeMux and answers policy
+// lookups for the middlewares in front of it.
+type Router struct {
+	mux      *http.ServeMux
+	routes   []Route
+	policies map[string]mw.RoutePolicy
+}
+
+func MainRouter() *Router {
+	return New(Routes())
+}
+
+// New registers routes plus GET /_routes, which lists them for admins.
+func New(routes []Route) *Router {
+	rt := &Router{
+		mux:      http.NewServeMux(),
+		policies: make(map[string]mw.RoutePolicy, len(routes)+1),
+	}
+	routes = append(routes, Route{
+		Method: "GET", Pattern: "/_routes", Handler: rt.listRoutes,
+		Roles: adminOnly, RateLimit: RateLimitDefault,
+	})
+	for _, route := range routes {
+		rt.mux.Handle(route.String(), authorize(route))
+		rt.policies[route.String()] = route.policy()
+	}
+	rt.routes = routes
+	return rt
+}
+
+// Policy is a middlewares.RouteLookup for the route that will serve r.
+func (rt *Router) Policy(r *http.Request) (mw.RoutePolicy, bool) {
+	_, pattern := rt.mux.Handler(r)
+	policy, ok := rt.policies[pattern]
+	return policy, ok
+}
+
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, span := otel.Tracer("restapi").Start(r.Context(), "handler")
+	defer span.End()
+
+	r = r.WithContext(ctx)
+	rt.mux.ServeHTTP(w, r)
+
+	// ServeMux stores the matched pattern on r
+	if r.Pattern != "" {
+		span.SetName("handler " + r.Pattern)
+		span.SetAttributes(attribute.String("http.route", r.Pattern))
+	}
+	metrics.SetRoute(r.Context(), r.Pattern)
+}
+
+func (rt *Router) listRoutes(w http.ResponseWriter, r *http.Request) {
+	response := struct {
+		Status string  `json:"status"`
+		Count  int     `json:"count"`
+		Data   []Route `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(rt.routes),
+		Data:   rt.routes,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
 
-	tRouter := teachersRouter()
-	sRouter := studentsRouter()
-	sRouter.Handle("/", execsRouter())
-	tRouter.Handle("/", sRouter)
-	return tRouter
+// authorize rejects callers whose JWT role isn't in route.Roles. It runs
+// after JWTMiddleware has put the role on the context.
+func authorize(route Route) http.Handler {
+	if route.Public {
+		return route.Handler
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		role, _ := r.Context().Value(utils.ContextKey("role")).(string)
+		if _, err := utils.AuthorizeUser(role, route.Roles...); err != nil {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		route.Handler(w, r)
+	})
 }
@@ -0,0 +1,114 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"restapi/internal/config"
+	"restapi/pkg/utils"
+	"testing"
+)
+
+// Каждый маршрут обязан явно объявить доступ и класс лимита
+func TestRoutesHavePolicy(t *testing.T) {
+	classes := config.Default().RateLimit.Classes
+	seen := make(map[string]bool)
+
+	for _, route := range New(Routes()).routes {
+		t.Run(route.String(), func(t *testing.T) {
+			if seen[route.String()] {
+				t.Errorf("route declared twice")
+			}
+			seen[route.String()] = true
+
+			if route.Handler == nil {
+				t.Errorf("route has no handler")
+			}
+			if route.Public == (len(route.Roles) > 0) {
+				t.Errorf("route must be either public or list its roles, got public=%v roles=%v", route.Public, route.Roles)
+			}
+			if _, ok := classes[route.RateLimit]; !ok && route.RateLimit != RateLimitDefault {
+				t.Errorf("unknown rate limit class %q", route.RateLimit)
+			}
+			if route.MaxBodyBytes < 0 {
+				t.Errorf("negative body limit %d", route.MaxBodyBytes)
+			}
+		})
+	}
+}
+
+func TestRouterPolicy(t *testing.T) {
+	rt := MainRouter()
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantOK     bool
+		wantPublic bool
+	}{
+		{"login is public", "POST", "/execs/login", true, true},
+		{"reset with code is public", "POST", "/execs/resetpassword/reset/abc", true, true},
+		// Раньше префикс делал публичным всё под /execs/resetpassword/reset
+		{"paths below reset are unknown", "POST", "/execs/resetpassword/reset/abc/extra", false, false},
+		{"students are private", "GET", "/students", true, false},
+		{"wrong method is unknown", "PUT", "/execs/login", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, ok := rt.Policy(httptest.NewRequest(tt.method, tt.path, nil))
+			if ok != tt.wantOK || policy.Public != tt.wantPublic {
+				t.Errorf("Policy() = %+v, %v, want public=%v, %v", policy, ok, tt.wantPublic, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestRouterAuthorize(t *testing.T) {
+	rt := New([]Route{
+		{Method: "DELETE", Pattern: "/things/{id}", Roles: []string{"admin"}, RateLimit: RateLimitDefault,
+			Handler: func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) }},
+	})
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		role           string
+		expectedStatus int
+	}{
+		{"allowed role", "DELETE", "/things/1", "admin", http.StatusNoContent},
+		{"other role", "DELETE", "/things/1", "exec", http.StatusForbidden},
+		{"routes listing for admin", "GET", "/_routes", "admin", http.StatusOK},
+		{"routes listing for others", "GET", "/_routes", "manager", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req = req.WithContext(context.WithValue(req.Context(), utils.ContextKey("role"), tt.role))
+			rr := httptest.NewRecorder()
+			rt.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("status = %v, want %v", rr.Code, tt.expectedStatus)
+			}
+			if tt.path != "/_routes" || rr.Code != http.StatusOK {
+				return
+			}
+
+			var response struct {
+				Count int     `json:"count"`
+				Data  []Route `json:"data"`
+			}
+			if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+				t.Fatal(err)
+			}
+			if response.Count != 2 || response.Data[0].Pattern != "/things/{id}" {
+				t.Errorf("routes = %+v", response)
+			}
+		})
+	}
+}
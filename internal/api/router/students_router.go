@@ -1,22 +1,22 @@
 package router
 
 import (
-	"net/http"
 	"restapi/internal/api/handlers"
+	"restapi/pkg/utils"
 )
 
-func studentsRouter() *http.ServeMux {
+func studentsRoutes() []Route {
+	return []Route{
+		// format=csv exports every matching record with its custom fields
+		{Method: "GET", Pattern: "/students", Handler: handlers.GetStudentsHandler, Roles: staff, RateLimit: RateLimitDefault, Query: append(utils.StudentListSchema.QueryParams(), "format")},
+		// Bulk import of JSON arrays or CSV files
+		{Method: "POST", Pattern: "/students", Handler: handlers.AddStudentHandler, Roles: staff, RateLimit: RateLimitDefault, MaxBodyBytes: 10 << 20},
+		{Method: "PATCH", Pattern: "/students", Handler: handlers.PatchStudentsHandler, Roles: staff, RateLimit: RateLimitDefault},
+		{Method: "DELETE", Pattern: "/students", Handler: handlers.DeleteStudentsHandler, Roles: adminOnly, RateLimit: RateLimitDefault},
 
-	mux := http.NewServeMux()
-	mux.HandleFunc("GET /students", handlers.GetStudentsHandler)
-	mux.HandleFunc("POST /students", handlers.AddStudentHandler)
-	mux.HandleFunc("PATCH /students", handlers.PatchStudentsHandler)
-	mux.HandleFunc("DELETE /students", handlers.DeleteStudentsHandler)
-
-	mux.HandleFunc("GET /students/{id}", handlers.GetOneStudentHandler)
-	mux.HandleFunc("PUT /students/{id}", handlers.UpdateStudentHandler)
-	mux.HandleFunc("PATCH /students/{id}", handlers.PatchOneStudentHandler)
-	mux.HandleFunc("DELETE /students/{id}", handlers.DeleteOneStudentHandler)
-
-	return mux
+		{Method: "GET", Pattern: "/students/{id}", Handler: handlers.GetOneStudentHandler, Roles: staff, RateLimit: RateLimitDefault},
+		{Method: "PUT", Pattern: "/students/{id}", Handler: handlers.UpdateStudentHandler, Roles: staff, RateLimit: RateLimitDefault},
+		{Method: "PATCH", Pattern: "/students/{id}", Handler: handlers.PatchOneStudentHandler, Roles: staff, RateLimit: RateLimitDefault},
+		{Method: "DELETE", Pattern: "/students/{id}", Handler: handlers.DeleteOneStudentHandler, Roles: adminOnly, RateLimit: RateLimitDefault},
+	}
 }
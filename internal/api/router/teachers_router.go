@@ -1,27 +1,25 @@
 package router
 
 import (
-	"net/http"
 	"restapi/internal/api/handlers"
+	"restapi/pkg/utils"
 )
 
-func teachersRouter() *http.ServeMux {
-
-	mux := http.NewServeMux()
-
-	mux.HandleFunc("GET /teachers", handlers.GetTeachersHandler)
-	mux.HandleFunc("POST /teachers", handlers.AddTeacherHandler)
-	mux.HandleFunc("PATCH /teachers", handlers.PatchTeachersHandler)
-	mux.HandleFunc("DELETE /teachers", handlers.DeleteTeachersHandler)
-
-	mux.HandleFunc("GET /teachers/{id}", handlers.GetOneTeacherHandler)
-	mux.HandleFunc("PUT /teachers/{id}", handlers.UpdateTeacherHandler)
-	mux.HandleFunc("PATCH /teachers/{id}", handlers.PatchOneTeacherHandler)
-	mux.HandleFunc("DELETE /teachers/{id}", handlers.DeleteOneTeacherHandler)
-
-	mux.HandleFunc("GET /teachers/{id}/students", handlers.GetStudentsByTeacherId)
-	mux.HandleFunc("GET /teachers/{id}/studentcount", handlers.GetStudentCountByTeacherId)
-
-	return mux
-
+func teachersRoutes() []Route {
+	return []Route{
+		// format=csv exports every matching record with its custom fields
+		{Method: "GET", Pattern: "/teachers", Handler: handlers.GetTeachersHandler, Roles: staff, RateLimit: RateLimitDefault, Query: append(utils.TeacherListSchema.QueryParams(), "format")},
+		// Bulk import of JSON arrays or CSV files
+		{Method: "POST", Pattern: "/teachers", Handler: handlers.AddTeacherHandler, Roles: managers, RateLimit: RateLimitDefault, MaxBodyBytes: 10 << 20},
+		{Method: "PATCH", Pattern: "/teachers", Handler: handlers.PatchTeachersHandler, Roles: managers, RateLimit: RateLimitDefault},
+		{Method: "DELETE", Pattern: "/teachers", Handler: handlers.DeleteTeachersHandler, Roles: adminOnly, RateLimit: RateLimitDefault},
+
+		{Method: "GET", Pattern: "/teachers/{id}", Handler: handlers.GetOneTeacherHandler, Roles: staff, RateLimit: RateLimitDefault},
+		{Method: "PUT", Pattern: "/teachers/{id}", Handler: handlers.UpdateTeacherHandler, Roles: managers, RateLimit: RateLimitDefault},
+		{Method: "PATCH", Pattern: "/teachers/{id}", Handler: handlers.PatchOneTeacherHandler, Roles: managers, RateLimit: RateLimitDefault},
+		{Method: "DELETE", Pattern: "/teachers/{id}", Handler: handlers.DeleteOneTeacherHandler, Roles: adminOnly, RateLimit: RateLimitDefault},
+
+		{Method: "GET", Pattern: "/teachers/{id}/students", Handler: handlers.GetStudentsByTeacherId, Roles: staff, RateLimit: RateLimitDefault},
+		{Method: "GET", Pattern: "/teachers/{id}/studentcount", Handler: handlers.GetStudentCountByTeacherId, Roles: staff, RateLimit: RateLimitDefault},
+	}
 }
@@ -0,0 +1,35 @@
+package router
+
+import (
+	"restapi/internal/api/handlers"
+)
+
+func timetableRoutes() []Route {
+	// Timetables are JSON or, with format=ics, an iCalendar file over the period
+	calendar := []string{"format", "from", "to", "term_id", "year_id"}
+
+	return []Route{
+		{Method: "GET", Pattern: "/periods", Handler: handlers.GetPeriodsHandler, Roles: staff, RateLimit: RateLimitDefault},
+		{Method: "POST", Pattern: "/periods", Handler: handlers.AddPeriodHandler, Roles: managers, RateLimit: RateLimitDefault},
+		{Method: "PATCH", Pattern: "/periods/{id}", Handler: handlers.PatchPeriodHandler, Roles: managers, RateLimit: RateLimitDefault},
+		{Method: "DELETE", Pattern: "/periods/{id}", Handler: handlers.DeletePeriodHandler, Roles: managers, RateLimit: RateLimitDefault},
+
+		{Method: "GET", Pattern: "/rooms", Handler: handlers.GetRoomsHandler, Roles: staff, RateLimit: RateLimitDefault},
+		{Method: "POST", Pattern: "/rooms", Handler: handlers.AddRoomHandler, Roles: managers, RateLimit: RateLimitDefault},
+		{Method: "PATCH", Pattern: "/rooms/{id}", Handler: handlers.PatchRoomHandler, Roles: managers, RateLimit: RateLimitDefault},
+		{Method: "DELETE", Pattern: "/rooms/{id}", Handler: handlers.DeleteRoomHandler, Roles: managers, RateLimit: RateLimitDefault},
+
+		{Method: "POST", Pattern: "/timetable/slots", Handler: handlers.AddSlotHandler, Roles: managers, RateLimit: RateLimitDefault},
+		{Method: "PATCH", Pattern: "/timetable/slots/{id}", Handler: handlers.PatchSlotHandler, Roles: managers, RateLimit: RateLimitDefault},
+		{Method: "DELETE", Pattern: "/timetable/slots/{id}", Handler: handlers.DeleteSlotHandler, Roles: managers, RateLimit: RateLimitDefault},
+		{Method: "GET", Pattern: "/timetable/conflicts", Handler: handlers.GetConflictsHandler, Roles: staff, RateLimit: RateLimitDefault},
+
+		{Method: "GET", Pattern: "/teachers/{id}/timetable", Handler: handlers.GetTeacherTimetableHandler, Roles: staff, RateLimit: RateLimitDefault, Query: calendar},
+		{Method: "GET", Pattern: "/classes/{class}/timetable", Handler: handlers.GetClassTimetableHandler, Roles: staff, RateLimit: RateLimitDefault, Query: calendar},
+		{Method: "GET", Pattern: "/rooms/{id}/timetable", Handler: handlers.GetRoomTimetableHandler, Roles: staff, RateLimit: RateLimitDefault, Query: calendar},
+
+		{Method: "GET", Pattern: "/teachers/{id}/unavailability", Handler: handlers.GetUnavailabilityHandler, Roles: staff, RateLimit: RateLimitDefault},
+		{Method: "POST", Pattern: "/teachers/{id}/unavailability", Handler: handlers.AddUnavailabilityHandler, Roles: managers, RateLimit: RateLimitDefault},
+		{Method: "DELETE", Pattern: "/teachers/{id}/unavailability/{entryId}", Handler: handlers.DeleteUnavailabilityHandler, Roles: managers, RateLimit: RateLimitDefault},
+	}
+}
@@ -0,0 +1,717 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	Server      ServerConfig      `yaml:"server" toml:"server"`
+	DB          DBConfig          `yaml:"db" toml:"db"`
+	JWT         JWTConfig         `yaml:"jwt" toml:"jwt"`
+	Mail        MailConfig        `yaml:"mail" toml:"mail"`
+	RateLimit   RateLimitConfig   `yaml:"rate_limit" toml:"rate_limit"`
+	CORS        CORSConfig        `yaml:"cors" toml:"cors"`
+	Compression CompressionConfig `yaml:"compression" toml:"compression"`
+	Limits      LimitsConfig      `yaml:"limits" toml:"limits"`
+	HPP         HPPConfig         `yaml:"hpp" toml:"hpp"`
+	Attendance  AttendanceConfig  `yaml:"attendance" toml:"attendance"`
+	Grading     GradingConfig     `yaml:"grading" toml:"grading"`
+	Reports     ReportsConfig     `yaml:"reports" toml:"reports"`
+	Academic    AcademicConfig    `yaml:"academic" toml:"academic"`
+	Admissions  AdmissionsConfig  `yaml:"admissions" toml:"admissions"`
+	Tracing     TracingConfig     `yaml:"tracing" toml:"tracing"`
+}
+
+type ServerConfig struct {
+	Port              string        `yaml:"port" toml:"port"`
+	PublicURL         string        `yaml:"public_url" toml:"public_url"`
+	CertFile          string        `yaml:"cert_file" toml:"cert_file"`
+	KeyFile           string        `yaml:"key_file" toml:"key_file"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" toml:"read_header_timeout"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" toml:"read_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" toml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" toml:"idle_timeout"`
+	MaxHeaderBytes    int           `yaml:"max_header_bytes" toml:"max_header_bytes"`
+	ShutdownDrain     time.Duration `yaml:"shutdown_drain_period" toml:"shutdown_drain_period"`
+	// ReadinessDelay keeps serving after readiness starts failing, so load
+	// balancers notice before the listener closes
+	ReadinessDelay time.Duration `yaml:"shutdown_readiness_delay" toml:"shutdown_readiness_delay"`
+	// CrashReportDir receives a file per recovered panic; empty disables reports
+	CrashReportDir string `yaml:"crash_report_dir" toml:"crash_report_dir"`
+}
+
+type DBConfig struct {
+	Host            string        `yaml:"host" toml:"host"`
+	Port            string        `yaml:"port" toml:"port"`
+	User            string        `yaml:"user" toml:"user"`
+	Password        string        `yaml:"password" toml:"password"`
+	Name            string        `yaml:"name" toml:"name"`
+	MaxOpenConns    int           `yaml:"max_open_conns" toml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" toml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" toml:"conn_max_lifetime"`
+}
+
+// DSN returns the go-sql-driver/mysql connection string.
+func (c DBConfig) DSN() string {
+	return fmt.Sprintf("%s:%s@tcp(%s:%s)/%s", c.User, c.Password, c.Host, c.Port, c.Name)
+}
+
+type JWTConfig struct {
+	Secret        string        `yaml:"secret" toml:"secret"`
+	ExpiresIn     time.Duration `yaml:"expires_in" toml:"expires_in"`
+	ResetTokenTTL time.Duration `yaml:"reset_token_ttl" toml:"reset_token_ttl"`
+}
+
+type MailConfig struct {
+	Host     string `yaml:"host" toml:"host"`
+	Port     int    `yaml:"port" toml:"port"`
+	User     string `yaml:"user" toml:"user"`
+	Password string `yaml:"password" toml:"password"`
+	From     string `yaml:"from" toml:"from"`
+}
+
+type RateLimitRule struct {
+	Limit  int           `yaml:"limit" toml:"limit"`
+	Window time.Duration `yaml:"window" toml:"window"`
+}
+
+type RouteRateLimitRule struct {
+	Method string        `yaml:"method" toml:"method"`
+	Path   string        `yaml:"path" toml:"path"`
+	Limit  int           `yaml:"limit" toml:"limit"`
+	Window time.Duration `yaml:"window" toml:"window"`
+}
+
+type RedisConfig struct {
+	Addr     string `yaml:"addr" toml:"addr"`
+	Password string `yaml:"password" toml:"password"`
+	DB       int    `yaml:"db" toml:"db"`
+}
+
+type RateLimitConfig struct {
+	Enabled bool `yaml:"enabled" toml:"enabled"`
+	// Store is where buckets live: memory (per instance), sql or redis (shared)
+	Store   string        `yaml:"store" toml:"store"`
+	Redis   RedisConfig   `yaml:"redis" toml:"redis"`
+	Default RateLimitRule `yaml:"default" toml:"default"`
+	// Classes are named in the route table, e.g. login or password_reset
+	Classes        map[string]RateLimitRule `yaml:"classes" toml:"classes"`
+	Roles          map[string]RateLimitRule `yaml:"roles" toml:"roles"`
+	Routes         []RouteRateLimitRule     `yaml:"routes" toml:"routes"`
+	TrustedProxies []string                 `yaml:"trusted_proxies" toml:"trusted_proxies"`
+}
+
+type CORSRule struct {
+	// AllowedOrigins accepts exact origins, "*" or wildcard subdomains like "https://*.example.com"
+	AllowedOrigins   []string      `yaml:"allowed_origins" toml:"allowed_origins"`
+	AllowedMethods   []string      `yaml:"allowed_methods" toml:"allowed_methods"`
+	AllowedHeaders   []string      `yaml:"allowed_headers" toml:"allowed_headers"`
+	ExposedHeaders   []string      `yaml:"exposed_headers" toml:"exposed_headers"`
+	AllowCredentials bool          `yaml:"allow_credentials" toml:"allow_credentials"`
+	MaxAge           time.Duration `yaml:"max_age" toml:"max_age"`
+}
+
+// RouteCORSRule overrides CORS for paths starting with Path. Empty lists
+// inherit the top-level values.
+type RouteCORSRule struct {
+	Path     string `yaml:"path" toml:"path"`
+	CORSRule `yaml:",inline"`
+}
+
+type CORSConfig struct {
+	CORSRule `yaml:",inline"`
+	Routes   []RouteCORSRule `yaml:"routes" toml:"routes"`
+}
+
+type CompressionConfig struct {
+	// MinSize is the smallest response body, in bytes, that gets compressed
+	MinSize int `yaml:"min_size" toml:"min_size"`
+	// ContentTypes entries ending in "/" match every subtype
+	ContentTypes []string `yaml:"content_types" toml:"content_types"`
+}
+
+type RouteLimitRule struct {
+	Method       string        `yaml:"method" toml:"method"`
+	Path         string        `yaml:"path" toml:"path"`
+	MaxBodyBytes int64         `yaml:"max_body_bytes" toml:"max_body_bytes"`
+	Timeout      time.Duration `yaml:"timeout" toml:"timeout"`
+}
+
+// LimitsConfig bounds request bodies and handler run time. Route rules
+// inherit zero values from the defaults.
+type LimitsConfig struct {
+	MaxBodyBytes int64            `yaml:"max_body_bytes" toml:"max_body_bytes"`
+	Timeout      time.Duration    `yaml:"timeout" toml:"timeout"`
+	Routes       []RouteLimitRule `yaml:"routes" toml:"routes"`
+}
+
+type HPPConfig struct {
+	// ReportOnly logs parameter pollution instead of stripping or rejecting it
+	ReportOnly bool `yaml:"report_only" toml:"report_only"`
+}
+
+type AttendanceConfig struct {
+	// Execs are alerted once a student has AbsenceThreshold unexcused
+	// absences within AbsenceWindow
+	AbsenceThreshold int           `yaml:"absence_threshold" toml:"absence_threshold"`
+	AbsenceWindow    time.Duration `yaml:"absence_window" toml:"absence_window"`
+}
+
+// GradeBand gives Letter to averages of at least Min percent.
+type GradeBand struct {
+	Letter string  `yaml:"letter" toml:"letter"`
+	Min    float64 `yaml:"min" toml:"min"`
+}
+
+type GradingConfig struct {
+	// Scale is ordered from the highest band down and must end at 0
+	Scale []GradeBand `yaml:"scale" toml:"scale"`
+}
+
+// SchoolHeader is printed at the top of every report card.
+type SchoolHeader struct {
+	Name    string `yaml:"name" toml:"name"`
+	Address string `yaml:"address" toml:"address"`
+	Phone   string `yaml:"phone" toml:"phone"`
+	Email   string `yaml:"email" toml:"email"`
+	Website string `yaml:"website" toml:"website"`
+}
+
+type ReportsConfig struct {
+	School SchoolHeader `yaml:"school" toml:"school"`
+	// FontFile is a TrueType font embedded into report cards; without it the
+	// built-in Helvetica prints Latin text only and transliterates Cyrillic
+	FontFile string `yaml:"font_file" toml:"font_file"`
+}
+
+type AcademicConfig struct {
+	// FinalGrade classes graduate on promotion instead of moving up
+	FinalGrade int `yaml:"final_grade" toml:"final_grade"`
+}
+
+// AdmissionStage is a step of the admissions pipeline. Open stages have no
+// Outcome; "enrolled" is reached by converting the lead into a student and
+// "declined" closes it.
+type AdmissionStage struct {
+	Name    string `yaml:"name" toml:"name"`
+	Outcome string `yaml:"outcome,omitempty" toml:"outcome"`
+}
+
+type AdmissionsConfig struct {
+	// Stages in pipeline order; new leads start in the first open stage
+	Stages []AdmissionStage `yaml:"stages" toml:"stages"`
+}
+
+type TracingConfig struct {
+	// Exporter is otlp, stdout (or console) or none; the OTLP exporter reads
+	// its endpoint and headers from the standard OTEL_EXPORTER_OTLP_* variables
+	Exporter    string `yaml:"exporter" toml:"exporter"`
+	ServiceName string `yaml:"service_name" toml:"service_name"`
+}
+
+// Default returns the configuration used when nothing else is set.
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Port:              ":3000",
+			PublicURL:         "http://localhost:3000",
+			ReadHeaderTimeout: 5 * time.Second,
+			ReadTimeout:       15 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       60 * time.Second,
+			MaxHeaderBytes:    1 << 20,
+			ShutdownDrain:     15 * time.Second,
+			ReadinessDelay:    5 * time.Second,
+		},
+		DB: DBConfig{
+			Host:            "localhost",
+			Port:            "3306",
+			MaxOpenConns:    25,
+			MaxIdleConns:    25,
+			ConnMaxLifetime: 5 * time.Minute,
+		},
+		JWT: JWTConfig{
+			ExpiresIn:     15 * time.Minute,
+			ResetTokenTTL: 15 * time.Minute,
+		},
+		Mail: MailConfig{
+			Host: "localhost",
+			Port: 1025,
+			From: "schooladmin@shool.com",
+		},
+		RateLimit: RateLimitConfig{
+			Enabled: true,
+			Store:   "memory",
+			Default: RateLimitRule{Limit: 100, Window: time.Minute},
+			Classes: map[string]RateLimitRule{
+				// Slow down password guessing and reset-email spam
+				"login":          {Limit: 5, Window: time.Minute},
+				"password_reset": {Limit: 3, Window: 15 * time.Minute},
+			},
+		},
+		CORS: CORSConfig{
+			CORSRule: CORSRule{
+				AllowedOrigins: []string{
+					"https://my-origin-url.com",
+					"https://www.myfrontend.com",
+					"https://localhost:3000",
+				},
+				AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
+				AllowedHeaders:   []string{"Content-Type", "Authorization"},
+				ExposedHeaders:   []string{"Authorization"},
+				AllowCredentials: true,
+				MaxAge:           time.Hour,
+			},
+		},
+		Compression: CompressionConfig{
+			MinSize: 1024,
+			ContentTypes: []string{
+				"text/",
+				"application/json",
+				"application/problem+json",
+				"application/javascript",
+				"application/xml",
+				"image/svg+xml",
+			},
+		},
+		Limits: LimitsConfig{
+			MaxBodyBytes: 1 << 20,
+			Timeout:      10 * time.Second,
+			Routes: []RouteLimitRule{
+				// Bulk imports; their body limit is declared in the route table
+				{Method: "POST", Path: "/teachers", Timeout: 30 * time.Second},
+				{Method: "POST", Path: "/students", Timeout: 30 * time.Second},
+			},
+		},
+		Attendance: AttendanceConfig{
+			AbsenceThreshold: 3,
+			AbsenceWindow:    30 * 24 * time.Hour,
+		},
+		Grading: GradingConfig{
+			Scale: []GradeBand{
+				{Letter: "A", Min: 90},
+				{Letter: "B", Min: 80},
+				{Letter: "C", Min: 70},
+				{Letter: "D", Min: 60},
+				{Letter: "F", Min: 0},
+			},
+		},
+		Reports: ReportsConfig{
+			School: SchoolHeader{Name: "School"},
+		},
+		Academic: AcademicConfig{
+			FinalGrade: 11,
+		},
+		Admissions: AdmissionsConfig{
+			Stages: []AdmissionStage{
+				{Name: "inquiry"},
+				{Name: "application"},
+				{Name: "interview"},
+				{Name: "offer"},
+				{Name: "enrolled", Outcome: "enrolled"},
+				{Name: "declined", Outcome: "declined"},
+			},
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			ServiceName: "crm-backend",
+		},
+	}
+}
+
+// Load builds the configuration from defaults, then the optional YAML/TOML
+// file at path, then .env and the process environment (highest priority),
+// and validates the result.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+
+	if path != "" {
+		if err := loadFile(path, cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	// .env never overrides variables already set in the environment
+	if err := godotenv.Load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("reading .env: %w", err)
+	}
+
+	if err := applyEnv(cfg); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func loadFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, cfg)
+	case ".toml":
+		err = toml.Unmarshal(data, cfg)
+	default:
+		return fmt.Errorf("unsupported config file format %q (use .yaml, .yml or .toml)", filepath.Ext(path))
+	}
+	if err != nil {
+		return fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+	return nil
+}
+
+func applyEnv(cfg *Config) error {
+	// DB_HOST comes after the legacy HOST variable so it wins when both are set
+	stringVars := []struct {
+		key    string
+		target *string
+	}{
+		{"API_PORT", &cfg.Server.Port},
+		{"APP_URL", &cfg.Server.PublicURL},
+		{"CERT_FILE", &cfg.Server.CertFile},
+		{"KEY_FILE", &cfg.Server.KeyFile},
+		{"CRASH_REPORT_DIR", &cfg.Server.CrashReportDir},
+		{"HOST", &cfg.DB.Host},
+		{"DB_HOST", &cfg.DB.Host},
+		{"DB_PORT", &cfg.DB.Port},
+		{"DB_USER", &cfg.DB.User},
+		{"DB_PASSWORD", &cfg.DB.Password},
+		{"DB_NAME", &cfg.DB.Name},
+		{"JWT_SECRET", &cfg.JWT.Secret},
+		{"SMTP_HOST", &cfg.Mail.Host},
+		{"SMTP_USER", &cfg.Mail.User},
+		{"SMTP_PASSWORD", &cfg.Mail.Password},
+		{"MAIL_FROM", &cfg.Mail.From},
+		{"RATE_LIMIT_STORE", &cfg.RateLimit.Store},
+		{"REDIS_ADDR", &cfg.RateLimit.Redis.Addr},
+		{"REDIS_PASSWORD", &cfg.RateLimit.Redis.Password},
+		{"REPORT_SCHOOL_NAME", &cfg.Reports.School.Name},
+		{"REPORT_FONT_FILE", &cfg.Reports.FontFile},
+		{"OTEL_TRACES_EXPORTER", &cfg.Tracing.Exporter},
+		{"OTEL_SERVICE_NAME", &cfg.Tracing.ServiceName},
+	}
+	for _, v := range stringVars {
+		if value := os.Getenv(v.key); value != "" {
+			*v.target = value
+		}
+	}
+
+	durations := map[string]*time.Duration{
+		"JWT_EXPIRES_IN":             &cfg.JWT.ExpiresIn,
+		"SERVER_READ_HEADER_TIMEOUT": &cfg.Server.ReadHeaderTimeout,
+		"SERVER_READ_TIMEOUT":        &cfg.Server.ReadTimeout,
+		"SERVER_WRITE_TIMEOUT":       &cfg.Server.WriteTimeout,
+		"SERVER_IDLE_TIMEOUT":        &cfg.Server.IdleTimeout,
+		"SHUTDOWN_DRAIN_PERIOD":      &cfg.Server.ShutdownDrain,
+		"SHUTDOWN_READINESS_DELAY":   &cfg.Server.ReadinessDelay,
+		"DB_CONN_MAX_LIFETIME":       &cfg.DB.ConnMaxLifetime,
+		"RATE_LIMIT_WINDOW":          &cfg.RateLimit.Default.Window,
+		"CORS_MAX_AGE":               &cfg.CORS.MaxAge,
+		"REQUEST_TIMEOUT":            &cfg.Limits.Timeout,
+		"ATTENDANCE_ABSENCE_WINDOW":  &cfg.Attendance.AbsenceWindow,
+	}
+	for key, target := range durations {
+		value := os.Getenv(key)
+		if value == "" {
+			continue
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%s: invalid duration %q", key, value)
+		}
+		*target = d
+	}
+
+	// RESET_TOKEN_EXP_DURATION has always been a number of minutes
+	if value := os.Getenv("RESET_TOKEN_EXP_DURATION"); value != "" {
+		d, err := parseMinutes(value)
+		if err != nil {
+			return fmt.Errorf("RESET_TOKEN_EXP_DURATION: invalid duration %q", value)
+		}
+		cfg.JWT.ResetTokenTTL = d
+	}
+
+	ints := map[string]*int{
+		"SERVER_MAX_HEADER_BYTES":      &cfg.Server.MaxHeaderBytes,
+		"SMTP_PORT":                    &cfg.Mail.Port,
+		"DB_MAX_OPEN_CONNS":            &cfg.DB.MaxOpenConns,
+		"DB_MAX_IDLE_CONNS":            &cfg.DB.MaxIdleConns,
+		"RATE_LIMIT_REQUESTS":          &cfg.RateLimit.Default.Limit,
+		"REDIS_DB":                     &cfg.RateLimit.Redis.DB,
+		"COMPRESSION_MIN_SIZE":         &cfg.Compression.MinSize,
+		"ATTENDANCE_ABSENCE_THRESHOLD": &cfg.Attendance.AbsenceThreshold,
+		"ACADEMIC_FINAL_GRADE":         &cfg.Academic.FinalGrade,
+	}
+	for key, target := range ints {
+		value := os.Getenv(key)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%s: invalid number %q", key, value)
+		}
+		*target = n
+	}
+
+	if value := os.Getenv("MAX_BODY_BYTES"); value != "" {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("MAX_BODY_BYTES: invalid number %q", value)
+		}
+		cfg.Limits.MaxBodyBytes = n
+	}
+
+	if value := os.Getenv("RATE_LIMIT_ENABLED"); value != "" {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("RATE_LIMIT_ENABLED: invalid boolean %q", value)
+		}
+		cfg.RateLimit.Enabled = enabled
+	}
+	if value := os.Getenv("HPP_REPORT_ONLY"); value != "" {
+		reportOnly, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("HPP_REPORT_ONLY: invalid boolean %q", value)
+		}
+		cfg.HPP.ReportOnly = reportOnly
+	}
+	if value := os.Getenv("TRUSTED_PROXIES"); value != "" {
+		cfg.RateLimit.TrustedProxies = strings.Split(value, ",")
+	}
+	if value := os.Getenv("CORS_ALLOWED_ORIGINS"); value != "" {
+		cfg.CORS.AllowedOrigins = strings.Split(value, ",")
+	}
+	if value := os.Getenv("CORS_ALLOW_CREDENTIALS"); value != "" {
+		allow, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("CORS_ALLOW_CREDENTIALS: invalid boolean %q", value)
+		}
+		cfg.CORS.AllowCredentials = allow
+	}
+	return nil
+}
+
+// parseMinutes accepts either a bare number of minutes ("15") or a Go duration ("15m").
+func parseMinutes(value string) (time.Duration, error) {
+	if n, err := strconv.Atoi(value); err == nil {
+		return time.Duration(n) * time.Minute, nil
+	}
+	return time.ParseDuration(value)
+}
+
+// Validate reports every problem at once so a bad deploy fails with a full list.
+func (c *Config) Validate() error {
+	var errs []error
+
+	if c.JWT.Secret == "" {
+		errs = append(errs, errors.New("JWT_SECRET is required"))
+	}
+	if c.DB.User == "" {
+		errs = append(errs, errors.New("DB_USER is required"))
+	}
+	if c.DB.Name == "" {
+		errs = append(errs, errors.New("DB_NAME is required"))
+	}
+	if c.DB.Host == "" {
+		errs = append(errs, errors.New("DB_HOST is required"))
+	}
+	if (c.Server.CertFile == "") != (c.Server.KeyFile == "") {
+		errs = append(errs, errors.New("CERT_FILE and KEY_FILE must be set together"))
+	}
+
+	positive := []struct {
+		key   string
+		value time.Duration
+	}{
+		{"JWT_EXPIRES_IN", c.JWT.ExpiresIn},
+		{"RESET_TOKEN_EXP_DURATION", c.JWT.ResetTokenTTL},
+		{"SERVER_READ_HEADER_TIMEOUT", c.Server.ReadHeaderTimeout},
+		{"SERVER_READ_TIMEOUT", c.Server.ReadTimeout},
+		{"SERVER_WRITE_TIMEOUT", c.Server.WriteTimeout},
+		{"SERVER_IDLE_TIMEOUT", c.Server.IdleTimeout},
+		{"SHUTDOWN_DRAIN_PERIOD", c.Server.ShutdownDrain},
+	}
+	for _, d := range positive {
+		if d.value <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be positive, got %v", d.key, d.value))
+		}
+	}
+	if c.Server.ReadinessDelay < 0 {
+		errs = append(errs, fmt.Errorf("SHUTDOWN_READINESS_DELAY must not be negative, got %v", c.Server.ReadinessDelay))
+	}
+
+	if c.RateLimit.Enabled {
+		errs = append(errs, c.RateLimit.Default.validate("rate_limit.default"))
+		for class, rule := range c.RateLimit.Classes {
+			errs = append(errs, rule.validate("rate_limit.classes."+class))
+		}
+		for role, rule := range c.RateLimit.Roles {
+			errs = append(errs, rule.validate("rate_limit.roles."+role))
+		}
+		for _, route := range c.RateLimit.Routes {
+			errs = append(errs, RateLimitRule{Limit: route.Limit, Window: route.Window}.validate("rate_limit.routes "+route.Method+" "+route.Path))
+		}
+		switch c.RateLimit.Store {
+		case "memory", "sql":
+		case "redis":
+			if c.RateLimit.Redis.Addr == "" {
+				errs = append(errs, errors.New("REDIS_ADDR is required when RATE_LIMIT_STORE is redis"))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("RATE_LIMIT_STORE must be memory, sql or redis, got %q", c.RateLimit.Store))
+		}
+	}
+
+	errs = append(errs, c.CORS.CORSRule.validate("cors"))
+
+	switch strings.ToLower(c.Tracing.Exporter) {
+	case "", "none", "otlp", "stdout", "console":
+	default:
+		errs = append(errs, fmt.Errorf("OTEL_TRACES_EXPORTER must be otlp, stdout or none, got %q", c.Tracing.Exporter))
+	}
+	for _, route := range c.CORS.Routes {
+		errs = append(errs, route.CORSRule.validate("cors.routes "+route.Path))
+	}
+
+	if c.Limits.MaxBodyBytes < 0 || c.Limits.Timeout < 0 {
+		errs = append(errs, errors.New("MAX_BODY_BYTES and REQUEST_TIMEOUT must not be negative"))
+	}
+	for _, route := range c.Limits.Routes {
+		if route.MaxBodyBytes < 0 || route.Timeout < 0 {
+			errs = append(errs, fmt.Errorf("limits.routes %s %s: max_body_bytes and timeout must not be negative", route.Method, route.Path))
+		}
+	}
+
+	if c.Attendance.AbsenceThreshold <= 0 || c.Attendance.AbsenceWindow <= 0 {
+		errs = append(errs, errors.New("ATTENDANCE_ABSENCE_THRESHOLD and ATTENDANCE_ABSENCE_WINDOW must be positive"))
+	}
+
+	errs = append(errs, c.Grading.validate())
+	errs = append(errs, c.Admissions.validate())
+	if c.Academic.FinalGrade <= 0 {
+		errs = append(errs, errors.New("ACADEMIC_FINAL_GRADE must be positive"))
+	}
+	if c.Reports.School.Name == "" {
+		errs = append(errs, errors.New("REPORT_SCHOOL_NAME must not be empty"))
+	}
+
+	if c.Compression.MinSize < 0 {
+		errs = append(errs, errors.New("COMPRESSION_MIN_SIZE must not be negative"))
+	}
+
+	if c.Server.MaxHeaderBytes <= 0 {
+		errs = append(errs, errors.New("SERVER_MAX_HEADER_BYTES must be positive"))
+	}
+	if c.Mail.Port <= 0 || c.Mail.Port > 65535 {
+		errs = append(errs, fmt.Errorf("SMTP_PORT out of range: %d", c.Mail.Port))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (r RateLimitRule) validate(name string) error {
+	if r.Limit <= 0 || r.Window <= 0 {
+		return fmt.Errorf("%s: limit and window must be positive", name)
+	}
+	return nil
+}
+
+func (r CORSRule) validate(name string) error {
+	if r.MaxAge < 0 {
+		return fmt.Errorf("%s: max_age must not be negative", name)
+	}
+	// Echoing any origin with credentials would let every site read authenticated responses
+	if r.AllowCredentials {
+		for _, origin := range r.AllowedOrigins {
+			if strings.TrimSpace(origin) == "*" {
+				return fmt.Errorf("%s: allowed origin \"*\" cannot be combined with allow_credentials", name)
+			}
+		}
+	}
+	return nil
+}
+
+const redacted = "[REDACTED]"
+
+// Redacted returns a copy with secrets masked, for --print-config and logs.
+func (c *Config) Redacted() *Config {
+	cp := *c
+	if cp.JWT.Secret != "" {
+		cp.JWT.Secret = redacted
+	}
+	if cp.DB.Password != "" {
+		cp.DB.Password = redacted
+	}
+	if cp.Mail.Password != "" {
+		cp.Mail.Password = redacted
+	}
+	if cp.RateLimit.Redis.Password != "" {
+		cp.RateLimit.Redis.Password = redacted
+	}
+	return &cp
+}
+
+// YAML renders the configuration with durations in human-readable form.
+func (c *Config) YAML() (string, error) {
+	out, err := yaml.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+func (a AdmissionsConfig) validate() error {
+	seen := make(map[string]bool, len(a.Stages))
+	outcomes := make(map[string]int)
+	for i, stage := range a.Stages {
+		if stage.Name == "" || seen[stage.Name] {
+			return fmt.Errorf("admissions.stages[%d]: name is required and must be unique", i)
+		}
+		switch stage.Outcome {
+		case "", "enrolled", "declined":
+		default:
+			return fmt.Errorf("admissions.stages[%d]: outcome must be empty, enrolled or declined", i)
+		}
+		seen[stage.Name] = true
+		outcomes[stage.Outcome]++
+	}
+	if outcomes[""] == 0 || outcomes["enrolled"] != 1 || outcomes["declined"] == 0 {
+		return errors.New("admissions.stages needs open stages, exactly one enrolled stage and a declined stage")
+	}
+	return nil
+}
+
+func (g GradingConfig) validate() error {
+	if len(g.Scale) == 0 {
+		return errors.New("grading.scale must not be empty")
+	}
+	for i, band := range g.Scale {
+		if band.Letter == "" || band.Min < 0 || band.Min > 100 {
+			return fmt.Errorf("grading.scale[%d]: letter is required and min must be between 0 and 100", i)
+		}
+		if i > 0 && band.Min >= g.Scale[i-1].Min {
+			return fmt.Errorf("grading.scale[%d]: bands must be ordered from the highest min down", i)
+		}
+	}
+	if g.Scale[len(g.Scale)-1].Min != 0 {
+		return errors.New("grading.scale must end with a band whose min is 0")
+	}
+	return nil
+}
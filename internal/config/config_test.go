@@ -0,0 +1,232 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func setRequiredEnv(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+	t.Setenv("DB_USER", "root")
+	t.Setenv("DB_NAME", "school")
+}
+
+func TestLoadFromEnv(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("JWT_EXPIRES_IN", "1h")
+	t.Setenv("RESET_TOKEN_EXP_DURATION", "30")
+	t.Setenv("HOST", "legacy-host")
+	t.Setenv("DB_HOST", "db.internal")
+
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	if cfg.JWT.ExpiresIn != time.Hour {
+		t.Errorf("JWT.ExpiresIn = %v, want 1h", cfg.JWT.ExpiresIn)
+	}
+	if cfg.JWT.ResetTokenTTL != 30*time.Minute {
+		t.Errorf("JWT.ResetTokenTTL = %v, want 30m", cfg.JWT.ResetTokenTTL)
+	}
+	if cfg.DB.Host != "db.internal" {
+		t.Errorf("DB.Host = %v, want DB_HOST to win over HOST", cfg.DB.Host)
+	}
+	if cfg.Server.Port != ":3000" {
+		t.Errorf("Server.Port = %v, want default :3000", cfg.Server.Port)
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{
+			name:    "yaml",
+			file:    "config.yaml",
+			content: "server:\n  port: \":8080\"\n  write_timeout: 45s\ndb:\n  name: from_file\n",
+		},
+		{
+			name:    "toml",
+			file:    "config.toml",
+			content: "[server]\nport = \":8080\"\nwrite_timeout = \"45s\"\n\n[db]\nname = \"from_file\"\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setRequiredEnv(t)
+			// Переменная окружения имеет приоритет над файлом
+			t.Setenv("DB_NAME", "")
+
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := Load(path)
+			if err != nil {
+				t.Fatalf("Load() failed: %v", err)
+			}
+			if cfg.Server.Port != ":8080" {
+				t.Errorf("Server.Port = %v, want :8080", cfg.Server.Port)
+			}
+			if cfg.Server.WriteTimeout != 45*time.Second {
+				t.Errorf("Server.WriteTimeout = %v, want 45s", cfg.Server.WriteTimeout)
+			}
+			if cfg.DB.Name != "from_file" {
+				t.Errorf("DB.Name = %v, want from_file", cfg.DB.Name)
+			}
+		})
+	}
+}
+
+func TestLoadValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr string
+	}{
+		{"missing JWT secret", map[string]string{"JWT_SECRET": ""}, "JWT_SECRET is required"},
+		{"bad duration", map[string]string{"JWT_EXPIRES_IN": "fifteen"}, "JWT_EXPIRES_IN"},
+		{"negative duration", map[string]string{"SERVER_READ_TIMEOUT": "-1s"}, "SERVER_READ_TIMEOUT must be positive"},
+		{"cert without key", map[string]string{"CERT_FILE": "cert.pem"}, "CERT_FILE and KEY_FILE"},
+		{"unknown rate limit store", map[string]string{"RATE_LIMIT_STORE": "memcached"}, "RATE_LIMIT_STORE"},
+		{"any origin with credentials", map[string]string{"CORS_ALLOWED_ORIGINS": "*"}, "cannot be combined with allow_credentials"},
+		{"redis store without address", map[string]string{"RATE_LIMIT_STORE": "redis"}, "REDIS_ADDR is required"},
+		{"zero absence threshold", map[string]string{"ATTENDANCE_ABSENCE_THRESHOLD": "0"}, "ATTENDANCE_ABSENCE_THRESHOLD"},
+		{"negative readiness delay", map[string]string{"SHUTDOWN_READINESS_DELAY": "-1s"}, "SHUTDOWN_READINESS_DELAY must not be negative"},
+		{"unknown tracing exporter", map[string]string{"OTEL_TRACES_EXPORTER": "jaeger"}, "OTEL_TRACES_EXPORTER"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setRequiredEnv(t)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			_, err := Load("")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadCORSRoutes(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{
+			name:    "yaml",
+			file:    "config.yaml",
+			content: "cors:\n  allowed_origins: [\"https://*.school.test\"]\n  routes:\n    - path: /public\n      allowed_origins: [\"*\"]\n",
+		},
+		{
+			name:    "toml",
+			file:    "config.toml",
+			content: "[cors]\nallowed_origins = [\"https://*.school.test\"]\n\n[[cors.routes]]\npath = \"/public\"\nallowed_origins = [\"*\"]\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setRequiredEnv(t)
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := Load(path)
+			if err != nil {
+				t.Fatalf("Load() failed: %v", err)
+			}
+			if len(cfg.CORS.AllowedOrigins) != 1 || cfg.CORS.AllowedOrigins[0] != "https://*.school.test" {
+				t.Errorf("CORS.AllowedOrigins = %v", cfg.CORS.AllowedOrigins)
+			}
+			// Методы не заданы в файле и остаются по умолчанию
+			if len(cfg.CORS.AllowedMethods) == 0 {
+				t.Errorf("CORS.AllowedMethods lost its default")
+			}
+			if len(cfg.CORS.Routes) != 1 || cfg.CORS.Routes[0].Path != "/public" || cfg.CORS.Routes[0].AllowedOrigins[0] != "*" {
+				t.Errorf("CORS.Routes = %+v", cfg.CORS.Routes)
+			}
+		})
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := Default()
+	cfg.JWT.Secret = "super-secret"
+	cfg.DB.Password = "db-password"
+
+	out, err := cfg.Redacted().YAML()
+	if err != nil {
+		t.Fatalf("YAML() failed: %v", err)
+	}
+	if strings.Contains(out, "super-secret") || strings.Contains(out, "db-password") {
+		t.Errorf("Redacted() leaked a secret:\n%s", out)
+	}
+	if cfg.JWT.Secret != "super-secret" {
+		t.Errorf("Redacted() modified the original config")
+	}
+}
+
+func TestGradingValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		scale   []GradeBand
+		wantErr bool
+	}{
+		{"default scale", Default().Grading.Scale, false},
+		{"pass/fail", []GradeBand{{Letter: "pass", Min: 50}, {Letter: "fail", Min: 0}}, false},
+		{"empty", nil, true},
+		// Шкала должна идти от высшей оценки к низшей
+		{"ascending", []GradeBand{{Letter: "F", Min: 0}, {Letter: "A", Min: 90}}, true},
+		{"no zero band", []GradeBand{{Letter: "A", Min: 90}, {Letter: "B", Min: 80}}, true},
+		{"missing letter", []GradeBand{{Letter: "", Min: 0}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := GradingConfig{Scale: tt.scale}.validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAdmissionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		stages  []AdmissionStage
+		wantErr bool
+	}{
+		{"default stages", Default().Admissions.Stages, false},
+		{"short pipeline", []AdmissionStage{{Name: "lead"}, {Name: "won", Outcome: "enrolled"}, {Name: "lost", Outcome: "declined"}}, false},
+		{"empty", nil, true},
+		// Конверсия в ученика требует ровно одной стадии enrolled
+		{"two enrolled", []AdmissionStage{{Name: "lead"}, {Name: "a", Outcome: "enrolled"}, {Name: "b", Outcome: "enrolled"}, {Name: "lost", Outcome: "declined"}}, true},
+		{"no open stage", []AdmissionStage{{Name: "won", Outcome: "enrolled"}, {Name: "lost", Outcome: "declined"}}, true},
+		{"duplicate name", []AdmissionStage{{Name: "lead"}, {Name: "lead"}, {Name: "won", Outcome: "enrolled"}, {Name: "lost", Outcome: "declined"}}, true},
+		{"unknown outcome", []AdmissionStage{{Name: "lead", Outcome: "won"}, {Name: "won", Outcome: "enrolled"}, {Name: "lost", Outcome: "declined"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := AdmissionsConfig{Stages: tt.stages}.validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Check reports whether a dependency is usable. It must honour ctx cancellation.
+type Check func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+var (
+	mu           sync.RWMutex
+	checks       []namedCheck
+	shuttingDown atomic.Bool
+)
+
+const checkTimeout = 2 * time.Second
+
+// Register adds a readiness check. Checks run in registration order.
+func Register(name string, check Check) {
+	mu.Lock()
+	defer mu.Unlock()
+	checks = append(checks, namedCheck{name: name, check: check})
+}
+
+// MarkShuttingDown makes /readyz fail so load balancers stop routing new
+// traffic while in-flight requests drain.
+func MarkShuttingDown() {
+	shuttingDown.Store(true)
+}
+
+// LivenessHandler answers as long as the process can serve HTTP.
+func LivenessHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Status string `json:"status"`
+	}{
+		Status: "ok",
+	})
+}
+
+// ReadinessHandler runs every registered check and returns 503 if any fails
+// or the server is shutting down.
+func ReadinessHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+	defer cancel()
+
+	mu.RLock()
+	registered := make([]namedCheck, len(checks))
+	copy(registered, checks)
+	mu.RUnlock()
+
+	ready := !shuttingDown.Load()
+	results := make(map[string]string, len(registered))
+	for _, c := range registered {
+		if err := c.check(ctx); err != nil {
+			ready = false
+			results[c.name] = err.Error()
+			continue
+		}
+		results[c.name] = "ok"
+	}
+
+	status := "ready"
+	code := http.StatusOK
+	if shuttingDown.Load() {
+		status = "shutting down"
+		code = http.StatusServiceUnavailable
+	} else if !ready {
+		status = "not ready"
+		code = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(struct {
+		Status string            `json:"status"`
+		Checks map[string]string `json:"checks"`
+	}{
+		Status: status,
+		Checks: results,
+	})
+}
@@ -0,0 +1,87 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func resetChecks() {
+	mu.Lock()
+	checks = nil
+	mu.Unlock()
+	shuttingDown.Store(false)
+}
+
+func TestReadinessHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		checkErr       error
+		shutdown       bool
+		expectedStatus int
+	}{
+		{"all checks pass", nil, false, http.StatusOK},
+		{"failing check", errors.New("connection refused"), false, http.StatusServiceUnavailable},
+		{"shutting down", nil, true, http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetChecks()
+			defer resetChecks()
+
+			Register("database", func(ctx context.Context) error { return tt.checkErr })
+			if tt.shutdown {
+				MarkShuttingDown()
+			}
+
+			rr := httptest.NewRecorder()
+			ReadinessHandler(rr, httptest.NewRequest("GET", "/readyz", nil))
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("ReadinessHandler() status = %v, want %v", rr.Code, tt.expectedStatus)
+			}
+
+			var body struct {
+				Checks map[string]string `json:"checks"`
+			}
+			if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+				t.Fatalf("invalid JSON response: %v", err)
+			}
+			if _, ok := body.Checks["database"]; !ok {
+				t.Errorf("response does not report the database check")
+			}
+		})
+	}
+}
+
+func TestLivenessHandler(t *testing.T) {
+	rr := httptest.NewRecorder()
+	LivenessHandler(rr, httptest.NewRequest("GET", "/healthz", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("LivenessHandler() status = %v, want %v", rr.Code, http.StatusOK)
+	}
+}
+
+func TestVersionHandler(t *testing.T) {
+	Commit = "abc123"
+	defer func() { Commit = "" }()
+
+	rr := httptest.NewRecorder()
+	VersionHandler(rr, httptest.NewRequest("GET", "/version", nil))
+
+	var info buildInfo
+	if err := json.NewDecoder(rr.Body).Decode(&info); err != nil {
+		t.Fatalf("invalid JSON response: %v", err)
+	}
+	if info.Commit != "abc123" {
+		t.Errorf("VersionHandler() commit = %v, want abc123", info.Commit)
+	}
+	if info.GoVersion == "" {
+		t.Errorf("VersionHandler() go_version is empty")
+	}
+}
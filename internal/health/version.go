@@ -0,0 +1,60 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+)
+
+// Set at build time:
+//
+//	go build -ldflags "-X restapi/internal/health.Commit=$(git rev-parse HEAD) -X restapi/internal/health.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./cmd/api
+//
+// When empty they fall back to the VCS stamp embedded by the Go toolchain.
+var (
+	Commit    string
+	BuildTime string
+)
+
+type buildInfo struct {
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+}
+
+func currentBuildInfo() buildInfo {
+	info := buildInfo{
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range bi.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = setting.Value
+				}
+			case "vcs.time":
+				if info.BuildTime == "" {
+					info.BuildTime = setting.Value
+				}
+			}
+		}
+	}
+
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	if info.BuildTime == "" {
+		info.BuildTime = "unknown"
+	}
+	return info
+}
+
+func VersionHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(currentBuildInfo())
+}
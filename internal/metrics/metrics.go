@@ -0,0 +1,108 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "crm"
+
+var Registry = prometheus.NewRegistry()
+
+var (
+	HTTPRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Number of HTTP requests by method, route pattern and status code.",
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, route pattern and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	LoginAttemptsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "login_attempts_total",
+		Help:      "Number of login attempts by result (success or failure).",
+	}, []string{"result"})
+
+	RateLimitRejectionsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limit_rejections_total",
+		Help:      "Number of requests rejected by the rate limiter.",
+	})
+
+	EmailOutboxDepth = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "email_outbox_depth",
+		Help:      "Number of emails queued but not yet handed to the SMTP server.",
+	})
+
+	EmailsSentTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "emails_sent_total",
+		Help:      "Number of emails sent by result (success or failure).",
+	}, []string{"result"})
+
+	PanicsRecoveredTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "panics_recovered_total",
+		Help:      "Number of handler panics turned into 500 responses.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequestsTotal,
+		HTTPRequestDuration,
+		LoginAttemptsTotal,
+		RateLimitRejectionsTotal,
+		EmailOutboxDepth,
+		EmailsSentTotal,
+		PanicsRecoveredTotal,
+	)
+}
+
+// Handler serves the registry in the Prometheus text exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// RegisterDBStats exposes sql.DBStats (open, idle, in-use connections, waits) for the pool.
+func RegisterDBStats(db *sql.DB, dbName string) error {
+	return Registry.Register(collectors.NewDBStatsCollector(db, dbName))
+}
+
+// Result maps an error to the "success"/"failure" label value.
+func Result(err error) string {
+	if err != nil {
+		return "failure"
+	}
+	return "success"
+}
+
+type routeKey struct{}
+
+// WithRoute stores a slot in the context that the router fills with the matched pattern.
+func WithRoute(ctx context.Context) (context.Context, *string) {
+	route := new(string)
+	return context.WithValue(ctx, routeKey{}, route), route
+}
+
+// SetRoute records the matched route pattern for the current request, if tracked.
+func SetRoute(ctx context.Context, pattern string) {
+	route, ok := ctx.Value(routeKey{}).(*string)
+	if ok {
+		*route = pattern
+	}
+}
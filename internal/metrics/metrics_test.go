@@ -0,0 +1,33 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSetRoute(t *testing.T) {
+	ctx, route := WithRoute(context.Background())
+	SetRoute(ctx, "GET /teachers/{id}")
+
+	if *route != "GET /teachers/{id}" {
+		t.Errorf("SetRoute() route = %q, want %q", *route, "GET /teachers/{id}")
+	}
+
+	// Контекст без слота не должен паниковать
+	SetRoute(context.Background(), "GET /students")
+}
+
+func TestHandler(t *testing.T) {
+	LoginAttemptsTotal.WithLabelValues("success").Inc()
+
+	rr := httptest.NewRecorder()
+	Handler().ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+
+	body, _ := io.ReadAll(rr.Body)
+	if !strings.Contains(string(body), `crm_login_attempts_total{result="success"}`) {
+		t.Errorf("Handler() output does not contain login counter:\n%s", body)
+	}
+}
@@ -0,0 +1,156 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"unicode"
+)
+
+// AcademicYear is a school year such as "2024/25". One year is current.
+type AcademicYear struct {
+	ID        int    `json:"id,omitempty" db:"id,omitempty"`
+	Name      string `json:"name,omitempty" db:"name,omitempty" sanitize:"strict"`
+	StartDate string `json:"start_date,omitempty" db:"start_date,omitempty"`
+	EndDate   string `json:"end_date,omitempty" db:"end_date,omitempty"`
+	Current   bool   `json:"current" db:"is_current"`
+}
+
+// Term is a part of an academic year: a quarter, trimester or semester.
+type Term struct {
+	ID        int    `json:"id,omitempty" db:"id,omitempty"`
+	YearID    int    `json:"year_id,omitempty" db:"year_id,omitempty"`
+	Name      string `json:"name,omitempty" db:"name,omitempty" sanitize:"strict"`
+	StartDate string `json:"start_date,omitempty" db:"start_date,omitempty"`
+	EndDate   string `json:"end_date,omitempty" db:"end_date,omitempty"`
+}
+
+type EnrollmentStatus string
+
+const (
+	EnrollmentActive    EnrollmentStatus = "active"
+	EnrollmentPromoted  EnrollmentStatus = "promoted"
+	EnrollmentRetained  EnrollmentStatus = "retained"
+	EnrollmentGraduated EnrollmentStatus = "graduated"
+)
+
+// Enrollment records the class a student was in during a year; past years
+// keep how the student left it.
+type Enrollment struct {
+	StudentID int              `json:"student_id"`
+	FirstName string           `json:"first_name,omitempty"`
+	LastName  string           `json:"last_name,omitempty"`
+	YearID    int              `json:"year_id"`
+	Year      string           `json:"year,omitempty"`
+	Class     string           `json:"class"`
+	Status    EnrollmentStatus `json:"status"`
+}
+
+// PromotionRequest moves the students of one year into the next. Classes
+// overrides the computed next class ("10A" -> "11A"); students in FinalGrade
+// classes graduate and Retained students repeat their class.
+type PromotionRequest struct {
+	ToYearID   int               `json:"to_year_id"`
+	Classes    map[string]string `json:"classes,omitempty"`
+	Retained   []int             `json:"retained,omitempty"`
+	FinalGrade int               `json:"final_grade,omitempty"`
+	DryRun     bool              `json:"dry_run"`
+}
+
+type PromotionAction string
+
+const (
+	PromotionPromote  PromotionAction = "promote"
+	PromotionRetain   PromotionAction = "retain"
+	PromotionGraduate PromotionAction = "graduate"
+)
+
+type PromotionEntry struct {
+	StudentID int             `json:"student_id"`
+	FirstName string          `json:"first_name"`
+	LastName  string          `json:"last_name"`
+	FromClass string          `json:"from_class"`
+	ToClass   string          `json:"to_class,omitempty"`
+	Action    PromotionAction `json:"action"`
+}
+
+// PromotionPlan is the preview of a promotion, or its result once Applied.
+type PromotionPlan struct {
+	FromYearID int                     `json:"from_year_id"`
+	ToYearID   int                     `json:"to_year_id"`
+	Applied    bool                    `json:"applied"`
+	Counts     map[PromotionAction]int `json:"counts"`
+	Entries    []PromotionEntry        `json:"entries"`
+}
+
+// NextClass returns the class a student moves up to: the leading grade number
+// plus one with the rest kept ("10A" -> "11A", "9-B" -> "10-B").
+func NextClass(class string) (string, error) {
+	grade, rest, err := splitClass(class)
+	if err != nil {
+		return "", err
+	}
+	return strconv.Itoa(grade+1) + rest, nil
+}
+
+// ClassGrade returns the leading grade number of class.
+func ClassGrade(class string) (int, error) {
+	grade, _, err := splitClass(class)
+	return grade, err
+}
+
+func splitClass(class string) (int, string, error) {
+	i := 0
+	for i < len(class) && unicode.IsDigit(rune(class[i])) {
+		i++
+	}
+	if i == 0 {
+		return 0, "", fmt.Errorf("class %q does not start with a grade number", class)
+	}
+	grade, err := strconv.Atoi(class[:i])
+	if err != nil {
+		return 0, "", fmt.Errorf("class %q: %w", class, err)
+	}
+	return grade, class[i:], nil
+}
+
+// PlanPromotion decides where each enrolled student goes. Classes without a
+// grade number need an explicit mapping in req.Classes.
+func PlanPromotion(fromYearID int, enrolled []Student, req PromotionRequest) (PromotionPlan, error) {
+	plan := PromotionPlan{
+		FromYearID: fromYearID,
+		ToYearID:   req.ToYearID,
+		Counts:     map[PromotionAction]int{PromotionPromote: 0, PromotionRetain: 0, PromotionGraduate: 0},
+		Entries:    make([]PromotionEntry, 0, len(enrolled)),
+	}
+	retained := make(map[int]bool, len(req.Retained))
+	for _, id := range req.Retained {
+		retained[id] = true
+	}
+
+	for _, student := range enrolled {
+		entry := PromotionEntry{StudentID: student.ID, FirstName: student.FirstName, LastName: student.LastName, FromClass: student.Class}
+		switch next, mapped := req.Classes[student.Class]; {
+		case retained[student.ID]:
+			entry.Action, entry.ToClass = PromotionRetain, student.Class
+		case mapped && next == "":
+			// An empty mapping marks a leaving class
+			entry.Action = PromotionGraduate
+		case mapped:
+			entry.Action, entry.ToClass = PromotionPromote, next
+		default:
+			grade, err := ClassGrade(student.Class)
+			if err != nil {
+				return PromotionPlan{}, err
+			}
+			if req.FinalGrade > 0 && grade >= req.FinalGrade {
+				entry.Action = PromotionGraduate
+				break
+			}
+			entry.Action = PromotionPromote
+			entry.ToClass, _ = NextClass(student.Class)
+		}
+		plan.Counts[entry.Action]++
+		plan.Entries = append(plan.Entries, entry)
+	}
+	return plan, nil
+}
@@ -0,0 +1,67 @@
+package models
+
+import "testing"
+
+func TestNextClass(t *testing.T) {
+	tests := []struct {
+		class   string
+		want    string
+		wantErr bool
+	}{
+		{"10A", "11A", false},
+		{"9-B", "10-B", false},
+		{"1", "2", false},
+		{"Prep", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := NextClass(tt.class)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("NextClass(%q) = %q, %v; want %q, error %v", tt.class, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestPlanPromotion(t *testing.T) {
+	students := []Student{
+		{ID: 1, Class: "10A"},
+		{ID: 2, Class: "10A"},
+		{ID: 3, Class: "11A"},
+		{ID: 4, Class: "Prep"},
+	}
+	req := PromotionRequest{
+		ToYearID:   2,
+		Retained:   []int{2},
+		FinalGrade: 11,
+		// Подготовительный класс без номера требует явного соответствия
+		Classes: map[string]string{"Prep": "1A"},
+	}
+
+	plan, err := PlanPromotion(1, students, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		action PromotionAction
+		to     string
+	}{
+		{PromotionPromote, "11A"},
+		{PromotionRetain, "10A"},
+		{PromotionGraduate, ""},
+		{PromotionPromote, "1A"},
+	}
+	for i, entry := range plan.Entries {
+		if entry.Action != want[i].action || entry.ToClass != want[i].to {
+			t.Errorf("student %d: %s to %q, want %s to %q", entry.StudentID, entry.Action, entry.ToClass, want[i].action, want[i].to)
+		}
+	}
+	if plan.Counts[PromotionPromote] != 2 || plan.Counts[PromotionRetain] != 1 || plan.Counts[PromotionGraduate] != 1 {
+		t.Errorf("counts = %v", plan.Counts)
+	}
+
+	// Без соответствия класс без номера — ошибка, а не молчаливый пропуск
+	if _, err := PlanPromotion(1, students, PromotionRequest{ToYearID: 2, FinalGrade: 11}); err == nil {
+		t.Error("expected an error for a class without a grade number")
+	}
+}
@@ -0,0 +1,307 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+type StageOutcome string
+
+const (
+	OutcomeOpen     StageOutcome = ""
+	OutcomeEnrolled StageOutcome = "enrolled"
+	OutcomeDeclined StageOutcome = "declined"
+)
+
+type AdmissionStage struct {
+	Name    string       `json:"name"`
+	Outcome StageOutcome `json:"outcome,omitempty"`
+}
+
+// Pipeline is the configured admission stages in order.
+type Pipeline []AdmissionStage
+
+func (p Pipeline) index(name string) int {
+	for i, stage := range p {
+		if stage.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Stage looks a stage up by name.
+func (p Pipeline) Stage(name string) (AdmissionStage, bool) {
+	if i := p.index(name); i >= 0 {
+		return p[i], true
+	}
+	return AdmissionStage{}, false
+}
+
+// Initial is the stage new leads start in: the first open one.
+func (p Pipeline) Initial() string {
+	for _, stage := range p {
+		if stage.Outcome == OutcomeOpen {
+			return stage.Name
+		}
+	}
+	return ""
+}
+
+// Enrolled is the stage converted leads end in.
+func (p Pipeline) Enrolled() string {
+	for _, stage := range p {
+		if stage.Outcome == OutcomeEnrolled {
+			return stage.Name
+		}
+	}
+	return ""
+}
+
+// ErrLeadConverted means the lead already became a student.
+var ErrLeadConverted = errors.New("lead was already converted into a student")
+
+// CheckMove validates moving a lead between stages. Leads may move back and
+// forth and declined leads may be reopened, but the enrolled stage is only
+// reached by conversion and never left.
+func (p Pipeline) CheckMove(from, to string) error {
+	target, ok := p.Stage(to)
+	if !ok {
+		return fmt.Errorf("unknown stage %q", to)
+	}
+	if current, ok := p.Stage(from); ok && current.Outcome == OutcomeEnrolled {
+		return ErrLeadConverted
+	}
+	if target.Outcome == OutcomeEnrolled {
+		return fmt.Errorf("leads reach %q by conversion", to)
+	}
+	if from == to {
+		return fmt.Errorf("lead is already in %q", to)
+	}
+	return nil
+}
+
+// Lead is a prospective student moving through the admissions pipeline.
+// StudentID is set once the lead was converted.
+type Lead struct {
+	ID           int            `json:"id,omitempty" db:"id,omitempty"`
+	FirstName    string         `json:"first_name,omitempty" db:"first_name,omitempty"`
+	LastName     string         `json:"last_name,omitempty" db:"last_name,omitempty"`
+	Email        string         `json:"email,omitempty" db:"email,omitempty" sanitize:"strict"`
+	Phone        string         `json:"phone,omitempty" db:"phone,omitempty" sanitize:"strict"`
+	DesiredClass string         `json:"desired_class,omitempty" db:"desired_class,omitempty" sanitize:"strict"`
+	Source       string         `json:"source,omitempty" db:"source,omitempty" sanitize:"strict"`
+	Stage        string         `json:"stage,omitempty" db:"stage,omitempty" sanitize:"strict"`
+	AssignedTo   int            `json:"assigned_to,omitempty" db:"assigned_to,omitempty"`
+	StudentID    int            `json:"student_id,omitempty" db:"student_id,omitempty"`
+	CreatedAt    string         `json:"created_at,omitempty" db:"created_at,omitempty"`
+	UpdatedAt    string         `json:"updated_at,omitempty" db:"updated_at,omitempty"`
+	Guardians    []LeadGuardian `json:"guardians,omitempty" db:"-"`
+}
+
+// LeadGuardian is a parent of a lead; conversion turns it into a guardian
+// of the new student.
+type LeadGuardian struct {
+	FirstName    string `json:"first_name"`
+	LastName     string `json:"last_name"`
+	Phone        string `json:"phone,omitempty" sanitize:"strict"`
+	Email        string `json:"email,omitempty" sanitize:"strict"`
+	Relationship string `json:"relationship,omitempty" sanitize:"strict"`
+	CanPickup    bool   `json:"can_pickup"`
+	Primary      bool   `json:"primary"`
+}
+
+var LeadActivityTypes = []string{"note", "call", "email", "meeting", "visit"}
+
+// LeadActivity is a contact with the family or a note about the lead.
+type LeadActivity struct {
+	ID        int    `json:"id,omitempty" db:"id,omitempty"`
+	LeadID    int    `json:"lead_id,omitempty" db:"lead_id,omitempty"`
+	Type      string `json:"type,omitempty" db:"type,omitempty" sanitize:"strict"`
+	Body      string `json:"body,omitempty" db:"body,omitempty"`
+	AuthorID  int    `json:"author_id,omitempty" db:"author_id,omitempty"`
+	CreatedAt string `json:"created_at,omitempty" db:"created_at,omitempty"`
+}
+
+// StageChange records a lead moving between stages; a new lead has an
+// empty FromStage.
+type StageChange struct {
+	ID        int    `json:"id,omitempty"`
+	LeadID    int    `json:"lead_id,omitempty"`
+	FromStage string `json:"from_stage"`
+	ToStage   string `json:"to_stage"`
+	Note      string `json:"note,omitempty"`
+	ChangedBy int    `json:"changed_by,omitempty"`
+	ChangedAt string `json:"changed_at,omitempty"`
+}
+
+// StageMove is the body of a stage change request.
+type StageMove struct {
+	Stage string `json:"stage" sanitize:"strict"`
+	Note  string `json:"note,omitempty"`
+}
+
+// LeadConversion is the body of a conversion; empty fields are taken from
+// the lead.
+type LeadConversion struct {
+	Class string `json:"class,omitempty" sanitize:"strict"`
+	Email string `json:"email,omitempty" sanitize:"strict"`
+}
+
+type ConversionResult struct {
+	Lead      Lead       `json:"lead"`
+	Student   Student    `json:"student"`
+	Guardians []Guardian `json:"guardians"`
+}
+
+// TimelineEntry is an activity or a stage change of a lead.
+type TimelineEntry struct {
+	Kind        string        `json:"kind"`
+	At          string        `json:"at"`
+	Activity    *LeadActivity `json:"activity,omitempty"`
+	StageChange *StageChange  `json:"stage_change,omitempty"`
+}
+
+// Timeline merges activities and stage changes, newest first.
+func Timeline(activities []LeadActivity, changes []StageChange) []TimelineEntry {
+	entries := make([]TimelineEntry, 0, len(activities)+len(changes))
+	for i := range activities {
+		entries = append(entries, TimelineEntry{Kind: "activity", At: activities[i].CreatedAt, Activity: &activities[i]})
+	}
+	for i := range changes {
+		entries = append(entries, TimelineEntry{Kind: "stage", At: changes[i].ChangedAt, StageChange: &changes[i]})
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].At > entries[j].At
+	})
+	return entries
+}
+
+// StageMetric counts the leads in a stage now and those that got at least
+// that far; Rate is Reached as a percentage of all leads.
+type StageMetric struct {
+	Stage   string  `json:"stage"`
+	Current int     `json:"current"`
+	Reached int     `json:"reached"`
+	Rate    float64 `json:"rate"`
+}
+
+type SourceMetric struct {
+	Source   string  `json:"source"`
+	Leads    int     `json:"leads"`
+	Enrolled int     `json:"enrolled"`
+	Rate     float64 `json:"rate"`
+}
+
+// AdmissionMetrics summarizes the leads created in a period.
+type AdmissionMetrics struct {
+	From            string         `json:"from"`
+	To              string         `json:"to"`
+	Leads           int            `json:"leads"`
+	Enrolled        int            `json:"enrolled"`
+	Declined        int            `json:"declined"`
+	ConversionRate  float64        `json:"conversion_rate"`
+	AvgDaysToEnroll float64        `json:"avg_days_to_enroll"`
+	Stages          []StageMetric  `json:"stages"`
+	Sources         []SourceMetric `json:"sources"`
+}
+
+// NewAdmissionMetrics builds the funnel of the leads from their stage
+// changes. A lead reached an open or enrolled stage when it ever got to that
+// stage or a later one, so skipped stages still count as passed; declined
+// stages count the leads that were ever in them.
+func NewAdmissionMetrics(p Pipeline, leads []Lead, changes []StageChange, from, to string) AdmissionMetrics {
+	m := AdmissionMetrics{From: from, To: to, Leads: len(leads), Stages: []StageMetric{}, Sources: []SourceMetric{}}
+
+	furthest := make(map[int]int, len(leads))
+	visited := make(map[int]map[string]bool, len(leads))
+	enrolledAt := make(map[int]string)
+	for _, lead := range leads {
+		furthest[lead.ID] = -1
+		visited[lead.ID] = map[string]bool{}
+	}
+	visit := func(leadID int, stage string) {
+		if visited[leadID] == nil {
+			return
+		}
+		visited[leadID][stage] = true
+		if i := p.index(stage); i >= 0 && p[i].Outcome != OutcomeDeclined && i > furthest[leadID] {
+			furthest[leadID] = i
+		}
+	}
+	for _, lead := range leads {
+		visit(lead.ID, lead.Stage)
+	}
+	for _, change := range changes {
+		visit(change.LeadID, change.ToStage)
+		if stage, ok := p.Stage(change.ToStage); ok && stage.Outcome == OutcomeEnrolled {
+			enrolledAt[change.LeadID] = change.ChangedAt
+		}
+	}
+
+	current := make(map[string]int)
+	sources := make(map[string]*SourceMetric)
+	var sourceOrder []string
+	var days float64
+	for _, lead := range leads {
+		current[lead.Stage]++
+		stage, _ := p.Stage(lead.Stage)
+		source := sources[lead.Source]
+		if source == nil {
+			source = &SourceMetric{Source: lead.Source}
+			sources[lead.Source] = source
+			sourceOrder = append(sourceOrder, lead.Source)
+		}
+		source.Leads++
+
+		switch stage.Outcome {
+		case OutcomeEnrolled:
+			m.Enrolled++
+			source.Enrolled++
+			created, err1 := time.Parse(time.DateTime, lead.CreatedAt)
+			enrolled, err2 := time.Parse(time.DateTime, enrolledAt[lead.ID])
+			if err1 == nil && err2 == nil {
+				days += enrolled.Sub(created).Hours() / 24
+			}
+		case OutcomeDeclined:
+			m.Declined++
+		}
+	}
+
+	for i, stage := range p {
+		metric := StageMetric{Stage: stage.Name, Current: current[stage.Name]}
+		for _, lead := range leads {
+			if stage.Outcome == OutcomeDeclined {
+				if visited[lead.ID][stage.Name] {
+					metric.Reached++
+				}
+			} else if furthest[lead.ID] >= i {
+				metric.Reached++
+			}
+		}
+		metric.Rate = percent(metric.Reached, m.Leads)
+		m.Stages = append(m.Stages, metric)
+	}
+
+	sort.Strings(sourceOrder)
+	for _, name := range sourceOrder {
+		source := sources[name]
+		source.Rate = percent(source.Enrolled, source.Leads)
+		m.Sources = append(m.Sources, *source)
+	}
+
+	m.ConversionRate = percent(m.Enrolled, m.Leads)
+	if m.Enrolled > 0 {
+		m.AvgDaysToEnroll = round2(days / float64(m.Enrolled))
+	}
+	return m
+}
+
+func percent(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return round2(float64(part) * 100 / float64(total))
+}
@@ -0,0 +1,89 @@
+package models
+
+import "testing"
+
+var testPipeline = Pipeline{
+	{Name: "inquiry"},
+	{Name: "application"},
+	{Name: "interview"},
+	{Name: "offer"},
+	{Name: "enrolled", Outcome: OutcomeEnrolled},
+	{Name: "declined", Outcome: OutcomeDeclined},
+}
+
+func TestPipelineCheckMove(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to string
+		wantErr  bool
+	}{
+		{"forward", "inquiry", "interview", false},
+		{"back", "offer", "application", false},
+		{"decline", "interview", "declined", false},
+		// Отклонённую заявку можно вернуть в работу
+		{"reopen", "declined", "inquiry", false},
+		{"unknown stage", "inquiry", "waitlist", true},
+		{"same stage", "offer", "offer", true},
+		// В enrolled попадают только через конверсию
+		{"enroll by move", "offer", "enrolled", true},
+		{"leave enrolled", "enrolled", "offer", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := testPipeline.CheckMove(tt.from, tt.to); (err != nil) != tt.wantErr {
+				t.Errorf("CheckMove(%q, %q) error = %v, wantErr %v", tt.from, tt.to, err, tt.wantErr)
+			}
+		})
+	}
+
+	if testPipeline.Initial() != "inquiry" || testPipeline.Enrolled() != "enrolled" {
+		t.Errorf("Initial() = %q, Enrolled() = %q", testPipeline.Initial(), testPipeline.Enrolled())
+	}
+}
+
+func TestNewAdmissionMetrics(t *testing.T) {
+	leads := []Lead{
+		{ID: 1, Stage: "enrolled", Source: "website", CreatedAt: "2024-03-01 10:00:00"},
+		{ID: 2, Stage: "declined", Source: "website", CreatedAt: "2024-03-02 10:00:00"},
+		{ID: 3, Stage: "application", Source: "open day", CreatedAt: "2024-03-03 10:00:00"},
+		{ID: 4, Stage: "inquiry", Source: "website", CreatedAt: "2024-03-04 10:00:00"},
+	}
+	changes := []StageChange{
+		{LeadID: 1, ToStage: "inquiry"},
+		// Собеседование пропущено, но стадия считается пройденной
+		{LeadID: 1, FromStage: "inquiry", ToStage: "offer"},
+		{LeadID: 1, FromStage: "offer", ToStage: "enrolled", ChangedAt: "2024-03-11 10:00:00"},
+		{LeadID: 2, ToStage: "inquiry"},
+		{LeadID: 2, FromStage: "inquiry", ToStage: "interview"},
+		{LeadID: 2, FromStage: "interview", ToStage: "declined"},
+		{LeadID: 3, ToStage: "inquiry"},
+		{LeadID: 3, FromStage: "inquiry", ToStage: "application"},
+		{LeadID: 4, ToStage: "inquiry"},
+	}
+
+	m := NewAdmissionMetrics(testPipeline, leads, changes, "2024-03-01", "2024-03-31")
+
+	if m.Leads != 4 || m.Enrolled != 1 || m.Declined != 1 || m.ConversionRate != 25 || m.AvgDaysToEnroll != 10 {
+		t.Errorf("totals = %+v", m)
+	}
+	reached := map[string]int{"inquiry": 4, "application": 3, "interview": 2, "offer": 1, "enrolled": 1, "declined": 1}
+	for _, stage := range m.Stages {
+		if stage.Reached != reached[stage.Stage] {
+			t.Errorf("stage %s reached by %d, want %d", stage.Stage, stage.Reached, reached[stage.Stage])
+		}
+	}
+	if len(m.Sources) != 2 || m.Sources[1].Source != "website" || m.Sources[1].Leads != 3 || m.Sources[1].Rate != 33.33 {
+		t.Errorf("sources = %+v", m.Sources)
+	}
+}
+
+func TestTimeline(t *testing.T) {
+	activities := []LeadActivity{{ID: 1, CreatedAt: "2024-03-02 09:00:00"}}
+	changes := []StageChange{{ID: 1, ChangedAt: "2024-03-01 09:00:00"}, {ID: 2, ChangedAt: "2024-03-03 09:00:00"}}
+
+	entries := Timeline(activities, changes)
+	if len(entries) != 3 || entries[0].StageChange.ID != 2 || entries[1].Kind != "activity" || entries[2].StageChange.ID != 1 {
+		t.Errorf("timeline out of order: %+v", entries)
+	}
+}
@@ -0,0 +1,108 @@
+package models
+
+type AttendanceStatus string
+
+const (
+	AttendancePresent AttendanceStatus = "present"
+	AttendanceAbsent  AttendanceStatus = "absent"
+	AttendanceLate    AttendanceStatus = "late"
+	AttendanceExcused AttendanceStatus = "excused"
+)
+
+func (s AttendanceStatus) Valid() bool {
+	switch s {
+	case AttendancePresent, AttendanceAbsent, AttendanceLate, AttendanceExcused:
+		return true
+	}
+	return false
+}
+
+// Attendance is one student's mark for one class session. A session is a
+// class on a date; Period tells several sessions on the same day apart.
+type Attendance struct {
+	ID         int              `json:"id,omitempty" db:"id,omitempty"`
+	StudentID  int              `json:"student_id,omitempty" db:"student_id,omitempty"`
+	Class      string           `json:"class,omitempty" db:"class,omitempty" sanitize:"strict"`
+	Date       string           `json:"date,omitempty" db:"session_date,omitempty"`
+	Period     int              `json:"period,omitempty" db:"period,omitempty"`
+	Status     AttendanceStatus `json:"status,omitempty" db:"status,omitempty" sanitize:"strict"`
+	Note       string           `json:"note,omitempty" db:"note,omitempty"`
+	RecordedBy int              `json:"recorded_by,omitempty" db:"recorded_by,omitempty"`
+	UpdatedAt  string           `json:"updated_at,omitempty" db:"updated_at,omitempty"`
+}
+
+// AttendanceSession records a whole class session in one request.
+type AttendanceSession struct {
+	Class   string           `json:"class"`
+	Date    string           `json:"date"`
+	Period  int              `json:"period"`
+	Records []AttendanceMark `json:"records"`
+}
+
+type AttendanceMark struct {
+	StudentID int              `json:"student_id"`
+	Status    AttendanceStatus `json:"status"`
+	Note      string           `json:"note,omitempty"`
+}
+
+// AttendanceChange is an entry of a record's edit history.
+type AttendanceChange struct {
+	ID           int              `json:"id"`
+	AttendanceID int              `json:"attendance_id"`
+	OldStatus    AttendanceStatus `json:"old_status"`
+	NewStatus    AttendanceStatus `json:"new_status"`
+	OldNote      string           `json:"old_note,omitempty"`
+	NewNote      string           `json:"new_note,omitempty"`
+	ChangedBy    int              `json:"changed_by,omitempty"`
+	ChangedAt    string           `json:"changed_at"`
+}
+
+type AttendanceSummary struct {
+	StudentID int    `json:"student_id"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Sessions  int    `json:"sessions"`
+	Present   int    `json:"present"`
+	Absent    int    `json:"absent"`
+	Late      int    `json:"late"`
+	Excused   int    `json:"excused"`
+	// Rate is the share of sessions attended, late included
+	Rate float64 `json:"rate"`
+}
+
+// Add counts one record into the summary.
+func (s *AttendanceSummary) Add(record Attendance) {
+	s.Sessions++
+	switch record.Status {
+	case AttendancePresent:
+		s.Present++
+	case AttendanceAbsent:
+		s.Absent++
+	case AttendanceLate:
+		s.Late++
+	case AttendanceExcused:
+		s.Excused++
+	}
+	s.Rate = float64(s.Present+s.Late) / float64(s.Sessions)
+}
+
+// RosterEntry is a student of a class with the marks of one day, empty if
+// attendance hasn't been taken yet.
+type RosterEntry struct {
+	StudentID int          `json:"student_id"`
+	FirstName string       `json:"first_name"`
+	LastName  string       `json:"last_name"`
+	Records   []Attendance `json:"records"`
+}
+
+// AttendanceAlert is raised when a student reaches the absence threshold.
+type AttendanceAlert struct {
+	ID          int    `json:"id"`
+	StudentID   int    `json:"student_id"`
+	Absences    int    `json:"absences"`
+	WindowStart string `json:"window_start"`
+	WindowEnd   string `json:"window_end"`
+	CreatedAt   string `json:"created_at,omitempty"`
+	// NotifiedAt is empty until the alert email went out
+	NotifiedAt string `json:"notified_at,omitempty"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "testing"
+
+func TestAttendanceSummaryAdd(t *testing.T) {
+	summary := AttendanceSummary{StudentID: 1}
+	for _, status := range []AttendanceStatus{AttendancePresent, AttendanceLate, AttendanceAbsent, AttendanceExcused} {
+		summary.Add(Attendance{StudentID: 1, Status: status})
+	}
+
+	// Опоздание считается посещением, уважительная причина — нет
+	if summary.Sessions != 4 || summary.Present != 1 || summary.Late != 1 || summary.Absent != 1 || summary.Excused != 1 {
+		t.Errorf("summary counts = %+v", summary)
+	}
+	if summary.Rate != 0.5 {
+		t.Errorf("Rate = %v, want 0.5", summary.Rate)
+	}
+}
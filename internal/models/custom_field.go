@@ -0,0 +1,322 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// CustomFieldResources are the records admins may define custom fields for.
+var CustomFieldResources = []string{ContactStudent, ContactTeacher}
+
+// Custom field types.
+const (
+	FieldText    = "text"
+	FieldNumber  = "number"
+	FieldBoolean = "boolean"
+	FieldDate    = "date"
+	FieldSelect  = "select"
+)
+
+var CustomFieldTypes = []string{FieldText, FieldNumber, FieldBoolean, FieldDate, FieldSelect}
+
+// customFieldRoles are the roles a field may be shown to; admins see every field.
+var customFieldRoles = []string{"admin", "manager", "exec"}
+
+// maxCustomText is the longest text value stored, in characters.
+const maxCustomText = 1000
+
+var customFieldName = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+
+// CustomField is an extra field admins define for a resource, such as a
+// student's bus route. Min and Max bound numbers, or the length of text.
+// VisibleTo lists the roles that may read and set the field; empty means
+// everyone who can reach the record.
+type CustomField struct {
+	ID        int      `json:"id,omitempty" db:"id,omitempty"`
+	Resource  string   `json:"resource,omitempty" db:"resource,omitempty" sanitize:"strict"`
+	Name      string   `json:"name,omitempty" db:"name,omitempty" sanitize:"strict"`
+	Label     string   `json:"label,omitempty" db:"label,omitempty"`
+	Type      string   `json:"type,omitempty" db:"type,omitempty" sanitize:"strict"`
+	Options   []string `json:"options,omitempty" db:"options,omitempty"`
+	Pattern   string   `json:"pattern,omitempty" db:"pattern,omitempty" sanitize:"none"`
+	Min       *float64 `json:"min,omitempty" db:"min_value,omitempty"`
+	Max       *float64 `json:"max,omitempty" db:"max_value,omitempty"`
+	Required  bool     `json:"required" db:"required"`
+	VisibleTo []string `json:"visible_to,omitempty" db:"visible_to,omitempty" sanitize:"strict"`
+	Position  int      `json:"position" db:"position"`
+}
+
+// Validate normalizes the definition and checks that its settings fit its type.
+func (f *CustomField) Validate() error {
+	f.Resource = strings.ToLower(strings.TrimSpace(f.Resource))
+	if !slices.Contains(CustomFieldResources, f.Resource) {
+		return errors.New("resource must be one of " + strings.Join(CustomFieldResources, ", "))
+	}
+	f.Name = strings.ToLower(strings.TrimSpace(f.Name))
+	if !customFieldName.MatchString(f.Name) {
+		return errors.New("name must start with a letter and have up to 50 lowercase letters, digits or _")
+	}
+	f.Label = strings.TrimSpace(f.Label)
+	if f.Label == "" {
+		f.Label = f.Name
+	}
+	if utf8.RuneCountInString(f.Label) > 100 {
+		return errors.New("label must be at most 100 characters")
+	}
+	f.Type = strings.ToLower(strings.TrimSpace(f.Type))
+	if f.Type == "" {
+		f.Type = FieldText
+	}
+	if !slices.Contains(CustomFieldTypes, f.Type) {
+		return errors.New("type must be one of " + strings.Join(CustomFieldTypes, ", "))
+	}
+
+	options := []string{}
+	for _, option := range f.Options {
+		option = strings.TrimSpace(option)
+		if option == "" || slices.Contains(options, option) {
+			continue
+		}
+		if utf8.RuneCountInString(option) > 100 {
+			return fmt.Errorf("option %q is longer than 100 characters", option)
+		}
+		options = append(options, option)
+	}
+	f.Options = options
+	if f.Type == FieldSelect && len(f.Options) == 0 {
+		return errors.New("select fields need options")
+	}
+	if f.Type != FieldSelect && len(f.Options) > 0 {
+		return errors.New("options apply to select fields only")
+	}
+
+	if f.Pattern != "" {
+		if f.Type != FieldText {
+			return errors.New("pattern applies to text fields only")
+		}
+		if _, err := regexp.Compile(f.Pattern); err != nil {
+			return fmt.Errorf("invalid pattern: %v", err)
+		}
+	}
+	if f.Min != nil || f.Max != nil {
+		if f.Type != FieldNumber && f.Type != FieldText {
+			return errors.New("min and max apply to number and text fields only")
+		}
+		if f.Min != nil && f.Max != nil && *f.Min > *f.Max {
+			return errors.New("min must not be greater than max")
+		}
+		if f.Type == FieldText && (f.Min != nil && *f.Min < 0 || f.Max != nil && *f.Max > maxCustomText) {
+			return fmt.Errorf("text length limits must be between 0 and %d", maxCustomText)
+		}
+	}
+
+	roles := []string{}
+	for _, role := range f.VisibleTo {
+		role = strings.ToLower(strings.TrimSpace(role))
+		if !slices.Contains(customFieldRoles, role) {
+			return errors.New("visible_to roles must be among " + strings.Join(customFieldRoles, ", "))
+		}
+		if !slices.Contains(roles, role) {
+			roles = append(roles, role)
+		}
+	}
+	f.VisibleTo = roles
+	return nil
+}
+
+// VisibleToRole reports whether users with the role may read and set the field.
+func (f CustomField) VisibleToRole(role string) bool {
+	return role == "admin" || len(f.VisibleTo) == 0 || slices.Contains(f.VisibleTo, role)
+}
+
+// Normalize checks a value sent for the field and returns it as stored. JSON
+// numbers and booleans may also come as strings, e.g. from CSV imports.
+// Empty values normalize to "", which clears the field.
+func (f CustomField) Normalize(value any) (string, error) {
+	if value == nil {
+		return "", nil
+	}
+	text, isText := value.(string)
+	if isText {
+		text = strings.TrimSpace(text)
+		if text == "" {
+			return "", nil
+		}
+	}
+
+	switch f.Type {
+	case FieldNumber:
+		number, ok := value.(float64)
+		if isText {
+			parsed, err := strconv.ParseFloat(text, 64)
+			number, ok = parsed, err == nil
+		}
+		if !ok || math.IsNaN(number) || math.IsInf(number, 0) {
+			return "", fmt.Errorf("custom field %q must be a number", f.Name)
+		}
+		if f.Min != nil && number < *f.Min || f.Max != nil && number > *f.Max {
+			return "", fmt.Errorf("custom field %q is out of range", f.Name)
+		}
+		return strconv.FormatFloat(number, 'f', -1, 64), nil
+	case FieldBoolean:
+		flag, ok := value.(bool)
+		if isText {
+			parsed, err := strconv.ParseBool(text)
+			flag, ok = parsed, err == nil
+		}
+		if !ok {
+			return "", fmt.Errorf("custom field %q must be true or false", f.Name)
+		}
+		return strconv.FormatBool(flag), nil
+	}
+
+	if !isText {
+		return "", fmt.Errorf("custom field %q must be a string", f.Name)
+	}
+	switch f.Type {
+	case FieldDate:
+		date, err := time.Parse(time.DateOnly, text)
+		if err != nil {
+			return "", fmt.Errorf("custom field %q must be a date, use YYYY-MM-DD", f.Name)
+		}
+		return date.Format(time.DateOnly), nil
+	case FieldSelect:
+		if !slices.Contains(f.Options, text) {
+			return "", fmt.Errorf("custom field %q must be one of %s", f.Name, strings.Join(f.Options, ", "))
+		}
+		return text, nil
+	}
+
+	length := utf8.RuneCountInString(text)
+	if length > maxCustomText || f.Max != nil && float64(length) > *f.Max || f.Min != nil && float64(length) < *f.Min {
+		return "", fmt.Errorf("custom field %q has an invalid length", f.Name)
+	}
+	if f.Pattern != "" {
+		pattern, err := regexp.Compile(`^(?:` + f.Pattern + `)$`)
+		if err != nil || !pattern.MatchString(text) {
+			return "", fmt.Errorf("custom field %q does not match the required format", f.Name)
+		}
+	}
+	return text, nil
+}
+
+// Decode turns a stored value back into its JSON type.
+func (f CustomField) Decode(stored string) any {
+	switch f.Type {
+	case FieldNumber:
+		if number, err := strconv.ParseFloat(stored, 64); err == nil {
+			return number
+		}
+	case FieldBoolean:
+		if flag, err := strconv.ParseBool(stored); err == nil {
+			return flag
+		}
+	}
+	return stored
+}
+
+// CustomFields are the definitions of one resource in display order.
+type CustomFields []CustomField
+
+// Visible keeps the fields users with the role may read and set.
+func (fs CustomFields) Visible(role string) CustomFields {
+	visible := CustomFields{}
+	for _, f := range fs {
+		if f.VisibleToRole(role) {
+			visible = append(visible, f)
+		}
+	}
+	return visible
+}
+
+// Find looks a field up by name.
+func (fs CustomFields) Find(name string) (CustomField, bool) {
+	for _, f := range fs {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return CustomField{}, false
+}
+
+// Parse validates the "custom" object of a request and returns the values to
+// store by field name. With replace, as for a new or replaced record, fields
+// left out are cleared and required fields must be given; otherwise only the
+// fields present change and required fields can't be cleared.
+func (fs CustomFields) Parse(input map[string]any, replace bool) (map[string]string, error) {
+	values := map[string]string{}
+	for name, value := range input {
+		f, ok := fs.Find(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown custom field %q", name)
+		}
+		normalized, err := f.Normalize(value)
+		if err != nil {
+			return nil, err
+		}
+		values[name] = normalized
+	}
+	for _, f := range fs {
+		value, ok := values[f.Name]
+		if !ok && !replace {
+			continue
+		}
+		if f.Required && value == "" {
+			return nil, fmt.Errorf("custom field %q is required", f.Name)
+		}
+		values[f.Name] = value
+	}
+	return values, nil
+}
+
+// Decode returns the stored values of the fields as JSON values, leaving out
+// empty ones; nil if there are none.
+func (fs CustomFields) Decode(values map[string]string) map[string]any {
+	var decoded map[string]any
+	for _, f := range fs {
+		if value := values[f.Name]; value != "" {
+			if decoded == nil {
+				decoded = map[string]any{}
+			}
+			decoded[f.Name] = f.Decode(value)
+		}
+	}
+	return decoded
+}
+
+// VisibleChanges removes from record changes the custom.<name> values of
+// fields not in fs, the fields the reader may see; removed fields count as
+// not visible. Updates left with nothing to show are dropped.
+func (fs CustomFields) VisibleChanges(changes []RecordChange) []RecordChange {
+	visible := make([]RecordChange, 0, len(changes))
+	for _, c := range changes {
+		hidden := false
+		kept := make(map[string]FieldChange, len(c.Changes))
+		for key, change := range c.Changes {
+			if name, ok := strings.CutPrefix(key, "custom."); ok {
+				if _, ok := fs.Find(name); !ok {
+					hidden = true
+					continue
+				}
+			}
+			kept[key] = change
+		}
+		if !hidden {
+			visible = append(visible, c)
+			continue
+		}
+		if len(kept) == 0 && c.Action == ActionUpdated {
+			continue
+		}
+		c.Changes = kept
+		visible = append(visible, c)
+	}
+	return visible
+}
@@ -0,0 +1,197 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCustomFieldValidate(t *testing.T) {
+	five, ten := 5.0, 10.0
+
+	tests := []struct {
+		name    string
+		field   CustomField
+		wantErr bool
+	}{
+		{"text", CustomField{Resource: "Student", Name: " Bus_Route "}, false},
+		{"select", CustomField{Resource: "teacher", Name: "contract", Type: "select", Options: []string{"full", "part", "full", " "}}, false},
+		{"number range", CustomField{Resource: "student", Name: "locker", Type: "number", Min: &five, Max: &ten}, false},
+		{"unknown resource", CustomField{Resource: "guardian", Name: "x"}, true},
+		{"bad name", CustomField{Resource: "student", Name: "bus route"}, true},
+		{"unknown type", CustomField{Resource: "student", Name: "x", Type: "json"}, true},
+		{"select without options", CustomField{Resource: "student", Name: "x", Type: "select"}, true},
+		{"options on text", CustomField{Resource: "student", Name: "x", Options: []string{"a"}}, true},
+		{"pattern on number", CustomField{Resource: "student", Name: "x", Type: "number", Pattern: "[0-9]+"}, true},
+		{"invalid pattern", CustomField{Resource: "student", Name: "x", Pattern: "("}, true},
+		{"min above max", CustomField{Resource: "student", Name: "x", Type: "number", Min: &ten, Max: &five}, true},
+		{"range on date", CustomField{Resource: "student", Name: "x", Type: "date", Min: &five}, true},
+		{"unknown role", CustomField{Resource: "student", Name: "x", VisibleTo: []string{"parent"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.field.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Имя, тип и подпись нормализуются, повторяющиеся варианты убираются
+	field := CustomField{Resource: "Student", Name: " Bus_Route ", VisibleTo: []string{"Manager", "manager"}}
+	if err := field.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if field.Resource != "student" || field.Name != "bus_route" || field.Type != FieldText || field.Label != "bus_route" || !reflect.DeepEqual(field.VisibleTo, []string{"manager"}) {
+		t.Errorf("Validate() normalized to %+v", field)
+	}
+	options := CustomField{Resource: "teacher", Name: "contract", Type: "select", Options: []string{"full", "part", "full", " "}}
+	options.Validate()
+	if !reflect.DeepEqual(options.Options, []string{"full", "part"}) {
+		t.Errorf("Options = %v, want [full part]", options.Options)
+	}
+}
+
+func TestCustomFieldNormalize(t *testing.T) {
+	one, hundred := 1.0, 100.0
+	tests := []struct {
+		name    string
+		field   CustomField
+		value   any
+		want    string
+		wantErr bool
+	}{
+		{"empty clears", CustomField{Name: "x", Type: FieldNumber}, "  ", "", false},
+		{"nil clears", CustomField{Name: "x", Type: FieldDate}, nil, "", false},
+		{"json number", CustomField{Name: "x", Type: FieldNumber}, 12.0, "12", false},
+		// Значения из CSV приходят строками
+		{"csv number", CustomField{Name: "x", Type: FieldNumber}, " 12.50 ", "12.5", false},
+		{"not a number", CustomField{Name: "x", Type: FieldNumber}, "twelve", "", true},
+		{"below min", CustomField{Name: "x", Type: FieldNumber, Min: &one, Max: &hundred}, 0.5, "", true},
+		{"json boolean", CustomField{Name: "x", Type: FieldBoolean}, true, "true", false},
+		{"csv boolean", CustomField{Name: "x", Type: FieldBoolean}, "0", "false", false},
+		{"bad boolean", CustomField{Name: "x", Type: FieldBoolean}, 1.0, "", true},
+		{"date", CustomField{Name: "x", Type: FieldDate}, "2024-09-01", "2024-09-01", false},
+		{"bad date", CustomField{Name: "x", Type: FieldDate}, "01.09.2024", "", true},
+		{"option", CustomField{Name: "x", Type: FieldSelect, Options: []string{"full", "part"}}, "part", "part", false},
+		{"unknown option", CustomField{Name: "x", Type: FieldSelect, Options: []string{"full", "part"}}, "none", "", true},
+		{"text", CustomField{Name: "x", Type: FieldText}, " Route 12 ", "Route 12", false},
+		{"text must be a string", CustomField{Name: "x", Type: FieldText}, 12.0, "", true},
+		// Шаблон должен совпадать со всем значением
+		{"pattern", CustomField{Name: "x", Type: FieldText, Pattern: "[A-Z]{2}[0-9]+"}, "AB123", "AB123", false},
+		{"pattern partial", CustomField{Name: "x", Type: FieldText, Pattern: "[A-Z]{2}[0-9]+"}, "AB123x", "", true},
+		{"too long", CustomField{Name: "x", Type: FieldText, Max: &one}, "ab", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.field.Normalize(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Normalize() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Normalize() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCustomFieldsVisible(t *testing.T) {
+	fields := CustomFields{
+		{Name: "bus_route"},
+		{Name: "medical", VisibleTo: []string{"manager"}},
+	}
+
+	tests := []struct {
+		role string
+		want int
+	}{
+		{"admin", 2},
+		{"manager", 2},
+		{"exec", 1},
+	}
+	for _, tt := range tests {
+		if got := fields.Visible(tt.role); len(got) != tt.want {
+			t.Errorf("Visible(%q) = %v, want %d fields", tt.role, got, tt.want)
+		}
+	}
+}
+
+func TestCustomFieldsParse(t *testing.T) {
+	fields := CustomFields{
+		{Name: "bus_route", Type: FieldText},
+		{Name: "locker", Type: FieldNumber, Required: true},
+	}
+
+	tests := []struct {
+		name    string
+		input   map[string]any
+		replace bool
+		want    map[string]string
+		wantErr bool
+	}{
+		{"create", map[string]any{"locker": 7.0}, true, map[string]string{"bus_route": "", "locker": "7"}, false},
+		{"create without required", map[string]any{"bus_route": "12"}, true, nil, true},
+		// Частичное обновление меняет только переданные поля
+		{"patch", map[string]any{"bus_route": "12"}, false, map[string]string{"bus_route": "12"}, false},
+		{"patch clears required", map[string]any{"locker": nil}, false, nil, true},
+		{"patch nothing", nil, false, map[string]string{}, false},
+		{"unknown field", map[string]any{"shoe_size": "40"}, false, nil, true},
+		{"invalid value", map[string]any{"locker": "seven"}, false, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := fields.Parse(tt.input, tt.replace)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCustomFieldsDecode(t *testing.T) {
+	fields := CustomFields{
+		{Name: "bus_route", Type: FieldText},
+		{Name: "locker", Type: FieldNumber},
+		{Name: "lunch", Type: FieldBoolean},
+	}
+
+	got := fields.Decode(map[string]string{"bus_route": "", "locker": "7", "lunch": "true", "removed": "x"})
+	want := map[string]any{"locker": 7.0, "lunch": true}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Decode() = %v, want %v", got, want)
+	}
+	if got := fields.Decode(nil); got != nil {
+		t.Errorf("Decode(nil) = %v, want nil", got)
+	}
+}
+
+func TestCustomFieldsVisibleChanges(t *testing.T) {
+	fields := CustomFields{{Name: "bus_route"}}
+	changes := []RecordChange{
+		{ID: 1, Action: ActionCreated, Changes: map[string]FieldChange{"class": {To: "10A"}}},
+		// Скрытое поле убирается, остальные изменения остаются
+		{ID: 2, Action: ActionUpdated, Changes: map[string]FieldChange{"custom.bus_route": {To: "12"}, "custom.medical": {To: "asthma"}}},
+		// Запись только со скрытыми полями не показывается
+		{ID: 3, Action: ActionUpdated, Changes: map[string]FieldChange{"custom.medical": {From: "asthma"}}},
+		// Значения удалённого поля тоже скрыты
+		{ID: 4, Action: ActionUpdated, Changes: map[string]FieldChange{"custom.removed": {To: "x"}, "email": {To: "a@example.com"}}},
+	}
+
+	got := fields.VisibleChanges(changes)
+	want := []RecordChange{
+		changes[0],
+		{ID: 2, Action: ActionUpdated, Changes: map[string]FieldChange{"custom.bus_route": {To: "12"}}},
+		{ID: 4, Action: ActionUpdated, Changes: map[string]FieldChange{"email": {To: "a@example.com"}}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("VisibleChanges() = %+v, want %+v", got, want)
+	}
+	if len(changes[1].Changes) != 2 {
+		t.Error("VisibleChanges() modified its input")
+	}
+}
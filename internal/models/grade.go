@@ -0,0 +1,163 @@
+package models
+
+import (
+	"math"
+	"slices"
+)
+
+var AssessmentTypes = []string{"homework", "quiz", "test", "exam", "project", "other"}
+
+// Assessment is a piece of graded work for one class and subject. Weight sets
+// its share in the weighted average relative to the other assessments.
+type Assessment struct {
+	ID        int     `json:"id,omitempty" db:"id,omitempty"`
+	Class     string  `json:"class,omitempty" db:"class,omitempty" sanitize:"strict"`
+	Subject   string  `json:"subject,omitempty" db:"subject,omitempty" sanitize:"strict"`
+	Type      string  `json:"type,omitempty" db:"type,omitempty" sanitize:"strict"`
+	Title     string  `json:"title,omitempty" db:"title,omitempty"`
+	MaxScore  float64 `json:"max_score,omitempty" db:"max_score,omitempty"`
+	Weight    float64 `json:"weight,omitempty" db:"weight,omitempty"`
+	Date      string  `json:"date,omitempty" db:"assessment_date,omitempty"`
+	CreatedBy int     `json:"created_by,omitempty" db:"created_by,omitempty"`
+}
+
+type Score struct {
+	ID           int     `json:"id,omitempty" db:"id,omitempty"`
+	AssessmentID int     `json:"assessment_id,omitempty" db:"assessment_id,omitempty"`
+	StudentID    int     `json:"student_id,omitempty" db:"student_id,omitempty"`
+	Score        float64 `json:"score" db:"score"`
+	Comment      string  `json:"comment,omitempty" db:"comment,omitempty"`
+	RecordedBy   int     `json:"recorded_by,omitempty" db:"recorded_by,omitempty"`
+	UpdatedAt    string  `json:"updated_at,omitempty" db:"updated_at,omitempty"`
+}
+
+// GradeEntry is a student's score together with its assessment.
+type GradeEntry struct {
+	Assessment Assessment `json:"assessment"`
+	Score      float64    `json:"score"`
+	Percent    float64    `json:"percent"`
+	Comment    string     `json:"comment,omitempty"`
+}
+
+// GradeBand gives Letter to averages of at least Min percent.
+type GradeBand struct {
+	Letter string  `json:"letter"`
+	Min    float64 `json:"min"`
+}
+
+// GradeScale is ordered from the highest band down.
+type GradeScale []GradeBand
+
+func (s GradeScale) Letter(percent float64) string {
+	for _, band := range s {
+		if percent >= band.Min {
+			return band.Letter
+		}
+	}
+	if len(s) > 0 {
+		return s[len(s)-1].Letter
+	}
+	return ""
+}
+
+type SubjectAverage struct {
+	Subject     string  `json:"subject"`
+	Assessments int     `json:"assessments"`
+	Average     float64 `json:"average"`
+	Letter      string  `json:"letter"`
+}
+
+// Percent converts a score to a percentage of maxScore, rounded to 2 decimals.
+func Percent(score, maxScore float64) float64 {
+	if maxScore <= 0 {
+		return 0
+	}
+	return round2(100 * score / maxScore)
+}
+
+// WeightedAverage returns the weighted mean percentage of entries, 0 for none.
+func WeightedAverage(entries []GradeEntry) float64 {
+	var sum, weights float64
+	for _, entry := range entries {
+		if entry.Assessment.MaxScore <= 0 {
+			continue
+		}
+		sum += entry.Assessment.Weight * entry.Score / entry.Assessment.MaxScore
+		weights += entry.Assessment.Weight
+	}
+	if weights == 0 {
+		return 0
+	}
+	return round2(100 * sum / weights)
+}
+
+// SubjectAverages groups entries by subject in order of first appearance.
+func SubjectAverages(entries []GradeEntry, scale GradeScale) []SubjectAverage {
+	bySubject := make(map[string][]GradeEntry)
+	var subjects []string
+	for _, entry := range entries {
+		subject := entry.Assessment.Subject
+		if _, ok := bySubject[subject]; !ok {
+			subjects = append(subjects, subject)
+		}
+		bySubject[subject] = append(bySubject[subject], entry)
+	}
+
+	averages := make([]SubjectAverage, 0, len(subjects))
+	for _, subject := range subjects {
+		average := WeightedAverage(bySubject[subject])
+		averages = append(averages, SubjectAverage{
+			Subject:     subject,
+			Assessments: len(bySubject[subject]),
+			Average:     average,
+			Letter:      scale.Letter(average),
+		})
+	}
+	return averages
+}
+
+// GradeDistribution describes the averages of a class in one subject.
+type GradeDistribution struct {
+	Class    string         `json:"class"`
+	Subject  string         `json:"subject"`
+	From     string         `json:"from"`
+	To       string         `json:"to"`
+	Students int            `json:"students"`
+	Mean     float64        `json:"mean"`
+	Median   float64        `json:"median"`
+	Min      float64        `json:"min"`
+	Max      float64        `json:"max"`
+	Letters  map[string]int `json:"letters"`
+}
+
+// Distribute fills the statistics from per-student averages.
+func (d *GradeDistribution) Distribute(averages []float64, scale GradeScale) {
+	d.Students = len(averages)
+	d.Letters = make(map[string]int, len(scale))
+	for _, band := range scale {
+		d.Letters[band.Letter] = 0
+	}
+	if len(averages) == 0 {
+		return
+	}
+
+	sorted := slices.Clone(averages)
+	slices.Sort(sorted)
+	var sum float64
+	for _, average := range sorted {
+		sum += average
+		d.Letters[scale.Letter(average)]++
+	}
+	d.Mean = round2(sum / float64(len(sorted)))
+	d.Min = sorted[0]
+	d.Max = sorted[len(sorted)-1]
+	if n := len(sorted); n%2 == 1 {
+		d.Median = sorted[n/2]
+	} else {
+		d.Median = round2((sorted[n/2-1] + sorted[n/2]) / 2)
+	}
+}
+
+func round2(value float64) float64 {
+	return math.Round(value*100) / 100
+}
@@ -0,0 +1,66 @@
+package models
+
+import "testing"
+
+var testScale = GradeScale{{Letter: "A", Min: 90}, {Letter: "B", Min: 80}, {Letter: "C", Min: 70}, {Letter: "F", Min: 0}}
+
+func TestGradeScaleLetter(t *testing.T) {
+	tests := []struct {
+		percent float64
+		want    string
+	}{
+		{100, "A"},
+		{90, "A"},
+		{89.99, "B"},
+		{70, "C"},
+		{12, "F"},
+	}
+
+	for _, tt := range tests {
+		if got := testScale.Letter(tt.percent); got != tt.want {
+			t.Errorf("Letter(%v) = %q, want %q", tt.percent, got, tt.want)
+		}
+	}
+}
+
+func TestSubjectAverages(t *testing.T) {
+	entries := []GradeEntry{
+		{Assessment: Assessment{Subject: "math", MaxScore: 10, Weight: 1}, Score: 10},
+		// Экзамен весит втрое больше домашней работы
+		{Assessment: Assessment{Subject: "math", MaxScore: 50, Weight: 3}, Score: 35},
+		{Assessment: Assessment{Subject: "art", MaxScore: 20, Weight: 1}, Score: 19},
+	}
+
+	averages := SubjectAverages(entries, testScale)
+	if len(averages) != 2 {
+		t.Fatalf("got %d subjects, want 2", len(averages))
+	}
+	if got := averages[0]; got.Subject != "math" || got.Assessments != 2 || got.Average != 77.5 || got.Letter != "C" {
+		t.Errorf("math = %+v, want 2 assessments, 77.5, C", got)
+	}
+	if got := averages[1]; got.Subject != "art" || got.Average != 95 || got.Letter != "A" {
+		t.Errorf("art = %+v, want 95, A", got)
+	}
+}
+
+func TestGradeDistribution(t *testing.T) {
+	var d GradeDistribution
+	d.Distribute([]float64{95, 60, 85, 72}, testScale)
+
+	if d.Students != 4 || d.Mean != 78 || d.Median != 78.5 || d.Min != 60 || d.Max != 95 {
+		t.Errorf("distribution = %+v", d)
+	}
+	want := map[string]int{"A": 1, "B": 1, "C": 1, "F": 1}
+	for letter, count := range want {
+		if d.Letters[letter] != count {
+			t.Errorf("Letters[%s] = %d, want %d", letter, d.Letters[letter], count)
+		}
+	}
+
+	// Пустой класс: все буквы присутствуют с нулём
+	var empty GradeDistribution
+	empty.Distribute(nil, testScale)
+	if empty.Students != 0 || len(empty.Letters) != len(testScale) {
+		t.Errorf("empty distribution = %+v", empty)
+	}
+}
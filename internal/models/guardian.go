@@ -0,0 +1,40 @@
+package models
+
+// Guardian is a parent or other adult responsible for one or more students.
+type Guardian struct {
+	ID                int    `json:"id,omitempty" db:"id,omitempty"`
+	FirstName         string `json:"first_name,omitempty" db:"first_name,omitempty"`
+	LastName          string `json:"last_name,omitempty" db:"last_name,omitempty"`
+	Phone             string `json:"phone,omitempty" db:"phone,omitempty" sanitize:"strict"`
+	Email             string `json:"email,omitempty" db:"email,omitempty" sanitize:"strict"`
+	Address           string `json:"address,omitempty" db:"address,omitempty"`
+	PreferredLanguage string `json:"preferred_language,omitempty" db:"preferred_language,omitempty" sanitize:"strict"`
+}
+
+var GuardianRelationships = []string{"mother", "father", "parent", "grandparent", "sibling", "legal_guardian", "other"}
+
+// GuardianLink ties a guardian to a student. At most one guardian per student
+// is the primary contact.
+type GuardianLink struct {
+	StudentID    int    `json:"student_id,omitempty" db:"student_id,omitempty"`
+	GuardianID   int    `json:"guardian_id,omitempty" db:"guardian_id,omitempty"`
+	Relationship string `json:"relationship,omitempty" db:"relationship,omitempty" sanitize:"strict"`
+	CanPickup    bool   `json:"can_pickup" db:"can_pickup"`
+	Primary      bool   `json:"primary" db:"is_primary"`
+}
+
+// StudentGuardian is a guardian as listed for one student.
+type StudentGuardian struct {
+	Guardian
+	Relationship string `json:"relationship"`
+	CanPickup    bool   `json:"can_pickup"`
+	Primary      bool   `json:"primary"`
+}
+
+// GuardianChild is a student as listed for one guardian.
+type GuardianChild struct {
+	Student
+	Relationship string `json:"relationship"`
+	CanPickup    bool   `json:"can_pickup"`
+	Primary      bool   `json:"primary"`
+}
@@ -0,0 +1,200 @@
+package models
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+type LeaveStatus string
+
+const (
+	LeavePending   LeaveStatus = "pending"
+	LeaveApproved  LeaveStatus = "approved"
+	LeaveRejected  LeaveStatus = "rejected"
+	LeaveCancelled LeaveStatus = "cancelled"
+)
+
+// LeaveRequest is a teacher's absence over whole days, from StartDate to
+// EndDate. Staff file it for the teacher and a manager decides on it.
+type LeaveRequest struct {
+	ID           int         `json:"id,omitempty" db:"id,omitempty"`
+	TeacherID    int         `json:"teacher_id,omitempty" db:"teacher_id,omitempty"`
+	StartDate    string      `json:"start_date,omitempty" db:"start_date,omitempty"`
+	EndDate      string      `json:"end_date,omitempty" db:"end_date,omitempty"`
+	Reason       string      `json:"reason,omitempty" db:"reason,omitempty"`
+	Status       LeaveStatus `json:"status,omitempty" db:"status,omitempty"`
+	RequestedBy  int         `json:"requested_by,omitempty" db:"requested_by,omitempty"`
+	DecidedBy    int         `json:"decided_by,omitempty" db:"decided_by,omitempty"`
+	DecisionNote string      `json:"decision_note,omitempty" db:"decision_note,omitempty"`
+	DecidedAt    string      `json:"decided_at,omitempty" db:"decided_at,omitempty"`
+	CreatedAt    string      `json:"created_at,omitempty" db:"created_at,omitempty"`
+}
+
+// LeaveDecision approves, rejects or cancels a leave request.
+type LeaveDecision struct {
+	Status LeaveStatus `json:"status"`
+	Note   string      `json:"note,omitempty"`
+}
+
+// CanMove reports whether a request in status s may take the decision's
+// status: pending requests are decided or cancelled, approved ones only
+// cancelled.
+func (s LeaveStatus) CanMove(to LeaveStatus) bool {
+	switch s {
+	case LeavePending:
+		return to == LeaveApproved || to == LeaveRejected || to == LeaveCancelled
+	case LeaveApproved:
+		return to == LeaveCancelled
+	}
+	return false
+}
+
+// Substitution assigns a substitute teacher to one lesson of an absent
+// teacher on a date.
+type Substitution struct {
+	ID         int    `json:"id,omitempty" db:"id,omitempty"`
+	LeaveID    int    `json:"leave_id,omitempty" db:"leave_id,omitempty"`
+	SlotID     int    `json:"slot_id,omitempty" db:"slot_id,omitempty"`
+	Date       string `json:"date,omitempty" db:"date,omitempty"`
+	PeriodID   int    `json:"period_id,omitempty" db:"period_id,omitempty"`
+	TeacherID  int    `json:"teacher_id,omitempty" db:"teacher_id,omitempty"`
+	Class      string `json:"class,omitempty" db:"-"`
+	Subject    string `json:"subject,omitempty" db:"-"`
+	Note       string `json:"note,omitempty" db:"note,omitempty"`
+	AssignedBy int    `json:"assigned_by,omitempty" db:"assigned_by,omitempty"`
+	// SubstituteNotifiedAt and ClassNotifiedAt are empty until the mail to
+	// the substitute and to the class went out
+	SubstituteNotifiedAt string `json:"substitute_notified_at,omitempty" db:"substitute_notified_at,omitempty"`
+	ClassNotifiedAt      string `json:"class_notified_at,omitempty" db:"class_notified_at,omitempty"`
+}
+
+// CoverLesson is a lesson missed through leave: the timetable slot on a
+// date, with its substitution or, while uncovered, suggested substitutes.
+type CoverLesson struct {
+	Date         string                `json:"date"`
+	Slot         TimetableSlot         `json:"slot"`
+	Substitution *Substitution         `json:"substitution,omitempty"`
+	Suggestions  []SubstituteCandidate `json:"suggestions,omitempty"`
+}
+
+// SubstituteCandidate is a teacher free to take a lesson.
+type SubstituteCandidate struct {
+	TeacherID      int    `json:"teacher_id"`
+	Name           string `json:"name"`
+	Subject        string `json:"subject,omitempty"`
+	SameSubject    bool   `json:"same_subject"`
+	KnowsClass     bool   `json:"knows_class"`
+	LessonsThatDay int    `json:"lessons_that_day"`
+}
+
+// AffectedLessons lists every lesson of the slots that falls between from
+// and to, day by day.
+func AffectedLessons(slots []TimetableSlot, from, to string) ([]CoverLesson, error) {
+	start, err := time.Parse(time.DateOnly, from)
+	if err != nil {
+		return nil, err
+	}
+	end, err := time.Parse(time.DateOnly, to)
+	if err != nil {
+		return nil, err
+	}
+
+	lessons := []CoverLesson{}
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		weekday := int(day.Weekday())
+		if weekday == 0 {
+			weekday = 7
+		}
+		for _, slot := range slots {
+			if slot.Weekday == weekday {
+				lessons = append(lessons, CoverLesson{Date: day.Format(time.DateOnly), Slot: slot})
+			}
+		}
+	}
+	return lessons, nil
+}
+
+// Staffing is what decides who can cover a lesson: all teachers, the whole
+// timetable, weekly unavailability, approved leave and substitutions
+// already assigned.
+type Staffing struct {
+	Teachers      []Teacher
+	Timetable     []TimetableSlot
+	Unavailable   []Unavailability
+	Leave         []LeaveRequest
+	Substitutions []Substitution
+}
+
+// SuggestSubstitutes returns the teachers free for the lesson, best first:
+// teachers of the same subject, then those who teach the class, then those
+// with the fewest lessons that day. A substitution of the lesson itself does
+// not make its teacher busy, so it can be reassigned.
+func (st Staffing) SuggestSubstitutes(lesson CoverLesson) []SubstituteCandidate {
+	slot := lesson.Slot
+	busy := map[int]bool{slot.TeacherID: true}
+	load := make(map[int]int)
+	knows := make(map[int]bool)
+
+	for _, other := range st.Timetable {
+		if other.Class == slot.Class {
+			knows[other.TeacherID] = true
+		}
+		if other.Weekday != slot.Weekday {
+			continue
+		}
+		load[other.TeacherID]++
+		if other.PeriodID == slot.PeriodID {
+			busy[other.TeacherID] = true
+		}
+	}
+	for _, u := range st.Unavailable {
+		if u.Weekday == slot.Weekday && (u.PeriodID == 0 || u.PeriodID == slot.PeriodID) {
+			busy[u.TeacherID] = true
+		}
+	}
+	for _, leave := range st.Leave {
+		if leave.Status == LeaveApproved && leave.StartDate <= lesson.Date && lesson.Date <= leave.EndDate {
+			busy[leave.TeacherID] = true
+		}
+	}
+	for _, sub := range st.Substitutions {
+		if sub.Date != lesson.Date || sub.SlotID == slot.ID {
+			continue
+		}
+		load[sub.TeacherID]++
+		if sub.PeriodID == slot.PeriodID {
+			busy[sub.TeacherID] = true
+		}
+	}
+
+	candidates := []SubstituteCandidate{}
+	for _, teacher := range st.Teachers {
+		if busy[teacher.ID] {
+			continue
+		}
+		candidates = append(candidates, SubstituteCandidate{
+			TeacherID:      teacher.ID,
+			Name:           strings.TrimSpace(teacher.FirstName + " " + teacher.LastName),
+			Subject:        teacher.Subject,
+			SameSubject:    strings.EqualFold(teacher.Subject, slot.Subject),
+			KnowsClass:     knows[teacher.ID] || teacher.Class == slot.Class,
+			LessonsThatDay: load[teacher.ID],
+		})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.SameSubject != b.SameSubject {
+			return a.SameSubject
+		}
+		if a.KnowsClass != b.KnowsClass {
+			return a.KnowsClass
+		}
+		if a.LessonsThatDay != b.LessonsThatDay {
+			return a.LessonsThatDay < b.LessonsThatDay
+		}
+		return a.Name < b.Name
+	})
+	return candidates
+}
@@ -0,0 +1,99 @@
+package models
+
+import "testing"
+
+func TestLeaveStatusCanMove(t *testing.T) {
+	tests := []struct {
+		from, to LeaveStatus
+		want     bool
+	}{
+		{LeavePending, LeaveApproved, true},
+		{LeavePending, LeaveRejected, true},
+		{LeavePending, LeaveCancelled, true},
+		{LeaveApproved, LeaveCancelled, true},
+		// Одобренный отпуск можно только отменить
+		{LeaveApproved, LeaveRejected, false},
+		{LeaveRejected, LeaveApproved, false},
+		{LeaveCancelled, LeavePending, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.from.CanMove(tt.to); got != tt.want {
+			t.Errorf("%s -> %s = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestAffectedLessons(t *testing.T) {
+	slots := []TimetableSlot{
+		{ID: 1, Weekday: 1, PeriodID: 1},
+		{ID: 2, Weekday: 3, PeriodID: 2},
+	}
+
+	// 2024-09-02 — понедельник; отпуск на неделю с понедельника до понедельника
+	lessons, err := AffectedLessons(slots, "2024-09-02", "2024-09-09")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		date string
+		slot int
+	}{
+		{"2024-09-02", 1},
+		{"2024-09-04", 2},
+		{"2024-09-09", 1},
+	}
+	if len(lessons) != len(want) {
+		t.Fatalf("got %d lessons, want %d: %+v", len(lessons), len(want), lessons)
+	}
+	for i, lesson := range lessons {
+		if lesson.Date != want[i].date || lesson.Slot.ID != want[i].slot {
+			t.Errorf("lesson %d = %s slot %d, want %s slot %d", i, lesson.Date, lesson.Slot.ID, want[i].date, want[i].slot)
+		}
+	}
+}
+
+func TestSuggestSubstitutes(t *testing.T) {
+	lesson := CoverLesson{Date: "2024-09-02", Slot: TimetableSlot{ID: 10, Weekday: 1, PeriodID: 2, Class: "10A", Subject: "math", TeacherID: 1}}
+	staffing := Staffing{
+		Teachers: []Teacher{
+			{ID: 1, FirstName: "Absent", Subject: "math"},
+			{ID: 2, FirstName: "Busy", Subject: "math"},
+			{ID: 3, FirstName: "Free", Subject: "math"},
+			{ID: 4, FirstName: "Class", Subject: "art"},
+			{ID: 5, FirstName: "Other", Subject: "art"},
+			{ID: 6, FirstName: "Away", Subject: "math"},
+			{ID: 7, FirstName: "Covering", Subject: "math"},
+			{ID: 8, FirstName: "Blocked", Subject: "math"},
+			{ID: 9, FirstName: "Loaded", Subject: "math"},
+		},
+		Timetable: []TimetableSlot{
+			{ID: 11, Weekday: 1, PeriodID: 2, Class: "10B", TeacherID: 2},
+			{ID: 12, Weekday: 2, PeriodID: 2, Class: "10A", TeacherID: 4},
+			{ID: 13, Weekday: 1, PeriodID: 1, Class: "9A", TeacherID: 9},
+			{ID: 14, Weekday: 1, PeriodID: 3, Class: "9A", TeacherID: 9},
+		},
+		Unavailable: []Unavailability{{TeacherID: 8, Weekday: 1}},
+		Leave:       []LeaveRequest{{TeacherID: 6, StartDate: "2024-09-01", EndDate: "2024-09-05", Status: LeaveApproved}},
+		Substitutions: []Substitution{
+			{SlotID: 15, Date: "2024-09-02", PeriodID: 2, TeacherID: 7},
+			// Замена этого же урока не делает учителя занятым
+			{SlotID: 10, Date: "2024-09-02", PeriodID: 2, TeacherID: 3},
+		},
+	}
+
+	got := staffing.SuggestSubstitutes(lesson)
+	want := []int{3, 9, 4, 5}
+	if len(got) != len(want) {
+		t.Fatalf("got %+v, want teachers %v", got, want)
+	}
+	for i, candidate := range got {
+		if candidate.TeacherID != want[i] {
+			t.Errorf("candidate %d = teacher %d, want %d", i, candidate.TeacherID, want[i])
+		}
+	}
+	if !got[2].KnowsClass || got[1].LessonsThatDay != 2 {
+		t.Errorf("unexpected details: %+v", got)
+	}
+}
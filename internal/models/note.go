@@ -0,0 +1,213 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Contact types notes, record changes and timelines are kept for.
+const (
+	ContactStudent  = "student"
+	ContactTeacher  = "teacher"
+	ContactGuardian = "guardian"
+)
+
+var NoteTypes = []string{"note", "call", "meeting", "incident"}
+
+type NoteVisibility string
+
+const (
+	// VisibleToStaff notes are read by every role
+	VisibleToStaff NoteVisibility = "staff"
+	// VisibleToManagers notes are read by admins and managers
+	VisibleToManagers NoteVisibility = "managers"
+	// VisibleToAuthor notes are read by their author and admins
+	VisibleToAuthor NoteVisibility = "private"
+)
+
+// Note is an interaction recorded against a student, teacher or guardian.
+type Note struct {
+	ID          int            `json:"id,omitempty" db:"id,omitempty"`
+	SubjectType string         `json:"subject_type,omitempty" db:"subject_type,omitempty"`
+	SubjectID   int            `json:"subject_id,omitempty" db:"subject_id,omitempty"`
+	Type        string         `json:"type,omitempty" db:"type,omitempty" sanitize:"strict"`
+	Body        string         `json:"body,omitempty" db:"body,omitempty"`
+	Visibility  NoteVisibility `json:"visibility,omitempty" db:"visibility,omitempty" sanitize:"strict"`
+	Tags        []string       `json:"tags,omitempty" db:"-"`
+	AuthorID    int            `json:"author_id,omitempty" db:"author_id,omitempty"`
+	CreatedAt   string         `json:"created_at,omitempty" db:"created_at,omitempty"`
+	UpdatedAt   string         `json:"updated_at,omitempty" db:"updated_at,omitempty"`
+}
+
+// VisibleTo reports whether a user with the role and id may read the note.
+func (n Note) VisibleTo(role string, userID int) bool {
+	switch n.Visibility {
+	case VisibleToStaff:
+		return true
+	case VisibleToManagers:
+		return role == "admin" || role == "manager"
+	case VisibleToAuthor:
+		return role == "admin" || n.AuthorID == userID
+	}
+	return false
+}
+
+// Visibilities lists the note visibilities a role may read; private notes
+// are filtered by author separately.
+func Visibilities(role string) []NoteVisibility {
+	switch role {
+	case "admin":
+		return []NoteVisibility{VisibleToStaff, VisibleToManagers, VisibleToAuthor}
+	case "manager":
+		return []NoteVisibility{VisibleToStaff, VisibleToManagers}
+	}
+	return []NoteVisibility{VisibleToStaff}
+}
+
+const maxTags = 20
+
+// NormalizeTags lowercases and trims the tags and drops duplicates. Tags are
+// up to 50 letters, digits, "-" or "_".
+func NormalizeTags(tags []string) ([]string, error) {
+	normalized := []string{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" {
+			continue
+		}
+		if utf8.RuneCountInString(tag) > 50 || strings.ContainsFunc(tag, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_'
+		}) {
+			return nil, fmt.Errorf("invalid tag %q", tag)
+		}
+		if !slices.Contains(normalized, tag) {
+			normalized = append(normalized, tag)
+		}
+	}
+	if len(normalized) > maxTags {
+		return nil, fmt.Errorf("at most %d tags", maxTags)
+	}
+	sort.Strings(normalized)
+	return normalized, nil
+}
+
+// TagCount is a tag with the number of notes carrying it.
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// ErrNoteForbidden means the user may neither edit nor delete the note.
+var ErrNoteForbidden = errors.New("only the author or an admin may change the note")
+
+// Record change actions.
+const (
+	ActionCreated = "created"
+	ActionUpdated = "updated"
+	ActionDeleted = "deleted"
+)
+
+// FieldChange is the old and new value of one field.
+type FieldChange struct {
+	From any `json:"from"`
+	To   any `json:"to"`
+}
+
+// RecordChange is an audit entry of a contact being created, updated or
+// deleted, with the fields that changed.
+type RecordChange struct {
+	ID          int                    `json:"id"`
+	SubjectType string                 `json:"subject_type"`
+	SubjectID   int                    `json:"subject_id"`
+	Action      string                 `json:"action"`
+	Changes     map[string]FieldChange `json:"changes,omitempty"`
+	ChangedBy   int                    `json:"changed_by,omitempty"`
+	ChangedAt   string                 `json:"changed_at"`
+}
+
+// Diff compares two values of the same struct type field by field and
+// returns the changed fields by JSON name, leaving out the id and fields not
+// stored in the row (db:"-"). Either value may be nil, e.g. the missing
+// "before" of a created record.
+func Diff(before, after any) map[string]FieldChange {
+	changes := map[string]FieldChange{}
+	value := before
+	if value == nil {
+		value = after
+	}
+	if value == nil {
+		return changes
+	}
+	typ := reflect.TypeOf(value)
+	if typ.Kind() != reflect.Struct {
+		return changes
+	}
+	field := func(v any, i int) any {
+		if v == nil {
+			return reflect.Zero(typ.Field(i).Type).Interface()
+		}
+		return reflect.ValueOf(v).Field(i).Interface()
+	}
+
+	for i := 0; i < typ.NumField(); i++ {
+		name, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+		if !typ.Field(i).IsExported() || name == "-" || name == "id" || typ.Field(i).Tag.Get("db") == "-" {
+			continue
+		}
+		if name == "" {
+			name = typ.Field(i).Name
+		}
+		from, to := field(before, i), field(after, i)
+		if !reflect.DeepEqual(from, to) {
+			changes[name] = FieldChange{From: from, To: to}
+		}
+	}
+	return changes
+}
+
+// SentEmail is one recipient of an email the API sent.
+type SentEmail struct {
+	ID        int    `json:"id"`
+	Recipient string `json:"recipient"`
+	Subject   string `json:"subject"`
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+	SentAt    string `json:"sent_at"`
+}
+
+// ContactEvent is a note, record change or email on a contact's timeline.
+type ContactEvent struct {
+	Kind   string        `json:"kind"`
+	At     string        `json:"at"`
+	Note   *Note         `json:"note,omitempty"`
+	Change *RecordChange `json:"change,omitempty"`
+	Email  *SentEmail    `json:"email,omitempty"`
+}
+
+// ContactTimeline merges notes, record changes and emails, newest first, and
+// keeps at most limit events.
+func ContactTimeline(notes []Note, changes []RecordChange, emails []SentEmail, limit int) []ContactEvent {
+	events := make([]ContactEvent, 0, len(notes)+len(changes)+len(emails))
+	for i := range notes {
+		events = append(events, ContactEvent{Kind: "note", At: notes[i].CreatedAt, Note: &notes[i]})
+	}
+	for i := range changes {
+		events = append(events, ContactEvent{Kind: "change", At: changes[i].ChangedAt, Change: &changes[i]})
+	}
+	for i := range emails {
+		events = append(events, ContactEvent{Kind: "email", At: emails[i].SentAt, Email: &emails[i]})
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].At > events[j].At
+	})
+	if limit > 0 && len(events) > limit {
+		events = events[:limit]
+	}
+	return events
+}
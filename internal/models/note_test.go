@@ -0,0 +1,120 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	tests := []struct {
+		name    string
+		tags    []string
+		want    []string
+		wantErr bool
+	}{
+		{"sorted and unique", []string{" Follow-Up", "behaviour", "follow-up", ""}, []string{"behaviour", "follow-up"}, false},
+		// Кириллица допустима
+		{"cyrillic", []string{"Родители"}, []string{"родители"}, false},
+		{"none", nil, []string{}, false},
+		{"space inside", []string{"two words"}, nil, true},
+		{"punctuation", []string{"a,b"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeTags(tt.tags)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NormalizeTags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NormalizeTags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	many := make([]string, maxTags+1)
+	for i := range many {
+		many[i] = string(rune('a' + i))
+	}
+	if _, err := NormalizeTags(many); err == nil {
+		t.Errorf("NormalizeTags() accepted %d tags", len(many))
+	}
+}
+
+func TestNoteVisibleTo(t *testing.T) {
+	tests := []struct {
+		visibility NoteVisibility
+		role       string
+		userID     int
+		want       bool
+	}{
+		{VisibleToStaff, "exec", 2, true},
+		{VisibleToManagers, "exec", 2, false},
+		{VisibleToManagers, "manager", 2, true},
+		// Личную заметку видят автор и admin
+		{VisibleToAuthor, "manager", 2, false},
+		{VisibleToAuthor, "exec", 1, true},
+		{VisibleToAuthor, "admin", 3, true},
+	}
+
+	for _, tt := range tests {
+		note := Note{Visibility: tt.visibility, AuthorID: 1}
+		if got := note.VisibleTo(tt.role, tt.userID); got != tt.want {
+			t.Errorf("%s note VisibleTo(%s, %d) = %v, want %v", tt.visibility, tt.role, tt.userID, got, tt.want)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	before := Student{ID: 1, FirstName: "Anna", LastName: "Ivanova", Email: "anna@example.com", Class: "10A"}
+	after := before
+	after.Class = "10B"
+
+	tests := []struct {
+		name          string
+		before, after any
+		want          map[string]FieldChange
+	}{
+		{"updated", before, after, map[string]FieldChange{"class": {From: "10A", To: "10B"}}},
+		{"unchanged", before, before, map[string]FieldChange{}},
+		// Созданная запись сравнивается с пустой, id не попадает в изменения
+		{"created", nil, Teacher{ID: 5, FirstName: "Oleg", Subject: "math"}, map[string]FieldChange{
+			"first_name": {From: "", To: "Oleg"},
+			"subject":    {From: "", To: "math"},
+		}},
+		{"deleted", Guardian{ID: 3, Phone: "123"}, nil, map[string]FieldChange{"phone": {From: "123", To: ""}}},
+		// Произвольные поля хранятся отдельно и пишутся в историю сами
+		{"custom skipped", before, Student{ID: 1, FirstName: "Anna", LastName: "Ivanova", Email: "anna@example.com", Class: "10A", Custom: map[string]any{"bus_route": "12"}}, map[string]FieldChange{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Diff(tt.before, tt.after); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diff() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContactTimeline(t *testing.T) {
+	notes := []Note{{ID: 1, CreatedAt: "2024-09-02 10:00:00"}, {ID: 2, CreatedAt: "2024-09-04 09:00:00"}}
+	changes := []RecordChange{{ID: 7, ChangedAt: "2024-09-03 12:00:00"}}
+	emails := []SentEmail{{ID: 9, SentAt: "2024-09-01 08:00:00"}}
+
+	events := ContactTimeline(notes, changes, emails, 0)
+	var kinds []string
+	for _, event := range events {
+		kinds = append(kinds, event.Kind)
+	}
+	if want := []string{"note", "change", "note", "email"}; !reflect.DeepEqual(kinds, want) {
+		t.Fatalf("kinds = %v, want %v", kinds, want)
+	}
+	if events[0].Note.ID != 2 || events[1].Change.ID != 7 || events[3].Email.ID != 9 {
+		t.Errorf("events are not newest first: %+v", events)
+	}
+
+	// Лимит оставляет самые новые события
+	if got := ContactTimeline(notes, changes, emails, 2); len(got) != 2 || got[1].Kind != "change" {
+		t.Errorf("limited timeline = %+v", got)
+	}
+}
@@ -0,0 +1,58 @@
+package models
+
+// ReportComment is a teacher's remark for a student's term report; an empty
+// Subject is the general comment.
+type ReportComment struct {
+	ID        int    `json:"id,omitempty" db:"id,omitempty"`
+	StudentID int    `json:"student_id,omitempty" db:"student_id,omitempty"`
+	Term      string `json:"term,omitempty" db:"term,omitempty" sanitize:"strict"`
+	Subject   string `json:"subject,omitempty" db:"subject,omitempty" sanitize:"strict"`
+	Comment   string `json:"comment,omitempty" db:"comment,omitempty"`
+	AuthorID  int    `json:"author_id,omitempty" db:"author_id,omitempty"`
+	UpdatedAt string `json:"updated_at,omitempty" db:"updated_at,omitempty"`
+}
+
+type ReportSubject struct {
+	SubjectAverage
+	Comment string `json:"comment,omitempty"`
+}
+
+// ReportCard is everything printed on one student's term report.
+type ReportCard struct {
+	Student    Student           `json:"student"`
+	Term       string            `json:"term"`
+	From       string            `json:"from"`
+	To         string            `json:"to"`
+	Subjects   []ReportSubject   `json:"subjects"`
+	Overall    float64           `json:"overall"`
+	Attendance AttendanceSummary `json:"attendance"`
+	Comment    string            `json:"comment,omitempty"`
+}
+
+// NewReportCard averages the entries per subject and attaches the comments,
+// keyed by subject ("" for the general one). Overall is the plain mean of the
+// subject averages.
+func NewReportCard(student Student, term, from, to string, entries []GradeEntry, attendance []Attendance, comments map[string]string, scale GradeScale) ReportCard {
+	card := ReportCard{
+		Student:    student,
+		Term:       term,
+		From:       from,
+		To:         to,
+		Attendance: AttendanceSummary{StudentID: student.ID, From: from, To: to},
+		Comment:    comments[""],
+	}
+
+	var sum float64
+	for _, average := range SubjectAverages(entries, scale) {
+		card.Subjects = append(card.Subjects, ReportSubject{SubjectAverage: average, Comment: comments[average.Subject]})
+		sum += average.Average
+	}
+	if len(card.Subjects) > 0 {
+		card.Overall = round2(sum / float64(len(card.Subjects)))
+	}
+
+	for _, record := range attendance {
+		card.Attendance.Add(record)
+	}
+	return card
+}
@@ -0,0 +1,25 @@
+package models
+
+import "testing"
+
+func TestNewReportCard(t *testing.T) {
+	entries := []GradeEntry{
+		{Assessment: Assessment{Subject: "math", MaxScore: 10, Weight: 1}, Score: 9},
+		{Assessment: Assessment{Subject: "art", MaxScore: 10, Weight: 1}, Score: 7},
+	}
+	attendance := []Attendance{{Status: AttendancePresent}, {Status: AttendanceAbsent}}
+	comments := map[string]string{"math": "Excellent", "": "Good term"}
+
+	card := NewReportCard(Student{ID: 5}, "Autumn", "2024-09-01", "2024-12-31", entries, attendance, comments, testScale)
+
+	if len(card.Subjects) != 2 || card.Subjects[0].Comment != "Excellent" || card.Subjects[1].Comment != "" {
+		t.Errorf("subjects = %+v", card.Subjects)
+	}
+	// Итог — среднее по предметам: (90 + 70) / 2
+	if card.Overall != 80 {
+		t.Errorf("Overall = %v, want 80", card.Overall)
+	}
+	if card.Attendance.Sessions != 2 || card.Attendance.StudentID != 5 || card.Comment != "Good term" {
+		t.Errorf("card = %+v", card)
+	}
+}
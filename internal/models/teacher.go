@@ -4,7 +4,9 @@ type Teacher struct {
 	ID        int    `json:"id,omitempty" db:"id,omitempty"`
 	FirstName string `json:"first_name,omitempty" db:"first_name,omitempty"`
 	LastName  string `json:"last_name,omitempty" db:"last_name,omitempty"`
-	Email     string `json:"email,omitempty" db:"email,omitempty"`
-	Class     string `json:"class,omitempty" db:"class,omitempty"`
+	Email     string `json:"email,omitempty" db:"email,omitempty" sanitize:"strict"`
+	Class     string `json:"class,omitempty" db:"class,omitempty" sanitize:"strict"`
 	Subject   string `json:"subject,omitempty" db:"subject,omitempty"`
+	// Custom holds the values of the custom fields the caller may see
+	Custom map[string]any `json:"custom,omitempty" db:"-"`
 }
@@ -0,0 +1,159 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Period is a lesson of the school day, such as "1" from 08:30 to 09:15.
+type Period struct {
+	ID        int    `json:"id,omitempty" db:"id,omitempty"`
+	Name      string `json:"name,omitempty" db:"name,omitempty" sanitize:"strict"`
+	StartTime string `json:"start_time,omitempty" db:"start_time,omitempty"`
+	EndTime   string `json:"end_time,omitempty" db:"end_time,omitempty"`
+}
+
+type Room struct {
+	ID       int    `json:"id,omitempty" db:"id,omitempty"`
+	Name     string `json:"name,omitempty" db:"name,omitempty" sanitize:"strict"`
+	Capacity int    `json:"capacity,omitempty" db:"capacity,omitempty"`
+}
+
+// TimetableSlot is a weekly lesson: a class learns a subject with a teacher
+// in a room on a weekday (1 Monday ... 7 Sunday) during a period. RoomID 0
+// means no room. The names are filled in when slots are read.
+type TimetableSlot struct {
+	ID          int    `json:"id,omitempty" db:"id,omitempty"`
+	Weekday     int    `json:"weekday,omitempty" db:"weekday,omitempty"`
+	PeriodID    int    `json:"period_id,omitempty" db:"period_id,omitempty"`
+	Class       string `json:"class,omitempty" db:"class,omitempty" sanitize:"strict"`
+	Subject     string `json:"subject,omitempty" db:"subject,omitempty"`
+	TeacherID   int    `json:"teacher_id,omitempty" db:"teacher_id,omitempty"`
+	RoomID      int    `json:"room_id,omitempty" db:"room_id,omitempty"`
+	PeriodName  string `json:"period_name,omitempty" db:"-"`
+	StartTime   string `json:"start_time,omitempty" db:"-"`
+	EndTime     string `json:"end_time,omitempty" db:"-"`
+	TeacherName string `json:"teacher_name,omitempty" db:"-"`
+	RoomName    string `json:"room_name,omitempty" db:"-"`
+}
+
+// Unavailability blocks a teacher on a weekday, for one period or, with
+// PeriodID 0, the whole day.
+type Unavailability struct {
+	ID        int    `json:"id,omitempty" db:"id,omitempty"`
+	TeacherID int    `json:"teacher_id,omitempty" db:"teacher_id,omitempty"`
+	Weekday   int    `json:"weekday,omitempty" db:"weekday,omitempty"`
+	PeriodID  int    `json:"period_id,omitempty" db:"period_id,omitempty"`
+	Reason    string `json:"reason,omitempty" db:"reason,omitempty"`
+}
+
+type ConflictKind string
+
+const (
+	ConflictTeacher     ConflictKind = "teacher"
+	ConflictRoom        ConflictKind = "room"
+	ConflictClass       ConflictKind = "class"
+	ConflictUnavailable ConflictKind = "unavailable"
+)
+
+// Conflict is a clash between timetable slots, or between a slot and the
+// teacher's unavailability. SlotIDs lists the existing slots involved.
+type Conflict struct {
+	Kind     ConflictKind `json:"kind"`
+	Weekday  int          `json:"weekday"`
+	PeriodID int          `json:"period_id"`
+	SlotIDs  []int        `json:"slot_ids,omitempty"`
+	Message  string       `json:"message"`
+}
+
+// WeekdayName returns the English name of an ISO weekday (1 Monday ... 7 Sunday).
+func WeekdayName(weekday int) string {
+	return time.Weekday(weekday % 7).String()
+}
+
+// when describes the slot's weekday and period for messages.
+func (s TimetableSlot) when() string {
+	period := s.PeriodName
+	if period == "" {
+		period = fmt.Sprint(s.PeriodID)
+	}
+	return fmt.Sprintf("%s, period %s", WeekdayName(s.Weekday), period)
+}
+
+// Conflicts returns what keeps the slot from being scheduled next to the
+// others: the same teacher, room or class booked in the same weekday and
+// period, or the teacher being unavailable then. A slot never conflicts
+// with itself.
+func (s TimetableSlot) Conflicts(others []TimetableSlot, unavailable []Unavailability) []Conflict {
+	var conflicts []Conflict
+	for _, other := range others {
+		if (s.ID != 0 && other.ID == s.ID) || other.Weekday != s.Weekday || other.PeriodID != s.PeriodID {
+			continue
+		}
+		conflicts = append(conflicts, s.clashes(other)...)
+	}
+	for _, u := range unavailable {
+		if conflict, ok := s.unavailable(u); ok {
+			conflicts = append(conflicts, conflict)
+		}
+	}
+	return conflicts
+}
+
+// FindConflicts checks a whole timetable and returns every clash once.
+func FindConflicts(slots []TimetableSlot, unavailable []Unavailability) []Conflict {
+	conflicts := []Conflict{}
+	for i, slot := range slots {
+		for _, other := range slots[i+1:] {
+			if other.Weekday == slot.Weekday && other.PeriodID == slot.PeriodID {
+				conflicts = append(conflicts, slot.clashes(other)...)
+			}
+		}
+		for _, u := range unavailable {
+			if conflict, ok := slot.unavailable(u); ok {
+				conflict.SlotIDs = []int{slot.ID}
+				conflicts = append(conflicts, conflict)
+			}
+		}
+	}
+	return conflicts
+}
+
+// clashes compares two slots of the same weekday and period.
+func (s TimetableSlot) clashes(other TimetableSlot) []Conflict {
+	var conflicts []Conflict
+	ids := []int{other.ID}
+	if s.ID != 0 {
+		ids = []int{s.ID, other.ID}
+	}
+	if other.TeacherID == s.TeacherID {
+		conflicts = append(conflicts, Conflict{
+			Kind: ConflictTeacher, Weekday: s.Weekday, PeriodID: s.PeriodID, SlotIDs: ids,
+			Message: fmt.Sprintf("teacher %d already teaches %s in %s on %s", s.TeacherID, other.Subject, other.Class, s.when()),
+		})
+	}
+	if s.RoomID != 0 && other.RoomID == s.RoomID {
+		conflicts = append(conflicts, Conflict{
+			Kind: ConflictRoom, Weekday: s.Weekday, PeriodID: s.PeriodID, SlotIDs: ids,
+			Message: fmt.Sprintf("room %d is already taken by %s on %s", s.RoomID, other.Class, s.when()),
+		})
+	}
+	if other.Class == s.Class {
+		conflicts = append(conflicts, Conflict{
+			Kind: ConflictClass, Weekday: s.Weekday, PeriodID: s.PeriodID, SlotIDs: ids,
+			Message: fmt.Sprintf("class %s already has %s on %s", s.Class, other.Subject, s.when()),
+		})
+	}
+	return conflicts
+}
+
+func (s TimetableSlot) unavailable(u Unavailability) (Conflict, bool) {
+	if u.TeacherID != s.TeacherID || u.Weekday != s.Weekday || (u.PeriodID != 0 && u.PeriodID != s.PeriodID) {
+		return Conflict{}, false
+	}
+	message := fmt.Sprintf("teacher %d is unavailable on %s", s.TeacherID, s.when())
+	if u.Reason != "" {
+		message += ": " + u.Reason
+	}
+	return Conflict{Kind: ConflictUnavailable, Weekday: s.Weekday, PeriodID: s.PeriodID, Message: message}, true
+}
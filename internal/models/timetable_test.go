@@ -0,0 +1,73 @@
+package models
+
+import "testing"
+
+func TestSlotConflicts(t *testing.T) {
+	existing := []TimetableSlot{
+		{ID: 1, Weekday: 1, PeriodID: 1, Class: "10A", Subject: "math", TeacherID: 7, RoomID: 3},
+		{ID: 2, Weekday: 1, PeriodID: 2, Class: "10B", Subject: "physics", TeacherID: 8, RoomID: 4},
+	}
+	unavailable := []Unavailability{
+		{TeacherID: 9, Weekday: 2, Reason: "course"},
+		{TeacherID: 9, Weekday: 3, PeriodID: 1},
+	}
+
+	tests := []struct {
+		name string
+		slot TimetableSlot
+		want []ConflictKind
+	}{
+		{"free", TimetableSlot{Weekday: 1, PeriodID: 1, Class: "10B", TeacherID: 8, RoomID: 4}, nil},
+		{"teacher", TimetableSlot{Weekday: 1, PeriodID: 1, Class: "10B", TeacherID: 7, RoomID: 4}, []ConflictKind{ConflictTeacher}},
+		{"room and class", TimetableSlot{Weekday: 1, PeriodID: 1, Class: "10A", TeacherID: 8, RoomID: 3}, []ConflictKind{ConflictRoom, ConflictClass}},
+		// Занятия без кабинета не конфликтуют по кабинету
+		{"no room", TimetableSlot{Weekday: 1, PeriodID: 2, Class: "10A", TeacherID: 7}, nil},
+		// Слот не конфликтует сам с собой при обновлении
+		{"itself", TimetableSlot{ID: 1, Weekday: 1, PeriodID: 1, Class: "10A", TeacherID: 7, RoomID: 3}, nil},
+		{"whole day", TimetableSlot{Weekday: 2, PeriodID: 5, Class: "10A", TeacherID: 9}, []ConflictKind{ConflictUnavailable}},
+		{"one period", TimetableSlot{Weekday: 3, PeriodID: 1, Class: "10A", TeacherID: 9}, []ConflictKind{ConflictUnavailable}},
+		{"other period", TimetableSlot{Weekday: 3, PeriodID: 2, Class: "10A", TeacherID: 9}, nil},
+	}
+
+	for _, tt := range tests {
+		got := tt.slot.Conflicts(existing, unavailable)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i, conflict := range got {
+			if conflict.Kind != tt.want[i] {
+				t.Errorf("%s: conflict %d is %s, want %s", tt.name, i, conflict.Kind, tt.want[i])
+			}
+		}
+	}
+}
+
+func TestFindConflicts(t *testing.T) {
+	slots := []TimetableSlot{
+		{ID: 1, Weekday: 1, PeriodID: 1, Class: "10A", TeacherID: 7, RoomID: 3},
+		{ID: 2, Weekday: 1, PeriodID: 1, Class: "10B", TeacherID: 7, RoomID: 4},
+		{ID: 3, Weekday: 2, PeriodID: 1, Class: "10A", TeacherID: 7, RoomID: 3},
+	}
+	unavailable := []Unavailability{{TeacherID: 7, Weekday: 2, PeriodID: 1}}
+
+	got := FindConflicts(slots, unavailable)
+	if len(got) != 2 {
+		t.Fatalf("got %d conflicts, want 2: %v", len(got), got)
+	}
+	if got[0].Kind != ConflictTeacher || len(got[0].SlotIDs) != 2 || got[0].SlotIDs[0] != 1 || got[0].SlotIDs[1] != 2 {
+		t.Errorf("first conflict = %+v", got[0])
+	}
+	if got[1].Kind != ConflictUnavailable || len(got[1].SlotIDs) != 1 || got[1].SlotIDs[0] != 3 {
+		t.Errorf("second conflict = %+v", got[1])
+	}
+}
+
+func TestWeekdayName(t *testing.T) {
+	if got := WeekdayName(1); got != "Monday" {
+		t.Errorf("WeekdayName(1) = %s", got)
+	}
+	if got := WeekdayName(7); got != "Sunday" {
+		t.Errorf("WeekdayName(7) = %s", got)
+	}
+}
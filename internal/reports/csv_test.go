@@ -0,0 +1,28 @@
+package reports
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestTable(t *testing.T) {
+	rows := [][]string{
+		{"1", "Anna", "12, north"},
+		// Формулы экранируются, отрицательные числа остаются числами
+		{"2", "=HYPERLINK(\"x\")", "-5"},
+		{"3", "@SUM(A1)", ""},
+	}
+
+	var buf bytes.Buffer
+	if err := Table(&buf, []string{"id", "first_name", "custom.bus_route"}, rows); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "id,first_name,custom.bus_route\n" +
+		"1,Anna,\"12, north\"\n" +
+		"2,\"'=HYPERLINK(\"\"x\"\")\",-5\n" +
+		"3,'@SUM(A1),\n"
+	if got := buf.String(); got != want {
+		t.Errorf("Table() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,111 @@
+package reports
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"restapi/internal/models"
+	"strings"
+	"time"
+)
+
+// Calendar writes the timetable slots as an iCalendar (RFC 5545) file of
+// weekly recurring events between from and to (YYYY-MM-DD). Times are local
+// school time without a time zone.
+func Calendar(w io.Writer, name string, slots []models.TimetableSlot, from, to string) error {
+	start, err := time.Parse(time.DateOnly, from)
+	if err != nil {
+		return err
+	}
+	end, err := time.Parse(time.DateOnly, to)
+	if err != nil {
+		return err
+	}
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+
+	out := bufio.NewWriter(w)
+	line := func(format string, args ...interface{}) {
+		out.WriteString(fold(fmt.Sprintf(format, args...)))
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//restapi//timetable//EN")
+	line("CALSCALE:GREGORIAN")
+	line("X-WR-CALNAME:%s", escapeText(name))
+	for _, slot := range slots {
+		day := firstWeekday(start, slot.Weekday)
+		if day.After(end) {
+			continue
+		}
+		begins, err := clock(slot.StartTime)
+		if err != nil {
+			return fmt.Errorf("slot %d: %w", slot.ID, err)
+		}
+		ends, err := clock(slot.EndTime)
+		if err != nil {
+			return fmt.Errorf("slot %d: %w", slot.ID, err)
+		}
+		date := day.Format("20060102")
+
+		line("BEGIN:VEVENT")
+		line("UID:timetable-slot-%d-%s@restapi", slot.ID, date)
+		line("DTSTAMP:%s", stamp)
+		line("DTSTART:%sT%s", date, begins)
+		line("DTEND:%sT%s", date, ends)
+		line("RRULE:FREQ=WEEKLY;UNTIL=%sT235959", end.Format("20060102"))
+		line("SUMMARY:%s", escapeText(slot.Subject+" "+slot.Class))
+		if slot.RoomName != "" {
+			line("LOCATION:%s", escapeText(slot.RoomName))
+		}
+		if slot.TeacherName != "" {
+			line("DESCRIPTION:%s", escapeText(slot.TeacherName))
+		}
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+	return out.Flush()
+}
+
+// firstWeekday returns the first day on or after start that falls on the ISO
+// weekday (1 Monday ... 7 Sunday).
+func firstWeekday(start time.Time, weekday int) time.Time {
+	offset := (weekday%7 - int(start.Weekday()) + 7) % 7
+	return start.AddDate(0, 0, offset)
+}
+
+// clock turns a TIME value ("08:30:00") into the iCalendar form "083000".
+func clock(value string) (string, error) {
+	t, err := time.Parse(time.TimeOnly, value)
+	if err != nil {
+		return "", fmt.Errorf("invalid time %q", value)
+	}
+	return t.Format("150405"), nil
+}
+
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+func escapeText(value string) string {
+	return textEscaper.Replace(value)
+}
+
+// fold ends a content line with CRLF, folding it into lines of at most 75
+// octets without splitting a UTF-8 character.
+func fold(line string) string {
+	var b strings.Builder
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		// The leading space of a continuation line counts too
+		limit = 74
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+	return b.String()
+}
@@ -0,0 +1,73 @@
+package reports
+
+import (
+	"bytes"
+	"restapi/internal/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCalendar(t *testing.T) {
+	slots := []models.TimetableSlot{
+		{ID: 1, Weekday: 3, Class: "10A", Subject: "math", StartTime: "08:30:00", EndTime: "09:15:00", RoomName: "101, east", TeacherName: "Ann Lee"},
+		// 2024-09-02 — понедельник, поэтому первое воскресенье уже за пределами периода
+		{ID: 2, Weekday: 7, Class: "10A", Subject: "art", StartTime: "10:00:00", EndTime: "10:45:00"},
+	}
+
+	var buf bytes.Buffer
+	if err := Calendar(&buf, "10A", slots, "2024-09-02", "2024-09-07"); err != nil {
+		t.Fatal(err)
+	}
+	ics := buf.String()
+
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"DTSTART:20240904T083000\r\n",
+		"DTEND:20240904T091500\r\n",
+		"RRULE:FREQ=WEEKLY;UNTIL=20240907T235959\r\n",
+		"SUMMARY:math 10A\r\n",
+		"LOCATION:101\\, east\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(ics, want) {
+			t.Errorf("calendar lacks %q:\n%s", want, ics)
+		}
+	}
+	if strings.Count(ics, "BEGIN:VEVENT") != 1 {
+		t.Errorf("expected one event:\n%s", ics)
+	}
+}
+
+func TestFold(t *testing.T) {
+	// Кириллица занимает два байта, строку нельзя резать посреди символа
+	line := "SUMMARY:" + strings.Repeat("ж", 100)
+	folded := fold(line)
+
+	for _, part := range strings.Split(strings.TrimSuffix(folded, "\r\n"), "\r\n") {
+		if len(part) > 75 {
+			t.Errorf("line of %d octets: %q", len(part), part)
+		}
+	}
+	if unfolded := strings.ReplaceAll(strings.TrimSuffix(folded, "\r\n"), "\r\n ", ""); unfolded != line {
+		t.Errorf("unfolded line differs: %q", unfolded)
+	}
+}
+
+func TestFirstWeekday(t *testing.T) {
+	tests := []struct {
+		weekday int
+		want    string
+	}{
+		{1, "2024-09-02"},
+		{3, "2024-09-04"},
+		{7, "2024-09-08"},
+	}
+
+	for _, tt := range tests {
+		start, _ := time.Parse(time.DateOnly, "2024-09-02")
+		if got := firstWeekday(start, tt.weekday).Format("2006-01-02"); got != tt.want {
+			t.Errorf("firstWeekday(%d) = %s, want %s", tt.weekday, got, tt.want)
+		}
+	}
+}
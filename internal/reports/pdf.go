@@ -0,0 +1,221 @@
+package reports
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// A4 in PDF points.
+const (
+	PageWidth  = 595.28
+	PageHeight = 841.89
+)
+
+// Document is a minimal PDF writer: text, lines and filled rectangles on A4
+// pages. Text uses the embedded TrueType font when one is set, otherwise the
+// built-in Helvetica, which only covers WinAnsi (Latin) characters.
+type Document struct {
+	font  *Font
+	pages []*Page
+	used  map[uint16]rune
+}
+
+type Page struct {
+	doc     *Document
+	content bytes.Buffer
+}
+
+func NewDocument(font *Font) *Document {
+	return &Document{font: font, used: make(map[uint16]rune)}
+}
+
+func (d *Document) AddPage() *Page {
+	page := &Page{doc: d}
+	d.pages = append(d.pages, page)
+	return page
+}
+
+// TextWidth returns the width of s in points.
+func (d *Document) TextWidth(s string, size float64, bold bool) float64 {
+	var units float64
+	if d.font != nil {
+		for _, r := range s {
+			units += d.font.width(d.font.glyph(r))
+		}
+	} else {
+		for _, b := range winAnsi(s) {
+			units += helveticaWidth(b, bold)
+		}
+	}
+	return units * size / 1000
+}
+
+// Text draws s with its baseline starting at x, y (origin at the bottom left).
+func (p *Page) Text(x, y, size float64, bold bool, s string) {
+	if s == "" {
+		return
+	}
+	var text string
+	font := "/F1"
+	if p.doc.font != nil {
+		text = p.doc.glyphHex(s)
+	} else {
+		text = "(" + escapeString(winAnsi(s)) + ")"
+		if bold {
+			font = "/F2"
+		}
+	}
+
+	p.content.WriteString("BT\n")
+	if bold && p.doc.font != nil {
+		// A TrueType font has a single face; bold is drawn by also stroking the outline
+		fmt.Fprintf(&p.content, "2 Tr %.2f w\n", size*0.03)
+	}
+	fmt.Fprintf(&p.content, "%s %.2f Tf %.2f %.2f Td %s Tj\nET\n", font, size, x, y, text)
+	if bold && p.doc.font != nil {
+		p.content.WriteString("0 Tr\n")
+	}
+}
+
+// TextRight draws s so that it ends at x.
+func (p *Page) TextRight(x, y, size float64, bold bool, s string) {
+	p.Text(x-p.doc.TextWidth(s, size, bold), y, size, bold, s)
+}
+
+// TextCenter draws s centered on x.
+func (p *Page) TextCenter(x, y, size float64, bold bool, s string) {
+	p.Text(x-p.doc.TextWidth(s, size, bold)/2, y, size, bold, s)
+}
+
+func (p *Page) Line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(&p.content, "%.2f w %.2f %.2f m %.2f %.2f l S\n", width, x1, y1, x2, y2)
+}
+
+// FillRect fills a rectangle with a gray level from 0 (black) to 1 (white).
+func (p *Page) FillRect(x, y, w, h, gray float64) {
+	fmt.Fprintf(&p.content, "q %.2f g %.2f %.2f %.2f %.2f re f Q\n", gray, x, y, w, h)
+}
+
+// glyphHex encodes s as 2-byte glyph IDs and remembers them for the font widths.
+func (d *Document) glyphHex(s string) string {
+	var b strings.Builder
+	b.WriteByte('<')
+	for _, r := range s {
+		gid := d.font.glyph(r)
+		if _, ok := d.used[gid]; !ok {
+			d.used[gid] = r
+		}
+		fmt.Fprintf(&b, "%04X", gid)
+	}
+	b.WriteByte('>')
+	return b.String()
+}
+
+// Bytes serializes the document.
+func (d *Document) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := d.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	if len(d.pages) == 0 {
+		d.AddPage()
+	}
+
+	var objects [][]byte
+	add := func(body []byte) int {
+		objects = append(objects, body)
+		return len(objects)
+	}
+	reserve := func() int { return add(nil) }
+
+	catalog := reserve()
+	pages := reserve()
+
+	var fonts string
+	if d.font != nil {
+		fontID, err := d.writeTrueType(add)
+		if err != nil {
+			return 0, err
+		}
+		fonts = fmt.Sprintf("/F1 %d 0 R", fontID)
+	} else {
+		regular := add([]byte("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>"))
+		bold := add([]byte("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>"))
+		fonts = fmt.Sprintf("/F1 %d 0 R /F2 %d 0 R", regular, bold)
+	}
+
+	kids := make([]string, 0, len(d.pages))
+	for _, page := range d.pages {
+		stream, err := deflate(page.content.Bytes())
+		if err != nil {
+			return 0, err
+		}
+		content := add(streamObject("", stream))
+		pageID := add([]byte(fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << %s >> >> /Contents %d 0 R >>",
+			pages, PageWidth, PageHeight, fonts, content)))
+		kids = append(kids, fmt.Sprintf("%d 0 R", pageID))
+	}
+	objects[catalog-1] = []byte(fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pages))
+	objects[pages-1] = []byte(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids)))
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(objects))
+	for i, body := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n", i+1)
+		buf.Write(body)
+		buf.WriteString("\nendobj\n")
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, catalog, xref)
+
+	n, err := w.Write(buf.Bytes())
+	return int64(n), err
+}
+
+// streamObject wraps Flate-compressed data; extra goes into the stream dictionary.
+func streamObject(extra string, data []byte) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "<< /Length %d /Filter /FlateDecode%s >>\nstream\n", len(data), extra)
+	buf.Write(data)
+	buf.WriteString("\nendstream")
+	return buf.Bytes()
+}
+
+func deflate(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func escapeString(s []byte) string {
+	var b strings.Builder
+	for _, c := range s {
+		switch c {
+		case '(', ')', '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,107 @@
+package reports
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+func TestDocumentStructure(t *testing.T) {
+	doc := NewDocument(nil)
+	doc.AddPage().Text(50, 700, 12, false, "Hello (world)")
+	doc.AddPage().Text(50, 700, 12, true, "Page two")
+
+	pdf, err := doc.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(pdf, []byte("%PDF-1.4")) || !bytes.HasSuffix(pdf, []byte("%%EOF\n")) {
+		t.Fatal("missing PDF header or trailer")
+	}
+
+	// Каждая запись xref должна указывать на начало своего объекта
+	start, err := strconv.Atoi(string(regexp.MustCompile(`startxref\n(\d+)`).FindSubmatch(pdf)[1]))
+	if err != nil || !bytes.HasPrefix(pdf[start:], []byte("xref")) {
+		t.Fatalf("startxref does not point at the xref table")
+	}
+	offsets := regexp.MustCompile(`(\d{10}) 00000 n`).FindAllSubmatch(pdf, -1)
+	for i, match := range offsets {
+		offset, _ := strconv.Atoi(string(match[1]))
+		if want := fmt.Sprintf("%d 0 obj", i+1); !bytes.HasPrefix(pdf[offset:], []byte(want)) {
+			t.Errorf("xref entry %d points at %q", i+1, pdf[offset:offset+10])
+		}
+	}
+	if !bytes.Contains(pdf, []byte("/Count 2")) {
+		t.Error("page tree should count 2 pages")
+	}
+
+	content := inflateStreams(t, pdf)
+	if !bytes.Contains(content, []byte(`(Hello \(world\)) Tj`)) {
+		t.Errorf("text not escaped in content: %s", content)
+	}
+	if !bytes.Contains(content, []byte("/F2 12.00 Tf")) {
+		t.Error("bold text should use Helvetica-Bold")
+	}
+}
+
+func inflateStreams(t *testing.T, pdf []byte) []byte {
+	t.Helper()
+	var all []byte
+	streams := regexp.MustCompile(`/Length (\d+) /Filter /FlateDecode[^>]*>>\nstream\n`)
+	for _, loc := range streams.FindAllSubmatchIndex(pdf, -1) {
+		length, _ := strconv.Atoi(string(pdf[loc[2]:loc[3]]))
+		zr, err := zlib.NewReader(bytes.NewReader(pdf[loc[1] : loc[1]+length]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		all = append(all, data...)
+	}
+	return all
+}
+
+func TestWinAnsi(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Plain ASCII", "Plain ASCII"},
+		{"Café", "Caf\xe9"},
+		{"A – B", "A \x96 B"},
+		// Без шрифта кириллица транслитерируется
+		{"Иван Щукин", "Ivan Shchukin"},
+		{"日本", "??"},
+	}
+
+	for _, tt := range tests {
+		if got := string(winAnsi(tt.in)); got != tt.want {
+			t.Errorf("winAnsi(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestTextWidth(t *testing.T) {
+	doc := NewDocument(nil)
+	// "Hi" в Helvetica: H = 722, i = 222
+	if got := doc.TextWidth("Hi", 10, false); got != 9.44 {
+		t.Errorf("TextWidth = %v, want 9.44", got)
+	}
+	if doc.TextWidth("Hi", 10, true) <= doc.TextWidth("Hi", 10, false) {
+		t.Error("bold text should be wider")
+	}
+}
+
+func TestParseFontRejectsOtherFormats(t *testing.T) {
+	for _, data := range [][]byte{nil, []byte("OTTO\x00\x00\x00\x00\x00\x00\x00\x00"), []byte("not a font at all")} {
+		if _, err := ParseFont(data); err == nil {
+			t.Errorf("ParseFont(%q) should fail", data)
+		}
+	}
+}
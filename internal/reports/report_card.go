@@ -0,0 +1,262 @@
+package reports
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"restapi/internal/config"
+	"restapi/internal/models"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var (
+	header = config.Default().Reports.School
+	font   *Font
+)
+
+// Configure sets the school header and loads the font file, if any. Call it at startup.
+func Configure(cfg config.ReportsConfig) error {
+	header = cfg.School
+	font = nil
+	if cfg.FontFile == "" {
+		return nil
+	}
+	f, err := LoadFont(cfg.FontFile)
+	if err != nil {
+		return err
+	}
+	font = f
+	return nil
+}
+
+const (
+	margin   = 50.0
+	bottom   = 70.0
+	fontSize = 10.0
+	lineGap  = 14.0
+)
+
+// Table columns: subject, assessments, average, grade, comment
+var columns = [...]float64{margin, margin + 140, margin + 210, margin + 270, margin + 315}
+
+type layout struct {
+	doc  *Document
+	page *Page
+	y    float64
+}
+
+func (l *layout) newPage() {
+	l.page = l.doc.AddPage()
+	l.y = PageHeight - margin
+}
+
+// need starts a new page unless height points are left above the footer.
+func (l *layout) need(height float64) bool {
+	if l.y-height >= bottom {
+		return false
+	}
+	l.newPage()
+	return true
+}
+
+// ReportCard renders one student's report card as a PDF.
+func ReportCard(card models.ReportCard) ([]byte, error) {
+	l := &layout{doc: NewDocument(font)}
+	l.newPage()
+	l.header()
+	l.student(card)
+	l.grades(card)
+	l.attendance(card.Attendance)
+	l.comment(card.Comment)
+	l.footer()
+	return l.doc.Bytes()
+}
+
+func (l *layout) header() {
+	center := PageWidth / 2
+	l.page.TextCenter(center, l.y, 16, true, header.Name)
+	l.y -= 16
+	var contacts []string
+	for _, value := range []string{header.Phone, header.Email, header.Website} {
+		if value != "" {
+			contacts = append(contacts, value)
+		}
+	}
+	for _, line := range []string{header.Address, strings.Join(contacts, "  |  ")} {
+		if line != "" {
+			l.page.TextCenter(center, l.y, 9, false, line)
+			l.y -= 12
+		}
+	}
+	l.page.Line(margin, l.y, PageWidth-margin, l.y, 1)
+	l.y -= 28
+}
+
+func (l *layout) student(card models.ReportCard) {
+	l.page.Text(margin, l.y, 14, true, "Report card: "+card.Term)
+	l.page.TextRight(PageWidth-margin, l.y, fontSize, false, card.From+" to "+card.To)
+	l.y -= 22
+	name := strings.TrimSpace(card.Student.FirstName + " " + card.Student.LastName)
+	l.page.Text(margin, l.y, fontSize, true, "Student:")
+	l.page.Text(margin+60, l.y, fontSize, false, name)
+	l.page.Text(margin+300, l.y, fontSize, true, "Class:")
+	l.page.Text(margin+340, l.y, fontSize, false, card.Student.Class)
+	l.y -= 28
+}
+
+func (l *layout) tableHeader() {
+	l.page.FillRect(margin, l.y-4, PageWidth-2*margin, lineGap+2, 0.9)
+	for i, title := range []string{"Subject", "Assessments", "Average", "Grade", "Teacher comment"} {
+		l.page.Text(columns[i]+4, l.y, fontSize, true, title)
+	}
+	l.y -= lineGap + 4
+}
+
+func (l *layout) grades(card models.ReportCard) {
+	l.tableHeader()
+	if len(card.Subjects) == 0 {
+		l.page.Text(columns[0]+4, l.y, fontSize, false, "No grades recorded for this term.")
+		l.y -= lineGap
+	}
+
+	commentWidth := PageWidth - margin - columns[4] - 8
+	for _, subject := range card.Subjects {
+		lines := wrap(l.doc, subject.Comment, fontSize, commentWidth)
+		if l.need(lineGap * float64(max(len(lines), 1))) {
+			l.tableHeader()
+		}
+		l.page.Text(columns[0]+4, l.y, fontSize, false, subject.Subject)
+		l.page.TextRight(columns[2]-8, l.y, fontSize, false, fmt.Sprint(subject.Assessments))
+		l.page.TextRight(columns[3]-8, l.y, fontSize, false, fmt.Sprintf("%.1f%%", subject.Average))
+		l.page.Text(columns[3]+4, l.y, fontSize, true, subject.Letter)
+		for i, line := range lines {
+			l.page.Text(columns[4]+4, l.y-float64(i)*lineGap, fontSize, false, line)
+		}
+		l.y -= lineGap * float64(max(len(lines), 1))
+		l.page.Line(margin, l.y+lineGap-4, PageWidth-margin, l.y+lineGap-4, 0.3)
+	}
+
+	if len(card.Subjects) > 0 {
+		l.need(lineGap)
+		l.page.Text(columns[0]+4, l.y, fontSize, true, "Overall")
+		l.page.TextRight(columns[3]-8, l.y, fontSize, true, fmt.Sprintf("%.1f%%", card.Overall))
+		l.y -= lineGap
+	}
+	l.y -= 18
+}
+
+func (l *layout) attendance(summary models.AttendanceSummary) {
+	l.need(3 * lineGap)
+	l.page.Text(margin, l.y, 12, true, "Attendance")
+	l.y -= lineGap + 4
+	items := []struct {
+		label string
+		value string
+	}{
+		{"Sessions", fmt.Sprint(summary.Sessions)},
+		{"Present", fmt.Sprint(summary.Present)},
+		{"Late", fmt.Sprint(summary.Late)},
+		{"Absent", fmt.Sprint(summary.Absent)},
+		{"Excused", fmt.Sprint(summary.Excused)},
+		{"Attended", fmt.Sprintf("%.1f%%", 100*summary.Rate)},
+	}
+	step := (PageWidth - 2*margin) / float64(len(items))
+	for i, item := range items {
+		x := margin + float64(i)*step
+		l.page.Text(x, l.y, 9, false, item.label)
+		l.page.Text(x, l.y-lineGap, fontSize, true, item.value)
+	}
+	l.y -= 2*lineGap + 18
+}
+
+func (l *layout) comment(text string) {
+	if text == "" {
+		return
+	}
+	l.need(2 * lineGap)
+	l.page.Text(margin, l.y, 12, true, "Comments")
+	l.y -= lineGap + 4
+	for _, line := range wrap(l.doc, text, fontSize, PageWidth-2*margin) {
+		l.need(lineGap)
+		l.page.Text(margin, l.y, fontSize, false, line)
+		l.y -= lineGap
+	}
+}
+
+// footer goes at the bottom of every page.
+func (l *layout) footer() {
+	generated := "Generated " + time.Now().Format(time.DateOnly)
+	for i, page := range l.doc.pages {
+		page.Line(margin, bottom-20, PageWidth-margin, bottom-20, 0.5)
+		page.Text(margin, bottom-34, 8, false, generated)
+		page.TextRight(PageWidth-margin, bottom-34, 8, false, fmt.Sprintf("Page %d of %d", i+1, len(l.doc.pages)))
+	}
+	last := l.doc.pages[len(l.doc.pages)-1]
+	if l.y > bottom+30 {
+		last.Line(PageWidth-margin-160, bottom+10, PageWidth-margin, bottom+10, 0.5)
+		last.TextRight(PageWidth-margin, bottom-2, 8, false, "Signature")
+	}
+}
+
+// wrap splits text into lines no wider than width, breaking at spaces.
+func wrap(doc *Document, text string, size, width float64) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			candidate := word
+			if line != "" {
+				candidate = line + " " + word
+			}
+			if line != "" && doc.TextWidth(candidate, size, false) > width {
+				lines = append(lines, line)
+				candidate = word
+			}
+			line = candidate
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// ClassZip writes one PDF per card into a zip archive.
+func ClassZip(w io.Writer, cards []models.ReportCard) error {
+	zw := zip.NewWriter(w)
+	names := make(map[string]bool, len(cards))
+	for _, card := range cards {
+		pdf, err := ReportCard(card)
+		if err != nil {
+			return err
+		}
+		name := fileName(card)
+		if names[name] {
+			name = fmt.Sprintf("%s_%d", name, card.Student.ID)
+		}
+		names[name] = true
+
+		file, err := zw.Create(name + ".pdf")
+		if err != nil {
+			return err
+		}
+		if _, err := file.Write(pdf); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// fileName is "<class>_<last name>_<first name>" with anything but letters and
+// digits replaced by '-'.
+func fileName(card models.ReportCard) string {
+	name := strings.Join([]string{card.Student.Class, card.Student.LastName, card.Student.FirstName}, "_")
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			return r
+		}
+		return '-'
+	}, name)
+}
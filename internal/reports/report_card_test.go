@@ -0,0 +1,79 @@
+package reports
+
+import (
+	"archive/zip"
+	"bytes"
+	"restapi/internal/models"
+	"strings"
+	"testing"
+)
+
+func TestWrap(t *testing.T) {
+	doc := NewDocument(nil)
+	lines := wrap(doc, "one two three four five six seven eight nine ten", 10, 80)
+	if len(lines) < 2 {
+		t.Fatalf("expected several lines, got %q", lines)
+	}
+	for _, line := range lines {
+		if doc.TextWidth(line, 10, false) > 80 {
+			t.Errorf("line %q is wider than 80pt", line)
+		}
+	}
+	if strings.Join(lines, " ") != "one two three four five six seven eight nine ten" {
+		t.Errorf("words lost: %q", lines)
+	}
+}
+
+func TestReportCardPages(t *testing.T) {
+	card := models.ReportCard{
+		Student: models.Student{ID: 1, FirstName: "Ann", LastName: "Lee", Class: "10A"},
+		Term:    "Autumn 2024",
+		Comment: "Steady progress.",
+	}
+	// Много предметов не помещается на одну страницу
+	for i := 0; i < 60; i++ {
+		card.Subjects = append(card.Subjects, models.ReportSubject{SubjectAverage: models.SubjectAverage{Subject: "subject", Average: 80, Letter: "B"}})
+	}
+
+	pdf, err := ReportCard(card)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(pdf, []byte("/Count 2")) {
+		t.Error("60 subjects should span 2 pages")
+	}
+	content := inflateStreams(t, pdf)
+	for _, want := range []string{"(Ann Lee)", "(Report card: Autumn 2024)", "(Steady progress.)", "(Page 2 of 2)"} {
+		if !bytes.Contains(content, []byte(want)) {
+			t.Errorf("report card is missing %s", want)
+		}
+	}
+}
+
+func TestClassZip(t *testing.T) {
+	cards := []models.ReportCard{
+		{Student: models.Student{ID: 1, FirstName: "Ann", LastName: "Lee", Class: "10A"}},
+		// Тёзки получают id в имени файла
+		{Student: models.Student{ID: 2, FirstName: "Ann", LastName: "Lee", Class: "10A"}},
+		{Student: models.Student{ID: 3, FirstName: "Jo", LastName: "O'Neil", Class: "10A"}},
+	}
+
+	var buf bytes.Buffer
+	if err := ClassZip(&buf, cards); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"10A_Lee_Ann.pdf", "10A_Lee_Ann_2.pdf", "10A_O-Neil_Jo.pdf"}
+	if len(zr.File) != len(want) {
+		t.Fatalf("got %d files, want %d", len(zr.File), len(want))
+	}
+	for i, file := range zr.File {
+		if file.Name != want[i] {
+			t.Errorf("file %d = %q, want %q", i, file.Name, want[i])
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package reports
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Font is a TrueType font embedded whole into the PDF, so report cards can
+// print any script the font covers.
+type Font struct {
+	name       string
+	data       []byte
+	unitsPerEm float64
+	advances   []uint16
+	cmap       map[rune]uint16
+	bbox       [4]int16
+	ascent     int16
+	descent    int16
+}
+
+// LoadFont reads a .ttf file.
+func LoadFont(path string) (*Font, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	font, err := ParseFont(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return font, nil
+}
+
+var errBadFont = errors.New("not a supported TrueType font")
+
+func ParseFont(data []byte) (*Font, error) {
+	if len(data) < 12 {
+		return nil, errBadFont
+	}
+	if v := binary.BigEndian.Uint32(data); v != 0x00010000 && v != 0x74727565 {
+		// OpenType with CFF outlines ("OTTO") and collections can't go into FontFile2
+		return nil, errBadFont
+	}
+
+	tables := make(map[string][]byte)
+	count := int(binary.BigEndian.Uint16(data[4:]))
+	for i := 0; i < count; i++ {
+		record := 12 + 16*i
+		if record+16 > len(data) {
+			return nil, errBadFont
+		}
+		tag := string(data[record : record+4])
+		offset := int(binary.BigEndian.Uint32(data[record+8:]))
+		length := int(binary.BigEndian.Uint32(data[record+12:]))
+		if offset+length > len(data) {
+			return nil, errBadFont
+		}
+		tables[tag] = data[offset : offset+length]
+	}
+
+	head, hhea, hmtx, cmap := tables["head"], tables["hhea"], tables["hmtx"], tables["cmap"]
+	if len(head) < 54 || len(hhea) < 36 || hmtx == nil || cmap == nil {
+		return nil, errBadFont
+	}
+
+	f := &Font{data: data, name: "ReportFont"}
+	f.unitsPerEm = float64(binary.BigEndian.Uint16(head[18:]))
+	if f.unitsPerEm == 0 {
+		return nil, errBadFont
+	}
+	for i := range f.bbox {
+		f.bbox[i] = int16(binary.BigEndian.Uint16(head[36+2*i:]))
+	}
+	f.ascent = int16(binary.BigEndian.Uint16(hhea[4:]))
+	f.descent = int16(binary.BigEndian.Uint16(hhea[6:]))
+
+	metrics := int(binary.BigEndian.Uint16(hhea[34:]))
+	if len(hmtx) < 4*metrics {
+		return nil, errBadFont
+	}
+	f.advances = make([]uint16, metrics)
+	for i := range f.advances {
+		f.advances[i] = binary.BigEndian.Uint16(hmtx[4*i:])
+	}
+
+	var err error
+	if f.cmap, err = parseCmap(cmap); err != nil {
+		return nil, err
+	}
+	if name := postScriptName(tables["name"]); name != "" {
+		f.name = name
+	}
+	return f, nil
+}
+
+// parseCmap reads the Unicode subtable: format 12 when present, else format 4.
+func parseCmap(table []byte) (map[rune]uint16, error) {
+	if len(table) < 4 {
+		return nil, errBadFont
+	}
+	var format4, format12 []byte
+	count := int(binary.BigEndian.Uint16(table[2:]))
+	for i := 0; i < count; i++ {
+		record := 4 + 8*i
+		if record+8 > len(table) {
+			return nil, errBadFont
+		}
+		platform := binary.BigEndian.Uint16(table[record:])
+		encoding := binary.BigEndian.Uint16(table[record+2:])
+		offset := int(binary.BigEndian.Uint32(table[record+4:]))
+		if offset+4 > len(table) || !(platform == 0 || (platform == 3 && (encoding == 1 || encoding == 10))) {
+			continue
+		}
+		switch binary.BigEndian.Uint16(table[offset:]) {
+		case 4:
+			format4 = table[offset:]
+		case 12:
+			format12 = table[offset:]
+		}
+	}
+
+	cmap := make(map[rune]uint16)
+	switch {
+	case len(format12) >= 16:
+		groups := int(binary.BigEndian.Uint32(format12[12:]))
+		if len(format12) < 16+12*groups {
+			return nil, errBadFont
+		}
+		for i := 0; i < groups; i++ {
+			group := format12[16+12*i:]
+			start := binary.BigEndian.Uint32(group)
+			end := binary.BigEndian.Uint32(group[4:])
+			gid := binary.BigEndian.Uint32(group[8:])
+			for c := start; c <= end && c <= 0x10FFFF; c++ {
+				cmap[rune(c)] = uint16(gid + c - start)
+			}
+		}
+	case len(format4) >= 14:
+		segments := int(binary.BigEndian.Uint16(format4[6:])) / 2
+		ends := 14
+		starts := ends + 2*segments + 2
+		deltas := starts + 2*segments
+		ranges := deltas + 2*segments
+		if len(format4) < ranges+2*segments {
+			return nil, errBadFont
+		}
+		for i := 0; i < segments; i++ {
+			end := binary.BigEndian.Uint16(format4[ends+2*i:])
+			start := binary.BigEndian.Uint16(format4[starts+2*i:])
+			delta := binary.BigEndian.Uint16(format4[deltas+2*i:])
+			rangeOffset := int(binary.BigEndian.Uint16(format4[ranges+2*i:]))
+			for c := uint32(start); c <= uint32(end) && c != 0xFFFF; c++ {
+				gid := uint16(c) + delta
+				if rangeOffset != 0 {
+					at := ranges + 2*i + rangeOffset + 2*int(c-uint32(start))
+					if at+2 > len(format4) {
+						continue
+					}
+					if gid = binary.BigEndian.Uint16(format4[at:]); gid != 0 {
+						gid += delta
+					}
+				}
+				if gid != 0 {
+					cmap[rune(c)] = gid
+				}
+			}
+		}
+	default:
+		return nil, errors.New("font has no Unicode cmap")
+	}
+	return cmap, nil
+}
+
+// postScriptName returns name ID 6, used as the PDF BaseFont.
+func postScriptName(table []byte) string {
+	if len(table) < 6 {
+		return ""
+	}
+	count := int(binary.BigEndian.Uint16(table[2:]))
+	storage := int(binary.BigEndian.Uint16(table[4:]))
+	for i := 0; i < count; i++ {
+		record := 6 + 12*i
+		if record+12 > len(table) {
+			return ""
+		}
+		platform := binary.BigEndian.Uint16(table[record:])
+		id := binary.BigEndian.Uint16(table[record+6:])
+		length := int(binary.BigEndian.Uint16(table[record+8:]))
+		offset := storage + int(binary.BigEndian.Uint16(table[record+10:]))
+		if id != 6 || offset+length > len(table) {
+			continue
+		}
+		raw := table[offset : offset+length]
+		var b strings.Builder
+		for j := 0; j < len(raw); j++ {
+			// Windows names are UTF-16BE; PostScript names are ASCII either way
+			if platform == 3 {
+				j++
+				if j >= len(raw) {
+					break
+				}
+			}
+			if c := raw[j]; c > 32 && c < 127 && !strings.ContainsRune("[](){}<>/%", rune(c)) {
+				b.WriteByte(c)
+			}
+		}
+		if b.Len() > 0 {
+			return b.String()
+		}
+	}
+	return ""
+}
+
+func (f *Font) glyph(r rune) uint16 {
+	return f.cmap[r]
+}
+
+// width returns the advance of a glyph in 1/1000 em.
+func (f *Font) width(gid uint16) float64 {
+	if len(f.advances) == 0 {
+		return 0
+	}
+	advance := f.advances[len(f.advances)-1]
+	if int(gid) < len(f.advances) {
+		advance = f.advances[gid]
+	}
+	return f.scale(int(advance))
+}
+
+func (f *Font) scale(units int) float64 {
+	return float64(units) * 1000 / f.unitsPerEm
+}
+
+// writeTrueType adds the Type0 font with its descendant, descriptor, font
+// file and ToUnicode map, returning the Type0 object number.
+func (d *Document) writeTrueType(add func([]byte) int) (int, error) {
+	f := d.font
+	file, err := deflate(f.data)
+	if err != nil {
+		return 0, err
+	}
+	fileID := add(streamObject(fmt.Sprintf(" /Length1 %d", len(f.data)), file))
+
+	descriptor := add([]byte(fmt.Sprintf("<< /Type /FontDescriptor /FontName /%s /Flags 32 /FontBBox [%.0f %.0f %.0f %.0f] /ItalicAngle 0 /Ascent %.0f /Descent %.0f /CapHeight %.0f /StemV 80 /FontFile2 %d 0 R >>",
+		f.name, f.scale(int(f.bbox[0])), f.scale(int(f.bbox[1])), f.scale(int(f.bbox[2])), f.scale(int(f.bbox[3])),
+		f.scale(int(f.ascent)), f.scale(int(f.descent)), f.scale(int(f.ascent)), fileID)))
+
+	gids := make([]uint16, 0, len(d.used))
+	for gid := range d.used {
+		gids = append(gids, gid)
+	}
+	slices.Sort(gids)
+
+	var widths, unicode strings.Builder
+	for _, gid := range gids {
+		fmt.Fprintf(&widths, "%d [%.0f] ", gid, f.width(gid))
+	}
+	// bfchar blocks hold at most 100 entries each
+	for start := 0; start < len(gids); start += 100 {
+		end := min(start+100, len(gids))
+		fmt.Fprintf(&unicode, "%d beginbfchar\n", end-start)
+		for _, gid := range gids[start:end] {
+			fmt.Fprintf(&unicode, "<%04X> <%s>\n", gid, utf16Hex(d.used[gid]))
+		}
+		unicode.WriteString("endbfchar\n")
+	}
+
+	cid := add([]byte(fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%s /CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> /FontDescriptor %d 0 R /CIDToGIDMap /Identity /W [%s] >>",
+		f.name, descriptor, widths.String())))
+
+	cmap, err := deflate([]byte("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n" +
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n" +
+		"1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n" + unicode.String() +
+		"endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend\n"))
+	if err != nil {
+		return 0, err
+	}
+	toUnicode := add(streamObject("", cmap))
+
+	return add([]byte(fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H /DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>",
+		f.name, cid, toUnicode))), nil
+}
+
+func utf16Hex(r rune) string {
+	if r < 0x10000 {
+		return fmt.Sprintf("%04X", r)
+	}
+	r -= 0x10000
+	return fmt.Sprintf("%04X%04X", 0xD800+(r>>10), 0xDC00+(r&0x3FF))
+}
@@ -0,0 +1,77 @@
+package reports
+
+import "strings"
+
+// winAnsiExtra maps the characters WinAnsiEncoding keeps in 0x80-0x9F.
+var winAnsiExtra = map[rune]byte{
+	'€': 0x80, '‚': 0x82, '„': 0x84, '…': 0x85, '‹': 0x8B, '‘': 0x91, '’': 0x92,
+	'“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '™': 0x99, '›': 0x9B, '№': 'N',
+}
+
+// cyrillic transliterates Russian letters for the built-in font, which has no
+// Cyrillic glyphs. Configure reports.font_file to print them as they are.
+var cyrillic = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh",
+	'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts",
+	'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu",
+	'я': "ya",
+}
+
+// winAnsi encodes s for the built-in Helvetica; characters it can't show
+// become '?'.
+func winAnsi(s string) []byte {
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r < 0x80 || (r >= 0xA0 && r <= 0xFF):
+			out = append(out, byte(r))
+		case winAnsiExtra[r] != 0:
+			out = append(out, winAnsiExtra[r])
+		default:
+			lower := []rune(strings.ToLower(string(r)))[0]
+			latin, ok := cyrillic[lower]
+			if !ok {
+				out = append(out, '?')
+				continue
+			}
+			if lower != r && latin != "" {
+				latin = strings.ToUpper(latin[:1]) + latin[1:]
+			}
+			out = append(out, latin...)
+		}
+	}
+	return out
+}
+
+// Widths of printable ASCII (32-126) in 1/1000 em, from the Helvetica AFM files.
+var helveticaWidths = [2][95]uint16{
+	{
+		278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+		1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+		333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+		556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+	},
+	{
+		278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+		975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+		333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+		611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+	},
+}
+
+func helveticaWidth(c byte, bold bool) float64 {
+	face := 0
+	if bold {
+		face = 1
+	}
+	if c >= 32 && c <= 126 {
+		return float64(helveticaWidths[face][c-32])
+	}
+	// Accented letters and symbols: close enough for layout
+	return 556
+}
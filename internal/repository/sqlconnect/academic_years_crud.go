@@ -0,0 +1,631 @@
+package sqlconnect
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"restapi/internal/models"
+	"strings"
+)
+
+const createAcademicYearsTable = `CREATE TABLE IF NOT EXISTS academic_years (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	name VARCHAR(50) NOT NULL,
+	start_date DATE NOT NULL,
+	end_date DATE NOT NULL,
+	is_current BOOLEAN NOT NULL DEFAULT FALSE,
+	UNIQUE KEY uniq_academic_years_name (name)
+)`
+
+const createTermsTable = `CREATE TABLE IF NOT EXISTS terms (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	year_id INT NOT NULL,
+	name VARCHAR(100) NOT NULL,
+	start_date DATE NOT NULL,
+	end_date DATE NOT NULL,
+	UNIQUE KEY uniq_terms_name (year_id, name),
+	FOREIGN KEY (year_id) REFERENCES academic_years (id) ON DELETE CASCADE
+)`
+
+const createClassEnrollmentsTable = `CREATE TABLE IF NOT EXISTS class_enrollments (
+	student_id INT NOT NULL,
+	year_id INT NOT NULL,
+	class VARCHAR(255) NOT NULL,
+	status VARCHAR(20) NOT NULL DEFAULT 'active',
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+	PRIMARY KEY (student_id, year_id),
+	KEY idx_class_enrollments_class (year_id, class),
+	FOREIGN KEY (student_id) REFERENCES students (id) ON DELETE CASCADE,
+	FOREIGN KEY (year_id) REFERENCES academic_years (id)
+)`
+
+var (
+	// ErrAlreadyPromoted means the students of the year were moved on before.
+	ErrAlreadyPromoted = errors.New("students of this year were already promoted")
+	// ErrPeriodOverlap means a term would overlap another term of its year.
+	ErrPeriodOverlap = errors.New("term overlaps another term of the year")
+	// ErrYearOverlap means a year would overlap another year, so a date
+	// would fall into two years' class lists.
+	ErrYearOverlap = errors.New("academic year overlaps another year")
+)
+
+const yearColumns = "id, name, start_date, end_date, is_current"
+
+func scanYear(scanner interface{ Scan(...any) error }) (models.AcademicYear, error) {
+	var y models.AcademicYear
+	err := scanner.Scan(&y.ID, &y.Name, &y.StartDate, &y.EndDate, &y.Current)
+	return y, err
+}
+
+// FinalGrade is the grade whose classes graduate on promotion.
+func FinalGrade() int {
+	return settings.Academic.FinalGrade
+}
+
+func GetYears(ctx context.Context) ([]models.AcademicYear, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT "+yearColumns+" FROM academic_years ORDER BY start_date DESC")
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	defer rows.Close()
+
+	years := []models.AcademicYear{}
+	for rows.Next() {
+		year, err := scanYear(rows)
+		if err != nil {
+			return nil, dbError(err, "error retrieving data")
+		}
+		years = append(years, year)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	return years, nil
+}
+
+func GetYearByID(ctx context.Context, id int) (models.AcademicYear, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return models.AcademicYear{}, dbError(err, "error retrieving data")
+	}
+
+	year, err := scanYear(db.QueryRowContext(ctx, "SELECT "+yearColumns+" FROM academic_years WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return models.AcademicYear{}, dbError(err, "Academic year not found")
+	} else if err != nil {
+		return models.AcademicYear{}, dbError(err, "error retrieving data")
+	}
+	return year, nil
+}
+
+func GetCurrentYear(ctx context.Context) (models.AcademicYear, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return models.AcademicYear{}, dbError(err, "error retrieving data")
+	}
+
+	year, err := scanYear(db.QueryRowContext(ctx, "SELECT "+yearColumns+" FROM academic_years WHERE is_current"))
+	if err == sql.ErrNoRows {
+		return models.AcademicYear{}, dbError(err, "No current academic year")
+	} else if err != nil {
+		return models.AcademicYear{}, dbError(err, "error retrieving data")
+	}
+	return year, nil
+}
+
+// SaveYear inserts the year when it has no ID and updates it otherwise. A
+// current year takes the flag from all others. Years must not overlap.
+func SaveYear(ctx context.Context, y models.AcademicYear) (models.AcademicYear, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return models.AcademicYear{}, dbError(err, "error updating data")
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.AcademicYear{}, dbError(err, "error updating data")
+	}
+	defer tx.Rollback()
+
+	var overlapping int
+	err = tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM academic_years WHERE id <> ? AND start_date <= ? AND end_date >= ?",
+		y.ID, y.EndDate, y.StartDate).Scan(&overlapping)
+	if err != nil {
+		return models.AcademicYear{}, dbError(err, "error updating data")
+	}
+	if overlapping > 0 {
+		return models.AcademicYear{}, ErrYearOverlap
+	}
+
+	if y.Current {
+		if _, err := tx.ExecContext(ctx, "UPDATE academic_years SET is_current = FALSE WHERE id <> ?", y.ID); err != nil {
+			return models.AcademicYear{}, dbError(err, "error updating data")
+		}
+	}
+
+	if y.ID == 0 {
+		res, err := tx.ExecContext(ctx, "INSERT INTO academic_years (name, start_date, end_date, is_current) VALUES (?, ?, ?, ?)", y.Name, y.StartDate, y.EndDate, y.Current)
+		if err != nil {
+			return models.AcademicYear{}, dbError(err, "error adding data")
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return models.AcademicYear{}, dbError(err, "error adding data")
+		}
+		y.ID = int(id)
+		// A new year containing today takes the students' current classes
+		if _, err := syncEnrollments(ctx, tx, y.ID, true); err != nil {
+			return models.AcademicYear{}, err
+		}
+	} else {
+		_, err := tx.ExecContext(ctx, "UPDATE academic_years SET name = ?, start_date = ?, end_date = ?, is_current = ? WHERE id = ?", y.Name, y.StartDate, y.EndDate, y.Current, y.ID)
+		if err != nil {
+			return models.AcademicYear{}, dbError(err, "error updating data")
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.AcademicYear{}, dbError(err, "error updating data")
+	}
+	return y, nil
+}
+
+const termColumns = "id, year_id, name, start_date, end_date"
+
+func scanTerm(scanner interface{ Scan(...any) error }) (models.Term, error) {
+	var t models.Term
+	err := scanner.Scan(&t.ID, &t.YearID, &t.Name, &t.StartDate, &t.EndDate)
+	return t, err
+}
+
+func GetTerms(ctx context.Context, yearID int) ([]models.Term, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT "+termColumns+" FROM terms WHERE year_id = ? ORDER BY start_date", yearID)
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	defer rows.Close()
+
+	terms := []models.Term{}
+	for rows.Next() {
+		term, err := scanTerm(rows)
+		if err != nil {
+			return nil, dbError(err, "error retrieving data")
+		}
+		terms = append(terms, term)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	return terms, nil
+}
+
+func GetTermByID(ctx context.Context, id int) (models.Term, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return models.Term{}, dbError(err, "error retrieving data")
+	}
+
+	term, err := scanTerm(db.QueryRowContext(ctx, "SELECT "+termColumns+" FROM terms WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return models.Term{}, dbError(err, "Term not found")
+	} else if err != nil {
+		return models.Term{}, dbError(err, "error retrieving data")
+	}
+	return term, nil
+}
+
+// SaveTerm inserts or updates a term. It must lie within its year and not
+// overlap the year's other terms.
+func SaveTerm(ctx context.Context, t models.Term) (models.Term, error) {
+	year, err := GetYearByID(ctx, t.YearID)
+	if err != nil {
+		return models.Term{}, err
+	}
+	if t.StartDate < year.StartDate || t.EndDate > year.EndDate {
+		return models.Term{}, fmt.Errorf("term must lie within %s (%s to %s)", year.Name, year.StartDate, year.EndDate)
+	}
+
+	db, err := ConnectDb()
+	if err != nil {
+		return models.Term{}, dbError(err, "error updating data")
+	}
+
+	var overlapping int
+	err = db.QueryRowContext(ctx, "SELECT COUNT(*) FROM terms WHERE year_id = ? AND id <> ? AND start_date <= ? AND end_date >= ?",
+		t.YearID, t.ID, t.EndDate, t.StartDate).Scan(&overlapping)
+	if err != nil {
+		return models.Term{}, dbError(err, "error updating data")
+	}
+	if overlapping > 0 {
+		return models.Term{}, ErrPeriodOverlap
+	}
+
+	if t.ID == 0 {
+		res, err := db.ExecContext(ctx, "INSERT INTO terms (year_id, name, start_date, end_date) VALUES (?, ?, ?, ?)", t.YearID, t.Name, t.StartDate, t.EndDate)
+		if err != nil {
+			return models.Term{}, dbError(err, "error adding data")
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return models.Term{}, dbError(err, "error adding data")
+		}
+		t.ID = int(id)
+		return t, nil
+	}
+
+	_, err = db.ExecContext(ctx, "UPDATE terms SET name = ?, start_date = ?, end_date = ? WHERE id = ?", t.Name, t.StartDate, t.EndDate, t.ID)
+	if err != nil {
+		return models.Term{}, dbError(err, "error updating data")
+	}
+	return t, nil
+}
+
+func DeleteTerm(ctx context.Context, id int) error {
+	db, err := ConnectDb()
+	if err != nil {
+		return dbError(err, "error deleting data")
+	}
+
+	result, err := db.ExecContext(ctx, "DELETE FROM terms WHERE id = ?", id)
+	if err != nil {
+		return dbError(err, "error deleting data")
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return dbError(err, "error deleting data")
+	}
+	if rowsAffected == 0 {
+		return dbError(errors.New("no rows deleted"), "Term not found")
+	}
+	return nil
+}
+
+// SyncEnrollments records every student's current class as their class in the
+// year. Students already moved on by a promotion keep their record.
+func SyncEnrollments(ctx context.Context, yearID int) (int, error) {
+	if _, err := GetYearByID(ctx, yearID); err != nil {
+		return 0, err
+	}
+
+	db, err := ConnectDb()
+	if err != nil {
+		return 0, dbError(err, "error updating data")
+	}
+	return syncEnrollments(ctx, db, yearID, false)
+}
+
+// syncEnrollments is SyncEnrollments on e; with current it only acts while
+// the year contains today, the year students.class belongs to.
+func syncEnrollments(ctx context.Context, e execer, yearID int, current bool) (int, error) {
+	query := `INSERT INTO class_enrollments (student_id, year_id, class, status)
+		SELECT s.id, y.id, s.class, 'active' FROM students s JOIN academic_years y ON y.id = ?
+		WHERE s.class <> '' AND ` + notGraduated
+	if current {
+		query += " AND CURRENT_DATE BETWEEN y.start_date AND y.end_date"
+	}
+	res, err := e.ExecContext(ctx, query+`
+		ON DUPLICATE KEY UPDATE class = IF(status = 'active', VALUES(class), class)`, yearID)
+	if err != nil {
+		return 0, dbError(err, "error updating data")
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, dbError(err, "error updating data")
+	}
+	return int(n), nil
+}
+
+// notGraduated filters students s down to those who have not graduated.
+// Graduates keep their last class in students.class, so it alone would bring
+// them back into class lists and new years.
+const notGraduated = "NOT EXISTS (SELECT 1 FROM class_enrollments g WHERE g.student_id = s.id AND g.status = 'graduated')"
+
+// enrollInCurrentYear records class as the student's class in the academic
+// year containing today, if there is one, so class lists follow new students
+// and class changes. An enrollment closed by a promotion is kept.
+func enrollInCurrentYear(ctx context.Context, e execer, studentID int, class string) error {
+	if class == "" {
+		return nil
+	}
+	_, err := e.ExecContext(ctx, `INSERT INTO class_enrollments (student_id, year_id, class, status)
+		SELECT s.id, y.id, ?, 'active' FROM students s JOIN academic_years y ON CURRENT_DATE BETWEEN y.start_date AND y.end_date
+		WHERE s.id = ? AND `+notGraduated+`
+		ON DUPLICATE KEY UPDATE class = IF(status = 'active', VALUES(class), class)`, class, studentID)
+	if err != nil {
+		return dbError(err, "error updating data")
+	}
+	return nil
+}
+
+// GetYearClass lists the students enrolled in class during the year.
+func GetYearClass(ctx context.Context, yearID int, class string) ([]models.Enrollment, error) {
+	return queryEnrollments(ctx, "e.year_id = ? AND e.class = ? ORDER BY s.last_name, s.first_name", yearID, class)
+}
+
+// GetStudentEnrollments returns a student's classes year by year, oldest first.
+func GetStudentEnrollments(ctx context.Context, studentID int) ([]models.Enrollment, error) {
+	return queryEnrollments(ctx, "e.student_id = ? ORDER BY y.start_date", studentID)
+}
+
+func queryEnrollments(ctx context.Context, where string, args ...interface{}) ([]models.Enrollment, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT e.student_id, s.first_name, s.last_name, e.year_id, y.name, e.class, e.status
+		FROM class_enrollments e
+		JOIN students s ON s.id = e.student_id
+		JOIN academic_years y ON y.id = e.year_id
+		WHERE `+where, args...)
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	defer rows.Close()
+
+	enrollments := []models.Enrollment{}
+	for rows.Next() {
+		var e models.Enrollment
+		if err := rows.Scan(&e.StudentID, &e.FirstName, &e.LastName, &e.YearID, &e.Year, &e.Class, &e.Status); err != nil {
+			return nil, dbError(err, "error retrieving data")
+		}
+		enrollments = append(enrollments, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	return enrollments, nil
+}
+
+// yearClassStudents returns the students enrolled in class during the year,
+// with the class they had then.
+func yearClassStudents(ctx context.Context, yearID int, class string) ([]models.Student, error) {
+	enrollments, err := GetYearClass(ctx, yearID, class)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]interface{}, len(enrollments))
+	for i, e := range enrollments {
+		ids[i] = e.StudentID
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	db, err := ConnectDb()
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	rows, err := db.QueryContext(ctx, "SELECT id, first_name, last_name, email FROM students WHERE id IN "+placeholders(len(ids))+" ORDER BY last_name, first_name", ids...)
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	defer rows.Close()
+
+	var students []models.Student
+	for rows.Next() {
+		student := models.Student{Class: class}
+		if err := rows.Scan(&student.ID, &student.FirstName, &student.LastName, &student.Email); err != nil {
+			return nil, dbError(err, "error retrieving data")
+		}
+		students = append(students, student)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	return students, nil
+}
+
+// classMembersQuery selects the ids of the students in a class on a date:
+// those enrolled in it for the academic year containing the date, or, when
+// no year covers the date, those whose current class it is. Its arguments
+// are classMembersArgs.
+const classMembersQuery = `SELECT e.student_id FROM class_enrollments e
+	JOIN academic_years y ON y.id = e.year_id
+	WHERE e.class = ? AND ? BETWEEN y.start_date AND y.end_date
+	UNION
+	SELECT s.id FROM students s
+	WHERE s.class = ? AND ` + notGraduated + `
+	AND NOT EXISTS (SELECT 1 FROM academic_years y WHERE ? BETWEEN y.start_date AND y.end_date)`
+
+func classMembersArgs(class, date string) []any {
+	return []any{class, date, class, date}
+}
+
+// classStudentIDs returns the ids of the students in class on date.
+func classStudentIDs(ctx context.Context, tx *sql.Tx, class, date string) (map[int]bool, error) {
+	rows, err := tx.QueryContext(ctx, classMembersQuery, classMembersArgs(class, date)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make(map[int]bool)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids[id] = true
+	}
+	return ids, rows.Err()
+}
+
+// placeholders returns "(?, ?, ...)" for n values.
+func placeholders(n int) string {
+	return "(" + strings.TrimSuffix(strings.Repeat("?, ", n), ", ") + ")"
+}
+
+// PromoteYear moves the students of a year into the next one in a single
+// transaction: the old enrollments keep how each student left (promoted,
+// retained, graduated), new ones are created for the next year and the
+// students' current class follows. Graduates keep their last class; every
+// other target class must have a class teacher.
+// Students without an enrollment are taken with their current class first.
+// A dry run does all of it and rolls back, returning the same plan.
+func PromoteYear(ctx context.Context, fromYearID int, req models.PromotionRequest) (models.PromotionPlan, error) {
+	from, err := GetYearByID(ctx, fromYearID)
+	if err != nil {
+		return models.PromotionPlan{}, err
+	}
+	to, err := GetYearByID(ctx, req.ToYearID)
+	if err != nil {
+		return models.PromotionPlan{}, err
+	}
+	if to.StartDate <= from.EndDate {
+		return models.PromotionPlan{}, fmt.Errorf("%s must start after %s ends", to.Name, from.Name)
+	}
+
+	db, err := ConnectDb()
+	if err != nil {
+		return models.PromotionPlan{}, dbError(err, "error updating data")
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.PromotionPlan{}, dbError(err, "error updating data")
+	}
+	defer tx.Rollback()
+
+	var moved int
+	err = tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM class_enrollments WHERE year_id = ? AND status <> 'active'", fromYearID).Scan(&moved)
+	if err != nil {
+		return models.PromotionPlan{}, dbError(err, "error updating data")
+	}
+	if moved > 0 {
+		return models.PromotionPlan{}, ErrAlreadyPromoted
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT IGNORE INTO class_enrollments (student_id, year_id, class, status)
+		SELECT s.id, ?, s.class, 'active' FROM students s WHERE s.class <> '' AND `+notGraduated, fromYearID)
+	if err != nil {
+		return models.PromotionPlan{}, dbError(err, "error updating data")
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT s.id, s.first_name, s.last_name, e.class
+		FROM class_enrollments e JOIN students s ON s.id = e.student_id
+		WHERE e.year_id = ? ORDER BY e.class, s.last_name, s.first_name FOR UPDATE`, fromYearID)
+	if err != nil {
+		return models.PromotionPlan{}, dbError(err, "error updating data")
+	}
+	var enrolled []models.Student
+	for rows.Next() {
+		var student models.Student
+		if err := rows.Scan(&student.ID, &student.FirstName, &student.LastName, &student.Class); err != nil {
+			rows.Close()
+			return models.PromotionPlan{}, dbError(err, "error updating data")
+		}
+		enrolled = append(enrolled, student)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return models.PromotionPlan{}, dbError(err, "error updating data")
+	}
+
+	enrolledIDs := make(map[int]bool, len(enrolled))
+	for _, student := range enrolled {
+		enrolledIDs[student.ID] = true
+	}
+	for _, id := range req.Retained {
+		if !enrolledIDs[id] {
+			return models.PromotionPlan{}, fmt.Errorf("retained student %d is not enrolled in %s", id, from.Name)
+		}
+	}
+
+	plan, err := models.PlanPromotion(fromYearID, enrolled, req)
+	if err != nil {
+		return models.PromotionPlan{}, err
+	}
+	if err := checkClassesExist(ctx, tx, plan); err != nil {
+		return models.PromotionPlan{}, err
+	}
+
+	status := map[models.PromotionAction]models.EnrollmentStatus{
+		models.PromotionPromote:  models.EnrollmentPromoted,
+		models.PromotionRetain:   models.EnrollmentRetained,
+		models.PromotionGraduate: models.EnrollmentGraduated,
+	}
+	for _, entry := range plan.Entries {
+		_, err := tx.ExecContext(ctx, "UPDATE class_enrollments SET status = ? WHERE student_id = ? AND year_id = ?", status[entry.Action], entry.StudentID, fromYearID)
+		if err != nil {
+			return models.PromotionPlan{}, dbError(err, "error updating data")
+		}
+		if entry.ToClass != "" {
+			_, err = tx.ExecContext(ctx, `INSERT INTO class_enrollments (student_id, year_id, class, status) VALUES (?, ?, ?, 'active')
+				ON DUPLICATE KEY UPDATE class = VALUES(class), status = 'active'`, entry.StudentID, req.ToYearID, entry.ToClass)
+			if err != nil {
+				return models.PromotionPlan{}, dbError(err, "error updating data")
+			}
+		}
+		// Graduates keep their last class: students.class references
+		// teachers(class), and the graduated enrollment marks them as gone
+		if entry.Action == models.PromotionGraduate {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE students SET class = ? WHERE id = ?", entry.ToClass, entry.StudentID); err != nil {
+			return models.PromotionPlan{}, dbError(err, "error updating data")
+		}
+		err = recordChange(ctx, tx, models.ContactStudent, entry.StudentID, models.ActionUpdated,
+			models.Student{Class: entry.FromClass}, models.Student{Class: entry.ToClass})
+		if err != nil {
+			return models.PromotionPlan{}, err
+		}
+	}
+
+	if req.DryRun {
+		return plan, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return models.PromotionPlan{}, dbError(err, "error updating data")
+	}
+	plan.Applied = true
+	return plan, nil
+}
+
+// checkClassesExist rejects a plan that moves students into a class with no
+// class teacher, which students.class cannot reference.
+func checkClassesExist(ctx context.Context, tx *sql.Tx, plan models.PromotionPlan) error {
+	var classes []any
+	seen := make(map[string]bool)
+	for _, entry := range plan.Entries {
+		if entry.ToClass != "" && !seen[entry.ToClass] {
+			seen[entry.ToClass] = true
+			classes = append(classes, entry.ToClass)
+		}
+	}
+	if len(classes) == 0 {
+		return nil
+	}
+
+	rows, err := tx.QueryContext(ctx, "SELECT class FROM teachers WHERE class IN "+placeholders(len(classes)), classes...)
+	if err != nil {
+		return dbError(err, "error updating data")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var class string
+		if err := rows.Scan(&class); err != nil {
+			return dbError(err, "error updating data")
+		}
+		delete(seen, class)
+	}
+	if err := rows.Err(); err != nil {
+		return dbError(err, "error updating data")
+	}
+	for _, c := range classes {
+		if seen[c.(string)] {
+			return fmt.Errorf("class %s does not exist", c)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,572 @@
+package sqlconnect
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"restapi/internal/models"
+	"restapi/pkg/utils"
+	"strings"
+)
+
+const createLeadsTable = `CREATE TABLE IF NOT EXISTS leads (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	first_name VARCHAR(255) NOT NULL,
+	last_name VARCHAR(255) NOT NULL,
+	email VARCHAR(255) NOT NULL DEFAULT '',
+	phone VARCHAR(50) NOT NULL DEFAULT '',
+	desired_class VARCHAR(255) NOT NULL DEFAULT '',
+	source VARCHAR(100) NOT NULL DEFAULT '',
+	stage VARCHAR(50) NOT NULL,
+	assigned_to INT NOT NULL DEFAULT 0,
+	student_id INT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+	KEY idx_leads_stage (stage),
+	KEY idx_leads_assigned_to (assigned_to),
+	FOREIGN KEY (student_id) REFERENCES students (id) ON DELETE SET NULL
+)`
+
+const createLeadGuardiansTable = `CREATE TABLE IF NOT EXISTS lead_guardians (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	lead_id INT NOT NULL,
+	first_name VARCHAR(255) NOT NULL,
+	last_name VARCHAR(255) NOT NULL,
+	phone VARCHAR(50) NOT NULL DEFAULT '',
+	email VARCHAR(255) NOT NULL DEFAULT '',
+	relationship VARCHAR(50) NOT NULL DEFAULT 'parent',
+	can_pickup BOOLEAN NOT NULL DEFAULT TRUE,
+	is_primary BOOLEAN NOT NULL DEFAULT FALSE,
+	FOREIGN KEY (lead_id) REFERENCES leads (id) ON DELETE CASCADE
+)`
+
+const createLeadActivitiesTable = `CREATE TABLE IF NOT EXISTS lead_activities (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	lead_id INT NOT NULL,
+	type VARCHAR(20) NOT NULL,
+	body TEXT NOT NULL,
+	author_id INT NOT NULL DEFAULT 0,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	KEY idx_lead_activities_lead (lead_id, created_at),
+	FOREIGN KEY (lead_id) REFERENCES leads (id) ON DELETE CASCADE
+)`
+
+const createLeadStageHistoryTable = `CREATE TABLE IF NOT EXISTS lead_stage_history (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	lead_id INT NOT NULL,
+	from_stage VARCHAR(50) NOT NULL DEFAULT '',
+	to_stage VARCHAR(50) NOT NULL,
+	note VARCHAR(500) NOT NULL DEFAULT '',
+	changed_by INT NOT NULL DEFAULT 0,
+	changed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	KEY idx_lead_stage_history_lead (lead_id, changed_at),
+	FOREIGN KEY (lead_id) REFERENCES leads (id) ON DELETE CASCADE
+)`
+
+// Pipeline returns the admission stages from the configuration.
+func Pipeline() models.Pipeline {
+	pipeline := make(models.Pipeline, 0, len(settings.Admissions.Stages))
+	for _, stage := range settings.Admissions.Stages {
+		pipeline = append(pipeline, models.AdmissionStage{Name: stage.Name, Outcome: models.StageOutcome(stage.Outcome)})
+	}
+	return pipeline
+}
+
+const leadColumns = "id, first_name, last_name, email, phone, desired_class, source, stage, assigned_to, COALESCE(student_id, 0), created_at, updated_at"
+
+func scanLead(scanner interface{ Scan(...any) error }) (models.Lead, error) {
+	var l models.Lead
+	err := scanner.Scan(&l.ID, &l.FirstName, &l.LastName, &l.Email, &l.Phone, &l.DesiredClass, &l.Source, &l.Stage, &l.AssignedTo, &l.StudentID, &l.CreatedAt, &l.UpdatedAt)
+	return l, err
+}
+
+// GetLeads returns one page of leads matching the list filters and the total
+// number of matches, newest first unless sorted otherwise.
+func GetLeads(ctx context.Context, r *http.Request, limit, page int) ([]models.Lead, int, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return nil, 0, dbError(err, "error retrieving data")
+	}
+
+	where, args := utils.LeadListSchema.Where(r, " WHERE 1=1", nil)
+
+	var total int
+	err = db.QueryRowContext(ctx, "SELECT COUNT(*) FROM leads"+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, dbError(err, "error retrieving data")
+	}
+
+	query := "SELECT " + leadColumns + " FROM leads" + where
+	if sorted := utils.LeadListSchema.OrderBy(r, query); sorted != query {
+		query = sorted
+	} else {
+		query += " ORDER BY created_at DESC, id DESC"
+	}
+	query += " LIMIT ? OFFSET ?"
+	args = append(args, limit, (page-1)*limit)
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, dbError(err, "error retrieving data")
+	}
+	defer rows.Close()
+
+	leads := []models.Lead{}
+	for rows.Next() {
+		lead, err := scanLead(rows)
+		if err != nil {
+			return nil, 0, dbError(err, "error retrieving data")
+		}
+		leads = append(leads, lead)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, dbError(err, "error retrieving data")
+	}
+	return leads, total, nil
+}
+
+// GetLeadByID returns the lead with its guardians.
+func GetLeadByID(ctx context.Context, id int) (models.Lead, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return models.Lead{}, dbError(err, "error retrieving data")
+	}
+
+	lead, err := scanLead(db.QueryRowContext(ctx, "SELECT "+leadColumns+" FROM leads WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return models.Lead{}, dbError(err, "Lead not found")
+	} else if err != nil {
+		return models.Lead{}, dbError(err, "error retrieving data")
+	}
+
+	lead.Guardians, err = leadGuardians(ctx, db, id)
+	if err != nil {
+		return models.Lead{}, dbError(err, "error retrieving data")
+	}
+	return lead, nil
+}
+
+// leadGuardians reads the guardians of a lead, the primary one first.
+func leadGuardians(ctx context.Context, q queryer, leadID int) ([]models.LeadGuardian, error) {
+	rows, err := q.QueryContext(ctx, "SELECT first_name, last_name, phone, email, relationship, can_pickup, is_primary FROM lead_guardians WHERE lead_id = ? ORDER BY is_primary DESC, id", leadID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var guardians []models.LeadGuardian
+	for rows.Next() {
+		var g models.LeadGuardian
+		if err := rows.Scan(&g.FirstName, &g.LastName, &g.Phone, &g.Email, &g.Relationship, &g.CanPickup, &g.Primary); err != nil {
+			return nil, err
+		}
+		guardians = append(guardians, g)
+	}
+	return guardians, rows.Err()
+}
+
+// AddLead creates the lead with its guardians and records its first stage.
+func AddLead(ctx context.Context, l models.Lead, createdBy int) (models.Lead, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return models.Lead{}, dbError(err, "error adding data")
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Lead{}, dbError(err, "error adding data")
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, "INSERT INTO leads (first_name, last_name, email, phone, desired_class, source, stage, assigned_to) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		l.FirstName, l.LastName, l.Email, l.Phone, l.DesiredClass, l.Source, l.Stage, l.AssignedTo)
+	if err != nil {
+		return models.Lead{}, dbError(err, "error adding data")
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return models.Lead{}, dbError(err, "error adding data")
+	}
+
+	if err := insertLeadGuardians(ctx, tx, int(id), l.Guardians); err != nil {
+		return models.Lead{}, err
+	}
+	if err := recordStageChange(ctx, tx, int(id), "", l.Stage, "", createdBy); err != nil {
+		return models.Lead{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return models.Lead{}, dbError(err, "error adding data")
+	}
+	return GetLeadByID(ctx, int(id))
+}
+
+func insertLeadGuardians(ctx context.Context, tx *sql.Tx, leadID int, guardians []models.LeadGuardian) error {
+	for _, g := range guardians {
+		_, err := tx.ExecContext(ctx, "INSERT INTO lead_guardians (lead_id, first_name, last_name, phone, email, relationship, can_pickup, is_primary) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+			leadID, g.FirstName, g.LastName, g.Phone, g.Email, g.Relationship, g.CanPickup, g.Primary)
+		if err != nil {
+			return dbError(err, "error adding data")
+		}
+	}
+	return nil
+}
+
+func recordStageChange(ctx context.Context, tx *sql.Tx, leadID int, from, to, note string, changedBy int) error {
+	_, err := tx.ExecContext(ctx, "INSERT INTO lead_stage_history (lead_id, from_stage, to_stage, note, changed_by) VALUES (?, ?, ?, ?, ?)",
+		leadID, from, to, note, changedBy)
+	if err != nil {
+		return dbError(err, "error updating data")
+	}
+	return nil
+}
+
+// UpdateLead saves the contact details of the lead; stage, assignee and
+// guardians have their own calls.
+func UpdateLead(ctx context.Context, l models.Lead) (models.Lead, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return models.Lead{}, dbError(err, "error updating data")
+	}
+
+	_, err = db.ExecContext(ctx, "UPDATE leads SET first_name = ?, last_name = ?, email = ?, phone = ?, desired_class = ?, source = ? WHERE id = ?",
+		l.FirstName, l.LastName, l.Email, l.Phone, l.DesiredClass, l.Source, l.ID)
+	if err != nil {
+		return models.Lead{}, dbError(err, "error updating data")
+	}
+	return GetLeadByID(ctx, l.ID)
+}
+
+func DeleteLead(ctx context.Context, id int) error {
+	return deleteByID(ctx, "leads", id, "Lead not found")
+}
+
+// lockLead reads the lead for update inside tx.
+func lockLead(ctx context.Context, tx *sql.Tx, id int) (models.Lead, error) {
+	lead, err := scanLead(tx.QueryRowContext(ctx, "SELECT "+leadColumns+" FROM leads WHERE id = ? FOR UPDATE", id))
+	if err == sql.ErrNoRows {
+		return models.Lead{}, dbError(err, "Lead not found")
+	} else if err != nil {
+		return models.Lead{}, dbError(err, "error updating data")
+	}
+	return lead, nil
+}
+
+// SetLeadGuardians replaces the guardians of a lead that was not converted yet.
+func SetLeadGuardians(ctx context.Context, leadID int, guardians []models.LeadGuardian) (models.Lead, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return models.Lead{}, dbError(err, "error updating data")
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Lead{}, dbError(err, "error updating data")
+	}
+	defer tx.Rollback()
+
+	lead, err := lockLead(ctx, tx, leadID)
+	if err != nil {
+		return models.Lead{}, err
+	}
+	if lead.StudentID != 0 {
+		return models.Lead{}, models.ErrLeadConverted
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM lead_guardians WHERE lead_id = ?", leadID); err != nil {
+		return models.Lead{}, dbError(err, "error updating data")
+	}
+	if err := insertLeadGuardians(ctx, tx, leadID, guardians); err != nil {
+		return models.Lead{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return models.Lead{}, dbError(err, "error updating data")
+	}
+	return GetLeadByID(ctx, leadID)
+}
+
+// AssignLead hands the lead to an active exec; execID 0 unassigns it.
+func AssignLead(ctx context.Context, leadID, execID int) (models.Lead, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return models.Lead{}, dbError(err, "error updating data")
+	}
+
+	if execID != 0 {
+		var inactive bool
+		err := db.QueryRowContext(ctx, "SELECT inactive_status FROM execs WHERE id = ?", execID).Scan(&inactive)
+		if err == sql.ErrNoRows || inactive {
+			return models.Lead{}, fmt.Errorf("exec %d not found or inactive", execID)
+		} else if err != nil {
+			return models.Lead{}, dbError(err, "error updating data")
+		}
+	}
+
+	res, err := db.ExecContext(ctx, "UPDATE leads SET assigned_to = ? WHERE id = ?", execID, leadID)
+	if err != nil {
+		return models.Lead{}, dbError(err, "error updating data")
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return models.Lead{}, dbError(err, "error updating data")
+	} else if n == 0 {
+		// Assigning the same exec again changes no row either
+		if _, err := GetLeadByID(ctx, leadID); err != nil {
+			return models.Lead{}, err
+		}
+	}
+	return GetLeadByID(ctx, leadID)
+}
+
+// MoveLead moves the lead to another stage and records the change.
+func MoveLead(ctx context.Context, leadID int, move models.StageMove, changedBy int) (models.Lead, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return models.Lead{}, dbError(err, "error updating data")
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Lead{}, dbError(err, "error updating data")
+	}
+	defer tx.Rollback()
+
+	lead, err := lockLead(ctx, tx, leadID)
+	if err != nil {
+		return models.Lead{}, err
+	}
+	if err := Pipeline().CheckMove(lead.Stage, move.Stage); err != nil {
+		return models.Lead{}, err
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE leads SET stage = ? WHERE id = ?", move.Stage, leadID); err != nil {
+		return models.Lead{}, dbError(err, "error updating data")
+	}
+	if err := recordStageChange(ctx, tx, leadID, lead.Stage, move.Stage, move.Note, changedBy); err != nil {
+		return models.Lead{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return models.Lead{}, dbError(err, "error updating data")
+	}
+	return GetLeadByID(ctx, leadID)
+}
+
+func AddLeadActivity(ctx context.Context, a models.LeadActivity) (models.LeadActivity, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return models.LeadActivity{}, dbError(err, "error adding data")
+	}
+
+	res, err := db.ExecContext(ctx, "INSERT INTO lead_activities (lead_id, type, body, author_id) VALUES (?, ?, ?, ?)", a.LeadID, a.Type, a.Body, a.AuthorID)
+	if err != nil {
+		return models.LeadActivity{}, dbError(err, "error adding data")
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return models.LeadActivity{}, dbError(err, "error adding data")
+	}
+
+	err = db.QueryRowContext(ctx, "SELECT id, created_at FROM lead_activities WHERE id = ?", id).Scan(&a.ID, &a.CreatedAt)
+	if err != nil {
+		return models.LeadActivity{}, dbError(err, "error adding data")
+	}
+	return a, nil
+}
+
+func GetLeadActivities(ctx context.Context, leadID int) ([]models.LeadActivity, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT id, lead_id, type, body, author_id, created_at FROM lead_activities WHERE lead_id = ? ORDER BY created_at, id", leadID)
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	defer rows.Close()
+
+	activities := []models.LeadActivity{}
+	for rows.Next() {
+		var a models.LeadActivity
+		if err := rows.Scan(&a.ID, &a.LeadID, &a.Type, &a.Body, &a.AuthorID, &a.CreatedAt); err != nil {
+			return nil, dbError(err, "error retrieving data")
+		}
+		activities = append(activities, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	return activities, nil
+}
+
+// GetLeadHistory returns the stage changes of a lead, oldest first.
+func GetLeadHistory(ctx context.Context, leadID int) ([]models.StageChange, error) {
+	return queryStageChanges(ctx, "lead_id = ?", leadID)
+}
+
+func queryStageChanges(ctx context.Context, where string, args ...interface{}) ([]models.StageChange, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT id, lead_id, from_stage, to_stage, note, changed_by, changed_at FROM lead_stage_history WHERE "+where+" ORDER BY changed_at, id", args...)
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	defer rows.Close()
+
+	changes := []models.StageChange{}
+	for rows.Next() {
+		var c models.StageChange
+		if err := rows.Scan(&c.ID, &c.LeadID, &c.FromStage, &c.ToStage, &c.Note, &c.ChangedBy, &c.ChangedAt); err != nil {
+			return nil, dbError(err, "error retrieving data")
+		}
+		changes = append(changes, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	return changes, nil
+}
+
+// ConvertLead turns the lead into a student in one transaction: the students
+// row is created, the lead's guardians become guardians of the student (an
+// existing guardian with the same email is linked instead, e.g. for
+// siblings) and the lead moves to the enrolled stage.
+func ConvertLead(ctx context.Context, leadID int, c models.LeadConversion, convertedBy int) (models.ConversionResult, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return models.ConversionResult{}, dbError(err, "error updating data")
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.ConversionResult{}, dbError(err, "error updating data")
+	}
+	defer tx.Rollback()
+
+	lead, err := lockLead(ctx, tx, leadID)
+	if err != nil {
+		return models.ConversionResult{}, err
+	}
+	pipeline := Pipeline()
+	if stage, _ := pipeline.Stage(lead.Stage); lead.StudentID != 0 || stage.Outcome == models.OutcomeEnrolled {
+		return models.ConversionResult{}, models.ErrLeadConverted
+	} else if stage.Outcome == models.OutcomeDeclined {
+		return models.ConversionResult{}, fmt.Errorf("lead is %s; move it back to an open stage first", lead.Stage)
+	}
+
+	student := models.Student{FirstName: lead.FirstName, LastName: lead.LastName, Email: lead.Email, Class: lead.DesiredClass}
+	if c.Email != "" {
+		student.Email = c.Email
+	}
+	if c.Class != "" {
+		student.Class = c.Class
+	}
+	if student.Email == "" || student.Class == "" {
+		return models.ConversionResult{}, errors.New("email and class are required to enroll the lead")
+	}
+
+	res, err := tx.ExecContext(ctx, "INSERT INTO students (first_name, last_name, email, class) VALUES (?, ?, ?, ?)",
+		student.FirstName, student.LastName, student.Email, student.Class)
+	if err != nil {
+		if strings.Contains(err.Error(), "FOREIGN KEY (`class`)") {
+			return models.ConversionResult{}, dbError(err, "class/class teacher does not exist")
+		}
+		return models.ConversionResult{}, dbError(err, "error adding data")
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return models.ConversionResult{}, dbError(err, "error adding data")
+	}
+	student.ID = int(id)
+	if err := recordChange(ctx, tx, models.ContactStudent, student.ID, models.ActionCreated, nil, student); err != nil {
+		return models.ConversionResult{}, err
+	}
+	if err := enrollInCurrentYear(ctx, tx, student.ID, student.Class); err != nil {
+		return models.ConversionResult{}, err
+	}
+
+	// Read through tx, not a second pool connection, so the guardians match
+	// the lead locked above
+	fromLead, err := leadGuardians(ctx, tx, leadID)
+	if err != nil {
+		return models.ConversionResult{}, dbError(err, "error adding data")
+	}
+	guardians := []models.Guardian{}
+	for _, g := range fromLead {
+		guardian := models.Guardian{FirstName: g.FirstName, LastName: g.LastName, Phone: g.Phone, Email: g.Email}
+		err := tx.QueryRowContext(ctx, "SELECT "+guardianColumns+" FROM guardians WHERE email = ? AND email <> '' LIMIT 1", g.Email).
+			Scan(&guardian.ID, &guardian.FirstName, &guardian.LastName, &guardian.Phone, &guardian.Email, &guardian.Address, &guardian.PreferredLanguage)
+		if err == sql.ErrNoRows {
+			res, err := tx.ExecContext(ctx, "INSERT INTO guardians (first_name, last_name, phone, email) VALUES (?, ?, ?, ?)", g.FirstName, g.LastName, g.Phone, g.Email)
+			if err != nil {
+				return models.ConversionResult{}, dbError(err, "error adding data")
+			}
+			gid, err := res.LastInsertId()
+			if err != nil {
+				return models.ConversionResult{}, dbError(err, "error adding data")
+			}
+			guardian.ID = int(gid)
+			if err := recordChange(ctx, tx, models.ContactGuardian, guardian.ID, models.ActionCreated, nil, guardian); err != nil {
+				return models.ConversionResult{}, err
+			}
+		} else if err != nil {
+			return models.ConversionResult{}, dbError(err, "error adding data")
+		}
+
+		_, err = tx.ExecContext(ctx, `INSERT INTO student_guardians (student_id, guardian_id, relationship, can_pickup, is_primary) VALUES (?, ?, ?, ?, ?)
+			ON DUPLICATE KEY UPDATE relationship = VALUES(relationship), can_pickup = VALUES(can_pickup), is_primary = VALUES(is_primary)`,
+			student.ID, guardian.ID, g.Relationship, g.CanPickup, g.Primary)
+		if err != nil {
+			return models.ConversionResult{}, dbError(err, "error adding data")
+		}
+		guardians = append(guardians, guardian)
+	}
+
+	enrolled := pipeline.Enrolled()
+	if _, err := tx.ExecContext(ctx, "UPDATE leads SET stage = ?, student_id = ? WHERE id = ?", enrolled, student.ID, leadID); err != nil {
+		return models.ConversionResult{}, dbError(err, "error updating data")
+	}
+	if err := recordStageChange(ctx, tx, leadID, lead.Stage, enrolled, "converted into student "+fmt.Sprint(student.ID), convertedBy); err != nil {
+		return models.ConversionResult{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return models.ConversionResult{}, dbError(err, "error updating data")
+	}
+
+	lead, err = GetLeadByID(ctx, leadID)
+	if err != nil {
+		return models.ConversionResult{}, err
+	}
+	return models.ConversionResult{Lead: lead, Student: student, Guardians: guardians}, nil
+}
+
+// GetAdmissionMetrics builds the funnel of the leads created between from and to.
+func GetAdmissionMetrics(ctx context.Context, from, to string) (models.AdmissionMetrics, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return models.AdmissionMetrics{}, dbError(err, "error retrieving data")
+	}
+
+	const created = "created_at >= ? AND created_at < DATE_ADD(?, INTERVAL 1 DAY)"
+	rows, err := db.QueryContext(ctx, "SELECT "+leadColumns+" FROM leads WHERE "+created, from, to)
+	if err != nil {
+		return models.AdmissionMetrics{}, dbError(err, "error retrieving data")
+	}
+	defer rows.Close()
+
+	leads := []models.Lead{}
+	for rows.Next() {
+		lead, err := scanLead(rows)
+		if err != nil {
+			return models.AdmissionMetrics{}, dbError(err, "error retrieving data")
+		}
+		leads = append(leads, lead)
+	}
+	if err := rows.Err(); err != nil {
+		return models.AdmissionMetrics{}, dbError(err, "error retrieving data")
+	}
+
+	changes, err := queryStageChanges(ctx, "lead_id IN (SELECT id FROM leads WHERE "+created+")", from, to)
+	if err != nil {
+		return models.AdmissionMetrics{}, err
+	}
+	return models.NewAdmissionMetrics(Pipeline(), leads, changes, from, to), nil
+}
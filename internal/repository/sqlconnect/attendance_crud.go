@@ -0,0 +1,447 @@
+package sqlconnect
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"restapi/internal/models"
+	"strings"
+	"time"
+
+	"github.com/go-mail/mail/v2"
+)
+
+const createAttendanceTable = `CREATE TABLE IF NOT EXISTS attendance (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	student_id INT NOT NULL,
+	class VARCHAR(255) NOT NULL,
+	session_date DATE NOT NULL,
+	period INT NOT NULL DEFAULT 1,
+	status ENUM('present', 'absent', 'late', 'excused') NOT NULL,
+	note VARCHAR(500) NOT NULL DEFAULT '',
+	recorded_by INT NOT NULL DEFAULT 0,
+	recorded_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+	UNIQUE KEY uniq_attendance_session (student_id, session_date, period),
+	KEY idx_attendance_class_date (class, session_date),
+	FOREIGN KEY (student_id) REFERENCES students (id) ON DELETE CASCADE
+)`
+
+const createAttendanceHistoryTable = `CREATE TABLE IF NOT EXISTS attendance_history (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	attendance_id INT NOT NULL,
+	old_status VARCHAR(10) NOT NULL,
+	new_status VARCHAR(10) NOT NULL,
+	old_note VARCHAR(500) NOT NULL DEFAULT '',
+	new_note VARCHAR(500) NOT NULL DEFAULT '',
+	changed_by INT NOT NULL DEFAULT 0,
+	changed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	KEY idx_attendance_history (attendance_id),
+	FOREIGN KEY (attendance_id) REFERENCES attendance (id) ON DELETE CASCADE
+)`
+
+const createAttendanceAlertsTable = `CREATE TABLE IF NOT EXISTS attendance_alerts (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	student_id INT NOT NULL,
+	absences INT NOT NULL,
+	window_start DATE NOT NULL,
+	window_end DATE NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	notified_at TIMESTAMP NULL,
+	KEY idx_attendance_alerts_student (student_id, window_end),
+	FOREIGN KEY (student_id) REFERENCES students (id) ON DELETE CASCADE
+)`
+
+const attendanceColumns = "id, student_id, class, session_date, period, status, note, recorded_by, updated_at"
+
+func scanAttendance(scanner interface{ Scan(...any) error }) (models.Attendance, error) {
+	var record models.Attendance
+	err := scanner.Scan(&record.ID, &record.StudentID, &record.Class, &record.Date, &record.Period, &record.Status, &record.Note, &record.RecordedBy, &record.UpdatedAt)
+	return record, err
+}
+
+// RecordAttendanceSession saves the marks of one class session. Existing marks
+// are updated, and every change is written to attendance_history.
+func RecordAttendanceSession(ctx context.Context, session models.AttendanceSession, recordedBy int) ([]models.Attendance, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return nil, dbError(err, "error recording attendance")
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, dbError(err, "error recording attendance")
+	}
+	defer tx.Rollback()
+
+	inClass, err := classStudentIDs(ctx, tx, session.Class, session.Date)
+	if err != nil {
+		return nil, dbError(err, "error recording attendance")
+	}
+
+	saved := make([]models.Attendance, 0, len(session.Records))
+	for _, mark := range session.Records {
+		if !inClass[mark.StudentID] {
+			return nil, dbError(errors.New("student not in class"), fmt.Sprintf("student %d is not in class %s", mark.StudentID, session.Class))
+		}
+
+		existing, err := scanAttendance(tx.QueryRowContext(ctx, "SELECT "+attendanceColumns+" FROM attendance WHERE student_id = ? AND session_date = ? AND period = ? FOR UPDATE", mark.StudentID, session.Date, session.Period))
+		switch {
+		case err == sql.ErrNoRows:
+			_, err = tx.ExecContext(ctx, "INSERT INTO attendance (student_id, class, session_date, period, status, note, recorded_by) VALUES (?, ?, ?, ?, ?, ?, ?)",
+				mark.StudentID, session.Class, session.Date, session.Period, mark.Status, mark.Note, recordedBy)
+		case err != nil:
+			return nil, dbError(err, "error recording attendance")
+		default:
+			err = updateAttendanceTx(ctx, tx, existing, mark.Status, mark.Note, recordedBy)
+		}
+		if err != nil {
+			return nil, dbError(err, "error recording attendance")
+		}
+
+		record, err := scanAttendance(tx.QueryRowContext(ctx, "SELECT "+attendanceColumns+" FROM attendance WHERE student_id = ? AND session_date = ? AND period = ?", mark.StudentID, session.Date, session.Period))
+		if err != nil {
+			return nil, dbError(err, "error recording attendance")
+		}
+		saved = append(saved, record)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, dbError(err, "error recording attendance")
+	}
+	return saved, nil
+}
+
+// updateAttendanceTx changes a mark and logs the change; unchanged marks are left alone.
+func updateAttendanceTx(ctx context.Context, tx *sql.Tx, existing models.Attendance, status models.AttendanceStatus, note string, changedBy int) error {
+	if existing.Status == status && existing.Note == note {
+		return nil
+	}
+	_, err := tx.ExecContext(ctx, "UPDATE attendance SET status = ?, note = ?, recorded_by = ? WHERE id = ?", status, note, changedBy, existing.ID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "INSERT INTO attendance_history (attendance_id, old_status, new_status, old_note, new_note, changed_by) VALUES (?, ?, ?, ?, ?, ?)",
+		existing.ID, existing.Status, status, existing.Note, note, changedBy)
+	return err
+}
+
+func UpdateAttendance(ctx context.Context, id int, status models.AttendanceStatus, note string, changedBy int) (models.Attendance, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return models.Attendance{}, dbError(err, "error updating data")
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Attendance{}, dbError(err, "error updating data")
+	}
+	defer tx.Rollback()
+
+	existing, err := scanAttendance(tx.QueryRowContext(ctx, "SELECT "+attendanceColumns+" FROM attendance WHERE id = ? FOR UPDATE", id))
+	if err == sql.ErrNoRows {
+		return models.Attendance{}, dbError(err, "Attendance record not found")
+	} else if err != nil {
+		return models.Attendance{}, dbError(err, "error updating data")
+	}
+
+	if err := updateAttendanceTx(ctx, tx, existing, status, note, changedBy); err != nil {
+		return models.Attendance{}, dbError(err, "error updating data")
+	}
+
+	updated, err := scanAttendance(tx.QueryRowContext(ctx, "SELECT "+attendanceColumns+" FROM attendance WHERE id = ?", id))
+	if err != nil {
+		return models.Attendance{}, dbError(err, "error updating data")
+	}
+	if err := tx.Commit(); err != nil {
+		return models.Attendance{}, dbError(err, "error updating data")
+	}
+	return updated, nil
+}
+
+func GetAttendanceHistory(ctx context.Context, id int) ([]models.AttendanceChange, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT id, attendance_id, old_status, new_status, old_note, new_note, changed_by, changed_at FROM attendance_history WHERE attendance_id = ? ORDER BY id", id)
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	defer rows.Close()
+
+	changes := []models.AttendanceChange{}
+	for rows.Next() {
+		var change models.AttendanceChange
+		err := rows.Scan(&change.ID, &change.AttendanceID, &change.OldStatus, &change.NewStatus, &change.OldNote, &change.NewNote, &change.ChangedBy, &change.ChangedAt)
+		if err != nil {
+			return nil, dbError(err, "error retrieving data")
+		}
+		changes = append(changes, change)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	return changes, nil
+}
+
+// GetStudentAttendance returns a student's marks between from and to, inclusive.
+func GetStudentAttendance(ctx context.Context, studentID int, from, to string) ([]models.Attendance, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT "+attendanceColumns+" FROM attendance WHERE student_id = ? AND session_date BETWEEN ? AND ? ORDER BY session_date, period", studentID, from, to)
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	defer rows.Close()
+
+	records := []models.Attendance{}
+	for rows.Next() {
+		record, err := scanAttendance(rows)
+		if err != nil {
+			return nil, dbError(err, "error retrieving data")
+		}
+		records = append(records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	return records, nil
+}
+
+// GetClassRoster lists every student of class with their marks on date,
+// taking the class members of the academic year containing date.
+func GetClassRoster(ctx context.Context, class, date string) ([]models.RosterEntry, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+
+	query := `SELECT s.id, s.first_name, s.last_name, a.id, a.session_date, a.period, a.status, a.note, a.recorded_by, a.updated_at
+		FROM students s
+		LEFT JOIN attendance a ON a.student_id = s.id AND a.session_date = ?
+		WHERE s.id IN (` + classMembersQuery + `)
+		ORDER BY s.last_name, s.first_name, s.id, a.period`
+	rows, err := db.QueryContext(ctx, query, append([]any{date}, classMembersArgs(class, date)...)...)
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	defer rows.Close()
+
+	roster := []models.RosterEntry{}
+	for rows.Next() {
+		var entry models.RosterEntry
+		var id, period, recordedBy sql.NullInt64
+		var sessionDate, status, note, updatedAt sql.NullString
+		err := rows.Scan(&entry.StudentID, &entry.FirstName, &entry.LastName, &id, &sessionDate, &period, &status, &note, &recordedBy, &updatedAt)
+		if err != nil {
+			return nil, dbError(err, "error retrieving data")
+		}
+
+		// Rows of the same student are adjacent
+		if n := len(roster); n == 0 || roster[n-1].StudentID != entry.StudentID {
+			entry.Records = []models.Attendance{}
+			roster = append(roster, entry)
+		}
+		if id.Valid {
+			last := &roster[len(roster)-1]
+			last.Records = append(last.Records, models.Attendance{
+				ID:         int(id.Int64),
+				StudentID:  entry.StudentID,
+				Class:      class,
+				Date:       sessionDate.String,
+				Period:     int(period.Int64),
+				Status:     models.AttendanceStatus(status.String),
+				Note:       note.String,
+				RecordedBy: int(recordedBy.Int64),
+				UpdatedAt:  updatedAt.String,
+			})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	return roster, nil
+}
+
+// RaiseAbsenceAlerts checks the given students against the absence threshold
+// as of date and stores an alert for each one that reached it. A student is
+// alerted at most once per window.
+func RaiseAbsenceAlerts(ctx context.Context, studentIDs []int, date string) ([]models.AttendanceAlert, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return nil, dbError(err, "error checking absences")
+	}
+
+	end, err := time.Parse(time.DateOnly, date)
+	if err != nil {
+		return nil, dbError(err, "invalid date")
+	}
+	windowStart := end.Add(-settings.Attendance.AbsenceWindow).AddDate(0, 0, 1).Format(time.DateOnly)
+
+	var alerts []models.AttendanceAlert
+	for _, studentID := range studentIDs {
+		var absences int
+		err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM attendance WHERE student_id = ? AND status = 'absent' AND session_date BETWEEN ? AND ?", studentID, windowStart, date).Scan(&absences)
+		if err != nil {
+			return nil, dbError(err, "error checking absences")
+		}
+		if absences < settings.Attendance.AbsenceThreshold {
+			continue
+		}
+
+		var recent int
+		err = db.QueryRowContext(ctx, "SELECT COUNT(*) FROM attendance_alerts WHERE student_id = ? AND window_end >= ?", studentID, windowStart).Scan(&recent)
+		if err != nil {
+			return nil, dbError(err, "error checking absences")
+		}
+		if recent > 0 {
+			continue
+		}
+
+		res, err := db.ExecContext(ctx, "INSERT INTO attendance_alerts (student_id, absences, window_start, window_end) VALUES (?, ?, ?, ?)", studentID, absences, windowStart, date)
+		if err != nil {
+			return nil, dbError(err, "error checking absences")
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return nil, dbError(err, "error checking absences")
+		}
+		alerts = append(alerts, models.AttendanceAlert{ID: int(id), StudentID: studentID, Absences: absences, WindowStart: windowStart, WindowEnd: date})
+	}
+	return alerts, nil
+}
+
+func GetAttendanceAlerts(ctx context.Context, limit int) ([]models.AttendanceAlert, error) {
+	return queryAttendanceAlerts(ctx, "ORDER BY id DESC LIMIT ?", limit)
+}
+
+// NotifyAbsenceAlerts emails every alert not sent yet, including those whose
+// earlier send failed. The alerts stay locked until they are marked notified,
+// so concurrent runs skip them instead of mailing them twice; a failed send
+// rolls back and leaves them for the next run.
+func NotifyAbsenceAlerts(ctx context.Context) error {
+	db, err := ConnectDb()
+	if err != nil {
+		return dbError(err, "error sending absence alerts")
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return dbError(err, "error sending absence alerts")
+	}
+	defer tx.Rollback()
+
+	alerts, err := readAttendanceAlerts(ctx, tx, "WHERE notified_at IS NULL ORDER BY id FOR UPDATE SKIP LOCKED")
+	if err != nil || len(alerts) == 0 {
+		return err
+	}
+	sent, err := sendAbsenceAlerts(ctx, tx, alerts)
+	if err != nil || !sent {
+		return err
+	}
+	if err := markAlertsNotified(ctx, tx, alerts); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return dbError(err, "error updating data")
+	}
+	return nil
+}
+
+func queryAttendanceAlerts(ctx context.Context, clause string, args ...any) ([]models.AttendanceAlert, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	return readAttendanceAlerts(ctx, db, clause, args...)
+}
+
+func readAttendanceAlerts(ctx context.Context, q queryer, clause string, args ...any) ([]models.AttendanceAlert, error) {
+	rows, err := q.QueryContext(ctx, "SELECT id, student_id, absences, window_start, window_end, created_at, notified_at FROM attendance_alerts "+clause, args...)
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	defer rows.Close()
+
+	alerts := []models.AttendanceAlert{}
+	for rows.Next() {
+		var alert models.AttendanceAlert
+		var notifiedAt sql.NullString
+		if err := rows.Scan(&alert.ID, &alert.StudentID, &alert.Absences, &alert.WindowStart, &alert.WindowEnd, &alert.CreatedAt, &notifiedAt); err != nil {
+			return nil, dbError(err, "error retrieving data")
+		}
+		alert.NotifiedAt = notifiedAt.String
+		alerts = append(alerts, alert)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	return alerts, nil
+}
+
+// sendAbsenceAlerts emails the alerts to every active admin and manager.
+// Without recipients nothing is sent and the alerts stay pending until
+// someone can receive them.
+func sendAbsenceAlerts(ctx context.Context, tx *sql.Tx, alerts []models.AttendanceAlert) (bool, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT email FROM execs WHERE role IN ('admin', 'manager') AND inactive_status = FALSE")
+	if err != nil {
+		return false, dbError(err, "error sending absence alerts")
+	}
+	var recipients []string
+	for rows.Next() {
+		var email string
+		if err := rows.Scan(&email); err != nil {
+			rows.Close()
+			return false, dbError(err, "error sending absence alerts")
+		}
+		recipients = append(recipients, email)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return false, dbError(err, "error sending absence alerts")
+	}
+	if len(recipients) == 0 {
+		log.Printf("Absence alerts not sent: no active admin or manager to notify (%d pending)", len(alerts))
+		return false, nil
+	}
+
+	var body strings.Builder
+	body.WriteString("The following students reached the absence threshold:\n\n")
+	for _, alert := range alerts {
+		var first, last, class string
+		err := tx.QueryRowContext(ctx, "SELECT first_name, last_name, class FROM students WHERE id = ?", alert.StudentID).Scan(&first, &last, &class)
+		if err != nil {
+			return false, dbError(err, "error sending absence alerts")
+		}
+		fmt.Fprintf(&body, "- %s %s (class %s, id %d): %d absences between %s and %s\n", first, last, class, alert.StudentID, alert.Absences, alert.WindowStart, alert.WindowEnd)
+	}
+
+	m := mail.NewMessage()
+	m.SetHeader("From", settings.Mail.From)
+	m.SetHeader("To", recipients...)
+	m.SetHeader("Subject", "Attendance alert")
+	m.SetBody("text/plain", body.String())
+
+	if err := sendMail(ctx, m); err != nil {
+		return false, dbError(err, "error sending absence alerts")
+	}
+	return true, nil
+}
+
+func markAlertsNotified(ctx context.Context, e execer, alerts []models.AttendanceAlert) error {
+	ids := make([]any, len(alerts))
+	for i, alert := range alerts {
+		ids[i] = alert.ID
+	}
+	query := "UPDATE attendance_alerts SET notified_at = CURRENT_TIMESTAMP WHERE id IN (?" + strings.Repeat(", ?", len(ids)-1) + ")"
+	if _, err := e.ExecContext(ctx, query, ids...); err != nil {
+		return dbError(err, "error updating data")
+	}
+	return nil
+}
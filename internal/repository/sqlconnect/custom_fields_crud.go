@@ -0,0 +1,339 @@
+package sqlconnect
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"restapi/internal/models"
+	"restapi/pkg/utils"
+	"slices"
+	"strings"
+)
+
+const createCustomFieldsTable = `CREATE TABLE IF NOT EXISTS custom_fields (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	resource VARCHAR(20) NOT NULL,
+	name VARCHAR(50) NOT NULL,
+	label VARCHAR(100) NOT NULL,
+	type VARCHAR(10) NOT NULL,
+	options JSON NOT NULL,
+	pattern VARCHAR(255) NOT NULL DEFAULT '',
+	min_value DOUBLE NULL,
+	max_value DOUBLE NULL,
+	required BOOLEAN NOT NULL DEFAULT FALSE,
+	visible_to JSON NOT NULL,
+	position INT NOT NULL DEFAULT 0,
+	UNIQUE KEY uq_custom_fields_name (resource, name)
+)`
+
+const createCustomFieldValuesTable = `CREATE TABLE IF NOT EXISTS custom_field_values (
+	field_id INT NOT NULL,
+	record_id INT NOT NULL,
+	value VARCHAR(1000) NOT NULL,
+	PRIMARY KEY (field_id, record_id),
+	KEY idx_custom_field_values_value (field_id, value(100)),
+	FOREIGN KEY (field_id) REFERENCES custom_fields (id) ON DELETE CASCADE
+)`
+
+const customFieldColumns = "id, resource, name, label, type, options, pattern, min_value, max_value, required, visible_to, position"
+
+func scanCustomField(scanner interface{ Scan(...any) error }) (models.CustomField, error) {
+	var f models.CustomField
+	var options, visibleTo []byte
+	var min, max sql.NullFloat64
+	err := scanner.Scan(&f.ID, &f.Resource, &f.Name, &f.Label, &f.Type, &options, &f.Pattern, &min, &max, &f.Required, &visibleTo, &f.Position)
+	if err != nil {
+		return f, err
+	}
+	if min.Valid {
+		f.Min = &min.Float64
+	}
+	if max.Valid {
+		f.Max = &max.Float64
+	}
+	if err := json.Unmarshal(options, &f.Options); err != nil {
+		log.Println("Invalid custom field options", f.ID, err)
+	}
+	if err := json.Unmarshal(visibleTo, &f.VisibleTo); err != nil {
+		log.Println("Invalid custom field roles", f.ID, err)
+	}
+	return f, nil
+}
+
+// GetCustomFields returns the fields defined for resource, or for every
+// resource when it is empty, in display order.
+func GetCustomFields(ctx context.Context, resource string) (models.CustomFields, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+
+	query := "SELECT " + customFieldColumns + " FROM custom_fields WHERE 1=1"
+	var args []interface{}
+	if resource != "" {
+		query += " AND resource = ?"
+		args = append(args, resource)
+	}
+	rows, err := db.QueryContext(ctx, query+" ORDER BY resource, position, id", args...)
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	defer rows.Close()
+
+	fields := models.CustomFields{}
+	for rows.Next() {
+		f, err := scanCustomField(rows)
+		if err != nil {
+			return nil, dbError(err, "error retrieving data")
+		}
+		fields = append(fields, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	return fields, nil
+}
+
+// VisibleCustomFields returns the fields of resource the user in ctx may read
+// and set.
+func VisibleCustomFields(ctx context.Context, resource string) (models.CustomFields, error) {
+	fields, err := GetCustomFields(ctx, resource)
+	if err != nil {
+		return nil, err
+	}
+	return fields.Visible(utils.UserRole(ctx)), nil
+}
+
+func GetCustomFieldByID(ctx context.Context, id int) (models.CustomField, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return models.CustomField{}, dbError(err, "error retrieving data")
+	}
+
+	f, err := scanCustomField(db.QueryRowContext(ctx, "SELECT "+customFieldColumns+" FROM custom_fields WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return models.CustomField{}, dbError(err, "Custom field not found")
+	} else if err != nil {
+		return models.CustomField{}, dbError(err, "error retrieving data")
+	}
+	return f, nil
+}
+
+// SaveCustomField inserts the field when it has no ID and updates it
+// otherwise. Names are unique per resource.
+func SaveCustomField(ctx context.Context, f models.CustomField) (models.CustomField, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return models.CustomField{}, dbError(err, "error updating data")
+	}
+
+	var taken int
+	err = db.QueryRowContext(ctx, "SELECT COUNT(*) FROM custom_fields WHERE resource = ? AND name = ? AND id <> ?", f.Resource, f.Name, f.ID).Scan(&taken)
+	if err != nil {
+		return models.CustomField{}, dbError(err, "error updating data")
+	}
+	if taken > 0 {
+		return models.CustomField{}, dbError(errors.New("duplicate custom field"), fmt.Sprintf("custom field %q already exists for %s", f.Name, f.Resource))
+	}
+
+	options, err := json.Marshal(f.Options)
+	if err != nil {
+		return models.CustomField{}, dbError(err, "error updating data")
+	}
+	visibleTo, err := json.Marshal(f.VisibleTo)
+	if err != nil {
+		return models.CustomField{}, dbError(err, "error updating data")
+	}
+
+	if f.ID == 0 {
+		res, err := db.ExecContext(ctx, `INSERT INTO custom_fields (resource, name, label, type, options, pattern, min_value, max_value, required, visible_to, position)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			f.Resource, f.Name, f.Label, f.Type, options, f.Pattern, f.Min, f.Max, f.Required, visibleTo, f.Position)
+		if err != nil {
+			return models.CustomField{}, dbError(err, "error adding data")
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return models.CustomField{}, dbError(err, "error adding data")
+		}
+		f.ID = int(id)
+		return f, nil
+	}
+
+	_, err = db.ExecContext(ctx, `UPDATE custom_fields SET label = ?, options = ?, pattern = ?, min_value = ?, max_value = ?, required = ?, visible_to = ?, position = ?
+		WHERE id = ?`, f.Label, options, f.Pattern, f.Min, f.Max, f.Required, visibleTo, f.Position, f.ID)
+	if err != nil {
+		return models.CustomField{}, dbError(err, "error updating data")
+	}
+	return f, nil
+}
+
+// DeleteCustomField removes the field together with its stored values.
+func DeleteCustomField(ctx context.Context, id int) error {
+	return deleteByID(ctx, "custom_fields", id, "Custom field not found")
+}
+
+// customValueBatch caps the record ids read per query.
+const customValueBatch = 500
+
+// GetCustomValues returns the stored values of fields for the records, by
+// record id and field name.
+func GetCustomValues(ctx context.Context, fields models.CustomFields, ids []int) (map[int]map[string]string, error) {
+	values := make(map[int]map[string]string)
+	if len(fields) == 0 || len(ids) == 0 {
+		return values, nil
+	}
+	db, err := ConnectDb()
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+
+	names := make(map[int]string, len(fields))
+	var fieldArgs []interface{}
+	for _, f := range fields {
+		names[f.ID] = f.Name
+		fieldArgs = append(fieldArgs, f.ID)
+	}
+
+	for batch := range slices.Chunk(ids, customValueBatch) {
+		args := append([]interface{}{}, fieldArgs...)
+		for _, id := range batch {
+			args = append(args, id)
+		}
+		rows, err := db.QueryContext(ctx, "SELECT field_id, record_id, value FROM custom_field_values WHERE field_id IN ("+
+			placeholders(len(fieldArgs))+") AND record_id IN ("+placeholders(len(batch))+")", args...)
+		if err != nil {
+			return nil, dbError(err, "error retrieving data")
+		}
+		for rows.Next() {
+			var fieldID, recordID int
+			var value string
+			if err := rows.Scan(&fieldID, &recordID, &value); err != nil {
+				rows.Close()
+				return nil, dbError(err, "error retrieving data")
+			}
+			if values[recordID] == nil {
+				values[recordID] = make(map[string]string)
+			}
+			values[recordID][names[fieldID]] = value
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, dbError(err, "error retrieving data")
+		}
+	}
+	return values, nil
+}
+
+// saveCustomValues stores values, by field name, for one record of resource
+// inside tx, the transaction writing the record itself; "" clears a field.
+// Changes are recorded in the record's history as custom.<name>.
+func saveCustomValues(ctx context.Context, tx *sql.Tx, resource string, recordID int, fields models.CustomFields, values map[string]string) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	changes := map[string]models.FieldChange{}
+	for name, value := range values {
+		f, ok := fields.Find(name)
+		if !ok {
+			continue
+		}
+		var current string
+		err := tx.QueryRowContext(ctx, "SELECT value FROM custom_field_values WHERE field_id = ? AND record_id = ? FOR UPDATE", f.ID, recordID).Scan(&current)
+		if err != nil && err != sql.ErrNoRows {
+			return dbError(err, "error updating data")
+		}
+		if current == value {
+			continue
+		}
+
+		if value == "" {
+			_, err = tx.ExecContext(ctx, "DELETE FROM custom_field_values WHERE field_id = ? AND record_id = ?", f.ID, recordID)
+		} else {
+			_, err = tx.ExecContext(ctx, `INSERT INTO custom_field_values (field_id, record_id, value) VALUES (?, ?, ?)
+				ON DUPLICATE KEY UPDATE value = VALUES(value)`, f.ID, recordID, value)
+		}
+		if err != nil {
+			return dbError(err, "error updating data")
+		}
+		change := models.FieldChange{}
+		if current != "" {
+			change.From = f.Decode(current)
+		}
+		if value != "" {
+			change.To = f.Decode(value)
+		}
+		changes["custom."+name] = change
+	}
+
+	return insertChanges(ctx, tx, resource, recordID, models.ActionUpdated, changes)
+}
+
+// deleteCustomValues removes the custom field values of a deleted record.
+func deleteCustomValues(ctx context.Context, e execer, resource string, recordID int) error {
+	_, err := e.ExecContext(ctx, `DELETE v FROM custom_field_values v JOIN custom_fields f ON f.id = v.field_id
+		WHERE f.resource = ? AND v.record_id = ?`, resource, recordID)
+	if err != nil {
+		return dbError(err, "error deleting data")
+	}
+	return nil
+}
+
+// customFieldFilter appends a condition for every "custom.<name>" param
+// naming one of fields, matching records by the id column. Values are
+// compared as stored, so 12.0 finds 12.
+func customFieldFilter(r *http.Request, fields models.CustomFields, column, query string, args []interface{}) (string, []interface{}) {
+	for _, f := range fields {
+		value := r.URL.Query().Get("custom." + f.Name)
+		if value == "" {
+			continue
+		}
+		if normalized, err := f.Normalize(value); err == nil {
+			value = normalized
+		}
+		query += " AND " + column + " IN (SELECT record_id FROM custom_field_values WHERE field_id = ? AND value = ?)"
+		args = append(args, f.ID, value)
+	}
+	return query, args
+}
+
+// listOrder turns the "sortby=field:asc|desc" params into an ORDER BY
+// clause, in the order given. Fields are the sortFields columns or
+// "custom.<name>" of one of fields, read for the record in the id column;
+// numbers sort by value. It returns "" when nothing valid is requested.
+func listOrder(r *http.Request, sortFields []string, fields models.CustomFields, column string) string {
+	var order []string
+	for _, param := range r.URL.Query()["sortby"] {
+		field, direction, ok := strings.Cut(param, ":")
+		if !ok || direction != "asc" && direction != "desc" {
+			continue
+		}
+		if slices.Contains(sortFields, field) {
+			order = append(order, field+" "+direction)
+			continue
+		}
+		name, ok := strings.CutPrefix(field, "custom.")
+		if !ok {
+			continue
+		}
+		f, ok := fields.Find(name)
+		if !ok {
+			continue
+		}
+		value := fmt.Sprintf("(SELECT value FROM custom_field_values WHERE field_id = %d AND record_id = %s)", f.ID, column)
+		if f.Type == models.FieldNumber {
+			value = "CAST(" + value + " AS DECIMAL(30,10))"
+		}
+		order = append(order, value+" "+direction)
+	}
+	if len(order) == 0 {
+		return ""
+	}
+	return " ORDER BY " + strings.Join(order, ", ")
+}
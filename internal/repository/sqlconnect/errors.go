@@ -0,0 +1,29 @@
+package sqlconnect
+
+import (
+	"context"
+	"errors"
+	"restapi/pkg/utils"
+)
+
+var (
+	// ErrQueryCanceled means the client went away before the query finished.
+	ErrQueryCanceled = errors.New("request canceled")
+	// ErrQueryTimeout means the request ran past its deadline.
+	ErrQueryTimeout = errors.New("request timed out")
+)
+
+// dbError logs err like utils.ErrorHandler and returns message to the caller,
+// except that context cancellation comes back as ErrQueryCanceled or
+// ErrQueryTimeout so handlers can tell it apart from a database failure.
+func dbError(err error, message string) error {
+	switch {
+	case errors.Is(err, context.Canceled):
+		utils.ErrorHandler(err, message)
+		return ErrQueryCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		utils.ErrorHandler(err, message)
+		return ErrQueryTimeout
+	}
+	return utils.ErrorHandler(err, message)
+}
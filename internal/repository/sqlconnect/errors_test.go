@@ -0,0 +1,35 @@
+package sqlconnect
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestDbError(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		want    error
+		wantMsg string
+	}{
+		{"canceled", context.Canceled, ErrQueryCanceled, "request canceled"},
+		// Драйвер оборачивает ошибку контекста
+		{"wrapped deadline", fmt.Errorf("query failed: %w", context.DeadlineExceeded), ErrQueryTimeout, "request timed out"},
+		{"other error keeps message", sql.ErrNoRows, nil, "error retrieving data"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := dbError(tt.err, "error retrieving data")
+			if tt.want != nil && !errors.Is(got, tt.want) {
+				t.Errorf("dbError() = %v, want %v", got, tt.want)
+			}
+			if got.Error() != tt.wantMsg {
+				t.Errorf("dbError() message = %q, want %q", got.Error(), tt.wantMsg)
+			}
+		})
+	}
+}
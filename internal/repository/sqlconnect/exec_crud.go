@@ -1,6 +1,7 @@
 package sqlconnect
 
 import (
+	"context"
 	"crypto/rand"
 	"crypto/sha256"
 	"database/sql"
@@ -8,24 +9,22 @@ import (
 	"fmt"
 	"log"
 	"net/http"
-	"os"
 	"reflect"
 	"restapi/internal/models"
 	"restapi/pkg/utils"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-mail/mail/v2"
 )
 
-func GetExecsDbHandler(execs []models.Exec, r *http.Request) ([]models.Exec, error) {
+func GetExecsDbHandler(ctx context.Context, execs []models.Exec, r *http.Request) ([]models.Exec, error) {
 	db, err := ConnectDb()
 	if err != nil {
-		return nil, utils.ErrorHandler(err, "error retrieving data")
+		return nil, dbError(err, "error retrieving data")
 	}
 
-	defer db.Close()
-
 	query := "SELECT id, first_name, last_name, email, username, user_created_at, inactive_status, role FROM execs WHERE 1=1"
 	var args []interface{}
 
@@ -33,10 +32,10 @@ func GetExecsDbHandler(execs []models.Exec, r *http.Request) ([]models.Exec, err
 
 	query = utils.AddSorting(r, query)
 
-	rows, err := db.Query(query, args...)
+	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
 		fmt.Println(err)
-		return nil, utils.ErrorHandler(err, "error retrieving data")
+		return nil, dbError(err, "error retrieving data")
 	}
 	defer rows.Close()
 
@@ -44,43 +43,42 @@ func GetExecsDbHandler(execs []models.Exec, r *http.Request) ([]models.Exec, err
 		var exec models.Exec
 		err := rows.Scan(&exec.ID, &exec.FirstName, &exec.LastName, &exec.Email, &exec.Username, &exec.UserCreatedAt, &exec.InactiveStatus, &exec.Role)
 		if err != nil {
-			return nil, utils.ErrorHandler(err, "error retrieving data")
+			return nil, dbError(err, "error retrieving data")
 		}
 		execs = append(execs, exec)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
 	return execs, nil
 }
 
-func GetExecByID(id int) (models.Exec, error) {
+func GetExecByID(ctx context.Context, id int) (models.Exec, error) {
 	db, err := ConnectDb()
 	if err != nil {
-		return models.Exec{}, utils.ErrorHandler(err, "error retrieving data")
+		return models.Exec{}, dbError(err, "error retrieving data")
 	}
 
-	defer db.Close()
-
 	var exec models.Exec
-	err = db.QueryRow("SELECT id, first_name, last_name, email, username, inactive_status, role FROM execs WHERE id = ?", id).Scan(&exec.ID, &exec.FirstName, &exec.LastName, &exec.Email, &exec.Username, &exec.InactiveStatus, &exec.Role)
+	err = db.QueryRowContext(ctx, "SELECT id, first_name, last_name, email, username, inactive_status, role FROM execs WHERE id = ?", id).Scan(&exec.ID, &exec.FirstName, &exec.LastName, &exec.Email, &exec.Username, &exec.InactiveStatus, &exec.Role)
 	if err == sql.ErrNoRows {
-		return models.Exec{}, utils.ErrorHandler(err, "error retrieving data")
+		return models.Exec{}, dbError(err, "error retrieving data")
 	} else if err != nil {
 		fmt.Println(err)
-		return models.Exec{}, utils.ErrorHandler(err, "error retrieving data")
+		return models.Exec{}, dbError(err, "error retrieving data")
 	}
 	return exec, nil
 }
 
-func AddExecsDBHandler(newExecs []models.Exec) ([]models.Exec, error) {
+func AddExecsDBHandler(ctx context.Context, newExecs []models.Exec) ([]models.Exec, error) {
 	db, err := ConnectDb()
 	if err != nil {
-		return nil, utils.ErrorHandler(err, "error adding data")
+		return nil, dbError(err, "error adding data")
 	}
 
-	defer db.Close()
-
-	stmt, err := db.Prepare(utils.GenerateInsertQuery("execs", models.Exec{}))
+	stmt, err := db.PrepareContext(ctx, utils.GenerateInsertQuery("execs", models.Exec{}))
 	if err != nil {
-		return nil, utils.ErrorHandler(err, "error adding data")
+		return nil, dbError(err, "error adding data")
 	}
 	defer stmt.Close()
 
@@ -88,17 +86,17 @@ func AddExecsDBHandler(newExecs []models.Exec) ([]models.Exec, error) {
 	for i, newExec := range newExecs {
 		newExec.Password, err = utils.HashPassword(newExec.Password)
 		if err != nil {
-			return nil, utils.ErrorHandler(err, "error adding exec into database")
+			return nil, dbError(err, "error adding exec into database")
 		}
 
 		values := utils.GetStructValues(newExec)
-		res, err := stmt.Exec(values...)
+		res, err := stmt.ExecContext(ctx, values...)
 		if err != nil {
-			return nil, utils.ErrorHandler(err, "error adding data")
+			return nil, dbError(err, "error adding data")
 		}
 		lastID, err := res.LastInsertId()
 		if err != nil {
-			return nil, utils.ErrorHandler(err, "error adding data")
+			return nil, dbError(err, "error adding data")
 		}
 		newExec.ID = int(lastID)
 		addedExecs[i] = newExec
@@ -106,39 +104,38 @@ func AddExecsDBHandler(newExecs []models.Exec) ([]models.Exec, error) {
 	return addedExecs, nil
 }
 
-func PatchExecs(updates []map[string]interface{}) error {
+func PatchExecs(ctx context.Context, updates []map[string]interface{}) error {
 	db, err := ConnectDb()
 	if err != nil {
-		return utils.ErrorHandler(err, "error updating data")
+		return dbError(err, "error updating data")
 	}
-	defer db.Close()
 
-	tx, err := db.Begin()
+	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
-		return utils.ErrorHandler(err, "error updating data")
+		return dbError(err, "error updating data")
 	}
 
 	for _, update := range updates {
 		idStr, ok := update["id"].(string)
 		if !ok {
 			tx.Rollback()
-			return utils.ErrorHandler(err, "invalid Id")
+			return dbError(err, "invalid Id")
 		}
 
 		id, err := strconv.Atoi(idStr)
 		if err != nil {
 			tx.Rollback()
-			return utils.ErrorHandler(err, "invalid Id")
+			return dbError(err, "invalid Id")
 		}
 
 		var ExecFromDb models.Exec
-		err = db.QueryRow("SELECT id, first_name, last_name, email, username FROM execs WHERE id = ?", id).Scan(&ExecFromDb.ID, &ExecFromDb.FirstName, &ExecFromDb.LastName, &ExecFromDb.Email, &ExecFromDb.Username)
+		err = db.QueryRowContext(ctx, "SELECT id, first_name, last_name, email, username FROM execs WHERE id = ?", id).Scan(&ExecFromDb.ID, &ExecFromDb.FirstName, &ExecFromDb.LastName, &ExecFromDb.Email, &ExecFromDb.Username)
 		if err != nil {
 			tx.Rollback()
 			if err == sql.ErrNoRows {
-				return utils.ErrorHandler(err, "Exec not found")
+				return dbError(err, "Exec not found")
 			}
-			return utils.ErrorHandler(err, "error updating data")
+			return dbError(err, "error updating data")
 		}
 
 		execVal := reflect.ValueOf(&ExecFromDb).Elem()
@@ -159,7 +156,7 @@ func PatchExecs(updates []map[string]interface{}) error {
 						} else {
 							tx.Rollback()
 							log.Printf("cannot convert %v to %v", val.Type(), fieldVal.Type())
-							return utils.ErrorHandler(err, "error updating data")
+							return dbError(err, "error updating data")
 						}
 					}
 					break
@@ -167,35 +164,34 @@ func PatchExecs(updates []map[string]interface{}) error {
 			}
 		}
 
-		_, err = tx.Exec("UPDATE execs SET first_name = ?, last_name = ?, email = ?, username = ? WHERE id = ?", ExecFromDb.FirstName, ExecFromDb.LastName, ExecFromDb.Email, ExecFromDb.Username, ExecFromDb.ID)
+		_, err = tx.ExecContext(ctx, "UPDATE execs SET first_name = ?, last_name = ?, email = ?, username = ? WHERE id = ?", ExecFromDb.FirstName, ExecFromDb.LastName, ExecFromDb.Email, ExecFromDb.Username, ExecFromDb.ID)
 		if err != nil {
 			tx.Rollback()
-			return utils.ErrorHandler(err, "error updating data")
+			return dbError(err, "error updating data")
 		}
 	}
 
 	err = tx.Commit()
 	if err != nil {
-		return utils.ErrorHandler(err, "error updating data")
+		return dbError(err, "error updating data")
 	}
 	return nil
 }
 
-func PatchOneExec(id int, updates map[string]interface{}) (models.Exec, error) {
+func PatchOneExec(ctx context.Context, id int, updates map[string]interface{}) (models.Exec, error) {
 	db, err := ConnectDb()
 	if err != nil {
 		log.Println(err)
-		return models.Exec{}, utils.ErrorHandler(err, "error updating data")
+		return models.Exec{}, dbError(err, "error updating data")
 	}
-	defer db.Close()
 
 	var existingExec models.Exec
-	err = db.QueryRow("SELECT id, first_name, last_name, email, username FROM execs WHERE id = ?", id).Scan(&existingExec.ID, &existingExec.FirstName, &existingExec.LastName, &existingExec.Email, &existingExec.Username)
+	err = db.QueryRowContext(ctx, "SELECT id, first_name, last_name, email, username FROM execs WHERE id = ?", id).Scan(&existingExec.ID, &existingExec.FirstName, &existingExec.LastName, &existingExec.Email, &existingExec.Username)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return models.Exec{}, utils.ErrorHandler(err, "Exec not found")
+			return models.Exec{}, dbError(err, "Exec not found")
 		}
-		return models.Exec{}, utils.ErrorHandler(err, "error updating data")
+		return models.Exec{}, dbError(err, "error updating data")
 	}
 
 	execVal := reflect.ValueOf(&existingExec).Elem()
@@ -213,115 +209,107 @@ func PatchOneExec(id int, updates map[string]interface{}) (models.Exec, error) {
 		}
 	}
 
-	_, err = db.Exec("UPDATE execs SET first_name = ?, last_name = ?, email = ?, username = ? WHERE id = ?", existingExec.FirstName, existingExec.LastName, existingExec.Email, &existingExec.Username, existingExec.ID)
+	_, err = db.ExecContext(ctx, "UPDATE execs SET first_name = ?, last_name = ?, email = ?, username = ? WHERE id = ?", existingExec.FirstName, existingExec.LastName, existingExec.Email, &existingExec.Username, existingExec.ID)
 	if err != nil {
-		return models.Exec{}, utils.ErrorHandler(err, "error updating data")
+		return models.Exec{}, dbError(err, "error updating data")
 	}
 	return existingExec, nil
 }
 
-func DeleteOneExec(id int) error {
+func DeleteOneExec(ctx context.Context, id int) error {
 	db, err := ConnectDb()
 	if err != nil {
-		return utils.ErrorHandler(err, "error updating data")
+		return dbError(err, "error updating data")
 	}
-	defer db.Close()
 
-	result, err := db.Exec("DELETE FROM execs WHERE id = ?", id)
+	result, err := db.ExecContext(ctx, "DELETE FROM execs WHERE id = ?", id)
 	if err != nil {
-		return utils.ErrorHandler(err, "error updating data")
+		return dbError(err, "error updating data")
 	}
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		return utils.ErrorHandler(err, "error updating data")
+		return dbError(err, "error updating data")
 	}
 
 	if rowsAffected == 0 {
-		return utils.ErrorHandler(err, "Exec not found")
+		return dbError(err, "Exec not found")
 	}
 	return nil
 }
 
-func GetUserByUsername(username string) (*models.Exec, error) {
+func GetUserByUsername(ctx context.Context, username string) (*models.Exec, error) {
 	db, err := ConnectDb()
 	if err != nil {
-		return nil, utils.ErrorHandler(err, "internal error")
+		return nil, dbError(err, "internal error")
 	}
-	defer db.Close()
 
 	user := &models.Exec{}
-	err = db.QueryRow(`SELECT id, first_name, last_name, email, username, password, inactive_status, role FROM execs WHERE username = ?`, username).Scan(&user.ID, &user.FirstName, &user.LastName, &user.Email, &user.Username, &user.Password, &user.InactiveStatus, &user.Role)
+	err = db.QueryRowContext(ctx, `SELECT id, first_name, last_name, email, username, password, inactive_status, role FROM execs WHERE username = ?`, username).Scan(&user.ID, &user.FirstName, &user.LastName, &user.Email, &user.Username, &user.Password, &user.InactiveStatus, &user.Role)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, utils.ErrorHandler(err, "user not found")
+			return nil, dbError(err, "user not found")
 		}
-		return nil, utils.ErrorHandler(err, "database error")
+		return nil, dbError(err, "database error")
 	}
 	return user, nil
 }
 
-func UpdatePasswordInDb(userId int, currentPassword, newPassword string) (bool, error) {
+func UpdatePasswordInDb(ctx context.Context, userId int, currentPassword, newPassword string) (bool, error) {
 	db, err := ConnectDb()
 	if err != nil {
-		return false, utils.ErrorHandler(err, "database connection error")
+		return false, dbError(err, "database connection error")
 	}
-	defer db.Close()
 
 	var username string
 	var userPassword string
 	var userRole string
 
-	err = db.QueryRow("SELECT username, password, role FROM execs WHERE id = ?", userId).Scan(&username, &userPassword, &userRole)
+	err = db.QueryRowContext(ctx, "SELECT username, password, role FROM execs WHERE id = ?", userId).Scan(&username, &userPassword, &userRole)
 	if err != nil {
-		return false, utils.ErrorHandler(err, "user not found")
+		return false, dbError(err, "user not found")
 	}
 
 	err = utils.VerifyPassword(currentPassword, userPassword)
 	if err != nil {
-		return false, utils.ErrorHandler(err, "The password you entered does not match the current password on file.")
+		return false, dbError(err, "The password you entered does not match the current password on file.")
 	}
 
 	hashedPassword, err := utils.HashPassword(newPassword)
 	if err != nil {
-		return false, utils.ErrorHandler(err, "internal error")
+		return false, dbError(err, "internal error")
 	}
 
 	currentTime := time.Now().Format(time.RFC3339)
 
-	_, err = db.Exec("UPDATE execs SET password = ?, password_changed_at = ? WHERE id = ?", hashedPassword, currentTime, userId)
+	_, err = db.ExecContext(ctx, "UPDATE execs SET password = ?, password_changed_at = ? WHERE id = ?", hashedPassword, currentTime, userId)
 	if err != nil {
-		return false, utils.ErrorHandler(err, "failed to update the password")
+		return false, dbError(err, "failed to update the password")
 	}
 
 	return true, nil
 }
 
-func ForgotPasswordDbHandler(emailId string) error {
+func ForgotPasswordDbHandler(ctx context.Context, emailId string) error {
 	db, err := ConnectDb()
 	if err != nil {
-		return utils.ErrorHandler(err, "Internal error")
+		return dbError(err, "Internal error")
 	}
-	defer db.Close()
 
 	var exec models.Exec
-	err = db.QueryRow("SELECT id FROM execs WHERE email = ?", emailId).Scan(&exec.ID)
+	err = db.QueryRowContext(ctx, "SELECT id FROM execs WHERE email = ?", emailId).Scan(&exec.ID)
 	if err != nil {
-		return utils.ErrorHandler(err, "User not found")
+		return dbError(err, "User not found")
 	}
 
-	duration, err := strconv.Atoi(os.Getenv("RESET_TOKEN_EXP_DURATION"))
-	if err != nil {
-		return utils.ErrorHandler(err, "Failed to send password reset email")
-	}
-	mins := time.Duration(duration)
+	ttl := settings.JWT.ResetTokenTTL
 
-	expiry := time.Now().Add(mins * time.Minute).Format(time.RFC3339)
+	expiry := time.Now().Add(ttl).Format(time.RFC3339)
 
 	tokenBytes := make([]byte, 32)
 	_, err = rand.Read(tokenBytes)
 	if err != nil {
-		return utils.ErrorHandler(err, "Failed to send password reset email")
+		return dbError(err, "Failed to send password reset email")
 	}
 
 	token := hex.EncodeToString(tokenBytes)
@@ -330,37 +318,34 @@ func ForgotPasswordDbHandler(emailId string) error {
 
 	hashedTokenString := hex.EncodeToString(hashedToken[:])
 
-	_, err = db.Exec("UPDATE execs SET password_reset_token = ?, password_token_expires = ? WHERE id = ?", hashedTokenString, expiry, exec.ID)
+	_, err = db.ExecContext(ctx, "UPDATE execs SET password_reset_token = ?, password_token_expires = ? WHERE id = ?", hashedTokenString, expiry, exec.ID)
 	if err != nil {
-		return utils.ErrorHandler(err, "Failed to send password reset email")
+		return dbError(err, "Failed to send password reset email")
 	}
 
-	resetURL := fmt.Sprintf("http://localhost:3000/execs/resetpassword/reset/%s", token)
+	resetURL := fmt.Sprintf("%s/execs/resetpassword/reset/%s", strings.TrimSuffix(settings.Server.PublicURL, "/"), token)
 	message := fmt.Sprintf(
 		"Forgot your password? Reset your password using the following link:\n%s\n\nIf you didn't request a password reset, please ignore this email. This link is only valid for %d minutes.",
-		resetURL, int(mins),
+		resetURL, int(ttl.Minutes()),
 	)
 
 	m := mail.NewMessage()
-	m.SetHeader("From", "schooladmin@shool.com")
+	m.SetHeader("From", settings.Mail.From)
 	m.SetHeader("To", emailId)
 	m.SetHeader("Subject", "Your password reset link")
 	m.SetBody("text/plain", message)
 
-	d := mail.NewDialer("localhost", 1025, "", "")
-	err = d.DialAndSend(m)
-	if err != nil {
-
-		return utils.ErrorHandler(err, "Failed to send password reset email")
+	if err := sendMail(ctx, m); err != nil {
+		return dbError(err, "Failed to send password reset email")
 	}
 	return nil
 }
 
-func ResetPasswordDbHandler(token, newPassword string) error {
+func ResetPasswordDbHandler(ctx context.Context, token, newPassword string) error {
 
 	bytes, err := hex.DecodeString(token)
 	if err != nil {
-		return utils.ErrorHandler(err, "Internal error")
+		return dbError(err, "Internal error")
 	}
 
 	hashedToken := sha256.Sum256(bytes)
@@ -368,27 +353,26 @@ func ResetPasswordDbHandler(token, newPassword string) error {
 
 	db, err := ConnectDb()
 	if err != nil {
-		return utils.ErrorHandler(err, "Internal error")
+		return dbError(err, "Internal error")
 	}
-	defer db.Close()
 
 	var user models.Exec
 
 	query := "SELECT id, email FROM execs WHERE password_reset_token = ? AND password_token_expires > ?"
-	err = db.QueryRow(query, hashedTokenString, time.Now().Format(time.RFC3339)).Scan(&user.ID, &user.Email)
+	err = db.QueryRowContext(ctx, query, hashedTokenString, time.Now().Format(time.RFC3339)).Scan(&user.ID, &user.Email)
 	if err != nil {
-		return utils.ErrorHandler(err, "Invalid or expired reset code")
+		return dbError(err, "Invalid or expired reset code")
 	}
 
 	hashedPassword, err := utils.HashPassword(newPassword)
 	if err != nil {
-		return utils.ErrorHandler(err, "Internal error")
+		return dbError(err, "Internal error")
 	}
 
 	updateQuery := "UPDATE execs SET password = ?, password_reset_token = NULL, password_token_expires = NULL, password_changed_at = ? WHERE id = ?"
-	_, err = db.Exec(updateQuery, hashedPassword, time.Now().Format(time.RFC3339), user.ID)
+	_, err = db.ExecContext(ctx, updateQuery, hashedPassword, time.Now().Format(time.RFC3339), user.ID)
 	if err != nil {
-		return utils.ErrorHandler(err, "Internal error")
+		return dbError(err, "Internal error")
 	}
 	return nil
 }
@@ -0,0 +1,318 @@
+package sqlconnect
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"restapi/internal/models"
+)
+
+const createAssessmentsTable = `CREATE TABLE IF NOT EXISTS assessments (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	class VARCHAR(255) NOT NULL,
+	subject VARCHAR(255) NOT NULL,
+	type VARCHAR(20) NOT NULL,
+	title VARCHAR(255) NOT NULL,
+	max_score DECIMAL(7,2) NOT NULL,
+	weight DECIMAL(5,2) NOT NULL DEFAULT 1,
+	assessment_date DATE NOT NULL,
+	created_by INT NOT NULL DEFAULT 0,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	KEY idx_assessments_class_subject (class, subject, assessment_date)
+)`
+
+const createScoresTable = `CREATE TABLE IF NOT EXISTS scores (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	assessment_id INT NOT NULL,
+	student_id INT NOT NULL,
+	score DECIMAL(7,2) NOT NULL,
+	comment VARCHAR(500) NOT NULL DEFAULT '',
+	recorded_by INT NOT NULL DEFAULT 0,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+	UNIQUE KEY uniq_scores_student (assessment_id, student_id),
+	KEY idx_scores_student (student_id),
+	FOREIGN KEY (assessment_id) REFERENCES assessments (id) ON DELETE CASCADE,
+	FOREIGN KEY (student_id) REFERENCES students (id) ON DELETE CASCADE
+)`
+
+const assessmentColumns = "id, class, subject, type, title, max_score, weight, assessment_date, created_by"
+
+func scanAssessment(scanner interface{ Scan(...any) error }) (models.Assessment, error) {
+	var a models.Assessment
+	err := scanner.Scan(&a.ID, &a.Class, &a.Subject, &a.Type, &a.Title, &a.MaxScore, &a.Weight, &a.Date, &a.CreatedBy)
+	return a, err
+}
+
+// GradeScale returns the letter-grade scale from the configuration.
+func GradeScale() models.GradeScale {
+	scale := make(models.GradeScale, 0, len(settings.Grading.Scale))
+	for _, band := range settings.Grading.Scale {
+		scale = append(scale, models.GradeBand{Letter: band.Letter, Min: band.Min})
+	}
+	return scale
+}
+
+func AddAssessment(ctx context.Context, a models.Assessment) (models.Assessment, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return models.Assessment{}, dbError(err, "error adding data")
+	}
+
+	res, err := db.ExecContext(ctx, "INSERT INTO assessments (class, subject, type, title, max_score, weight, assessment_date, created_by) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		a.Class, a.Subject, a.Type, a.Title, a.MaxScore, a.Weight, a.Date, a.CreatedBy)
+	if err != nil {
+		return models.Assessment{}, dbError(err, "error adding data")
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return models.Assessment{}, dbError(err, "error adding data")
+	}
+	a.ID = int(id)
+	return a, nil
+}
+
+// GetAssessments lists assessments; empty arguments don't filter.
+func GetAssessments(ctx context.Context, class, subject, from, to string) ([]models.Assessment, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+
+	query := "SELECT " + assessmentColumns + " FROM assessments WHERE 1=1"
+	var args []interface{}
+	if class != "" {
+		query += " AND class = ?"
+		args = append(args, class)
+	}
+	if subject != "" {
+		query += " AND subject = ?"
+		args = append(args, subject)
+	}
+	if from != "" {
+		query += " AND assessment_date >= ?"
+		args = append(args, from)
+	}
+	if to != "" {
+		query += " AND assessment_date <= ?"
+		args = append(args, to)
+	}
+	query += " ORDER BY assessment_date, id"
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	defer rows.Close()
+
+	assessments := []models.Assessment{}
+	for rows.Next() {
+		a, err := scanAssessment(rows)
+		if err != nil {
+			return nil, dbError(err, "error retrieving data")
+		}
+		assessments = append(assessments, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	return assessments, nil
+}
+
+func GetAssessmentByID(ctx context.Context, id int) (models.Assessment, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return models.Assessment{}, dbError(err, "error retrieving data")
+	}
+
+	a, err := scanAssessment(db.QueryRowContext(ctx, "SELECT "+assessmentColumns+" FROM assessments WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return models.Assessment{}, dbError(err, "Assessment not found")
+	} else if err != nil {
+		return models.Assessment{}, dbError(err, "error retrieving data")
+	}
+	return a, nil
+}
+
+func UpdateAssessment(ctx context.Context, a models.Assessment) (models.Assessment, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return models.Assessment{}, dbError(err, "error updating data")
+	}
+
+	_, err = db.ExecContext(ctx, "UPDATE assessments SET class = ?, subject = ?, type = ?, title = ?, max_score = ?, weight = ?, assessment_date = ? WHERE id = ?",
+		a.Class, a.Subject, a.Type, a.Title, a.MaxScore, a.Weight, a.Date, a.ID)
+	if err != nil {
+		return models.Assessment{}, dbError(err, "error updating data")
+	}
+	return a, nil
+}
+
+func DeleteAssessment(ctx context.Context, id int) error {
+	db, err := ConnectDb()
+	if err != nil {
+		return dbError(err, "error deleting data")
+	}
+
+	result, err := db.ExecContext(ctx, "DELETE FROM assessments WHERE id = ?", id)
+	if err != nil {
+		return dbError(err, "error deleting data")
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return dbError(err, "error deleting data")
+	}
+	if rowsAffected == 0 {
+		return dbError(sql.ErrNoRows, "Assessment not found")
+	}
+	return nil
+}
+
+// RecordScores saves the scores of an assessment in one transaction; entering
+// a score again replaces it. Students must belong to the assessment's class
+// and scores must be between 0 and the maximum.
+func RecordScores(ctx context.Context, assessmentID int, scores []models.Score, recordedBy int) ([]models.Score, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return nil, dbError(err, "error recording scores")
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, dbError(err, "error recording scores")
+	}
+	defer tx.Rollback()
+
+	a, err := scanAssessment(tx.QueryRowContext(ctx, "SELECT "+assessmentColumns+" FROM assessments WHERE id = ?", assessmentID))
+	if err == sql.ErrNoRows {
+		return nil, dbError(err, "Assessment not found")
+	} else if err != nil {
+		return nil, dbError(err, "error recording scores")
+	}
+
+	inClass, err := classStudentIDs(ctx, tx, a.Class, a.Date)
+	if err != nil {
+		return nil, dbError(err, "error recording scores")
+	}
+
+	for i, score := range scores {
+		if !inClass[score.StudentID] {
+			return nil, dbError(errors.New("student not in class"), fmt.Sprintf("student %d is not in class %s", score.StudentID, a.Class))
+		}
+		if score.Score < 0 || score.Score > a.MaxScore {
+			return nil, dbError(errors.New("score out of range"), fmt.Sprintf("score of student %d must be between 0 and %g", score.StudentID, a.MaxScore))
+		}
+
+		_, err := tx.ExecContext(ctx, `INSERT INTO scores (assessment_id, student_id, score, comment, recorded_by) VALUES (?, ?, ?, ?, ?)
+			ON DUPLICATE KEY UPDATE score = VALUES(score), comment = VALUES(comment), recorded_by = VALUES(recorded_by)`,
+			assessmentID, score.StudentID, score.Score, score.Comment, recordedBy)
+		if err != nil {
+			return nil, dbError(err, "error recording scores")
+		}
+		scores[i].AssessmentID = assessmentID
+		scores[i].RecordedBy = recordedBy
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, dbError(err, "error recording scores")
+	}
+	return scores, nil
+}
+
+func GetAssessmentScores(ctx context.Context, assessmentID int) ([]models.Score, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT id, assessment_id, student_id, score, comment, recorded_by, updated_at FROM scores WHERE assessment_id = ? ORDER BY student_id", assessmentID)
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	defer rows.Close()
+
+	scores := []models.Score{}
+	for rows.Next() {
+		var s models.Score
+		if err := rows.Scan(&s.ID, &s.AssessmentID, &s.StudentID, &s.Score, &s.Comment, &s.RecordedBy, &s.UpdatedAt); err != nil {
+			return nil, dbError(err, "error retrieving data")
+		}
+		scores = append(scores, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	return scores, nil
+}
+
+const gradeEntryQuery = `SELECT a.id, a.class, a.subject, a.type, a.title, a.max_score, a.weight, a.assessment_date, a.created_by, s.student_id, s.score, s.comment
+	FROM scores s JOIN assessments a ON a.id = s.assessment_id
+	WHERE a.assessment_date BETWEEN ? AND ?`
+
+// queryGradeEntries runs gradeEntryQuery with extra conditions and returns the
+// entries keyed by student in the order they were read.
+func queryGradeEntries(ctx context.Context, where string, args ...interface{}) (map[int][]models.GradeEntry, []int, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, gradeEntryQuery+where+" ORDER BY a.assessment_date, a.id", args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	entries := make(map[int][]models.GradeEntry)
+	var students []int
+	for rows.Next() {
+		var entry models.GradeEntry
+		var studentID int
+		a := &entry.Assessment
+		err := rows.Scan(&a.ID, &a.Class, &a.Subject, &a.Type, &a.Title, &a.MaxScore, &a.Weight, &a.Date, &a.CreatedBy, &studentID, &entry.Score, &entry.Comment)
+		if err != nil {
+			return nil, nil, err
+		}
+		entry.Percent = models.Percent(entry.Score, a.MaxScore)
+		if _, ok := entries[studentID]; !ok {
+			students = append(students, studentID)
+		}
+		entries[studentID] = append(entries[studentID], entry)
+	}
+	return entries, students, rows.Err()
+}
+
+// GetStudentGrades returns a student's scores between from and to, optionally
+// for one subject.
+func GetStudentGrades(ctx context.Context, studentID int, subject, from, to string) ([]models.GradeEntry, error) {
+	where := " AND s.student_id = ?"
+	args := []interface{}{from, to, studentID}
+	if subject != "" {
+		where += " AND a.subject = ?"
+		args = append(args, subject)
+	}
+
+	entries, _, err := queryGradeEntries(ctx, where, args...)
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	if entries[studentID] == nil {
+		return []models.GradeEntry{}, nil
+	}
+	return entries[studentID], nil
+}
+
+// GetClassAverages returns the weighted average of every student of class with
+// at least one score in subject between from and to.
+func GetClassAverages(ctx context.Context, class, subject, from, to string) ([]float64, error) {
+	entries, students, err := queryGradeEntries(ctx, " AND a.class = ? AND a.subject = ?", from, to, class, subject)
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+
+	averages := make([]float64, 0, len(students))
+	for _, studentID := range students {
+		averages = append(averages, models.WeightedAverage(entries[studentID]))
+	}
+	return averages, nil
+}
@@ -0,0 +1,333 @@
+package sqlconnect
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"restapi/internal/models"
+	"restapi/pkg/utils"
+)
+
+const createGuardiansTable = `CREATE TABLE IF NOT EXISTS guardians (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	first_name VARCHAR(255) NOT NULL,
+	last_name VARCHAR(255) NOT NULL,
+	phone VARCHAR(50) NOT NULL DEFAULT '',
+	email VARCHAR(255) NOT NULL DEFAULT '',
+	address VARCHAR(500) NOT NULL DEFAULT '',
+	preferred_language VARCHAR(50) NOT NULL DEFAULT '',
+	KEY idx_guardians_name (last_name, first_name)
+)`
+
+const createStudentGuardiansTable = `CREATE TABLE IF NOT EXISTS student_guardians (
+	student_id INT NOT NULL,
+	guardian_id INT NOT NULL,
+	relationship VARCHAR(30) NOT NULL,
+	can_pickup BOOLEAN NOT NULL DEFAULT FALSE,
+	is_primary BOOLEAN NOT NULL DEFAULT FALSE,
+	PRIMARY KEY (student_id, guardian_id),
+	KEY idx_student_guardians_guardian (guardian_id),
+	FOREIGN KEY (student_id) REFERENCES students (id) ON DELETE CASCADE,
+	FOREIGN KEY (guardian_id) REFERENCES guardians (id) ON DELETE CASCADE
+)`
+
+const guardianColumns = "id, first_name, last_name, phone, email, address, preferred_language"
+
+func scanGuardian(scanner interface{ Scan(...any) error }) (models.Guardian, error) {
+	var g models.Guardian
+	err := scanner.Scan(&g.ID, &g.FirstName, &g.LastName, &g.Phone, &g.Email, &g.Address, &g.PreferredLanguage)
+	return g, err
+}
+
+// GetGuardians returns one page of guardians matching the list filters and
+// the total number of matches.
+func GetGuardians(ctx context.Context, r *http.Request, limit, page int) ([]models.Guardian, int, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return nil, 0, dbError(err, "error retrieving data")
+	}
+
+	where, args := utils.GuardianListSchema.Where(r, " WHERE 1=1", nil)
+	where, args, err = tagFilter(r, models.ContactGuardian, "id", where, args)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int
+	err = db.QueryRowContext(ctx, "SELECT COUNT(*) FROM guardians"+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, dbError(err, "error retrieving data")
+	}
+
+	query := utils.GuardianListSchema.OrderBy(r, "SELECT "+guardianColumns+" FROM guardians"+where)
+	query += " LIMIT ? OFFSET ?"
+	args = append(args, limit, (page-1)*limit)
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, dbError(err, "error retrieving data")
+	}
+	defer rows.Close()
+
+	guardians := []models.Guardian{}
+	for rows.Next() {
+		guardian, err := scanGuardian(rows)
+		if err != nil {
+			return nil, 0, dbError(err, "error retrieving data")
+		}
+		guardians = append(guardians, guardian)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, dbError(err, "error retrieving data")
+	}
+	return guardians, total, nil
+}
+
+func GetGuardianByID(ctx context.Context, id int) (models.Guardian, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return models.Guardian{}, dbError(err, "error retrieving data")
+	}
+
+	guardian, err := scanGuardian(db.QueryRowContext(ctx, "SELECT "+guardianColumns+" FROM guardians WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return models.Guardian{}, dbError(err, "Guardian not found")
+	} else if err != nil {
+		return models.Guardian{}, dbError(err, "error retrieving data")
+	}
+	return guardian, nil
+}
+
+// AddGuardians inserts all guardians in one transaction.
+func AddGuardians(ctx context.Context, guardians []models.Guardian) ([]models.Guardian, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return nil, dbError(err, "error adding data")
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, dbError(err, "error adding data")
+	}
+	defer tx.Rollback()
+
+	added := make([]models.Guardian, len(guardians))
+	for i, g := range guardians {
+		res, err := tx.ExecContext(ctx, "INSERT INTO guardians (first_name, last_name, phone, email, address, preferred_language) VALUES (?, ?, ?, ?, ?, ?)",
+			g.FirstName, g.LastName, g.Phone, g.Email, g.Address, g.PreferredLanguage)
+		if err != nil {
+			return nil, dbError(err, "error adding data")
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return nil, dbError(err, "error adding data")
+		}
+		g.ID = int(id)
+		if err := recordChange(ctx, tx, models.ContactGuardian, g.ID, models.ActionCreated, nil, g); err != nil {
+			return nil, err
+		}
+		added[i] = g
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, dbError(err, "error adding data")
+	}
+	return added, nil
+}
+
+func UpdateGuardian(ctx context.Context, g models.Guardian) (models.Guardian, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return models.Guardian{}, dbError(err, "error updating data")
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Guardian{}, dbError(err, "error updating data")
+	}
+	defer tx.Rollback()
+
+	before, err := scanGuardian(tx.QueryRowContext(ctx, "SELECT "+guardianColumns+" FROM guardians WHERE id = ? FOR UPDATE", g.ID))
+	if err == sql.ErrNoRows {
+		return models.Guardian{}, dbError(err, "Guardian not found")
+	} else if err != nil {
+		return models.Guardian{}, dbError(err, "error updating data")
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE guardians SET first_name = ?, last_name = ?, phone = ?, email = ?, address = ?, preferred_language = ? WHERE id = ?",
+		g.FirstName, g.LastName, g.Phone, g.Email, g.Address, g.PreferredLanguage, g.ID)
+	if err != nil {
+		return models.Guardian{}, dbError(err, "error updating data")
+	}
+	if err := recordChange(ctx, tx, models.ContactGuardian, g.ID, models.ActionUpdated, before, g); err != nil {
+		return models.Guardian{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return models.Guardian{}, dbError(err, "error updating data")
+	}
+	return g, nil
+}
+
+// DeleteGuardian removes the guardian together with its links to students.
+func DeleteGuardian(ctx context.Context, id int) error {
+	db, err := ConnectDb()
+	if err != nil {
+		return dbError(err, "error deleting data")
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return dbError(err, "error deleting data")
+	}
+	defer tx.Rollback()
+
+	before, err := scanGuardian(tx.QueryRowContext(ctx, "SELECT "+guardianColumns+" FROM guardians WHERE id = ? FOR UPDATE", id))
+	if err == sql.ErrNoRows {
+		return dbError(errors.New("no rows deleted"), "Guardian not found")
+	} else if err != nil {
+		return dbError(err, "error deleting data")
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM guardians WHERE id = ?", id); err != nil {
+		return dbError(err, "error deleting data")
+	}
+	if err := recordChange(ctx, tx, models.ContactGuardian, id, models.ActionDeleted, before, nil); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return dbError(err, "error deleting data")
+	}
+	return nil
+}
+
+// LinkGuardian creates or replaces the link between a student and a guardian.
+// Making a guardian primary clears the flag on the student's other guardians.
+func LinkGuardian(ctx context.Context, link models.GuardianLink) (models.GuardianLink, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return models.GuardianLink{}, dbError(err, "error updating data")
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.GuardianLink{}, dbError(err, "error updating data")
+	}
+	defer tx.Rollback()
+
+	for _, check := range []struct {
+		query   string
+		id      int
+		message string
+	}{
+		{"SELECT id FROM students WHERE id = ?", link.StudentID, "Student not found"},
+		{"SELECT id FROM guardians WHERE id = ?", link.GuardianID, "Guardian not found"},
+	} {
+		var id int
+		err := tx.QueryRowContext(ctx, check.query, check.id).Scan(&id)
+		if err == sql.ErrNoRows {
+			return models.GuardianLink{}, dbError(err, check.message)
+		} else if err != nil {
+			return models.GuardianLink{}, dbError(err, "error updating data")
+		}
+	}
+
+	if link.Primary {
+		_, err = tx.ExecContext(ctx, "UPDATE student_guardians SET is_primary = FALSE WHERE student_id = ? AND guardian_id <> ?", link.StudentID, link.GuardianID)
+		if err != nil {
+			return models.GuardianLink{}, dbError(err, "error updating data")
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO student_guardians (student_id, guardian_id, relationship, can_pickup, is_primary) VALUES (?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE relationship = VALUES(relationship), can_pickup = VALUES(can_pickup), is_primary = VALUES(is_primary)`,
+		link.StudentID, link.GuardianID, link.Relationship, link.CanPickup, link.Primary)
+	if err != nil {
+		return models.GuardianLink{}, dbError(err, "error updating data")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.GuardianLink{}, dbError(err, "error updating data")
+	}
+	return link, nil
+}
+
+func UnlinkGuardian(ctx context.Context, studentID, guardianID int) error {
+	db, err := ConnectDb()
+	if err != nil {
+		return dbError(err, "error deleting data")
+	}
+
+	result, err := db.ExecContext(ctx, "DELETE FROM student_guardians WHERE student_id = ? AND guardian_id = ?", studentID, guardianID)
+	if err != nil {
+		return dbError(err, "error deleting data")
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return dbError(err, "error deleting data")
+	}
+	if rowsAffected == 0 {
+		return dbError(errors.New("no rows deleted"), "Guardian is not linked to this student")
+	}
+	return nil
+}
+
+// GetStudentGuardians lists a student's guardians, primary contact first.
+func GetStudentGuardians(ctx context.Context, studentID int) ([]models.StudentGuardian, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT g.id, g.first_name, g.last_name, g.phone, g.email, g.address, g.preferred_language, sg.relationship, sg.can_pickup, sg.is_primary
+		FROM student_guardians sg JOIN guardians g ON g.id = sg.guardian_id
+		WHERE sg.student_id = ? ORDER BY sg.is_primary DESC, g.last_name, g.first_name`, studentID)
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	defer rows.Close()
+
+	guardians := []models.StudentGuardian{}
+	for rows.Next() {
+		var sg models.StudentGuardian
+		g := &sg.Guardian
+		err := rows.Scan(&g.ID, &g.FirstName, &g.LastName, &g.Phone, &g.Email, &g.Address, &g.PreferredLanguage, &sg.Relationship, &sg.CanPickup, &sg.Primary)
+		if err != nil {
+			return nil, dbError(err, "error retrieving data")
+		}
+		guardians = append(guardians, sg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	return guardians, nil
+}
+
+// GetGuardianChildren lists the students linked to a guardian.
+func GetGuardianChildren(ctx context.Context, guardianID int) ([]models.GuardianChild, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT s.id, s.first_name, s.last_name, s.email, s.class, sg.relationship, sg.can_pickup, sg.is_primary
+		FROM student_guardians sg JOIN students s ON s.id = sg.student_id
+		WHERE sg.guardian_id = ? ORDER BY s.last_name, s.first_name`, guardianID)
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	defer rows.Close()
+
+	children := []models.GuardianChild{}
+	for rows.Next() {
+		var child models.GuardianChild
+		s := &child.Student
+		err := rows.Scan(&s.ID, &s.FirstName, &s.LastName, &s.Email, &s.Class, &child.Relationship, &child.CanPickup, &child.Primary)
+		if err != nil {
+			return nil, dbError(err, "error retrieving data")
+		}
+		children = append(children, child)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	return children, nil
+}
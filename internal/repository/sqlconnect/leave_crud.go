@@ -0,0 +1,516 @@
+package sqlconnect
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"restapi/internal/models"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-mail/mail/v2"
+)
+
+const createLeaveRequestsTable = `CREATE TABLE IF NOT EXISTS leave_requests (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	teacher_id INT NOT NULL,
+	start_date DATE NOT NULL,
+	end_date DATE NOT NULL,
+	reason VARCHAR(500) NOT NULL DEFAULT '',
+	status VARCHAR(20) NOT NULL DEFAULT 'pending',
+	requested_by INT NOT NULL DEFAULT 0,
+	decided_by INT NOT NULL DEFAULT 0,
+	decision_note VARCHAR(500) NOT NULL DEFAULT '',
+	decided_at TIMESTAMP NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	KEY idx_leave_requests_teacher (teacher_id, start_date),
+	FOREIGN KEY (teacher_id) REFERENCES teachers (id) ON DELETE CASCADE
+)`
+
+// The unique keys keep a lesson to one substitute and a substitute to one
+// lesson per period.
+const createSubstitutionsTable = `CREATE TABLE IF NOT EXISTS substitutions (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	leave_id INT NOT NULL,
+	slot_id INT NOT NULL,
+	date DATE NOT NULL,
+	period_id INT NOT NULL,
+	teacher_id INT NOT NULL,
+	note VARCHAR(500) NOT NULL DEFAULT '',
+	assigned_by INT NOT NULL DEFAULT 0,
+	substitute_notified_at TIMESTAMP NULL,
+	class_notified_at TIMESTAMP NULL,
+	UNIQUE KEY uniq_substitutions_lesson (slot_id, date),
+	UNIQUE KEY uniq_substitutions_teacher (teacher_id, date, period_id),
+	FOREIGN KEY (leave_id) REFERENCES leave_requests (id) ON DELETE CASCADE,
+	FOREIGN KEY (slot_id) REFERENCES timetable_slots (id) ON DELETE CASCADE,
+	FOREIGN KEY (teacher_id) REFERENCES teachers (id) ON DELETE CASCADE
+)`
+
+var (
+	// ErrLeaveOverlap means the teacher already has pending or approved leave then.
+	ErrLeaveOverlap = errors.New("teacher already has leave in this period")
+	// ErrLeaveDecided means the request's status does not allow the decision.
+	ErrLeaveDecided = errors.New("leave request cannot change to this status")
+	// ErrLeaveNotApproved means substitutes are assigned to approved leave only.
+	ErrLeaveNotApproved = errors.New("leave request is not approved")
+	// ErrSubstituteBusy means the chosen teacher is not free for the lesson.
+	ErrSubstituteBusy = errors.New("teacher is not free for this lesson")
+)
+
+const leaveColumns = "id, teacher_id, start_date, end_date, reason, status, requested_by, decided_by, decision_note, COALESCE(decided_at, ''), created_at"
+
+func scanLeave(scanner interface{ Scan(...any) error }) (models.LeaveRequest, error) {
+	var l models.LeaveRequest
+	err := scanner.Scan(&l.ID, &l.TeacherID, &l.StartDate, &l.EndDate, &l.Reason, &l.Status, &l.RequestedBy, &l.DecidedBy, &l.DecisionNote, &l.DecidedAt, &l.CreatedAt)
+	return l, err
+}
+
+// GetLeaveRequests lists leave filtered by status and teacher; with from and
+// to only leave overlapping that period. Empty filters match everything.
+func GetLeaveRequests(ctx context.Context, status string, teacherID int, from, to string) ([]models.LeaveRequest, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+
+	query := "SELECT " + leaveColumns + " FROM leave_requests WHERE 1=1"
+	var args []interface{}
+	if status != "" {
+		query += " AND status = ?"
+		args = append(args, status)
+	}
+	if teacherID > 0 {
+		query += " AND teacher_id = ?"
+		args = append(args, teacherID)
+	}
+	if from != "" && to != "" {
+		query += " AND start_date <= ? AND end_date >= ?"
+		args = append(args, to, from)
+	}
+
+	rows, err := db.QueryContext(ctx, query+" ORDER BY start_date DESC, id DESC", args...)
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	defer rows.Close()
+
+	requests := []models.LeaveRequest{}
+	for rows.Next() {
+		l, err := scanLeave(rows)
+		if err != nil {
+			return nil, dbError(err, "error retrieving data")
+		}
+		requests = append(requests, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	return requests, nil
+}
+
+func GetLeaveByID(ctx context.Context, id int) (models.LeaveRequest, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return models.LeaveRequest{}, dbError(err, "error retrieving data")
+	}
+
+	l, err := scanLeave(db.QueryRowContext(ctx, "SELECT "+leaveColumns+" FROM leave_requests WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return models.LeaveRequest{}, dbError(err, "Leave request not found")
+	} else if err != nil {
+		return models.LeaveRequest{}, dbError(err, "error retrieving data")
+	}
+	return l, nil
+}
+
+// AddLeaveRequest files pending leave. It must not overlap the teacher's
+// other pending or approved leave.
+func AddLeaveRequest(ctx context.Context, l models.LeaveRequest) (models.LeaveRequest, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return models.LeaveRequest{}, dbError(err, "error adding data")
+	}
+
+	var overlapping int
+	err = db.QueryRowContext(ctx, "SELECT COUNT(*) FROM leave_requests WHERE teacher_id = ? AND status IN ('pending', 'approved') AND start_date <= ? AND end_date >= ?",
+		l.TeacherID, l.EndDate, l.StartDate).Scan(&overlapping)
+	if err != nil {
+		return models.LeaveRequest{}, dbError(err, "error adding data")
+	}
+	if overlapping > 0 {
+		return models.LeaveRequest{}, ErrLeaveOverlap
+	}
+
+	res, err := db.ExecContext(ctx, "INSERT INTO leave_requests (teacher_id, start_date, end_date, reason, status, requested_by) VALUES (?, ?, ?, ?, 'pending', ?)",
+		l.TeacherID, l.StartDate, l.EndDate, l.Reason, l.RequestedBy)
+	if err != nil {
+		return models.LeaveRequest{}, dbError(err, "error adding data")
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return models.LeaveRequest{}, dbError(err, "error adding data")
+	}
+	return GetLeaveByID(ctx, int(id))
+}
+
+// DecideLeave moves the request to the decision's status. Cancelling leave
+// drops the substitutions assigned for it.
+func DecideLeave(ctx context.Context, id int, d models.LeaveDecision, decidedBy int) (models.LeaveRequest, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return models.LeaveRequest{}, dbError(err, "error updating data")
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.LeaveRequest{}, dbError(err, "error updating data")
+	}
+	defer tx.Rollback()
+
+	l, err := scanLeave(tx.QueryRowContext(ctx, "SELECT "+leaveColumns+" FROM leave_requests WHERE id = ? FOR UPDATE", id))
+	if err == sql.ErrNoRows {
+		return models.LeaveRequest{}, dbError(err, "Leave request not found")
+	} else if err != nil {
+		return models.LeaveRequest{}, dbError(err, "error updating data")
+	}
+	if !l.Status.CanMove(d.Status) {
+		return models.LeaveRequest{}, fmt.Errorf("%w: it is %s", ErrLeaveDecided, l.Status)
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE leave_requests SET status = ?, decided_by = ?, decision_note = ?, decided_at = CURRENT_TIMESTAMP WHERE id = ?",
+		d.Status, decidedBy, d.Note, id)
+	if err != nil {
+		return models.LeaveRequest{}, dbError(err, "error updating data")
+	}
+	if d.Status == models.LeaveCancelled {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM substitutions WHERE leave_id = ?", id); err != nil {
+			return models.LeaveRequest{}, dbError(err, "error updating data")
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return models.LeaveRequest{}, dbError(err, "error updating data")
+	}
+	return GetLeaveByID(ctx, id)
+}
+
+// loadStaffing reads everything that decides who is free between from and to.
+func loadStaffing(ctx context.Context, from, to string) (models.Staffing, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return models.Staffing{}, dbError(err, "error retrieving data")
+	}
+
+	var st models.Staffing
+	rows, err := db.QueryContext(ctx, "SELECT id, first_name, last_name, email, class, subject FROM teachers ORDER BY last_name, first_name")
+	if err != nil {
+		return models.Staffing{}, dbError(err, "error retrieving data")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var t models.Teacher
+		if err := rows.Scan(&t.ID, &t.FirstName, &t.LastName, &t.Email, &t.Class, &t.Subject); err != nil {
+			return models.Staffing{}, dbError(err, "error retrieving data")
+		}
+		st.Teachers = append(st.Teachers, t)
+	}
+	if err := rows.Err(); err != nil {
+		return models.Staffing{}, dbError(err, "error retrieving data")
+	}
+
+	if st.Timetable, err = querySlots(ctx, db, "1=1"); err != nil {
+		return models.Staffing{}, err
+	}
+	if st.Unavailable, err = queryUnavailability(ctx, db, "1=1"); err != nil {
+		return models.Staffing{}, err
+	}
+	if st.Leave, err = GetLeaveRequests(ctx, string(models.LeaveApproved), 0, from, to); err != nil {
+		return models.Staffing{}, err
+	}
+	if st.Substitutions, err = querySubstitutions(ctx, "s.date BETWEEN ? AND ?", from, to); err != nil {
+		return models.Staffing{}, err
+	}
+	return st, nil
+}
+
+// GetLeaveCover lists the lessons the teacher misses through the leave. Each
+// covered lesson carries its substitution, the others up to limit suggested
+// substitutes.
+func GetLeaveCover(ctx context.Context, id, limit int) ([]models.CoverLesson, error) {
+	l, err := GetLeaveByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	slots, err := GetTeacherTimetable(ctx, l.TeacherID)
+	if err != nil {
+		return nil, err
+	}
+	lessons, err := models.AffectedLessons(slots, l.StartDate, l.EndDate)
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+
+	st, err := loadStaffing(ctx, l.StartDate, l.EndDate)
+	if err != nil {
+		return nil, err
+	}
+	covered := make(map[string]models.Substitution)
+	for _, sub := range st.Substitutions {
+		if sub.LeaveID == id {
+			covered[fmt.Sprint(sub.SlotID, sub.Date)] = sub
+		}
+	}
+
+	for i, lesson := range lessons {
+		if sub, ok := covered[fmt.Sprint(lesson.Slot.ID, lesson.Date)]; ok {
+			lessons[i].Substitution = &sub
+			continue
+		}
+		suggestions := st.SuggestSubstitutes(lesson)
+		if len(suggestions) > limit {
+			suggestions = suggestions[:limit]
+		}
+		lessons[i].Suggestions = suggestions
+	}
+	return lessons, nil
+}
+
+// AssignSubstitute makes sub.TeacherID cover the lesson of sub.SlotID on
+// sub.Date, replacing an earlier substitute. The leave must be approved, the
+// lesson one the absent teacher misses and the substitute free then.
+func AssignSubstitute(ctx context.Context, leaveID int, sub models.Substitution) (models.Substitution, error) {
+	l, err := GetLeaveByID(ctx, leaveID)
+	if err != nil {
+		return models.Substitution{}, err
+	}
+	if l.Status != models.LeaveApproved {
+		return models.Substitution{}, ErrLeaveNotApproved
+	}
+	slot, err := GetSlotByID(ctx, sub.SlotID)
+	if err != nil {
+		return models.Substitution{}, err
+	}
+	if slot.TeacherID != l.TeacherID {
+		return models.Substitution{}, fmt.Errorf("slot %d is not taught by teacher %d", slot.ID, l.TeacherID)
+	}
+	lessons, err := models.AffectedLessons([]models.TimetableSlot{slot}, sub.Date, sub.Date)
+	if err != nil {
+		return models.Substitution{}, dbError(err, "error updating data")
+	}
+	if len(lessons) == 0 || sub.Date < l.StartDate || sub.Date > l.EndDate {
+		return models.Substitution{}, fmt.Errorf("slot %d has no lesson on %s during the leave", slot.ID, sub.Date)
+	}
+
+	db, err := ConnectDb()
+	if err != nil {
+		return models.Substitution{}, dbError(err, "error updating data")
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Substitution{}, dbError(err, "error updating data")
+	}
+	defer tx.Rollback()
+
+	// Assignments of the same substitute wait for each other here, so the
+	// check below sees the lessons a concurrent assignment just gave them
+	var locked int
+	err = tx.QueryRowContext(ctx, "SELECT id FROM teachers WHERE id = ? FOR UPDATE", sub.TeacherID).Scan(&locked)
+	if err == sql.ErrNoRows {
+		return models.Substitution{}, dbError(err, "Teacher not found")
+	}
+	if err != nil {
+		return models.Substitution{}, dbError(err, "error updating data")
+	}
+
+	st, err := loadStaffing(ctx, sub.Date, sub.Date)
+	if err != nil {
+		return models.Substitution{}, err
+	}
+	candidates := st.SuggestSubstitutes(lessons[0])
+	if !slices.ContainsFunc(candidates, func(c models.SubstituteCandidate) bool { return c.TeacherID == sub.TeacherID }) {
+		return models.Substitution{}, ErrSubstituteBusy
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM substitutions WHERE slot_id = ? AND date = ?", sub.SlotID, sub.Date); err != nil {
+		return models.Substitution{}, dbError(err, "error updating data")
+	}
+	res, err := tx.ExecContext(ctx, "INSERT INTO substitutions (leave_id, slot_id, date, period_id, teacher_id, note, assigned_by) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		leaveID, sub.SlotID, sub.Date, slot.PeriodID, sub.TeacherID, sub.Note, sub.AssignedBy)
+	if err != nil {
+		return models.Substitution{}, dbError(err, "error adding data")
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return models.Substitution{}, dbError(err, "error adding data")
+	}
+	if err := tx.Commit(); err != nil {
+		return models.Substitution{}, dbError(err, "error updating data")
+	}
+
+	sub.ID, sub.LeaveID, sub.PeriodID = int(id), leaveID, slot.PeriodID
+	sub.Class, sub.Subject = slot.Class, slot.Subject
+	return sub, nil
+}
+
+func DeleteSubstitution(ctx context.Context, id int) error {
+	return deleteByID(ctx, "substitutions", id, "Substitution not found")
+}
+
+// GetTeacherSubstitutions lists the lessons the teacher covers between from and to.
+func GetTeacherSubstitutions(ctx context.Context, teacherID int, from, to string) ([]models.Substitution, error) {
+	return querySubstitutions(ctx, "s.teacher_id = ? AND s.date BETWEEN ? AND ?", teacherID, from, to)
+}
+
+func querySubstitutions(ctx context.Context, where string, args ...interface{}) ([]models.Substitution, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT s.id, s.leave_id, s.slot_id, s.date, s.period_id, s.teacher_id, t.class, t.subject, s.note, s.assigned_by,
+		COALESCE(s.substitute_notified_at, ''), COALESCE(s.class_notified_at, '')
+		FROM substitutions s JOIN timetable_slots t ON t.id = s.slot_id
+		WHERE `+where+" ORDER BY s.date, s.period_id", args...)
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	defer rows.Close()
+
+	subs := []models.Substitution{}
+	for rows.Next() {
+		var s models.Substitution
+		err := rows.Scan(&s.ID, &s.LeaveID, &s.SlotID, &s.Date, &s.PeriodID, &s.TeacherID, &s.Class, &s.Subject, &s.Note, &s.AssignedBy, &s.SubstituteNotifiedAt, &s.ClassNotifiedAt)
+		if err != nil {
+			return nil, dbError(err, "error retrieving data")
+		}
+		subs = append(subs, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	return subs, nil
+}
+
+// NotifySubstitutions sends the notices of upcoming substitutions not
+// notified yet, including those whose earlier send failed.
+func NotifySubstitutions(ctx context.Context) error {
+	subs, err := querySubstitutions(ctx, "(s.substitute_notified_at IS NULL OR s.class_notified_at IS NULL) AND s.date >= CURRENT_DATE")
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, sub := range subs {
+		errs = append(errs, SendSubstitutionNotices(ctx, sub))
+	}
+	return errors.Join(errs...)
+}
+
+// SendSubstitutionNotices emails the substitute and the class: its students
+// and the teachers whose class it is. Each mail is marked sent on its own, so
+// a retry after a failed class mail does not mail the substitute again.
+func SendSubstitutionNotices(ctx context.Context, sub models.Substitution) error {
+	slot, err := GetSlotByID(ctx, sub.SlotID)
+	if err != nil {
+		return err
+	}
+	substitute, err := GetTeacherByID(ctx, sub.TeacherID)
+	if err != nil {
+		return err
+	}
+
+	date, _ := time.Parse(time.DateOnly, sub.Date)
+	lesson := fmt.Sprintf("%s on %s, period %s (%s-%s)", slot.Subject, date.Format("Monday 2 January"),
+		slot.PeriodName, strings.TrimSuffix(slot.StartTime, ":00"), strings.TrimSuffix(slot.EndTime, ":00"))
+	if slot.RoomName != "" {
+		lesson += " in room " + slot.RoomName
+	}
+
+	err = sendSubstitutionNotice(ctx, sub.ID, "substitute_notified_at", func(tx *sql.Tx) error {
+		if substitute.Email == "" {
+			return nil
+		}
+		m := mail.NewMessage()
+		m.SetHeader("From", settings.Mail.From)
+		m.SetHeader("To", substitute.Email)
+		m.SetHeader("Subject", "Cover lesson "+sub.Date)
+		body := fmt.Sprintf("You are covering %s for class %s instead of %s.\n", lesson, slot.Class, slot.TeacherName)
+		if sub.Note != "" {
+			body += "\nNote: " + sub.Note + "\n"
+		}
+		m.SetBody("text/plain", body)
+		return sendMail(ctx, m)
+	})
+	if err != nil {
+		return err
+	}
+
+	return sendSubstitutionNotice(ctx, sub.ID, "class_notified_at", func(tx *sql.Tx) error {
+		// The class as enrolled on the day of the lesson
+		args := append(classMembersArgs(slot.Class, sub.Date), slot.Class, sub.TeacherID)
+		rows, err := tx.QueryContext(ctx, `SELECT email FROM students WHERE id IN (`+classMembersQuery+`) AND email <> ''
+			UNION SELECT email FROM teachers WHERE class = ? AND email <> '' AND id <> ?`, args...)
+		if err != nil {
+			return err
+		}
+		var recipients []string
+		for rows.Next() {
+			var email string
+			if err := rows.Scan(&email); err != nil {
+				rows.Close()
+				return err
+			}
+			recipients = append(recipients, email)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if len(recipients) == 0 {
+			return nil
+		}
+
+		m := mail.NewMessage()
+		m.SetHeader("From", settings.Mail.From)
+		m.SetHeader("To", settings.Mail.From)
+		m.SetHeader("Bcc", recipients...)
+		m.SetHeader("Subject", fmt.Sprintf("Class %s: substitute teacher %s", slot.Class, sub.Date))
+		m.SetBody("text/plain", fmt.Sprintf("%s %s takes %s instead of %s.\n", substitute.FirstName, substitute.LastName, lesson, slot.TeacherName))
+		return sendMail(ctx, m)
+	})
+}
+
+// sendSubstitutionNotice claims one notice of substitution id, the one whose
+// time is kept in column, runs send and marks it sent. The row stays locked
+// until then, so a concurrent run skips the notice instead of sending it
+// again; a notice already sent is skipped as well.
+func sendSubstitutionNotice(ctx context.Context, id int, column string, send func(tx *sql.Tx) error) error {
+	db, err := ConnectDb()
+	if err != nil {
+		return dbError(err, "error sending substitution notices")
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return dbError(err, "error sending substitution notices")
+	}
+	defer tx.Rollback()
+
+	var claimed int
+	err = tx.QueryRowContext(ctx, "SELECT id FROM substitutions WHERE id = ? AND "+column+" IS NULL FOR UPDATE SKIP LOCKED", id).Scan(&claimed)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return dbError(err, "error sending substitution notices")
+	}
+	if err := send(tx); err != nil {
+		return dbError(err, "error sending substitution notices")
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE substitutions SET "+column+" = CURRENT_TIMESTAMP WHERE id = ?", id); err != nil {
+		return dbError(err, "error updating data")
+	}
+	if err := tx.Commit(); err != nil {
+		return dbError(err, "error updating data")
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package sqlconnect
+
+import (
+	"context"
+	"log"
+	"net"
+	"restapi/internal/metrics"
+	"strconv"
+	"strings"
+
+	"github.com/go-mail/mail/v2"
+)
+
+func mailDialer() *mail.Dialer {
+	return mail.NewDialer(settings.Mail.Host, settings.Mail.Port, settings.Mail.User, settings.Mail.Password)
+}
+
+// sendMail delivers m and records it in the email metrics and, for contact
+// timelines, in email_log once per recipient.
+func sendMail(ctx context.Context, m *mail.Message) error {
+	metrics.EmailOutboxDepth.Inc()
+	err := mailDialer().DialAndSend(m)
+	metrics.EmailOutboxDepth.Dec()
+	metrics.EmailsSentTotal.WithLabelValues(metrics.Result(err)).Inc()
+	logEmail(ctx, m, err)
+	return err
+}
+
+// logEmail records the recipients of m; the sender itself, used as To for
+// Bcc-only mail, is left out. Failing to log does not fail the send.
+func logEmail(ctx context.Context, m *mail.Message, sendErr error) {
+	status, message := "sent", ""
+	if sendErr != nil {
+		status, message = "failed", sendErr.Error()
+		if len(message) > 500 {
+			message = message[:500]
+		}
+	}
+	subject := strings.Join(m.GetHeader("Subject"), " ")
+
+	db, err := ConnectDb()
+	if err != nil {
+		log.Println("Error logging email:", err)
+		return
+	}
+	for _, header := range []string{"To", "Cc", "Bcc"} {
+		for _, recipient := range m.GetHeader(header) {
+			if recipient == settings.Mail.From {
+				continue
+			}
+			_, err := db.ExecContext(ctx, "INSERT INTO email_log (recipient, subject, status, error) VALUES (?, ?, ?, ?)", recipient, subject, status, message)
+			if err != nil {
+				log.Println("Error logging email:", err)
+				return
+			}
+		}
+	}
+}
+
+// CheckMailer verifies the SMTP server accepts TCP connections.
+func CheckMailer(ctx context.Context) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(settings.Mail.Host, strconv.Itoa(settings.Mail.Port)))
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
@@ -0,0 +1,470 @@
+package sqlconnect
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"restapi/internal/models"
+	"restapi/pkg/utils"
+	"strings"
+)
+
+const createNotesTable = `CREATE TABLE IF NOT EXISTS notes (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	subject_type VARCHAR(20) NOT NULL,
+	subject_id INT NOT NULL,
+	type VARCHAR(20) NOT NULL,
+	body TEXT NOT NULL,
+	visibility VARCHAR(20) NOT NULL DEFAULT 'staff',
+	author_id INT NOT NULL DEFAULT 0,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+	KEY idx_notes_subject (subject_type, subject_id, created_at),
+	KEY idx_notes_author (author_id)
+)`
+
+const createNoteTagsTable = `CREATE TABLE IF NOT EXISTS note_tags (
+	note_id INT NOT NULL,
+	tag VARCHAR(50) NOT NULL,
+	PRIMARY KEY (note_id, tag),
+	KEY idx_note_tags_tag (tag),
+	FOREIGN KEY (note_id) REFERENCES notes (id) ON DELETE CASCADE
+)`
+
+const createRecordChangesTable = `CREATE TABLE IF NOT EXISTS record_changes (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	subject_type VARCHAR(20) NOT NULL,
+	subject_id INT NOT NULL,
+	action VARCHAR(10) NOT NULL,
+	changes JSON NOT NULL,
+	changed_by INT NOT NULL DEFAULT 0,
+	changed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	KEY idx_record_changes_subject (subject_type, subject_id, changed_at)
+)`
+
+const createEmailLogTable = `CREATE TABLE IF NOT EXISTS email_log (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	recipient VARCHAR(255) NOT NULL,
+	subject VARCHAR(500) NOT NULL DEFAULT '',
+	status VARCHAR(10) NOT NULL,
+	error VARCHAR(500) NOT NULL DEFAULT '',
+	sent_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	KEY idx_email_log_recipient (recipient, sent_at)
+)`
+
+// contactTables maps the contact types to their tables.
+var contactTables = map[string]string{
+	models.ContactStudent:  "students",
+	models.ContactTeacher:  "teachers",
+	models.ContactGuardian: "guardians",
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// visibleNotes restricts notes aliased n to those the user in ctx may read,
+// matching Note.VisibleTo: the visibilities of the role and the user's own
+// private notes.
+func visibleNotes(ctx context.Context) (string, []interface{}) {
+	visibilities := models.Visibilities(utils.UserRole(ctx))
+	args := make([]interface{}, 0, len(visibilities)+2)
+	for _, v := range visibilities {
+		args = append(args, v)
+	}
+	args = append(args, models.VisibleToAuthor, utils.UserID(ctx))
+	return " AND (n.visibility IN (" + placeholders(len(visibilities)) + ") OR (n.visibility = ? AND n.author_id = ?))", args
+}
+
+// requestTags returns the comma-separated tags of the "tag" query param.
+func requestTags(r *http.Request) ([]string, error) {
+	value := r.URL.Query().Get("tag")
+	if value == "" {
+		return nil, nil
+	}
+	return models.NormalizeTags(strings.Split(value, ","))
+}
+
+// tagFilter appends a condition matching the contacts of subjectType, by the
+// id column, that have a note visible to the user with one of the requested
+// tags. It appends nothing without a "tag" param.
+func tagFilter(r *http.Request, subjectType, column, query string, args []interface{}) (string, []interface{}, error) {
+	tags, err := requestTags(r)
+	if err != nil || len(tags) == 0 {
+		return query, args, err
+	}
+	visible, visibleArgs := visibleNotes(r.Context())
+	query += " AND " + column + ` IN (SELECT n.subject_id FROM notes n JOIN note_tags t ON t.note_id = n.id
+		WHERE n.subject_type = ? AND t.tag IN (` + placeholders(len(tags)) + ")" + visible + ")"
+	args = append(args, subjectType)
+	for _, tag := range tags {
+		args = append(args, tag)
+	}
+	return query, append(args, visibleArgs...), nil
+}
+
+const noteColumns = "n.id, n.subject_type, n.subject_id, n.type, n.body, n.visibility, n.author_id, n.created_at, n.updated_at"
+
+// queryNotes reads the notes matching where, which must restrict notes
+// aliased n, and fills in their tags.
+func queryNotes(ctx context.Context, q queryer, where string, args ...interface{}) ([]models.Note, error) {
+	rows, err := q.QueryContext(ctx, "SELECT "+noteColumns+" FROM notes n WHERE "+where, args...)
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	defer rows.Close()
+
+	notes := []models.Note{}
+	index := make(map[int]int)
+	for rows.Next() {
+		var n models.Note
+		if err := rows.Scan(&n.ID, &n.SubjectType, &n.SubjectID, &n.Type, &n.Body, &n.Visibility, &n.AuthorID, &n.CreatedAt, &n.UpdatedAt); err != nil {
+			return nil, dbError(err, "error retrieving data")
+		}
+		n.Tags = []string{}
+		index[n.ID] = len(notes)
+		notes = append(notes, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	if len(notes) == 0 {
+		return notes, nil
+	}
+
+	ids := make([]interface{}, 0, len(notes))
+	for _, n := range notes {
+		ids = append(ids, n.ID)
+	}
+	tagRows, err := q.QueryContext(ctx, "SELECT note_id, tag FROM note_tags WHERE note_id IN ("+placeholders(len(ids))+") ORDER BY tag", ids...)
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	defer tagRows.Close()
+	for tagRows.Next() {
+		var id int
+		var tag string
+		if err := tagRows.Scan(&id, &tag); err != nil {
+			return nil, dbError(err, "error retrieving data")
+		}
+		notes[index[id]].Tags = append(notes[index[id]].Tags, tag)
+	}
+	if err := tagRows.Err(); err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	return notes, nil
+}
+
+// GetNotes returns one page of the notes visible to the user that match the
+// list filters and tags, newest first unless sorted otherwise. A subjectType
+// restricts them to the notes of one contact.
+func GetNotes(ctx context.Context, r *http.Request, subjectType string, subjectID, limit, page int) ([]models.Note, int, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return nil, 0, dbError(err, "error retrieving data")
+	}
+
+	where, args := utils.NoteListSchema.Where(r, "1=1", nil)
+	if subjectType != "" {
+		where += " AND subject_type = ? AND subject_id = ?"
+		args = append(args, subjectType, subjectID)
+	}
+	visible, visibleArgs := visibleNotes(ctx)
+	where, args = where+visible, append(args, visibleArgs...)
+	tags, err := requestTags(r)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(tags) > 0 {
+		where += " AND n.id IN (SELECT note_id FROM note_tags WHERE tag IN (" + placeholders(len(tags)) + "))"
+		for _, tag := range tags {
+			args = append(args, tag)
+		}
+	}
+
+	var total int
+	err = db.QueryRowContext(ctx, "SELECT COUNT(*) FROM notes n WHERE "+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, dbError(err, "error retrieving data")
+	}
+
+	if sorted := utils.NoteListSchema.OrderBy(r, where); sorted != where {
+		where = sorted
+	} else {
+		where += " ORDER BY created_at DESC, id DESC"
+	}
+	where += " LIMIT ? OFFSET ?"
+	args = append(args, limit, (page-1)*limit)
+
+	notes, err := queryNotes(ctx, db, where, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	return notes, total, nil
+}
+
+// GetNoteByID returns the note whether or not the user may read it; callers
+// check VisibleTo.
+func GetNoteByID(ctx context.Context, id int) (models.Note, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return models.Note{}, dbError(err, "error retrieving data")
+	}
+
+	notes, err := queryNotes(ctx, db, "n.id = ?", id)
+	if err != nil {
+		return models.Note{}, err
+	}
+	if len(notes) == 0 {
+		return models.Note{}, dbError(sql.ErrNoRows, "Note not found")
+	}
+	return notes[0], nil
+}
+
+// ContactEmail returns the email of the contact and fails when there is no
+// such contact.
+func ContactEmail(ctx context.Context, subjectType string, id int) (string, error) {
+	table, ok := contactTables[subjectType]
+	if !ok {
+		return "", errors.New("unknown contact type")
+	}
+	db, err := ConnectDb()
+	if err != nil {
+		return "", dbError(err, "error retrieving data")
+	}
+
+	var email string
+	err = db.QueryRowContext(ctx, "SELECT email FROM "+table+" WHERE id = ?", id).Scan(&email)
+	if err == sql.ErrNoRows {
+		return "", dbError(err, strings.ToUpper(subjectType[:1])+subjectType[1:]+" not found")
+	} else if err != nil {
+		return "", dbError(err, "error retrieving data")
+	}
+	return email, nil
+}
+
+// AddNote saves the note with its tags.
+func AddNote(ctx context.Context, n models.Note) (models.Note, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return models.Note{}, dbError(err, "error adding data")
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Note{}, dbError(err, "error adding data")
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, "INSERT INTO notes (subject_type, subject_id, type, body, visibility, author_id) VALUES (?, ?, ?, ?, ?, ?)",
+		n.SubjectType, n.SubjectID, n.Type, n.Body, n.Visibility, n.AuthorID)
+	if err != nil {
+		return models.Note{}, dbError(err, "error adding data")
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return models.Note{}, dbError(err, "error adding data")
+	}
+	if err := insertNoteTags(ctx, tx, int(id), n.Tags); err != nil {
+		return models.Note{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return models.Note{}, dbError(err, "error adding data")
+	}
+	return GetNoteByID(ctx, int(id))
+}
+
+func insertNoteTags(ctx context.Context, tx *sql.Tx, noteID int, tags []string) error {
+	for _, tag := range tags {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO note_tags (note_id, tag) VALUES (?, ?)", noteID, tag); err != nil {
+			return dbError(err, "error adding data")
+		}
+	}
+	return nil
+}
+
+// UpdateNote saves the type, body, visibility and tags of the note.
+func UpdateNote(ctx context.Context, n models.Note) (models.Note, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return models.Note{}, dbError(err, "error updating data")
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Note{}, dbError(err, "error updating data")
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, "UPDATE notes SET type = ?, body = ?, visibility = ? WHERE id = ?", n.Type, n.Body, n.Visibility, n.ID)
+	if err != nil {
+		return models.Note{}, dbError(err, "error updating data")
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM note_tags WHERE note_id = ?", n.ID); err != nil {
+		return models.Note{}, dbError(err, "error updating data")
+	}
+	if err := insertNoteTags(ctx, tx, n.ID, n.Tags); err != nil {
+		return models.Note{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return models.Note{}, dbError(err, "error updating data")
+	}
+	return GetNoteByID(ctx, n.ID)
+}
+
+func DeleteNote(ctx context.Context, id int) error {
+	return deleteByID(ctx, "notes", id, "Note not found")
+}
+
+// GetNoteTags counts the tags on the notes visible to the user, most used
+// first.
+func GetNoteTags(ctx context.Context) ([]models.TagCount, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+
+	visible, args := visibleNotes(ctx)
+	rows, err := db.QueryContext(ctx, "SELECT t.tag, COUNT(*) FROM note_tags t JOIN notes n ON n.id = t.note_id WHERE 1=1"+visible+
+		" GROUP BY t.tag ORDER BY COUNT(*) DESC, t.tag", args...)
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	defer rows.Close()
+
+	tags := []models.TagCount{}
+	for rows.Next() {
+		var tag models.TagCount
+		if err := rows.Scan(&tag.Tag, &tag.Count); err != nil {
+			return nil, dbError(err, "error retrieving data")
+		}
+		tags = append(tags, tag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	return tags, nil
+}
+
+// recordChange writes an audit entry for a contact, with the fields that
+// differ between before and after, by the user in ctx. Updates that change
+// nothing are not recorded.
+func recordChange(ctx context.Context, e execer, subjectType string, subjectID int, action string, before, after any) error {
+	return insertChanges(ctx, e, subjectType, subjectID, action, models.Diff(before, after))
+}
+
+// insertChanges writes an audit entry with changes worked out by the caller,
+// e.g. for custom field values kept outside the row.
+func insertChanges(ctx context.Context, e execer, subjectType string, subjectID int, action string, changes map[string]models.FieldChange) error {
+	if action == models.ActionUpdated && len(changes) == 0 {
+		return nil
+	}
+	encoded, err := json.Marshal(changes)
+	if err != nil {
+		return dbError(err, "error updating data")
+	}
+	_, err = e.ExecContext(ctx, "INSERT INTO record_changes (subject_type, subject_id, action, changes, changed_by) VALUES (?, ?, ?, ?, ?)",
+		subjectType, subjectID, action, encoded, utils.UserID(ctx))
+	if err != nil {
+		return dbError(err, "error updating data")
+	}
+	return nil
+}
+
+// GetRecordChanges returns up to limit of the latest changes of a contact.
+// Custom field values the user in ctx may not see are left out.
+func GetRecordChanges(ctx context.Context, subjectType string, subjectID, limit int) ([]models.RecordChange, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT id, subject_type, subject_id, action, changes, changed_by, changed_at FROM record_changes
+		WHERE subject_type = ? AND subject_id = ? ORDER BY changed_at DESC, id DESC LIMIT ?`, subjectType, subjectID, limit)
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	defer rows.Close()
+
+	changes := []models.RecordChange{}
+	for rows.Next() {
+		var c models.RecordChange
+		var encoded []byte
+		if err := rows.Scan(&c.ID, &c.SubjectType, &c.SubjectID, &c.Action, &encoded, &c.ChangedBy, &c.ChangedAt); err != nil {
+			return nil, dbError(err, "error retrieving data")
+		}
+		if err := json.Unmarshal(encoded, &c.Changes); err != nil {
+			log.Println("Invalid record change", c.ID, err)
+		}
+		changes = append(changes, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+
+	fields, err := VisibleCustomFields(ctx, subjectType)
+	if err != nil {
+		return nil, err
+	}
+	return fields.VisibleChanges(changes), nil
+}
+
+// GetSentEmails returns up to limit of the latest emails sent to address.
+func GetSentEmails(ctx context.Context, address string, limit int) ([]models.SentEmail, error) {
+	emails := []models.SentEmail{}
+	if address == "" {
+		return emails, nil
+	}
+	db, err := ConnectDb()
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT id, recipient, subject, status, error, sent_at FROM email_log WHERE recipient = ? ORDER BY sent_at DESC, id DESC LIMIT ?", address, limit)
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e models.SentEmail
+		if err := rows.Scan(&e.ID, &e.Recipient, &e.Subject, &e.Status, &e.Error, &e.SentAt); err != nil {
+			return nil, dbError(err, "error retrieving data")
+		}
+		emails = append(emails, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	return emails, nil
+}
+
+// GetContactTimeline merges the latest notes visible to the user, record
+// changes and emails sent to the contact's current address.
+func GetContactTimeline(ctx context.Context, subjectType string, id, limit int) ([]models.ContactEvent, error) {
+	email, err := ContactEmail(ctx, subjectType, id)
+	if err != nil {
+		return nil, err
+	}
+	db, err := ConnectDb()
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+
+	visible, args := visibleNotes(ctx)
+	notes, err := queryNotes(ctx, db, "n.subject_type = ? AND n.subject_id = ?"+visible+" ORDER BY n.created_at DESC, n.id DESC LIMIT ?",
+		append(append([]interface{}{subjectType, id}, args...), limit)...)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := GetRecordChanges(ctx, subjectType, id, limit)
+	if err != nil {
+		return nil, err
+	}
+	emails, err := GetSentEmails(ctx, email, limit)
+	if err != nil {
+		return nil, err
+	}
+	return models.ContactTimeline(notes, changes, emails, limit), nil
+}
@@ -0,0 +1,40 @@
+package sqlconnect
+
+import (
+	"context"
+	"reflect"
+	"restapi/internal/models"
+	"restapi/pkg/utils"
+	"strings"
+	"testing"
+)
+
+func TestVisibleNotes(t *testing.T) {
+	tests := []struct {
+		role string
+		want []interface{}
+	}{
+		{"admin", []interface{}{models.VisibleToStaff, models.VisibleToManagers, models.VisibleToAuthor, models.VisibleToAuthor, 7}},
+		{"manager", []interface{}{models.VisibleToStaff, models.VisibleToManagers, models.VisibleToAuthor, 7}},
+		// Автор видит свою заметку только если она личная, как в Note.VisibleTo
+		{"exec", []interface{}{models.VisibleToStaff, models.VisibleToAuthor, 7}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.role, func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), utils.ContextKey("role"), tt.role)
+			ctx = context.WithValue(ctx, utils.ContextKey("userId"), 7)
+
+			clause, args := visibleNotes(ctx)
+			if !strings.Contains(clause, "(n.visibility = ? AND n.author_id = ?)") {
+				t.Errorf("visibleNotes() = %q, want the author exception limited to private notes", clause)
+			}
+			if strings.Count(clause, "?") != len(args) {
+				t.Errorf("visibleNotes() has %d placeholders for %d args", strings.Count(clause, "?"), len(args))
+			}
+			if !reflect.DeepEqual(args, tt.want) {
+				t.Errorf("visibleNotes() args = %v, want %v", args, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package sqlconnect
+
+import (
+	"context"
+	"restapi/internal/models"
+	"time"
+)
+
+const createReportCommentsTable = `CREATE TABLE IF NOT EXISTS report_comments (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	student_id INT NOT NULL,
+	term VARCHAR(100) NOT NULL,
+	subject VARCHAR(255) NOT NULL DEFAULT '',
+	comment TEXT NOT NULL,
+	author_id INT NOT NULL DEFAULT 0,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+	UNIQUE KEY uniq_report_comments (student_id, term, subject),
+	FOREIGN KEY (student_id) REFERENCES students (id) ON DELETE CASCADE
+)`
+
+// SetReportComment creates or replaces the comment for the student, term and subject.
+func SetReportComment(ctx context.Context, c models.ReportComment) (models.ReportComment, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return models.ReportComment{}, dbError(err, "error updating data")
+	}
+
+	_, err = db.ExecContext(ctx, `INSERT INTO report_comments (student_id, term, subject, comment, author_id) VALUES (?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE comment = VALUES(comment), author_id = VALUES(author_id)`,
+		c.StudentID, c.Term, c.Subject, c.Comment, c.AuthorID)
+	if err != nil {
+		return models.ReportComment{}, dbError(err, "error updating data")
+	}
+
+	err = db.QueryRowContext(ctx, "SELECT id, updated_at FROM report_comments WHERE student_id = ? AND term = ? AND subject = ?",
+		c.StudentID, c.Term, c.Subject).Scan(&c.ID, &c.UpdatedAt)
+	if err != nil {
+		return models.ReportComment{}, dbError(err, "error updating data")
+	}
+	return c, nil
+}
+
+// GetReportCard assembles one student's report card for the term.
+func GetReportCard(ctx context.Context, studentID int, term, from, to string) (models.ReportCard, error) {
+	student, err := GetStudentByID(ctx, studentID)
+	if err != nil {
+		return models.ReportCard{}, err
+	}
+
+	cards, err := reportCards(ctx, []models.Student{student}, term, from, to)
+	if err != nil {
+		return models.ReportCard{}, err
+	}
+	return cards[0], nil
+}
+
+// GetClassReportCards assembles the report cards of every student in class,
+// ordered by last and first name. With a yearID the class is the one enrolled
+// that year, otherwise the one enrolled on the last day of the period, or
+// today when the period is open.
+func GetClassReportCards(ctx context.Context, class string, yearID int, term, from, to string) ([]models.ReportCard, error) {
+	if yearID > 0 {
+		students, err := yearClassStudents(ctx, yearID, class)
+		if err != nil || len(students) == 0 {
+			return []models.ReportCard{}, err
+		}
+		return reportCards(ctx, students, term, from, to)
+	}
+
+	db, err := ConnectDb()
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+
+	date := to
+	if date == "" {
+		date = time.Now().Format(time.DateOnly)
+	}
+	rows, err := db.QueryContext(ctx, "SELECT id, first_name, last_name, email FROM students WHERE id IN ("+classMembersQuery+") ORDER BY last_name, first_name", classMembersArgs(class, date)...)
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	defer rows.Close()
+
+	var students []models.Student
+	for rows.Next() {
+		student := models.Student{Class: class}
+		if err := rows.Scan(&student.ID, &student.FirstName, &student.LastName, &student.Email); err != nil {
+			return nil, dbError(err, "error retrieving data")
+		}
+		students = append(students, student)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	if len(students) == 0 {
+		return []models.ReportCard{}, nil
+	}
+
+	return reportCards(ctx, students, term, from, to)
+}
+
+// reportCards loads grades, attendance and comments of all students with one
+// query each.
+func reportCards(ctx context.Context, students []models.Student, term, from, to string) ([]models.ReportCard, error) {
+	ids := make([]interface{}, len(students))
+	for i, student := range students {
+		ids[i] = student.ID
+	}
+	in := placeholders(len(ids))
+
+	entries, _, err := queryGradeEntries(ctx, " AND s.student_id IN "+in, append([]interface{}{from, to}, ids...)...)
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+
+	db, err := ConnectDb()
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+
+	attendance := make(map[int][]models.Attendance)
+	rows, err := db.QueryContext(ctx, "SELECT "+attendanceColumns+" FROM attendance WHERE session_date BETWEEN ? AND ? AND student_id IN "+in,
+		append([]interface{}{from, to}, ids...)...)
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		record, err := scanAttendance(rows)
+		if err != nil {
+			return nil, dbError(err, "error retrieving data")
+		}
+		attendance[record.StudentID] = append(attendance[record.StudentID], record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+
+	comments := make(map[int]map[string]string)
+	commentRows, err := db.QueryContext(ctx, "SELECT student_id, subject, comment FROM report_comments WHERE term = ? AND student_id IN "+in,
+		append([]interface{}{term}, ids...)...)
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	defer commentRows.Close()
+	for commentRows.Next() {
+		var studentID int
+		var subject, comment string
+		if err := commentRows.Scan(&studentID, &subject, &comment); err != nil {
+			return nil, dbError(err, "error retrieving data")
+		}
+		if comments[studentID] == nil {
+			comments[studentID] = make(map[string]string)
+		}
+		comments[studentID][subject] = comment
+	}
+	if err := commentRows.Err(); err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+
+	scale := GradeScale()
+	cards := make([]models.ReportCard, 0, len(students))
+	for _, student := range students {
+		cards = append(cards, models.NewReportCard(student, term, from, to, entries[student.ID], attendance[student.ID], comments[student.ID], scale))
+	}
+	return cards, nil
+}
@@ -0,0 +1,58 @@
+package sqlconnect
+
+import (
+	"context"
+)
+
+// schemaTable is a table added on top of the original execs, students and
+// teachers schema. DDL must be idempotent.
+type schemaTable struct {
+	name string
+	ddl  string
+}
+
+// moduleTables are created by EnsureSchema in order, so referenced tables come first.
+var moduleTables = []schemaTable{
+	{"attendance", createAttendanceTable},
+	{"attendance_history", createAttendanceHistoryTable},
+	{"attendance_alerts", createAttendanceAlertsTable},
+	{"assessments", createAssessmentsTable},
+	{"scores", createScoresTable},
+	{"report_comments", createReportCommentsTable},
+	{"guardians", createGuardiansTable},
+	{"student_guardians", createStudentGuardiansTable},
+	{"academic_years", createAcademicYearsTable},
+	{"terms", createTermsTable},
+	{"class_enrollments", createClassEnrollmentsTable},
+	{"periods", createPeriodsTable},
+	{"rooms", createRoomsTable},
+	{"timetable_slots", createTimetableSlotsTable},
+	{"teacher_unavailability", createTeacherUnavailabilityTable},
+	{"leave_requests", createLeaveRequestsTable},
+	{"substitutions", createSubstitutionsTable},
+	{"leads", createLeadsTable},
+	{"lead_guardians", createLeadGuardiansTable},
+	{"lead_activities", createLeadActivitiesTable},
+	{"lead_stage_history", createLeadStageHistoryTable},
+	{"notes", createNotesTable},
+	{"note_tags", createNoteTagsTable},
+	{"record_changes", createRecordChangesTable},
+	{"email_log", createEmailLogTable},
+	{"custom_fields", createCustomFieldsTable},
+	{"custom_field_values", createCustomFieldValuesTable},
+}
+
+// EnsureSchema creates missing module tables. Call it once after InitDBPool.
+func EnsureSchema(ctx context.Context) error {
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	for _, table := range moduleTables {
+		if _, err := db.ExecContext(ctx, table.ddl); err != nil {
+			return dbError(err, "error creating table "+table.name)
+		}
+	}
+	return nil
+}
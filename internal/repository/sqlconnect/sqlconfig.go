@@ -1,26 +1,126 @@
 package sqlconnect
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"os"
+	"restapi/internal/config"
+	"sync"
 
+	"github.com/XSAM/otelsql"
 	_ "github.com/go-sql-driver/mysql"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 )
 
-func ConnectDb() (*sql.DB, error) {
+var (
+	settings = config.Default()
+
+	dbPool *sql.DB
+	dbErr  error
+	once   sync.Once
+	poolMu sync.RWMutex
+)
+
+// Configure installs the configuration loaded at startup. Call it before InitDBPool.
+func Configure(cfg *config.Config) {
+	settings = cfg
+}
+
+// InitDBPool opens the shared connection pool once and verifies it with a ping.
+// Repeated calls return the result of the first initialization.
+func InitDBPool() error {
+	once.Do(func() {
+		connectionString := settings.DB.DSN()
+		// Every statement gets a span; it joins the request trace when a context is passed
+		db, err := otelsql.Open("mysql", connectionString,
+			otelsql.WithAttributes(semconv.DBSystemMySQL),
+			otelsql.WithSpanOptions(otelsql.SpanOptions{OmitConnResetSession: true, OmitRows: true}),
+		)
+		if err != nil {
+			dbErr = err
+			return
+		}
+
+		db.SetMaxOpenConns(settings.DB.MaxOpenConns)
+		db.SetMaxIdleConns(settings.DB.MaxIdleConns)
+		db.SetConnMaxLifetime(settings.DB.ConnMaxLifetime)
+
+		err = db.Ping()
+		if err != nil {
+			db.Close()
+			dbErr = err
+			return
+		}
+
+		poolMu.Lock()
+		dbPool = db
+		dbErr = nil
+		poolMu.Unlock()
+	})
+	return dbErr
+}
+
+// GetDB returns the shared connection pool created by InitDBPool.
+func GetDB() (*sql.DB, error) {
+	poolMu.RLock()
+	defer poolMu.RUnlock()
+
+	if dbPool == nil {
+		return nil, errors.New("database pool is not initialized")
+	}
+	return dbPool, nil
+}
 
-	user := os.Getenv("DB_USER")
-	password := os.Getenv("DB_PASSWORD")
-	dbname := os.Getenv("DB_NAME")
-	dbport := os.Getenv("DB_PORT")
-	host := os.Getenv("HOST")
+// CloseDBPool closes the shared connection pool. It is safe to call more than once.
+func CloseDBPool() error {
+	poolMu.Lock()
+	defer poolMu.Unlock()
 
-	connectionString := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s", user, password, host, dbport, dbname)
-	db, err := sql.Open("mysql", connectionString)
+	if dbPool == nil {
+		return nil
+	}
+	err := dbPool.Close()
+	dbPool = nil
+	return err
+}
+
+// PingDb checks that the database is reachable.
+func PingDb(ctx context.Context) error {
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+	return db.PingContext(ctx)
+}
+
+var requiredTables = []string{"execs", "students", "teachers"}
+
+// CheckSchema verifies that the tables the API depends on exist.
+func CheckSchema(ctx context.Context) error {
+	db, err := GetDB()
 	if err != nil {
+		return err
+	}
+
+	tables := append([]string{}, requiredTables...)
+	for _, table := range moduleTables {
+		tables = append(tables, table.name)
+	}
 
-		return nil, err
+	for _, table := range tables {
+		var count int
+		err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?", table).Scan(&count)
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return fmt.Errorf("table %s is missing", table)
+		}
 	}
-	return db, nil
+	return nil
+}
+
+func ConnectDb() (*sql.DB, error) {
+	return GetDB()
 }
@@ -1,6 +1,7 @@
 package sqlconnect
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
@@ -12,29 +13,35 @@ import (
 	"strings"
 )
 
-func GetStudentsDbHandler(students []models.Student, r *http.Request, limit, page int) ([]models.Student, int, error) {
+// GetStudentsDbHandler lists a page of students; limit 0 returns all of them,
+// as for exports. Filters and sorting may use the custom fields given.
+func GetStudentsDbHandler(ctx context.Context, students []models.Student, r *http.Request, limit, page int, fields models.CustomFields) ([]models.Student, int, error) {
 	db, err := ConnectDb()
 	if err != nil {
-		return nil, 0, utils.ErrorHandler(err, "error retrieving data")
+		return nil, 0, dbError(err, "error retrieving data")
 	}
 
-	defer db.Close()
-
 	query := "SELECT id, first_name, last_name, email, class FROM students WHERE 1=1"
 	var args []interface{}
 
 	query, args = utils.AddFilters(r, query, args)
+	query, args, err = tagFilter(r, models.ContactStudent, "id", query, args)
+	if err != nil {
+		return nil, 0, err
+	}
+	query, args = customFieldFilter(r, fields, "id", query, args)
+	query += listOrder(r, utils.StudentListSchema.SortFields, fields, "students.id")
 
-	offset := (page - 1) * limit
-	query += " LIMIT ? OFFSET ?"
-	args = append(args, limit, offset)
-
-	query = utils.AddSorting(r, query)
+	if limit > 0 {
+		offset := (page - 1) * limit
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, limit, offset)
+	}
 
-	rows, err := db.Query(query, args...)
+	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
 		fmt.Println(err)
-		return nil, 0, utils.ErrorHandler(err, "error retrieving data")
+		return nil, 0, dbError(err, "error retrieving data")
 	}
 	defer rows.Close()
 
@@ -42,136 +49,177 @@ func GetStudentsDbHandler(students []models.Student, r *http.Request, limit, pag
 		var student models.Student
 		err := rows.Scan(&student.ID, &student.FirstName, &student.LastName, &student.Email, &student.Class)
 		if err != nil {
-			return nil, 0, utils.ErrorHandler(err, "error retrieving data")
+			return nil, 0, dbError(err, "error retrieving data")
 		}
 		students = append(students, student)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, dbError(err, "error retrieving data")
+	}
 
 	var totalStudents int
-	err = db.QueryRow("SELECT COUNT(*) FROM students").Scan(&totalStudents)
+	err = db.QueryRowContext(ctx, "SELECT COUNT(*) FROM students").Scan(&totalStudents)
 	if err != nil {
-		utils.ErrorHandler(err, "")
+		dbError(err, "")
 		totalStudents = 0
 	}
 	return students, totalStudents, nil
 }
 
-func GetStudentByID(id int) (models.Student, error) {
+func GetStudentByID(ctx context.Context, id int) (models.Student, error) {
 	db, err := ConnectDb()
 	if err != nil {
-		return models.Student{}, utils.ErrorHandler(err, "error retrieving data")
+		return models.Student{}, dbError(err, "error retrieving data")
 	}
 
-	defer db.Close()
-
 	var student models.Student
-	err = db.QueryRow("SELECT id, first_name, last_name, email, class FROM students WHERE id = ?", id).Scan(&student.ID, &student.FirstName, &student.LastName, &student.Email, &student.Class)
+	err = db.QueryRowContext(ctx, "SELECT id, first_name, last_name, email, class FROM students WHERE id = ?", id).Scan(&student.ID, &student.FirstName, &student.LastName, &student.Email, &student.Class)
 	if err == sql.ErrNoRows {
-		return models.Student{}, utils.ErrorHandler(err, "error retrieving data")
+		return models.Student{}, dbError(err, "error retrieving data")
 	} else if err != nil {
 		fmt.Println(err)
-		return models.Student{}, utils.ErrorHandler(err, "error retrieving data")
+		return models.Student{}, dbError(err, "error retrieving data")
 	}
 	return student, nil
 }
 
-func AddStudentsDBHandler(newStudents []models.Student) ([]models.Student, error) {
+// AddStudentsDBHandler inserts the students in one transaction with custom[i],
+// the custom field values of newStudents[i].
+func AddStudentsDBHandler(ctx context.Context, newStudents []models.Student, fields models.CustomFields, custom []map[string]string) ([]models.Student, error) {
 	db, err := ConnectDb()
 	if err != nil {
-		return nil, utils.ErrorHandler(err, "error adding data")
+		return nil, dbError(err, "error adding data")
 	}
 
-	defer db.Close()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, dbError(err, "error adding data")
+	}
+	defer tx.Rollback()
 
-	stmt, err := db.Prepare(utils.GenerateInsertQuery("students", models.Student{}))
+	stmt, err := tx.PrepareContext(ctx, utils.GenerateInsertQuery("students", models.Student{}))
 	if err != nil {
-		return nil, utils.ErrorHandler(err, "error adding data")
+		return nil, dbError(err, "error adding data")
 	}
 	defer stmt.Close()
 
 	addedStudents := make([]models.Student, len(newStudents))
 	for i, newStudent := range newStudents {
 		values := utils.GetStructValues(newStudent)
-		res, err := stmt.Exec(values...)
+		res, err := stmt.ExecContext(ctx, values...)
 		if err != nil {
 			fmt.Println("----- Error:", err.Error())
 			if strings.Contains(err.Error(), "a foreign key constraint fails (`school`.`students`, CONSTRAINT `students_ibfk_1` FOREIGN KEY (`class`) REFERENCES `teachers` (`class`))") {
-				return nil, utils.ErrorHandler(err, "class/class teacher does not exist")
+				return nil, dbError(err, "class/class teacher does not exist")
 			}
-			return nil, utils.ErrorHandler(err, "error adding data")
+			return nil, dbError(err, "error adding data")
 		}
 		lastID, err := res.LastInsertId()
 		if err != nil {
-			return nil, utils.ErrorHandler(err, "error adding data")
+			return nil, dbError(err, "error adding data")
 		}
 		newStudent.ID = int(lastID)
+		if err := recordChange(ctx, tx, models.ContactStudent, newStudent.ID, models.ActionCreated, nil, newStudent); err != nil {
+			return nil, err
+		}
+		if err := saveCustomValues(ctx, tx, models.ContactStudent, newStudent.ID, fields, custom[i]); err != nil {
+			return nil, err
+		}
+		if err := enrollInCurrentYear(ctx, tx, newStudent.ID, newStudent.Class); err != nil {
+			return nil, err
+		}
 		addedStudents[i] = newStudent
 	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, dbError(err, "error adding data")
+	}
 	return addedStudents, nil
 }
 
-func UpdateStudent(id int, updatedStudent models.Student) (models.Student, error) {
+// UpdateStudent replaces the student and its custom field values in one transaction.
+func UpdateStudent(ctx context.Context, id int, updatedStudent models.Student, fields models.CustomFields, custom map[string]string) (models.Student, error) {
 	db, err := ConnectDb()
 	if err != nil {
 		log.Println(err)
-		return models.Student{}, utils.ErrorHandler(err, "error updating data")
+		return models.Student{}, dbError(err, "error updating data")
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Student{}, dbError(err, "error updating data")
 	}
-	defer db.Close()
+	defer tx.Rollback()
 
 	var existingStudent models.Student
-	err = db.QueryRow("SELECT id, first_name, last_name, email, class FROM students WHERE id = ?", id).Scan(&existingStudent.ID, &existingStudent.FirstName, &existingStudent.LastName, &existingStudent.Email, &existingStudent.Class)
+	err = tx.QueryRowContext(ctx, "SELECT id, first_name, last_name, email, class FROM students WHERE id = ?", id).Scan(&existingStudent.ID, &existingStudent.FirstName, &existingStudent.LastName, &existingStudent.Email, &existingStudent.Class)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return models.Student{}, utils.ErrorHandler(err, "error updating data")
+			return models.Student{}, dbError(err, "error updating data")
 		}
-		return models.Student{}, utils.ErrorHandler(err, "error updating data")
+		return models.Student{}, dbError(err, "error updating data")
 	}
 
 	updatedStudent.ID = existingStudent.ID
-	_, err = db.Exec("UPDATE students SET first_name = ?, last_name = ?, email = ?, class = ? WHERE id = ?", updatedStudent.FirstName, updatedStudent.LastName, updatedStudent.Email, updatedStudent.Class, updatedStudent.ID)
+	_, err = tx.ExecContext(ctx, "UPDATE students SET first_name = ?, last_name = ?, email = ?, class = ? WHERE id = ?", updatedStudent.FirstName, updatedStudent.LastName, updatedStudent.Email, updatedStudent.Class, updatedStudent.ID)
 	if err != nil {
-		return models.Student{}, utils.ErrorHandler(err, "error updating data")
+		return models.Student{}, dbError(err, "error updating data")
+	}
+	if err := recordChange(ctx, tx, models.ContactStudent, updatedStudent.ID, models.ActionUpdated, existingStudent, updatedStudent); err != nil {
+		return models.Student{}, err
+	}
+	if err := saveCustomValues(ctx, tx, models.ContactStudent, updatedStudent.ID, fields, custom); err != nil {
+		return models.Student{}, err
+	}
+	if err := enrollInCurrentYear(ctx, tx, updatedStudent.ID, updatedStudent.Class); err != nil {
+		return models.Student{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Student{}, dbError(err, "error updating data")
 	}
 	return updatedStudent, nil
 }
 
-func PatchStudent(updates []map[string]interface{}) error {
+// PatchStudent applies the updates in one transaction; custom[n] holds the
+// custom field values changed by updates[n].
+func PatchStudent(ctx context.Context, updates []map[string]interface{}, fields models.CustomFields, custom []map[string]string) error {
 	db, err := ConnectDb()
 	if err != nil {
-		return utils.ErrorHandler(err, "error updating data")
+		return dbError(err, "error updating data")
 	}
-	defer db.Close()
 
-	tx, err := db.Begin()
+	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
-		return utils.ErrorHandler(err, "error updating data")
+		return dbError(err, "error updating data")
 	}
 
-	for _, update := range updates {
+	for n, update := range updates {
 		idStr, ok := update["id"].(string)
 		if !ok {
 			tx.Rollback()
-			return utils.ErrorHandler(err, "invalid Id")
+			return dbError(err, "invalid Id")
 		}
 
 		id, err := strconv.Atoi(idStr)
 		if err != nil {
 			tx.Rollback()
-			return utils.ErrorHandler(err, "invalid Id")
+			return dbError(err, "invalid Id")
 		}
 
 		var StudentFromDb models.Student
-		err = db.QueryRow("SELECT id, first_name, last_name, email, class FROM students WHERE id = ?", id).Scan(&StudentFromDb.ID, &StudentFromDb.FirstName, &StudentFromDb.LastName, &StudentFromDb.Email, &StudentFromDb.Class)
+		err = tx.QueryRowContext(ctx, "SELECT id, first_name, last_name, email, class FROM students WHERE id = ?", id).Scan(&StudentFromDb.ID, &StudentFromDb.FirstName, &StudentFromDb.LastName, &StudentFromDb.Email, &StudentFromDb.Class)
 		if err != nil {
 			log.Println("ID:", id)
 			log.Printf("Type: %T", id)
 			log.Println(err)
 			tx.Rollback()
 			if err == sql.ErrNoRows {
-				return utils.ErrorHandler(err, "Student not found")
+				return dbError(err, "Student not found")
 			}
-			return utils.ErrorHandler(err, "error updating data")
+			return dbError(err, "error updating data")
 		}
+		before := StudentFromDb
 
 		studentVal := reflect.ValueOf(&StudentFromDb).Elem()
 		studentType := studentVal.Type()
@@ -191,7 +239,7 @@ func PatchStudent(updates []map[string]interface{}) error {
 						} else {
 							tx.Rollback()
 							log.Printf("cannot convert %v to %v", val.Type(), fieldVal.Type())
-							return utils.ErrorHandler(err, "error updating data")
+							return dbError(err, "error updating data")
 						}
 					}
 					break
@@ -199,36 +247,56 @@ func PatchStudent(updates []map[string]interface{}) error {
 			}
 		}
 
-		_, err = tx.Exec("UPDATE students SET first_name = ?, last_name = ?, email = ?, class = ? WHERE id = ?", StudentFromDb.FirstName, StudentFromDb.LastName, StudentFromDb.Email, StudentFromDb.Class, StudentFromDb.ID)
+		_, err = tx.ExecContext(ctx, "UPDATE students SET first_name = ?, last_name = ?, email = ?, class = ? WHERE id = ?", StudentFromDb.FirstName, StudentFromDb.LastName, StudentFromDb.Email, StudentFromDb.Class, StudentFromDb.ID)
 		if err != nil {
 			tx.Rollback()
-			return utils.ErrorHandler(err, "error updating data")
+			return dbError(err, "error updating data")
+		}
+		if err := recordChange(ctx, tx, models.ContactStudent, StudentFromDb.ID, models.ActionUpdated, before, StudentFromDb); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := saveCustomValues(ctx, tx, models.ContactStudent, StudentFromDb.ID, fields, custom[n]); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := enrollInCurrentYear(ctx, tx, StudentFromDb.ID, StudentFromDb.Class); err != nil {
+			tx.Rollback()
+			return err
 		}
 	}
 
 	err = tx.Commit()
 	if err != nil {
-		return utils.ErrorHandler(err, "error updating data")
+		return dbError(err, "error updating data")
 	}
 	return nil
 }
 
-func PatchOneStudent(id int, updates map[string]interface{}) (models.Student, error) {
+// PatchOneStudent applies updates and the custom field values in custom in one
+// transaction.
+func PatchOneStudent(ctx context.Context, id int, updates map[string]interface{}, fields models.CustomFields, custom map[string]string) (models.Student, error) {
 	db, err := ConnectDb()
 	if err != nil {
 		log.Println(err)
-		return models.Student{}, utils.ErrorHandler(err, "error updating data")
+		return models.Student{}, dbError(err, "error updating data")
 	}
-	defer db.Close()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Student{}, dbError(err, "error updating data")
+	}
+	defer tx.Rollback()
 
 	var existingStudent models.Student
-	err = db.QueryRow("SELECT id, first_name, last_name, email, class FROM students WHERE id = ?", id).Scan(&existingStudent.ID, &existingStudent.FirstName, &existingStudent.LastName, &existingStudent.Email, &existingStudent.Class)
+	err = tx.QueryRowContext(ctx, "SELECT id, first_name, last_name, email, class FROM students WHERE id = ?", id).Scan(&existingStudent.ID, &existingStudent.FirstName, &existingStudent.LastName, &existingStudent.Email, &existingStudent.Class)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return models.Student{}, utils.ErrorHandler(err, "Student not found")
+			return models.Student{}, dbError(err, "Student not found")
 		}
-		return models.Student{}, utils.ErrorHandler(err, "error updating data")
+		return models.Student{}, dbError(err, "error updating data")
 	}
+	before := existingStudent
 
 	studentVal := reflect.ValueOf(&existingStudent).Elem()
 	studentType := studentVal.Type()
@@ -245,68 +313,100 @@ func PatchOneStudent(id int, updates map[string]interface{}) (models.Student, er
 		}
 	}
 
-	_, err = db.Exec("UPDATE students SET first_name = ?, last_name = ?, email = ?, class = ? WHERE id = ?", existingStudent.FirstName, existingStudent.LastName, existingStudent.Email, existingStudent.Class, existingStudent.ID)
+	_, err = tx.ExecContext(ctx, "UPDATE students SET first_name = ?, last_name = ?, email = ?, class = ? WHERE id = ?", existingStudent.FirstName, existingStudent.LastName, existingStudent.Email, existingStudent.Class, existingStudent.ID)
 	if err != nil {
-		return models.Student{}, utils.ErrorHandler(err, "error updating data")
+		return models.Student{}, dbError(err, "error updating data")
+	}
+	if err := recordChange(ctx, tx, models.ContactStudent, existingStudent.ID, models.ActionUpdated, before, existingStudent); err != nil {
+		return models.Student{}, err
+	}
+	if err := saveCustomValues(ctx, tx, models.ContactStudent, existingStudent.ID, fields, custom); err != nil {
+		return models.Student{}, err
+	}
+	if err := enrollInCurrentYear(ctx, tx, existingStudent.ID, existingStudent.Class); err != nil {
+		return models.Student{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Student{}, dbError(err, "error updating data")
 	}
 	return existingStudent, nil
 }
 
-func DeleteOneStudent(id int) error {
+func DeleteOneStudent(ctx context.Context, id int) error {
 	db, err := ConnectDb()
 	if err != nil {
-		return utils.ErrorHandler(err, "error updating data")
+		return dbError(err, "error updating data")
 	}
-	defer db.Close()
-
-	result, err := db.Exec("DELETE FROM students WHERE id = ?", id)
+	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
-		return utils.ErrorHandler(err, "error updating data")
+		return dbError(err, "error updating data")
 	}
+	defer tx.Rollback()
 
-	rowsAffected, err := result.RowsAffected()
+	var before models.Student
+	err = tx.QueryRowContext(ctx, "SELECT id, first_name, last_name, email, class FROM students WHERE id = ?", id).Scan(&before.ID, &before.FirstName, &before.LastName, &before.Email, &before.Class)
+	if err == sql.ErrNoRows {
+		return dbError(err, "Student not found")
+	}
 	if err != nil {
-		return utils.ErrorHandler(err, "error updating data")
+		return dbError(err, "error updating data")
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM students WHERE id = ?", id); err != nil {
+		return dbError(err, "error updating data")
+	}
+	if err := recordChange(ctx, tx, models.ContactStudent, id, models.ActionDeleted, before, nil); err != nil {
+		return err
+	}
+	if err := deleteCustomValues(ctx, tx, models.ContactStudent, id); err != nil {
+		return err
 	}
 
-	if rowsAffected == 0 {
-		return utils.ErrorHandler(err, "Student not found")
+	if err := tx.Commit(); err != nil {
+		return dbError(err, "error updating data")
 	}
 	return nil
 }
 
-func DeleteStudents(ids []int) ([]int, error) {
+func DeleteStudents(ctx context.Context, ids []int) ([]int, error) {
 	db, err := ConnectDb()
 	if err != nil {
-		return nil, utils.ErrorHandler(err, "error updating data")
+		return nil, dbError(err, "error updating data")
 	}
-	defer db.Close()
 
-	tx, err := db.Begin()
+	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
-		return nil, utils.ErrorHandler(err, "error updating data")
+		return nil, dbError(err, "error updating data")
 	}
 
-	stmt, err := tx.Prepare("DELETE FROM students WHERE id = ?")
+	stmt, err := tx.PrepareContext(ctx, "DELETE FROM students WHERE id = ?")
 	if err != nil {
 		tx.Rollback()
-		return nil, utils.ErrorHandler(err, "error updating data")
+		return nil, dbError(err, "error updating data")
 	}
 	defer stmt.Close()
 
 	deletedIds := []int{}
 
 	for _, id := range ids {
-		result, err := stmt.Exec(id)
+		var before models.Student
+		err := tx.QueryRowContext(ctx, "SELECT id, first_name, last_name, email, class FROM students WHERE id = ?", id).Scan(&before.ID, &before.FirstName, &before.LastName, &before.Email, &before.Class)
+		if err != nil && err != sql.ErrNoRows {
+			tx.Rollback()
+			return nil, dbError(err, "error updating data")
+		}
+
+		result, err := stmt.ExecContext(ctx, id)
 		if err != nil {
 			tx.Rollback()
-			return nil, utils.ErrorHandler(err, "error updating data")
+			return nil, dbError(err, "error updating data")
 		}
 
 		rowsAffected, err := result.RowsAffected()
 		if err != nil {
 			tx.Rollback()
-			return nil, utils.ErrorHandler(err, "error updating data")
+			return nil, dbError(err, "error updating data")
 		}
 
 		if rowsAffected > 0 {
@@ -315,17 +415,25 @@ func DeleteStudents(ids []int) ([]int, error) {
 
 		if rowsAffected < 1 {
 			tx.Rollback()
-			return nil, utils.ErrorHandler(err, fmt.Sprintf("ID %d not found", id))
+			return nil, dbError(err, fmt.Sprintf("ID %d not found", id))
+		}
+		if err := recordChange(ctx, tx, models.ContactStudent, id, models.ActionDeleted, before, nil); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		if err := deleteCustomValues(ctx, tx, models.ContactStudent, id); err != nil {
+			tx.Rollback()
+			return nil, err
 		}
 	}
 
 	err = tx.Commit()
 	if err != nil {
-		return nil, utils.ErrorHandler(err, "error updating data")
+		return nil, dbError(err, "error updating data")
 	}
 
 	if len(deletedIds) < 1 {
-		return nil, utils.ErrorHandler(err, "IDs do not exist")
+		return nil, dbError(err, "IDs do not exist")
 	}
 	return deletedIds, nil
 }
@@ -1,6 +1,7 @@
 package sqlconnect
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
@@ -11,25 +12,29 @@ import (
 	"strconv"
 )
 
-func GetTeachersDbHandler(teachers []models.Teacher, r *http.Request) ([]models.Teacher, error) {
+// GetTeachersDbHandler lists the teachers; filters and sorting may use the
+// custom fields given.
+func GetTeachersDbHandler(ctx context.Context, teachers []models.Teacher, r *http.Request, fields models.CustomFields) ([]models.Teacher, error) {
 	db, err := ConnectDb()
 	if err != nil {
-		return nil, utils.ErrorHandler(err, "error retrieving data")
+		return nil, dbError(err, "error retrieving data")
 	}
 
-	defer db.Close()
-
 	query := "SELECT id, first_name, last_name, email, class, subject FROM teachers WHERE 1=1"
 	var args []interface{}
 
 	query, args = utils.AddFilters(r, query, args)
+	query, args, err = tagFilter(r, models.ContactTeacher, "id", query, args)
+	if err != nil {
+		return nil, err
+	}
+	query, args = customFieldFilter(r, fields, "id", query, args)
+	query += listOrder(r, utils.TeacherListSchema.SortFields, fields, "teachers.id")
 
-	query = utils.AddSorting(r, query)
-
-	rows, err := db.Query(query, args...)
+	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
 		fmt.Println(err)
-		return nil, utils.ErrorHandler(err, "error retrieving data")
+		return nil, dbError(err, "error retrieving data")
 	}
 	defer rows.Close()
 
@@ -37,43 +42,50 @@ func GetTeachersDbHandler(teachers []models.Teacher, r *http.Request) ([]models.
 		var teacher models.Teacher
 		err := rows.Scan(&teacher.ID, &teacher.FirstName, &teacher.LastName, &teacher.Email, &teacher.Class, &teacher.Subject)
 		if err != nil {
-			return nil, utils.ErrorHandler(err, "error retrieving data")
+			return nil, dbError(err, "error retrieving data")
 		}
 		teachers = append(teachers, teacher)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
 	return teachers, nil
 }
 
-func GetTeacherByID(id int) (models.Teacher, error) {
+func GetTeacherByID(ctx context.Context, id int) (models.Teacher, error) {
 	db, err := ConnectDb()
 	if err != nil {
-		return models.Teacher{}, utils.ErrorHandler(err, "error retrieving data")
+		return models.Teacher{}, dbError(err, "error retrieving data")
 	}
 
-	defer db.Close()
-
 	var teacher models.Teacher
-	err = db.QueryRow("SELECT id, first_name, last_name, email, class, subject FROM teachers WHERE id = ?", id).Scan(&teacher.ID, &teacher.FirstName, &teacher.LastName, &teacher.Email, &teacher.Class, &teacher.Subject)
+	err = db.QueryRowContext(ctx, "SELECT id, first_name, last_name, email, class, subject FROM teachers WHERE id = ?", id).Scan(&teacher.ID, &teacher.FirstName, &teacher.LastName, &teacher.Email, &teacher.Class, &teacher.Subject)
 	if err == sql.ErrNoRows {
-		return models.Teacher{}, utils.ErrorHandler(err, "error retrieving data")
+		return models.Teacher{}, dbError(err, "error retrieving data")
 	} else if err != nil {
 		fmt.Println(err)
-		return models.Teacher{}, utils.ErrorHandler(err, "error retrieving data")
+		return models.Teacher{}, dbError(err, "error retrieving data")
 	}
 	return teacher, nil
 }
 
-func AddTeachersDBHandler(newTeachers []models.Teacher) ([]models.Teacher, error) {
+// AddTeachersDBHandler inserts the teachers in one transaction with custom[i],
+// the custom field values of newTeachers[i].
+func AddTeachersDBHandler(ctx context.Context, newTeachers []models.Teacher, fields models.CustomFields, custom []map[string]string) ([]models.Teacher, error) {
 	db, err := ConnectDb()
 	if err != nil {
-		return nil, utils.ErrorHandler(err, "error adding data")
+		return nil, dbError(err, "error adding data")
 	}
 
-	defer db.Close()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, dbError(err, "error adding data")
+	}
+	defer tx.Rollback()
 
-	stmt, err := db.Prepare(utils.GenerateInsertQuery("teachers", models.Teacher{}))
+	stmt, err := tx.PrepareContext(ctx, utils.GenerateInsertQuery("teachers", models.Teacher{}))
 	if err != nil {
-		return nil, utils.ErrorHandler(err, "error adding data")
+		return nil, dbError(err, "error adding data")
 	}
 	defer stmt.Close()
 
@@ -81,82 +93,110 @@ func AddTeachersDBHandler(newTeachers []models.Teacher) ([]models.Teacher, error
 	for i, newTeacher := range newTeachers {
 
 		values := utils.GetStructValues(newTeacher)
-		res, err := stmt.Exec(values...)
+		res, err := stmt.ExecContext(ctx, values...)
 		if err != nil {
-			return nil, utils.ErrorHandler(err, "error adding data")
+			return nil, dbError(err, "error adding data")
 		}
 		lastID, err := res.LastInsertId()
 		if err != nil {
-			return nil, utils.ErrorHandler(err, "error adding data")
+			return nil, dbError(err, "error adding data")
 		}
 		newTeacher.ID = int(lastID)
+		if err := recordChange(ctx, tx, models.ContactTeacher, newTeacher.ID, models.ActionCreated, nil, newTeacher); err != nil {
+			return nil, err
+		}
+		if err := saveCustomValues(ctx, tx, models.ContactTeacher, newTeacher.ID, fields, custom[i]); err != nil {
+			return nil, err
+		}
 		addedTeachers[i] = newTeacher
 	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, dbError(err, "error adding data")
+	}
 	return addedTeachers, nil
 }
 
-func UpdateTeacher(id int, updatedTeacher models.Teacher) (models.Teacher, error) {
+// UpdateTeacher replaces the teacher and its custom field values in one transaction.
+func UpdateTeacher(ctx context.Context, id int, updatedTeacher models.Teacher, fields models.CustomFields, custom map[string]string) (models.Teacher, error) {
 	db, err := ConnectDb()
 	if err != nil {
 		log.Println(err)
-		return models.Teacher{}, utils.ErrorHandler(err, "error updating data")
+		return models.Teacher{}, dbError(err, "error updating data")
 	}
-	defer db.Close()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Teacher{}, dbError(err, "error updating data")
+	}
+	defer tx.Rollback()
 
 	var existingTeacher models.Teacher
-	err = db.QueryRow("SELECT id, first_name, last_name, email, class, subject FROM teachers WHERE id = ?", id).Scan(&existingTeacher.ID, &existingTeacher.FirstName, &existingTeacher.LastName, &existingTeacher.Email, &existingTeacher.Class, &existingTeacher.Subject)
+	err = tx.QueryRowContext(ctx, "SELECT id, first_name, last_name, email, class, subject FROM teachers WHERE id = ?", id).Scan(&existingTeacher.ID, &existingTeacher.FirstName, &existingTeacher.LastName, &existingTeacher.Email, &existingTeacher.Class, &existingTeacher.Subject)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return models.Teacher{}, utils.ErrorHandler(err, "error updating data")
+			return models.Teacher{}, dbError(err, "error updating data")
 		}
-		return models.Teacher{}, utils.ErrorHandler(err, "error updating data")
+		return models.Teacher{}, dbError(err, "error updating data")
 	}
 
 	updatedTeacher.ID = existingTeacher.ID
-	_, err = db.Exec("UPDATE teachers SET first_name = ?, last_name = ?, email = ?, class = ?, subject = ? WHERE id = ?", updatedTeacher.FirstName, updatedTeacher.LastName, updatedTeacher.Email, updatedTeacher.Class, updatedTeacher.Subject, updatedTeacher.ID)
+	_, err = tx.ExecContext(ctx, "UPDATE teachers SET first_name = ?, last_name = ?, email = ?, class = ?, subject = ? WHERE id = ?", updatedTeacher.FirstName, updatedTeacher.LastName, updatedTeacher.Email, updatedTeacher.Class, updatedTeacher.Subject, updatedTeacher.ID)
 	if err != nil {
-		return models.Teacher{}, utils.ErrorHandler(err, "error updating data")
+		return models.Teacher{}, dbError(err, "error updating data")
+	}
+	if err := recordChange(ctx, tx, models.ContactTeacher, updatedTeacher.ID, models.ActionUpdated, existingTeacher, updatedTeacher); err != nil {
+		return models.Teacher{}, err
+	}
+	if err := saveCustomValues(ctx, tx, models.ContactTeacher, updatedTeacher.ID, fields, custom); err != nil {
+		return models.Teacher{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Teacher{}, dbError(err, "error updating data")
 	}
 	return updatedTeacher, nil
 }
 
-func PatchTeachers(updates []map[string]interface{}) error {
+// PatchTeachers applies the updates in one transaction; custom[n] holds the
+// custom field values changed by updates[n].
+func PatchTeachers(ctx context.Context, updates []map[string]interface{}, fields models.CustomFields, custom []map[string]string) error {
 	db, err := ConnectDb()
 	if err != nil {
-		return utils.ErrorHandler(err, "error updating data")
+		return dbError(err, "error updating data")
 	}
-	defer db.Close()
 
-	tx, err := db.Begin()
+	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
-		return utils.ErrorHandler(err, "error updating data")
+		return dbError(err, "error updating data")
 	}
 
-	for _, update := range updates {
+	for n, update := range updates {
 		idStr, ok := update["id"].(string)
 		if !ok {
 			tx.Rollback()
-			return utils.ErrorHandler(err, "invalid Id")
+			return dbError(err, "invalid Id")
 		}
 
 		id, err := strconv.Atoi(idStr)
 		if err != nil {
 			tx.Rollback()
-			return utils.ErrorHandler(err, "invalid Id")
+			return dbError(err, "invalid Id")
 		}
 
 		var teacherFromDb models.Teacher
-		err = db.QueryRow("SELECT id, first_name, last_name, email, class, subject FROM teachers WHERE id = ?", id).Scan(&teacherFromDb.ID, &teacherFromDb.FirstName, &teacherFromDb.LastName, &teacherFromDb.Email, &teacherFromDb.Class, &teacherFromDb.Subject)
+		err = tx.QueryRowContext(ctx, "SELECT id, first_name, last_name, email, class, subject FROM teachers WHERE id = ?", id).Scan(&teacherFromDb.ID, &teacherFromDb.FirstName, &teacherFromDb.LastName, &teacherFromDb.Email, &teacherFromDb.Class, &teacherFromDb.Subject)
 		if err != nil {
 			log.Println("ID:", id)
 			log.Printf("Type: %T", id)
 			log.Println(err)
 			tx.Rollback()
 			if err == sql.ErrNoRows {
-				return utils.ErrorHandler(err, "Teacher not found")
+				return dbError(err, "Teacher not found")
 			}
-			return utils.ErrorHandler(err, "error updating data")
+			return dbError(err, "error updating data")
 		}
+		before := teacherFromDb
 
 		teacherVal := reflect.ValueOf(&teacherFromDb).Elem()
 		teacherType := teacherVal.Type()
@@ -176,7 +216,7 @@ func PatchTeachers(updates []map[string]interface{}) error {
 						} else {
 							tx.Rollback()
 							log.Printf("cannot convert %v to %v", val.Type(), fieldVal.Type())
-							return utils.ErrorHandler(err, "error updating data")
+							return dbError(err, "error updating data")
 						}
 					}
 					break
@@ -184,36 +224,52 @@ func PatchTeachers(updates []map[string]interface{}) error {
 			}
 		}
 
-		_, err = tx.Exec("UPDATE teachers SET first_name = ?, last_name = ?, email = ?, class = ?, subject = ? WHERE id = ?", teacherFromDb.FirstName, teacherFromDb.LastName, teacherFromDb.Email, teacherFromDb.Class, teacherFromDb.Subject, teacherFromDb.ID)
+		_, err = tx.ExecContext(ctx, "UPDATE teachers SET first_name = ?, last_name = ?, email = ?, class = ?, subject = ? WHERE id = ?", teacherFromDb.FirstName, teacherFromDb.LastName, teacherFromDb.Email, teacherFromDb.Class, teacherFromDb.Subject, teacherFromDb.ID)
 		if err != nil {
 			tx.Rollback()
-			return utils.ErrorHandler(err, "error updating data")
+			return dbError(err, "error updating data")
+		}
+		if err := recordChange(ctx, tx, models.ContactTeacher, teacherFromDb.ID, models.ActionUpdated, before, teacherFromDb); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := saveCustomValues(ctx, tx, models.ContactTeacher, teacherFromDb.ID, fields, custom[n]); err != nil {
+			tx.Rollback()
+			return err
 		}
 	}
 
 	err = tx.Commit()
 	if err != nil {
-		return utils.ErrorHandler(err, "error updating data")
+		return dbError(err, "error updating data")
 	}
 	return nil
 }
 
-func PatchOneTeacher(id int, updates map[string]interface{}) (models.Teacher, error) {
+// PatchOneTeacher applies updates and the custom field values in custom in one
+// transaction.
+func PatchOneTeacher(ctx context.Context, id int, updates map[string]interface{}, fields models.CustomFields, custom map[string]string) (models.Teacher, error) {
 	db, err := ConnectDb()
 	if err != nil {
 		log.Println(err)
-		return models.Teacher{}, utils.ErrorHandler(err, "error updating data")
+		return models.Teacher{}, dbError(err, "error updating data")
 	}
-	defer db.Close()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Teacher{}, dbError(err, "error updating data")
+	}
+	defer tx.Rollback()
 
 	var existingTeacher models.Teacher
-	err = db.QueryRow("SELECT id, first_name, last_name, email, class, subject FROM teachers WHERE id = ?", id).Scan(&existingTeacher.ID, &existingTeacher.FirstName, &existingTeacher.LastName, &existingTeacher.Email, &existingTeacher.Class, &existingTeacher.Subject)
+	err = tx.QueryRowContext(ctx, "SELECT id, first_name, last_name, email, class, subject FROM teachers WHERE id = ?", id).Scan(&existingTeacher.ID, &existingTeacher.FirstName, &existingTeacher.LastName, &existingTeacher.Email, &existingTeacher.Class, &existingTeacher.Subject)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return models.Teacher{}, utils.ErrorHandler(err, "Teacher not found")
+			return models.Teacher{}, dbError(err, "Teacher not found")
 		}
-		return models.Teacher{}, utils.ErrorHandler(err, "error updating data")
+		return models.Teacher{}, dbError(err, "error updating data")
 	}
+	before := existingTeacher
 
 	teacherVal := reflect.ValueOf(&existingTeacher).Elem()
 	teacherType := teacherVal.Type()
@@ -230,68 +286,97 @@ func PatchOneTeacher(id int, updates map[string]interface{}) (models.Teacher, er
 		}
 	}
 
-	_, err = db.Exec("UPDATE teachers SET first_name = ?, last_name = ?, email = ?, class = ?, subject = ? WHERE id = ?", existingTeacher.FirstName, existingTeacher.LastName, existingTeacher.Email, existingTeacher.Class, existingTeacher.Subject, existingTeacher.ID)
+	_, err = tx.ExecContext(ctx, "UPDATE teachers SET first_name = ?, last_name = ?, email = ?, class = ?, subject = ? WHERE id = ?", existingTeacher.FirstName, existingTeacher.LastName, existingTeacher.Email, existingTeacher.Class, existingTeacher.Subject, existingTeacher.ID)
 	if err != nil {
-		return models.Teacher{}, utils.ErrorHandler(err, "error updating data")
+		return models.Teacher{}, dbError(err, "error updating data")
+	}
+	if err := recordChange(ctx, tx, models.ContactTeacher, existingTeacher.ID, models.ActionUpdated, before, existingTeacher); err != nil {
+		return models.Teacher{}, err
+	}
+	if err := saveCustomValues(ctx, tx, models.ContactTeacher, existingTeacher.ID, fields, custom); err != nil {
+		return models.Teacher{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Teacher{}, dbError(err, "error updating data")
 	}
 	return existingTeacher, nil
 }
 
-func DeleteOneTeacher(id int) error {
+func DeleteOneTeacher(ctx context.Context, id int) error {
 	db, err := ConnectDb()
 	if err != nil {
-		return utils.ErrorHandler(err, "error updating data")
+		return dbError(err, "error updating data")
 	}
-	defer db.Close()
-
-	result, err := db.Exec("DELETE FROM teachers WHERE id = ?", id)
+	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
-		return utils.ErrorHandler(err, "error updating data")
+		return dbError(err, "error updating data")
 	}
+	defer tx.Rollback()
 
-	rowsAffected, err := result.RowsAffected()
+	var before models.Teacher
+	err = tx.QueryRowContext(ctx, "SELECT id, first_name, last_name, email, class, subject FROM teachers WHERE id = ?", id).Scan(&before.ID, &before.FirstName, &before.LastName, &before.Email, &before.Class, &before.Subject)
+	if err == sql.ErrNoRows {
+		return dbError(err, "Teacher not found")
+	}
 	if err != nil {
-		return utils.ErrorHandler(err, "error updating data")
+		return dbError(err, "error updating data")
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM teachers WHERE id = ?", id); err != nil {
+		return dbError(err, "error updating data")
+	}
+	if err := recordChange(ctx, tx, models.ContactTeacher, id, models.ActionDeleted, before, nil); err != nil {
+		return err
+	}
+	if err := deleteCustomValues(ctx, tx, models.ContactTeacher, id); err != nil {
+		return err
 	}
 
-	if rowsAffected == 0 {
-		return utils.ErrorHandler(err, "Teacher not found")
+	if err := tx.Commit(); err != nil {
+		return dbError(err, "error updating data")
 	}
 	return nil
 }
 
-func DeleteTeachers(ids []int) ([]int, error) {
+func DeleteTeachers(ctx context.Context, ids []int) ([]int, error) {
 	db, err := ConnectDb()
 	if err != nil {
-		return nil, utils.ErrorHandler(err, "error deleting data")
+		return nil, dbError(err, "error deleting data")
 	}
-	defer db.Close()
 
-	tx, err := db.Begin()
+	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
-		return nil, utils.ErrorHandler(err, "error deleting data")
+		return nil, dbError(err, "error deleting data")
 	}
 
-	stmt, err := tx.Prepare("DELETE FROM teachers WHERE id = ?")
+	stmt, err := tx.PrepareContext(ctx, "DELETE FROM teachers WHERE id = ?")
 	if err != nil {
 		tx.Rollback()
-		return nil, utils.ErrorHandler(err, "error deleting data")
+		return nil, dbError(err, "error deleting data")
 	}
 	defer stmt.Close()
 
 	deletedIds := []int{}
 
 	for _, id := range ids {
-		result, err := stmt.Exec(id)
+		var before models.Teacher
+		err := tx.QueryRowContext(ctx, "SELECT id, first_name, last_name, email, class, subject FROM teachers WHERE id = ?", id).Scan(&before.ID, &before.FirstName, &before.LastName, &before.Email, &before.Class, &before.Subject)
+		if err != nil && err != sql.ErrNoRows {
+			tx.Rollback()
+			return nil, dbError(err, "error deleting data")
+		}
+
+		result, err := stmt.ExecContext(ctx, id)
 		if err != nil {
 			tx.Rollback()
-			return nil, utils.ErrorHandler(err, "error deleting data")
+			return nil, dbError(err, "error deleting data")
 		}
 
 		rowsAffected, err := result.RowsAffected()
 		if err != nil {
 			tx.Rollback()
-			return nil, utils.ErrorHandler(err, "error deleting data")
+			return nil, dbError(err, "error deleting data")
 		}
 
 		if rowsAffected > 0 {
@@ -300,32 +385,39 @@ func DeleteTeachers(ids []int) ([]int, error) {
 
 		if rowsAffected < 1 {
 			tx.Rollback()
-			return nil, utils.ErrorHandler(err, fmt.Sprintf("ID %d not found", id))
+			return nil, dbError(err, fmt.Sprintf("ID %d not found", id))
+		}
+		if err := recordChange(ctx, tx, models.ContactTeacher, id, models.ActionDeleted, before, nil); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		if err := deleteCustomValues(ctx, tx, models.ContactTeacher, id); err != nil {
+			tx.Rollback()
+			return nil, err
 		}
 	}
 
 	err = tx.Commit()
 	if err != nil {
-		return nil, utils.ErrorHandler(err, "error deleting data")
+		return nil, dbError(err, "error deleting data")
 	}
 
 	if len(deletedIds) < 1 {
-		return nil, utils.ErrorHandler(err, "IDs do not exist")
+		return nil, dbError(err, "IDs do not exist")
 	}
 	return deletedIds, nil
 }
 
-func GetStudentsByTeacherIdFromDb(teacherId string, students []models.Student) ([]models.Student, error) {
+func GetStudentsByTeacherIdFromDb(ctx context.Context, teacherId string, students []models.Student) ([]models.Student, error) {
 	db, err := ConnectDb()
 	if err != nil {
-		return nil, utils.ErrorHandler(err, "error retrieving data")
+		return nil, dbError(err, "error retrieving data")
 	}
-	defer db.Close()
 
 	query := `SELECT id, first_name, last_name, email, class FROM students WHERE class = (SELECT class from teachers WHERE id = ?)`
-	rows, err := db.Query(query, teacherId)
+	rows, err := db.QueryContext(ctx, query, teacherId)
 	if err != nil {
-		return nil, utils.ErrorHandler(err, "error retrieving data")
+		return nil, dbError(err, "error retrieving data")
 	}
 	defer rows.Close()
 
@@ -333,30 +425,28 @@ func GetStudentsByTeacherIdFromDb(teacherId string, students []models.Student) (
 		var student models.Student
 		err := rows.Scan(&student.ID, &student.FirstName, &student.LastName, &student.Email, &student.Class)
 		if err != nil {
-			return nil, utils.ErrorHandler(err, "error retrieving data")
+			return nil, dbError(err, "error retrieving data")
 		}
 		students = append(students, student)
 	}
 	err = rows.Err()
 	if err != nil {
-		return nil, utils.ErrorHandler(err, "error retrieving data")
+		return nil, dbError(err, "error retrieving data")
 	}
 	return students, nil
 }
 
-func GetStudentCountByTeacherIdFromDb(teacherId string) (int, error) {
+func GetStudentCountByTeacherIdFromDb(ctx context.Context, teacherId string) (int, error) {
 	db, err := ConnectDb()
 	if err != nil {
-		return 0, utils.ErrorHandler(err, "error retrieving data")
+		return 0, dbError(err, "error retrieving data")
 	}
 
-	defer db.Close()
-
 	query := `SELECT COUNT(*) FROM students WHERE class = (SELECT class FROM teachers WHERE id = ?)`
 	var studentCount int
-	err = db.QueryRow(query, teacherId).Scan(&studentCount)
+	err = db.QueryRowContext(ctx, query, teacherId).Scan(&studentCount)
 	if err != nil {
-		return 0, utils.ErrorHandler(err, "error retrieving data")
+		return 0, dbError(err, "error retrieving data")
 	}
 	return studentCount, nil
 }
@@ -0,0 +1,481 @@
+package sqlconnect
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"restapi/internal/models"
+)
+
+const createPeriodsTable = `CREATE TABLE IF NOT EXISTS periods (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	name VARCHAR(50) NOT NULL,
+	start_time TIME NOT NULL,
+	end_time TIME NOT NULL,
+	UNIQUE KEY uniq_periods_name (name)
+)`
+
+const createRoomsTable = `CREATE TABLE IF NOT EXISTS rooms (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	name VARCHAR(100) NOT NULL,
+	capacity INT NOT NULL DEFAULT 0,
+	UNIQUE KEY uniq_rooms_name (name)
+)`
+
+// The unique keys back up the conflict check in SaveSlot; a NULL room never clashes.
+const createTimetableSlotsTable = `CREATE TABLE IF NOT EXISTS timetable_slots (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	weekday TINYINT NOT NULL,
+	period_id INT NOT NULL,
+	class VARCHAR(255) NOT NULL,
+	subject VARCHAR(255) NOT NULL,
+	teacher_id INT NOT NULL,
+	room_id INT NULL,
+	UNIQUE KEY uniq_timetable_teacher (weekday, period_id, teacher_id),
+	UNIQUE KEY uniq_timetable_class (weekday, period_id, class),
+	UNIQUE KEY uniq_timetable_room (weekday, period_id, room_id),
+	FOREIGN KEY (period_id) REFERENCES periods (id),
+	FOREIGN KEY (teacher_id) REFERENCES teachers (id) ON DELETE CASCADE,
+	FOREIGN KEY (room_id) REFERENCES rooms (id)
+)`
+
+// period_id 0 blocks the whole day.
+const createTeacherUnavailabilityTable = `CREATE TABLE IF NOT EXISTS teacher_unavailability (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	teacher_id INT NOT NULL,
+	weekday TINYINT NOT NULL,
+	period_id INT NOT NULL DEFAULT 0,
+	reason VARCHAR(255) NOT NULL DEFAULT '',
+	KEY idx_teacher_unavailability (teacher_id, weekday),
+	FOREIGN KEY (teacher_id) REFERENCES teachers (id) ON DELETE CASCADE
+)`
+
+// ErrTimetableConflict means a slot clashes with the timetable; SaveSlot
+// returns the conflicts with it.
+var ErrTimetableConflict = errors.New("slot conflicts with the timetable")
+
+// ErrLessonPeriodOverlap means a period's times overlap another period, so
+// lessons in both could clash without sharing a period.
+var ErrLessonPeriodOverlap = errors.New("period overlaps another period")
+
+func GetPeriods(ctx context.Context) ([]models.Period, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT id, name, start_time, end_time FROM periods ORDER BY start_time")
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	defer rows.Close()
+
+	periods := []models.Period{}
+	for rows.Next() {
+		var p models.Period
+		if err := rows.Scan(&p.ID, &p.Name, &p.StartTime, &p.EndTime); err != nil {
+			return nil, dbError(err, "error retrieving data")
+		}
+		periods = append(periods, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	return periods, nil
+}
+
+func GetPeriodByID(ctx context.Context, id int) (models.Period, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return models.Period{}, dbError(err, "error retrieving data")
+	}
+
+	var p models.Period
+	err = db.QueryRowContext(ctx, "SELECT id, name, start_time, end_time FROM periods WHERE id = ?", id).Scan(&p.ID, &p.Name, &p.StartTime, &p.EndTime)
+	if err == sql.ErrNoRows {
+		return models.Period{}, dbError(err, "Period not found")
+	} else if err != nil {
+		return models.Period{}, dbError(err, "error retrieving data")
+	}
+	return p, nil
+}
+
+// SavePeriod inserts the period when it has no ID and updates it otherwise.
+// Periods must not overlap; adjacent ones may share a boundary.
+func SavePeriod(ctx context.Context, p models.Period) (models.Period, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return models.Period{}, dbError(err, "error updating data")
+	}
+
+	var overlapping int
+	err = db.QueryRowContext(ctx, "SELECT COUNT(*) FROM periods WHERE id <> ? AND start_time < ? AND end_time > ?",
+		p.ID, p.EndTime, p.StartTime).Scan(&overlapping)
+	if err != nil {
+		return models.Period{}, dbError(err, "error updating data")
+	}
+	if overlapping > 0 {
+		return models.Period{}, ErrLessonPeriodOverlap
+	}
+
+	if p.ID == 0 {
+		res, err := db.ExecContext(ctx, "INSERT INTO periods (name, start_time, end_time) VALUES (?, ?, ?)", p.Name, p.StartTime, p.EndTime)
+		if err != nil {
+			return models.Period{}, dbError(err, "error adding data")
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return models.Period{}, dbError(err, "error adding data")
+		}
+		p.ID = int(id)
+		return p, nil
+	}
+
+	_, err = db.ExecContext(ctx, "UPDATE periods SET name = ?, start_time = ?, end_time = ? WHERE id = ?", p.Name, p.StartTime, p.EndTime, p.ID)
+	if err != nil {
+		return models.Period{}, dbError(err, "error updating data")
+	}
+	return p, nil
+}
+
+// DeletePeriod fails while timetable slots still use the period.
+func DeletePeriod(ctx context.Context, id int) error {
+	return deleteByID(ctx, "periods", id, "Period not found")
+}
+
+func GetRooms(ctx context.Context) ([]models.Room, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT id, name, capacity FROM rooms ORDER BY name")
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	defer rows.Close()
+
+	rooms := []models.Room{}
+	for rows.Next() {
+		var room models.Room
+		if err := rows.Scan(&room.ID, &room.Name, &room.Capacity); err != nil {
+			return nil, dbError(err, "error retrieving data")
+		}
+		rooms = append(rooms, room)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	return rooms, nil
+}
+
+func GetRoomByID(ctx context.Context, id int) (models.Room, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return models.Room{}, dbError(err, "error retrieving data")
+	}
+
+	var room models.Room
+	err = db.QueryRowContext(ctx, "SELECT id, name, capacity FROM rooms WHERE id = ?", id).Scan(&room.ID, &room.Name, &room.Capacity)
+	if err == sql.ErrNoRows {
+		return models.Room{}, dbError(err, "Room not found")
+	} else if err != nil {
+		return models.Room{}, dbError(err, "error retrieving data")
+	}
+	return room, nil
+}
+
+// SaveRoom inserts the room when it has no ID and updates it otherwise.
+func SaveRoom(ctx context.Context, room models.Room) (models.Room, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return models.Room{}, dbError(err, "error updating data")
+	}
+
+	if room.ID == 0 {
+		res, err := db.ExecContext(ctx, "INSERT INTO rooms (name, capacity) VALUES (?, ?)", room.Name, room.Capacity)
+		if err != nil {
+			return models.Room{}, dbError(err, "error adding data")
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return models.Room{}, dbError(err, "error adding data")
+		}
+		room.ID = int(id)
+		return room, nil
+	}
+
+	_, err = db.ExecContext(ctx, "UPDATE rooms SET name = ?, capacity = ? WHERE id = ?", room.Name, room.Capacity, room.ID)
+	if err != nil {
+		return models.Room{}, dbError(err, "error updating data")
+	}
+	return room, nil
+}
+
+// DeleteRoom fails while timetable slots still use the room.
+func DeleteRoom(ctx context.Context, id int) error {
+	return deleteByID(ctx, "rooms", id, "Room not found")
+}
+
+func deleteByID(ctx context.Context, table string, id int, notFound string) error {
+	db, err := ConnectDb()
+	if err != nil {
+		return dbError(err, "error deleting data")
+	}
+
+	result, err := db.ExecContext(ctx, "DELETE FROM "+table+" WHERE id = ?", id)
+	if err != nil {
+		return dbError(err, "error deleting data")
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return dbError(err, "error deleting data")
+	}
+	if rowsAffected == 0 {
+		return dbError(errors.New("no rows deleted"), notFound)
+	}
+	return nil
+}
+
+const slotQuery = `SELECT t.id, t.weekday, t.period_id, p.name, p.start_time, p.end_time, t.class, t.subject,
+	t.teacher_id, CONCAT(te.first_name, ' ', te.last_name), COALESCE(t.room_id, 0), COALESCE(r.name, '')
+	FROM timetable_slots t
+	JOIN periods p ON p.id = t.period_id
+	JOIN teachers te ON te.id = t.teacher_id
+	LEFT JOIN rooms r ON r.id = t.room_id`
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// querySlots reads the slots matching where, ordered through the week.
+func querySlots(ctx context.Context, q queryer, where string, args ...interface{}) ([]models.TimetableSlot, error) {
+	return readSlots(ctx, q, slotSelect(where, false), args...)
+}
+
+// lockSlots is querySlots taking row locks on the slots until tx ends.
+func lockSlots(ctx context.Context, tx *sql.Tx, where string, args ...interface{}) ([]models.TimetableSlot, error) {
+	return readSlots(ctx, tx, slotSelect(where, true), args...)
+}
+
+// slotSelect builds the slot query; FOR UPDATE must follow ORDER BY.
+func slotSelect(where string, lock bool) string {
+	query := slotQuery + " WHERE " + where + " ORDER BY t.weekday, p.start_time, t.class"
+	if lock {
+		query += " FOR UPDATE"
+	}
+	return query
+}
+
+func readSlots(ctx context.Context, q queryer, query string, args ...interface{}) ([]models.TimetableSlot, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	defer rows.Close()
+
+	slots := []models.TimetableSlot{}
+	for rows.Next() {
+		var s models.TimetableSlot
+		err := rows.Scan(&s.ID, &s.Weekday, &s.PeriodID, &s.PeriodName, &s.StartTime, &s.EndTime, &s.Class, &s.Subject,
+			&s.TeacherID, &s.TeacherName, &s.RoomID, &s.RoomName)
+		if err != nil {
+			return nil, dbError(err, "error retrieving data")
+		}
+		slots = append(slots, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	return slots, nil
+}
+
+func GetTeacherTimetable(ctx context.Context, teacherID int) ([]models.TimetableSlot, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	return querySlots(ctx, db, "t.teacher_id = ?", teacherID)
+}
+
+func GetClassTimetable(ctx context.Context, class string) ([]models.TimetableSlot, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	return querySlots(ctx, db, "t.class = ?", class)
+}
+
+func GetRoomTimetable(ctx context.Context, roomID int) ([]models.TimetableSlot, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	return querySlots(ctx, db, "t.room_id = ?", roomID)
+}
+
+func GetSlotByID(ctx context.Context, id int) (models.TimetableSlot, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return models.TimetableSlot{}, dbError(err, "error retrieving data")
+	}
+	slots, err := querySlots(ctx, db, "t.id = ?", id)
+	if err != nil {
+		return models.TimetableSlot{}, err
+	}
+	if len(slots) == 0 {
+		return models.TimetableSlot{}, dbError(sql.ErrNoRows, "Timetable slot not found")
+	}
+	return slots[0], nil
+}
+
+// SaveSlot inserts the slot when it has no ID and updates it otherwise. The
+// slots of the same weekday and period are locked while they are checked, so
+// two requests cannot book the same teacher, room or class; on a clash the
+// conflicts are returned with ErrTimetableConflict.
+func SaveSlot(ctx context.Context, s models.TimetableSlot) (models.TimetableSlot, []models.Conflict, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return models.TimetableSlot{}, nil, dbError(err, "error updating data")
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.TimetableSlot{}, nil, dbError(err, "error updating data")
+	}
+	defer tx.Rollback()
+
+	others, err := lockSlots(ctx, tx, "t.weekday = ? AND t.period_id = ?", s.Weekday, s.PeriodID)
+	if err != nil {
+		return models.TimetableSlot{}, nil, err
+	}
+	unavailable, err := queryUnavailability(ctx, tx, "teacher_id = ? AND weekday = ?", s.TeacherID, s.Weekday)
+	if err != nil {
+		return models.TimetableSlot{}, nil, err
+	}
+	if conflicts := s.Conflicts(others, unavailable); len(conflicts) > 0 {
+		return models.TimetableSlot{}, conflicts, ErrTimetableConflict
+	}
+
+	if s.ID == 0 {
+		res, err := tx.ExecContext(ctx, "INSERT INTO timetable_slots (weekday, period_id, class, subject, teacher_id, room_id) VALUES (?, ?, ?, ?, ?, NULLIF(?, 0))",
+			s.Weekday, s.PeriodID, s.Class, s.Subject, s.TeacherID, s.RoomID)
+		if err != nil {
+			return models.TimetableSlot{}, nil, dbError(err, "error adding data")
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return models.TimetableSlot{}, nil, dbError(err, "error adding data")
+		}
+		s.ID = int(id)
+	} else {
+		_, err := tx.ExecContext(ctx, "UPDATE timetable_slots SET weekday = ?, period_id = ?, class = ?, subject = ?, teacher_id = ?, room_id = NULLIF(?, 0) WHERE id = ?",
+			s.Weekday, s.PeriodID, s.Class, s.Subject, s.TeacherID, s.RoomID, s.ID)
+		if err != nil {
+			return models.TimetableSlot{}, nil, dbError(err, "error updating data")
+		}
+	}
+
+	saved, err := querySlots(ctx, tx, "t.id = ?", s.ID)
+	if err != nil {
+		return models.TimetableSlot{}, nil, err
+	}
+	if len(saved) == 0 {
+		return models.TimetableSlot{}, nil, dbError(sql.ErrNoRows, "Timetable slot not found")
+	}
+	if err := tx.Commit(); err != nil {
+		return models.TimetableSlot{}, nil, dbError(err, "error updating data")
+	}
+	return saved[0], nil, nil
+}
+
+func DeleteSlot(ctx context.Context, id int) error {
+	return deleteByID(ctx, "timetable_slots", id, "Timetable slot not found")
+}
+
+// GetTimetableConflicts checks the whole timetable, e.g. after a teacher was
+// marked unavailable.
+func GetTimetableConflicts(ctx context.Context) ([]models.Conflict, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	slots, err := querySlots(ctx, db, "1=1")
+	if err != nil {
+		return nil, err
+	}
+	unavailable, err := queryUnavailability(ctx, db, "1=1")
+	if err != nil {
+		return nil, err
+	}
+	return models.FindConflicts(slots, unavailable), nil
+}
+
+func queryUnavailability(ctx context.Context, q queryer, where string, args ...interface{}) ([]models.Unavailability, error) {
+	rows, err := q.QueryContext(ctx, "SELECT id, teacher_id, weekday, period_id, reason FROM teacher_unavailability WHERE "+where+" ORDER BY weekday, period_id", args...)
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	defer rows.Close()
+
+	entries := []models.Unavailability{}
+	for rows.Next() {
+		var u models.Unavailability
+		if err := rows.Scan(&u.ID, &u.TeacherID, &u.Weekday, &u.PeriodID, &u.Reason); err != nil {
+			return nil, dbError(err, "error retrieving data")
+		}
+		entries = append(entries, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	return entries, nil
+}
+
+func GetTeacherUnavailability(ctx context.Context, teacherID int) ([]models.Unavailability, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return nil, dbError(err, "error retrieving data")
+	}
+	return queryUnavailability(ctx, db, "teacher_id = ?", teacherID)
+}
+
+// AddUnavailability blocks the teacher. Slots already scheduled then are
+// kept and show up in GetTimetableConflicts.
+func AddUnavailability(ctx context.Context, u models.Unavailability) (models.Unavailability, error) {
+	db, err := ConnectDb()
+	if err != nil {
+		return models.Unavailability{}, dbError(err, "error adding data")
+	}
+
+	res, err := db.ExecContext(ctx, "INSERT INTO teacher_unavailability (teacher_id, weekday, period_id, reason) VALUES (?, ?, ?, ?)",
+		u.TeacherID, u.Weekday, u.PeriodID, u.Reason)
+	if err != nil {
+		return models.Unavailability{}, dbError(err, "error adding data")
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return models.Unavailability{}, dbError(err, "error adding data")
+	}
+	u.ID = int(id)
+	return u, nil
+}
+
+func DeleteUnavailability(ctx context.Context, teacherID, id int) error {
+	db, err := ConnectDb()
+	if err != nil {
+		return dbError(err, "error deleting data")
+	}
+
+	result, err := db.ExecContext(ctx, "DELETE FROM teacher_unavailability WHERE id = ? AND teacher_id = ?", id, teacherID)
+	if err != nil {
+		return dbError(err, "error deleting data")
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return dbError(err, "error deleting data")
+	}
+	if rowsAffected == 0 {
+		return dbError(errors.New("no rows deleted"), "Unavailability not found")
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package sqlconnect
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSlotSelect(t *testing.T) {
+	tests := []struct {
+		name string
+		lock bool
+		want string
+	}{
+		{"read", false, " WHERE t.id = ? ORDER BY t.weekday, p.start_time, t.class"},
+		// MySQL принимает FOR UPDATE только после ORDER BY
+		{"lock", true, " WHERE t.id = ? ORDER BY t.weekday, p.start_time, t.class FOR UPDATE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := slotSelect("t.id = ?", tt.lock)
+			if !strings.HasPrefix(got, slotQuery) || !strings.HasSuffix(got, tt.want) {
+				t.Errorf("slotSelect() = %q, want it to end with %q", got, tt.want)
+			}
+			if tt.lock != strings.Contains(got, "FOR UPDATE") {
+				t.Errorf("slotSelect() = %q, lock %v", got, tt.lock)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"restapi/internal/config"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+const defaultServiceName = "crm-backend"
+
+// Init installs the W3C trace-context propagator and, depending on
+// cfg.Exporter ("otlp", "stdout" or "none"), a tracer provider with the
+// matching exporter. The OTLP exporter reads its endpoint and headers from
+// the standard OTEL_EXPORTER_OTLP_* variables.
+//
+// The returned function flushes and stops the provider.
+func Init(ctx context.Context, cfg config.TracingConfig) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	noop := func(context.Context) error { return nil }
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch kind := strings.ToLower(cfg.Exporter); kind {
+	case "", "none":
+		return noop, nil
+	case "otlp":
+		exporter, err = otlptracehttp.New(ctx)
+	case "stdout", "console":
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	default:
+		return noop, fmt.Errorf("unknown tracing exporter %q", kind)
+	}
+	if err != nil {
+		return noop, err
+	}
+
+	serviceName := cfg.ServiceName
+	if serviceName == "" {
+		serviceName = defaultServiceName
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(serviceName),
+	))
+	if err != nil {
+		return noop, err
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(tp)
+
+	return tp.Shutdown, nil
+}
@@ -1,6 +1,10 @@
 package utils
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"strconv"
+)
 
 type ContextKey string
 
@@ -13,3 +17,23 @@ func AuthorizeUser(userRole string, allowedRoles ...string) (bool, error) {
 
 	return false, errors.New("user not authorized")
 }
+
+// UserID returns the exec id from the JWT claims in ctx, 0 if there is none.
+func UserID(ctx context.Context) int {
+	switch id := ctx.Value(ContextKey("userId")).(type) {
+	case float64:
+		return int(id)
+	case int:
+		return id
+	case string:
+		n, _ := strconv.Atoi(id)
+		return n
+	}
+	return 0
+}
+
+// UserRole returns the role from the JWT claims in ctx.
+func UserRole(ctx context.Context) string {
+	role, _ := ctx.Value(ContextKey("role")).(string)
+	return role
+}
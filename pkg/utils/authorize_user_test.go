@@ -1,51 +1,52 @@
 package utils
 
 import (
+	"context"
 	"testing"
 )
 
 func TestAuthorizeUser(t *testing.T) {
 	tests := []struct {
-		name        string
-		userRole    string
+		name         string
+		userRole     string
 		allowedRoles []string
-		want        bool
-		wantErr     bool
+		want         bool
+		wantErr      bool
 	}{
 		{
-			name:        "authorized user",
-			userRole:    "admin",
+			name:         "authorized user",
+			userRole:     "admin",
 			allowedRoles: []string{"admin", "user"},
-			want:        true,
-			wantErr:     false,
+			want:         true,
+			wantErr:      false,
 		},
 		{
-			name:        "unauthorized user",
-			userRole:    "guest",
+			name:         "unauthorized user",
+			userRole:     "guest",
 			allowedRoles: []string{"admin", "user"},
-			want:        false,
-			wantErr:     true,
+			want:         false,
+			wantErr:      true,
 		},
 		{
-			name:        "single allowed role match",
-			userRole:    "admin",
+			name:         "single allowed role match",
+			userRole:     "admin",
 			allowedRoles: []string{"admin"},
-			want:        true,
-			wantErr:     false,
+			want:         true,
+			wantErr:      false,
 		},
 		{
-			name:        "empty allowed roles",
-			userRole:    "admin",
+			name:         "empty allowed roles",
+			userRole:     "admin",
 			allowedRoles: []string{},
-			want:        false,
-			wantErr:     true,
+			want:         false,
+			wantErr:      true,
 		},
 		{
-			name:        "empty user role",
-			userRole:    "",
+			name:         "empty user role",
+			userRole:     "",
 			allowedRoles: []string{"admin"},
-			want:        false,
-			wantErr:     true,
+			want:         false,
+			wantErr:      true,
 		},
 	}
 
@@ -63,3 +64,28 @@ func TestAuthorizeUser(t *testing.T) {
 	}
 }
 
+func TestUserID(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  int
+	}{
+		// Числа из JWT-claims декодируются как float64
+		{"float64 claim", float64(7), 7},
+		{"int", 3, 3},
+		{"string", "12", 12},
+		{"missing", nil, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.value != nil {
+				ctx = context.WithValue(ctx, ContextKey("userId"), tt.value)
+			}
+			if got := UserID(ctx); got != tt.want {
+				t.Errorf("UserID() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
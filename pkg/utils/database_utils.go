@@ -15,7 +15,7 @@ func GenerateInsertQuery(tableName string, model interface{}) string {
 		dbTag := modelType.Field(i).Tag.Get("db")
 		fmt.Println("dbTag:", dbTag)
 		dbTag = strings.TrimSuffix(dbTag, ",omitempty")
-		if dbTag != "" && dbTag != "id" {
+		if dbTag != "" && dbTag != "id" && dbTag != "-" {
 			if columns != "" {
 				columns += ", "
 				placeholders += ", "
@@ -35,7 +35,7 @@ func GetStructValues(model interface{}) []interface{} {
 	values := []interface{}{}
 	for i := 0; i < modelType.NumField(); i++ {
 		dbTag := modelType.Field(i).Tag.Get("db")
-		if dbTag != "" && dbTag != "id,omitempty" {
+		if dbTag != "" && dbTag != "id,omitempty" && dbTag != "-" {
 			values = append(values, modelValue.Field(i).Interface())
 		}
 	}
@@ -189,3 +189,19 @@ func TestGetStructValues(t *testing.T) {
 	}
 }
 
+
+func TestGenerateInsertQuerySkipsUnstoredFields(t *testing.T) {
+	type TestModel struct {
+		ID        int            `db:"id,omitempty"`
+		FirstName string         `db:"first_name,omitempty"`
+		Custom    map[string]any `db:"-"`
+	}
+
+	// Поля с db:"-" не попадают ни в запрос, ни в значения
+	if query := GenerateInsertQuery("test_table", TestModel{}); query != "INSERT INTO test_table (first_name) VALUES (?)" {
+		t.Errorf("GenerateInsertQuery() = %q", query)
+	}
+	if values := GetStructValues(TestModel{FirstName: "John", Custom: map[string]any{"a": 1}}); len(values) != 1 {
+		t.Errorf("GetStructValues() returned %v, want only first_name", values)
+	}
+}
@@ -1,35 +1,56 @@
 package utils
 
 import (
-	"os"
+	"errors"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
+var (
+	jwtMu        sync.RWMutex
+	jwtSecret    []byte
+	jwtExpiresIn = 15 * time.Minute
+)
+
+// SetJWTConfig installs the signing secret and token lifetime loaded at startup.
+func SetJWTConfig(secret string, expiresIn time.Duration) {
+	jwtMu.Lock()
+	defer jwtMu.Unlock()
+
+	jwtSecret = []byte(secret)
+	if expiresIn > 0 {
+		jwtExpiresIn = expiresIn
+	}
+}
+
+// JWTSecret returns the key used to sign and verify tokens.
+func JWTSecret() []byte {
+	jwtMu.RLock()
+	defer jwtMu.RUnlock()
+	return jwtSecret
+}
+
 func SignToken(userId int, username, role string) (string, error) {
-	jwtSecret := os.Getenv("JWT_SECRET")
-	jwtExpiresIn := os.Getenv("JWT_EXPIRES_IN")
+	jwtMu.RLock()
+	secret, expiresIn := jwtSecret, jwtExpiresIn
+	jwtMu.RUnlock()
+
+	if len(secret) == 0 {
+		return "", ErrorHandler(errors.New("JWT secret is not configured"), "Internal error")
+	}
 
 	claims := jwt.MapClaims{
 		"uid":  userId,
 		"user": username,
 		"role": role,
-	}
-
-	if jwtExpiresIn != "" {
-		duration, err := time.ParseDuration(jwtExpiresIn)
-		if err != nil {
-			return "", ErrorHandler(err, "Internal error")
-		}
-		claims["exp"] = jwt.NewNumericDate(time.Now().Add(duration))
-	} else {
-		claims["exp"] = jwt.NewNumericDate(time.Now().Add(15 * time.Minute))
+		"exp":  jwt.NewNumericDate(time.Now().Add(expiresIn)),
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
-	signedToken, err := token.SignedString([]byte(jwtSecret))
+	signedToken, err := token.SignedString(secret)
 	if err != nil {
 		return "", ErrorHandler(err, "Internal error")
 	}
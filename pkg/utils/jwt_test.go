@@ -1,29 +1,14 @@
 package utils
 
 import (
-	"os"
 	"testing"
+	"time"
 )
 
 func TestSignToken(t *testing.T) {
-	// Устанавливаем тестовые переменные окружения
-	originalSecret := os.Getenv("JWT_SECRET")
-	originalExpires := os.Getenv("JWT_EXPIRES_IN")
-	
-	os.Setenv("JWT_SECRET", "test-secret-key-for-jwt-signing")
-	os.Setenv("JWT_EXPIRES_IN", "1h")
-	defer func() {
-		if originalSecret != "" {
-			os.Setenv("JWT_SECRET", originalSecret)
-		} else {
-			os.Unsetenv("JWT_SECRET")
-		}
-		if originalExpires != "" {
-			os.Setenv("JWT_EXPIRES_IN", originalExpires)
-		} else {
-			os.Unsetenv("JWT_EXPIRES_IN")
-		}
-	}()
+	// Устанавливаем тестовую конфигурацию JWT
+	SetJWTConfig("test-secret-key-for-jwt-signing", time.Hour)
+	defer SetJWTConfig("", 15*time.Minute)
 
 	tests := []struct {
 		name     string
@@ -70,21 +55,8 @@ func TestSignToken(t *testing.T) {
 }
 
 func TestSignTokenDefaultExpiration(t *testing.T) {
-	originalSecret := os.Getenv("JWT_SECRET")
-	originalExpires := os.Getenv("JWT_EXPIRES_IN")
-	
-	os.Setenv("JWT_SECRET", "test-secret-key")
-	os.Unsetenv("JWT_EXPIRES_IN")
-	defer func() {
-		if originalSecret != "" {
-			os.Setenv("JWT_SECRET", originalSecret)
-		} else {
-			os.Unsetenv("JWT_SECRET")
-		}
-		if originalExpires != "" {
-			os.Setenv("JWT_EXPIRES_IN", originalExpires)
-		}
-	}()
+	SetJWTConfig("test-secret-key", 0)
+	defer SetJWTConfig("", 15*time.Minute)
 
 	token, err := SignToken(1, "testuser", "admin")
 	if err != nil {
@@ -97,15 +69,8 @@ func TestSignTokenDefaultExpiration(t *testing.T) {
 }
 
 func TestSignTokenDifferentUsers(t *testing.T) {
-	originalSecret := os.Getenv("JWT_SECRET")
-	os.Setenv("JWT_SECRET", "test-secret-key")
-	defer func() {
-		if originalSecret != "" {
-			os.Setenv("JWT_SECRET", originalSecret)
-		} else {
-			os.Unsetenv("JWT_SECRET")
-		}
-	}()
+	SetJWTConfig("test-secret-key", 15*time.Minute)
+	defer SetJWTConfig("", 15*time.Minute)
 
 	token1, err1 := SignToken(1, "user1", "admin")
 	if err1 != nil {
@@ -122,3 +87,13 @@ func TestSignTokenDifferentUsers(t *testing.T) {
 	}
 }
 
+
+func TestSignTokenWithoutSecret(t *testing.T) {
+	SetJWTConfig("", 15*time.Minute)
+
+	// Без секрета токен не должен подписываться пустым ключом
+	_, err := SignToken(1, "testuser", "admin")
+	if err == nil {
+		t.Errorf("SignToken() should fail when JWT secret is not configured")
+	}
+}
@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// ListSchema describes the query parameters a list endpoint understands.
+// Filters and SortFields must be handled by AddFilters and AddSorting.
+type ListSchema struct {
+	Filters    []string
+	SortFields []string
+	// Tagged lists also accept "tag": comma-separated note tags, any of which
+	// must be on a note the caller can read
+	Tagged bool
+	// Custom lists also filter on "custom.<name>" params and sort by
+	// "custom.<name>" on the resource's custom fields
+	Custom bool
+}
+
+var (
+	StudentListSchema = ListSchema{
+		Filters:    []string{"first_name", "last_name", "email", "class"},
+		SortFields: []string{"first_name", "last_name", "email", "class"},
+		Tagged:     true,
+		Custom:     true,
+	}
+	TeacherListSchema = ListSchema{
+		Filters:    []string{"first_name", "last_name", "email", "class", "subject"},
+		SortFields: []string{"first_name", "last_name", "email", "class", "subject"},
+		Tagged:     true,
+		Custom:     true,
+	}
+	ExecListSchema = ListSchema{
+		Filters:    []string{"first_name", "last_name", "email"},
+		SortFields: []string{"first_name", "last_name", "email"},
+	}
+	GuardianListSchema = ListSchema{
+		Filters:    []string{"first_name", "last_name", "email", "phone", "preferred_language"},
+		SortFields: []string{"first_name", "last_name", "email", "preferred_language"},
+		Tagged:     true,
+	}
+	NoteListSchema = ListSchema{
+		Filters:    []string{"subject_type", "subject_id", "type", "visibility", "author_id"},
+		SortFields: []string{"created_at", "updated_at", "type"},
+		Tagged:     true,
+	}
+	LeadListSchema = ListSchema{
+		Filters:    []string{"first_name", "last_name", "email", "phone", "desired_class", "source", "stage", "assigned_to"},
+		SortFields: []string{"first_name", "last_name", "desired_class", "stage", "created_at", "updated_at"},
+	}
+)
+
+// QueryParams lists every parameter the endpoint accepts, including
+// pagination. Only "sortby" may repeat.
+func (s ListSchema) QueryParams() []string {
+	params := append([]string{}, s.Filters...)
+	if s.Tagged {
+		params = append(params, "tag")
+	}
+	if s.Custom {
+		params = append(params, "custom.*")
+	}
+	return append(params, "sortby", "page", "limit")
+}
+
+// Where appends an equality condition for every filter present in the query.
+// Filter names must be column names.
+func (s ListSchema) Where(r *http.Request, query string, args []interface{}) (string, []interface{}) {
+	for _, param := range s.Filters {
+		if value := r.URL.Query().Get(param); value != "" {
+			query += " AND " + param + " = ?"
+			args = append(args, value)
+		}
+	}
+	return query, args
+}
+
+// OrderBy appends the valid "sortby=field:asc|desc" params as an ORDER BY clause.
+func (s ListSchema) OrderBy(r *http.Request, query string) string {
+	var order []string
+	for _, param := range r.URL.Query()["sortby"] {
+		field, direction, ok := strings.Cut(param, ":")
+		if !ok || !slices.Contains(s.SortFields, field) || !isValidSortOrder(direction) {
+			continue
+		}
+		order = append(order, field+" "+direction)
+	}
+	if len(order) == 0 {
+		return query
+	}
+	return query + " ORDER BY " + strings.Join(order, ", ")
+}
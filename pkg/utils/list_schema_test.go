@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestListSchemaWhere(t *testing.T) {
+	req := httptest.NewRequest("GET", "/guardians?phone=555&class=10A&preferred_language=ru", nil)
+	query, args := GuardianListSchema.Where(req, " WHERE 1=1", nil)
+
+	// class не входит в схему опекунов и игнорируется
+	want := " WHERE 1=1 AND phone = ? AND preferred_language = ?"
+	if query != want {
+		t.Errorf("Where() query = %q, want %q", query, want)
+	}
+	if len(args) != 2 || args[0] != "555" || args[1] != "ru" {
+		t.Errorf("Where() args = %v", args)
+	}
+}
+
+func TestListSchemaOrderBy(t *testing.T) {
+	tests := []struct {
+		name string
+		url  string
+		want string
+	}{
+		{"no sorting", "/guardians", "SELECT"},
+		{"two fields", "/guardians?sortby=last_name:asc&sortby=first_name:desc", "SELECT ORDER BY last_name asc, first_name desc"},
+		// Поля вне схемы и неверный порядок отбрасываются
+		{"invalid fields", "/guardians?sortby=password:asc&sortby=email:sideways&sortby=email:desc", "SELECT ORDER BY email desc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GuardianListSchema.OrderBy(httptest.NewRequest("GET", tt.url, nil), "SELECT"); got != tt.want {
+				t.Errorf("OrderBy() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListSchemaQueryParams(t *testing.T) {
+	// tag принимают только списки с заметками
+	if params := StudentListSchema.QueryParams(); !slices.Contains(params, "tag") {
+		t.Errorf("StudentListSchema.QueryParams() = %v, want tag", params)
+	}
+	if params := ExecListSchema.QueryParams(); slices.Contains(params, "tag") {
+		t.Errorf("ExecListSchema.QueryParams() = %v, want no tag", params)
+	}
+	// Произвольные поля есть только у учеников и учителей
+	if params := TeacherListSchema.QueryParams(); !slices.Contains(params, "custom.*") {
+		t.Errorf("TeacherListSchema.QueryParams() = %v, want custom.*", params)
+	}
+	if params := GuardianListSchema.QueryParams(); slices.Contains(params, "custom.*") {
+		t.Errorf("GuardianListSchema.QueryParams() = %v, want no custom.*", params)
+	}
+}
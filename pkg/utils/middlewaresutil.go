@@ -1,12 +1,48 @@
 package utils
 
-import "net/http"
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+)
+
+const tracerName = "restapi"
 
 type Middleware func(http.Handler) http.Handler
 
+// ApplyMiddlewares wraps handler with the middlewares in order, so the last one
+// runs first. Every middleware gets its own tracing span covering the rest of the chain.
 func ApplyMiddlewares(handler http.Handler, middlewares ...Middleware) http.Handler {
 	for _, middleware := range middlewares {
-		handler = middleware(handler)
+		handler = traceMiddleware(middlewareName(middleware), middleware(handler))
 	}
 	return handler
 }
+
+func traceMiddleware(name string, next http.Handler) http.Handler {
+	tracer := otel.Tracer(tracerName)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := tracer.Start(r.Context(), "middleware "+name)
+		defer span.End()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+var closureSuffix = regexp.MustCompile(`(\.func\d+)+$|-fm$`)
+
+// middlewareName turns "restapi/internal/api/middlewares.Hpp.func1" into "middlewares.Hpp".
+func middlewareName(middleware Middleware) string {
+	fn := runtime.FuncForPC(reflect.ValueOf(middleware).Pointer())
+	if fn == nil {
+		return "unknown"
+	}
+	name := fn.Name()
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	return closureSuffix.ReplaceAllString(name, "")
+}
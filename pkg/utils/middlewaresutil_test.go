@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func passThrough(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r)
+	})
+}
+
+func withOption(option string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return next
+	}
+}
+
+func TestMiddlewareName(t *testing.T) {
+	tests := []struct {
+		name       string
+		middleware Middleware
+		want       string
+	}{
+		{"plain function", passThrough, "utils.passThrough"},
+		{"closure from constructor", withOption("x"), "utils.withOption"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := middlewareName(tt.middleware); got != tt.want {
+				t.Errorf("middlewareName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyMiddlewaresSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	original := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(original)
+
+	handler := ApplyMiddlewares(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}), passThrough, withOption("x"))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/test", nil))
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("ApplyMiddlewares() recorded %d spans, want 2", len(spans))
+	}
+
+	// Последний middleware — внешний, его span является родителем
+	inner, outer := spans[0], spans[1]
+	if outer.Name() != "middleware utils.withOption" || inner.Name() != "middleware utils.passThrough" {
+		t.Errorf("unexpected span names: %s, %s", outer.Name(), inner.Name())
+	}
+	if inner.Parent().SpanID() != outer.SpanContext().SpanID() {
+		t.Errorf("inner middleware span is not a child of the outer one")
+	}
+}
@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"html"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/microcosm-cc/bluemonday"
+)
+
+// SanitizePolicy says how a string field is cleaned on input. Responses are
+// JSON-encoded, which escapes <, > and &, so input cleaning only removes what
+// must never be stored.
+type SanitizePolicy string
+
+const (
+	// SanitizeText keeps the value as typed, minus control characters and surrounding space
+	SanitizeText SanitizePolicy = "text"
+	// SanitizeStrict also strips any HTML markup, for identifiers such as emails and usernames
+	SanitizeStrict SanitizePolicy = "strict"
+	// SanitizeRich allows the safe HTML subset of bluemonday's UGC policy
+	SanitizeRich SanitizePolicy = "rich"
+	// SanitizeNone leaves the value untouched, for passwords and tokens
+	SanitizeNone SanitizePolicy = "none"
+)
+
+var (
+	strictPolicy = bluemonday.StrictPolicy()
+	richPolicy   = bluemonday.UGCPolicy()
+)
+
+func SanitizeString(policy SanitizePolicy, value string) string {
+	switch policy {
+	case SanitizeNone:
+		return value
+	case SanitizeRich:
+		return richPolicy.Sanitize(stripControl(value))
+	case SanitizeStrict:
+		// bluemonday escapes what it keeps; unescape so O'Brien stays O'Brien
+		return strings.TrimSpace(html.UnescapeString(strictPolicy.Sanitize(stripControl(value))))
+	default:
+		return strings.TrimSpace(stripControl(value))
+	}
+}
+
+// stripControl drops control characters other than newlines and tabs.
+func stripControl(value string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) && r != '\n' && r != '\t' && r != '\r' {
+			return -1
+		}
+		return r
+	}, value)
+}
+
+// FieldPolicies maps JSON field names to their sanitization policy.
+type FieldPolicies map[string]SanitizePolicy
+
+// FieldPoliciesOf reads `sanitize:"..."` tags from a model struct. Fields
+// without the tag use SanitizeText.
+func FieldPoliciesOf(model interface{}) FieldPolicies {
+	policies := FieldPolicies{}
+	t := reflect.TypeOf(model)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return policies
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		policy := SanitizePolicy(field.Tag.Get("sanitize"))
+		if policy == "" {
+			policy = SanitizeText
+		}
+		policies[name] = policy
+	}
+	return policies
+}
+
+// PolicyFor returns the policy for a field, SanitizeText if it has none.
+func (p FieldPolicies) PolicyFor(key string) SanitizePolicy {
+	if policy, ok := p[key]; ok {
+		return policy
+	}
+	return SanitizeText
+}
+
+// Sanitize cleans decoded JSON in place: objects by field policy, arrays
+// element by element (bulk requests), bare strings with SanitizeText.
+func (p FieldPolicies) Sanitize(data interface{}) interface{} {
+	return p.sanitizeValue(data, SanitizeText)
+}
+
+func (p FieldPolicies) sanitizeValue(data interface{}, policy SanitizePolicy) interface{} {
+	switch v := data.(type) {
+	case string:
+		return SanitizeString(policy, v)
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key] = p.sanitizeValue(value, p.PolicyFor(key))
+		}
+		return v
+	case []interface{}:
+		for i, value := range v {
+			v[i] = p.sanitizeValue(value, policy)
+		}
+		return v
+	default:
+		return v
+	}
+}
@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+type sanitizeTestModel struct {
+	Name     string `json:"name,omitempty"`
+	Email    string `json:"email,omitempty" sanitize:"strict"`
+	Password string `json:"password" sanitize:"none"`
+	Bio      string `json:"bio" sanitize:"rich"`
+}
+
+func TestSanitizeString(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy SanitizePolicy
+		value  string
+		want   string
+	}{
+		// Апостроф не должен превращаться в &#39;
+		{"apostrophe in text", SanitizeText, "O'Brien", "O'Brien"},
+		{"less-than kept in text", SanitizeText, "a < b", "a < b"},
+		{"control characters removed", SanitizeText, " Anna\x00\x07 ", "Anna"},
+		{"strict strips tags", SanitizeStrict, "<script>alert(1)</script>john@example.com", "john@example.com"},
+		{"strict keeps apostrophe", SanitizeStrict, "o'brien@example.com", "o'brien@example.com"},
+		{"rich keeps safe markup", SanitizeRich, "<b>hi</b><script>x</script>", "<b>hi</b>"},
+		{"password untouched", SanitizeNone, " p<a>ss'\x01 ", " p<a>ss'\x01 "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SanitizeString(tt.policy, tt.value); got != tt.want {
+				t.Errorf("SanitizeString() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFieldPoliciesOf(t *testing.T) {
+	want := FieldPolicies{
+		"name":     SanitizeText,
+		"email":    SanitizeStrict,
+		"password": SanitizeNone,
+		"bio":      SanitizeRich,
+	}
+	if got := FieldPoliciesOf(&sanitizeTestModel{}); !reflect.DeepEqual(got, want) {
+		t.Errorf("FieldPoliciesOf() = %v, want %v", got, want)
+	}
+	if got := FieldPoliciesOf(nil); len(got) != 0 {
+		t.Errorf("FieldPoliciesOf(nil) = %v, want empty", got)
+	}
+}
+
+func TestFieldPoliciesSanitize(t *testing.T) {
+	policies := FieldPoliciesOf(sanitizeTestModel{})
+
+	// Массив объектов — как в bulk-запросах
+	input := []interface{}{
+		map[string]interface{}{
+			"name":     "<b>O'Brien</b>",
+			"email":    "<i>x@example.com</i>",
+			"password": "<secret>",
+			"unknown":  " padded ",
+		},
+	}
+	want := []interface{}{
+		map[string]interface{}{
+			"name":     "<b>O'Brien</b>",
+			"email":    "x@example.com",
+			"password": "<secret>",
+			"unknown":  "padded",
+		},
+	}
+
+	if got := policies.Sanitize(input); !reflect.DeepEqual(got, want) {
+		t.Errorf("Sanitize() = %v, want %v", got, want)
+	}
+}